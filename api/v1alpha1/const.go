@@ -24,10 +24,20 @@ const (
 	ConditionReasonBackOffUpscale = "BackoffUpscale"
 	// ConditionReasonBackOff Condition when scaling is forbidden
 	ConditionReasonBackOff = "BackoffBoth"
+	// ConditionReasonScaleVetoed Condition when the scale approval webhook did not approve the scale of the target
+	ConditionReasonScaleVetoed = "ScaleVetoed"
+	// ConditionReasonWithinTolerance Condition when the target is not scaled since the metrics are within their watermarks
+	ConditionReasonWithinTolerance = "WithinTolerance"
+	// ConditionReasonForbiddenWindow Condition when the target is not scaled since the previous scale is too recent
+	ConditionReasonForbiddenWindow = "ForbiddenWindow"
 	// ConditionReasonFailedGetExternalMetrics Condition when the External Metrics Server does not serve a metric
 	ConditionReasonFailedGetExternalMetrics = "FailedGetExternalMetric"
 	// ConditionReasonFailedGetResourceMetric Condition when the Resource Metrics Server does not serve a metric
 	ConditionReasonFailedGetResourceMetric = "FailedGetResourceMetric"
+	// ConditionReasonFailedGetObjectMetric Condition when the Custom Metrics Server does not serve a metric
+	ConditionReasonFailedGetObjectMetric = "FailedGetObjectMetric"
+	// ConditionReasonFailedGetPodsMetric Condition when the Custom Metrics Server does not serve a metric of the pods
+	ConditionReasonFailedGetPodsMetric = "FailedGetPodsMetric"
 	// ConditionValidMetricFound Condition when a valid metric is retrieved
 	ConditionValidMetricFound = "ValidMetricFound"
 	// ReasonFailedSpecCheck Reason when the spec of the WPA is incorrect
@@ -36,6 +46,20 @@ const (
 	ReasonNotScaling = "NotScaling"
 	// ReasonScaling Reason when scaling
 	ReasonScaling = "Scaling"
+	// ReasonScaledUp Reason when the target was scaled up
+	ReasonScaledUp = "ScaledUp"
+	// ReasonScaledDown Reason when the target was scaled down
+	ReasonScaledDown = "ScaledDown"
+	// ReasonWithinBounds Reason when the metrics are within the watermarks and the replicas are kept
+	ReasonWithinBounds = "WithinBounds"
+	// ReasonScaleVetoed Reason when the scale approval webhook did not approve the scale of the target
+	ReasonScaleVetoed = "ScaleVetoed"
+	// ReasonDryRun Reason when the target would have been scaled if the dry-run mode was disabled
+	ReasonDryRun = "DryRun"
+	// ReasonMetricUnavailable Reason when a metric can't be used to compute the replica count
+	ReasonMetricUnavailable = "MetricUnavailable"
+	// ReasonInvalidWatermarkSource Reason when the watermarks of a metric can't be read from its watermarkSource
+	ReasonInvalidWatermarkSource = "InvalidWatermarkSource"
 	// ReasonFailedScale Reason when unable to scale
 	ReasonFailedScale = "FailedScale"
 	// ReasonFailedUpdateReplicasStatus Reason when unable to scale and update the target's status
@@ -44,4 +68,53 @@ const (
 	ReasonFailedUpdateStatus = "FailedUpdateStatus"
 	// ReasonFailedProcessWPA Reason when the WPA can't be processed
 	ReasonFailedProcessWPA = "FailedProcessWPA"
+	// ReasonScalingBlockedUnhealthy Reason when the target is not scaled because too few of its pods are ready
+	ReasonScalingBlockedUnhealthy = "ScalingBlockedUnhealthy"
 )
+
+// Reasons of the last scaling decision, reported in the LastDecisionReason of the status.
+const (
+	// DecisionReasonWithinTolerance Reason when the metrics are within the watermarks and their tolerance
+	DecisionReasonWithinTolerance = "WithinTolerance"
+	// DecisionReasonAboveHighWatermark Reason when a metric is above its high watermark
+	DecisionReasonAboveHighWatermark = "AboveHighWatermark"
+	// DecisionReasonBelowLowWatermark Reason when the metrics are below their low watermark
+	DecisionReasonBelowLowWatermark = "BelowLowWatermark"
+	// DecisionReasonBelowIdleWatermark Reason when the metrics are below their idle watermark
+	DecisionReasonBelowIdleWatermark = "BelowIdleWatermark"
+	// DecisionReasonRatioUndefined Reason when the denominator of a ratio of external metrics is zero, the current number of replicas is kept
+	DecisionReasonRatioUndefined = "RatioUndefined"
+	// DecisionReasonClampedToMax Reason when the number of replicas is capped by maxReplicas
+	DecisionReasonClampedToMax = "ClampedToMax"
+	// DecisionReasonClampedToMin Reason when the number of replicas is raised to minReplicas
+	DecisionReasonClampedToMin = "ClampedToMin"
+	// DecisionReasonScaleLimited Reason when the change of replicas is capped by the scale up or scale down limits
+	DecisionReasonScaleLimited = "ScaleLimited"
+	// DecisionReasonInCooldown Reason when the last scaling event is still within the forbidden window
+	DecisionReasonInCooldown = "InCooldown"
+	// DecisionReasonBreachDelayed Reason when the breach of the watermarks didn't last for the delay counts or the delays yet
+	DecisionReasonBreachDelayed = "BreachDelayed"
+	// DecisionReasonStabilized Reason when the recommendation is held back by the stabilization windows
+	DecisionReasonStabilized = "Stabilized"
+	// DecisionReasonDirectionBlocked Reason when the recommendation goes in a direction disallowed by the scaleDirection
+	DecisionReasonDirectionBlocked = "DirectionBlocked"
+	// DecisionReasonUnhealthy Reason when the recommendation is held back because fewer pods than the minReadyPercentage are ready
+	DecisionReasonUnhealthy = "Unhealthy"
+	// DecisionReasonBelowMinReplicaChange Reason when the recommendation is held back because it differs from the current number of replicas by less than the minReplicaChange
+	DecisionReasonBelowMinReplicaChange = "BelowMinReplicaChange"
+	// DecisionReasonMetricStale Reason when none of the metrics can be used and at least one of them is stale
+	DecisionReasonMetricStale = "MetricStale"
+	// DecisionReasonMetricUnavailable Reason when none of the metrics can be retrieved
+	DecisionReasonMetricUnavailable = "MetricUnavailable"
+	// DecisionReasonScalingDisabled Reason when the target was scaled to zero replicas manually
+	DecisionReasonScalingDisabled = "ScalingDisabled"
+	// DecisionReasonDryRun Reason when the scaling decision is not applied because of the dry-run mode
+	DecisionReasonDryRun = "DryRun"
+	// DecisionReasonScaleVetoed Reason when the scaling decision is not applied because the scale approval webhook did not approve it
+	DecisionReasonScaleVetoed = "ScaleVetoed"
+	// DecisionReasonPaused Reason when no scaling decision is made because the WPA is paused with the PausedAnnotationKey annotation
+	DecisionReasonPaused = "Paused"
+)
+
+// PausedAnnotationKey is the annotation pausing the scaling of a WPA when set to "true", its metrics are still computed.
+const PausedAnnotationKey = "wpa.datadoghq.com/paused"
@@ -7,6 +7,7 @@ package v1alpha1
 
 import (
 	"fmt"
+	"net/url"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 )
@@ -20,6 +21,18 @@ const (
 	// Most common use case is to autoscale over avg:kubernetes.cpu.usage, which directly correlates to the # replicas.
 	defaultAlgorithm         = "absolute"
 	defaultMinReplicas int32 = 1
+	// Shorter intervals would put too much pressure on the metrics providers.
+	minReconcileIntervalSeconds = 5
+)
+
+// MaxMetricCacheTTLSeconds is the longest time the values of the external metrics can be cached for.
+const MaxMetricCacheTTLSeconds = 300
+
+const (
+	// DefaultRecommendationHistoryLimit is the number of recommendations kept in the status when the limit is unset.
+	DefaultRecommendationHistoryLimit = 10
+	// MaxRecommendationHistoryLimit is the largest number of recommendations kept in the status, to cap its size.
+	MaxRecommendationHistoryLimit = 50
 )
 
 // DefaultWatermarkPodAutoscaler sets the default in the WPA
@@ -88,9 +101,66 @@ func CheckWPAValidity(wpa *WatermarkPodAutoscaler) error {
 		msg := fmt.Sprintf("watermark pod autoscaler requires the minimum number of replicas to be configured and inferior to the maximum")
 		return fmt.Errorf(msg)
 	}
+	if *wpa.Spec.MinReplicas == 0 && !wpa.Spec.ScaleDownToZeroEnabled {
+		return fmt.Errorf("minReplicas can only be set to 0 when scaleDownToZeroEnabled is true")
+	}
 	if wpa.Spec.Tolerance.MilliValue() > 1000 || wpa.Spec.Tolerance.MilliValue() < 0 {
 		return fmt.Errorf("tolerance should be set as a quantity between 0 and 1, currently set to : %v, which is %.0f%%", wpa.Spec.Tolerance.String(), float64(wpa.Spec.Tolerance.MilliValue())/10)
 	}
+	if wpa.Spec.UpscaleTolerance != nil && (wpa.Spec.UpscaleTolerance.MilliValue() > 1000 || wpa.Spec.UpscaleTolerance.MilliValue() < 0) {
+		return fmt.Errorf("upscaletolerance should be set as a quantity between 0 and 1, currently set to : %v, which is %.0f%%", wpa.Spec.UpscaleTolerance.String(), float64(wpa.Spec.UpscaleTolerance.MilliValue())/10)
+	}
+	if wpa.Spec.DownscaleTolerance != nil && (wpa.Spec.DownscaleTolerance.MilliValue() > 1000 || wpa.Spec.DownscaleTolerance.MilliValue() < 0) {
+		return fmt.Errorf("downscaletolerance should be set as a quantity between 0 and 1, currently set to : %v, which is %.0f%%", wpa.Spec.DownscaleTolerance.String(), float64(wpa.Spec.DownscaleTolerance.MilliValue())/10)
+	}
+	if wpa.Spec.SmoothingFactor != nil && (wpa.Spec.SmoothingFactor.MilliValue() > 1000 || wpa.Spec.SmoothingFactor.MilliValue() <= 0) {
+		return fmt.Errorf("smoothingFactor should be set as a quantity between 0 (exc.) and 1, currently set to : %v", wpa.Spec.SmoothingFactor.String())
+	}
+	if !isValidAlgorithm(wpa.Spec.Algorithm) {
+		return fmt.Errorf("algorithm should be either absolute, average, averageByRequest, count or logarithmic, currently set to : %s", wpa.Spec.Algorithm)
+	}
+	if !isValidAlgorithm(wpa.Spec.UpscaleAlgorithm) {
+		return fmt.Errorf("upscaleAlgorithm should be either absolute, average, averageByRequest, count or logarithmic, currently set to : %s", wpa.Spec.UpscaleAlgorithm)
+	}
+	if !isValidAlgorithm(wpa.Spec.DownscaleAlgorithm) {
+		return fmt.Errorf("downscaleAlgorithm should be either absolute, average, averageByRequest, count or logarithmic, currently set to : %s", wpa.Spec.DownscaleAlgorithm)
+	}
+	if !isValidToleranceMode(wpa.Spec.ToleranceMode) {
+		return fmt.Errorf("toleranceMode should be either multiplicative or band, currently set to : %s", wpa.Spec.ToleranceMode)
+	}
+	if !isValidReplicaRounding(wpa.Spec.ReplicaRounding) {
+		return fmt.Errorf("replicaRounding should be either legacy, ceil, floor or nearest, currently set to : %s", wpa.Spec.ReplicaRounding)
+	}
+	if !isValidMetricAggregation(wpa.Spec.MetricAggregation) {
+		return fmt.Errorf("metricAggregation should be either max or weighted-sum, currently set to : %s", wpa.Spec.MetricAggregation)
+	}
+	if !isValidMetricErrorPolicy(wpa.Spec.MetricErrorPolicy) {
+		return fmt.Errorf("metricErrorPolicy should be either maintain, scaleToMin or lastKnownGood, currently set to : %s", wpa.Spec.MetricErrorPolicy)
+	}
+	if !isValidScaleDirection(wpa.Spec.ScaleDirection) {
+		return fmt.Errorf("scaleDirection should be either both, up or down, currently set to : %s", wpa.Spec.ScaleDirection)
+	}
+	if !isValidScaleApprovalWebhookURL(wpa.Spec.ScaleApprovalWebhookURL) {
+		return fmt.Errorf("scaleApprovalWebhookURL should be an absolute http or https URL, currently set to : %s", wpa.Spec.ScaleApprovalWebhookURL)
+	}
+	if wpa.Spec.ReconcileIntervalSeconds != 0 && wpa.Spec.ReconcileIntervalSeconds < minReconcileIntervalSeconds {
+		return fmt.Errorf("reconcileIntervalSeconds should be at least %d seconds, currently set to : %d", minReconcileIntervalSeconds, wpa.Spec.ReconcileIntervalSeconds)
+	}
+	if wpa.Spec.MetricCacheTTLSeconds < 0 || wpa.Spec.MetricCacheTTLSeconds > MaxMetricCacheTTLSeconds {
+		return fmt.Errorf("metricCacheTTLSeconds should be between 0 and %d seconds, currently set to : %d", MaxMetricCacheTTLSeconds, wpa.Spec.MetricCacheTTLSeconds)
+	}
+	if wpa.Spec.MinReadyPercentage < 0 || wpa.Spec.MinReadyPercentage > 100 {
+		return fmt.Errorf("minReadyPercentage should be between 0 and 100, currently set to : %d", wpa.Spec.MinReadyPercentage)
+	}
+	if wpa.Spec.InitialReadinessDelaySeconds < 0 {
+		return fmt.Errorf("initialReadinessDelaySeconds should be positive, currently set to : %d", wpa.Spec.InitialReadinessDelaySeconds)
+	}
+	if wpa.Spec.MinReplicaChange < 0 {
+		return fmt.Errorf("minReplicaChange should be positive, currently set to : %d", wpa.Spec.MinReplicaChange)
+	}
+	if wpa.Spec.RecommendationHistoryLimit < 0 || wpa.Spec.RecommendationHistoryLimit > MaxRecommendationHistoryLimit {
+		return fmt.Errorf("recommendationHistoryLimit should be between 0 and %d, currently set to : %d", MaxRecommendationHistoryLimit, wpa.Spec.RecommendationHistoryLimit)
+	}
 	if wpa.Spec.ScaleUpLimitFactor == nil || wpa.Spec.ScaleDownLimitFactor == nil {
 		return fmt.Errorf("scaleuplimitfactor and scaledownlimitfactor can't be nil, make sure the WPA spec is defaulted")
 	}
@@ -100,6 +170,9 @@ func CheckWPAValidity(wpa *WatermarkPodAutoscaler) error {
 	if wpa.Spec.ScaleDownLimitFactor.MilliValue() >= 100000 || wpa.Spec.ScaleDownLimitFactor.MilliValue() < 0 {
 		return fmt.Errorf("scaledownlimitfactor should be set as a quantity between 0 and 100 (exc.), currently set to : %v, which could yield a %.0f%% decrease", wpa.Spec.ScaleDownLimitFactor.String(), float64(wpa.Spec.ScaleDownLimitFactor.MilliValue())/1000)
 	}
+	if err := checkWatermarkScheduleValidity(wpa); err != nil {
+		return err
+	}
 	return checkWPAMetricsValidity(wpa)
 }
 
@@ -108,12 +181,34 @@ func checkWPAMetricsValidity(wpa *WatermarkPodAutoscaler) (err error) {
 	// For now we check only nil pointers here as they crash the default controller algorithm
 	// We also make sure that the Watermarks are properly set.
 	for _, metric := range wpa.Spec.Metrics {
+		if metric.Weight != nil && metric.Weight.MilliValue() <= 0 {
+			return fmt.Errorf("the weight of a %s metric has to be strictly positive, currently set to : %v", metric.Type, metric.Weight.String())
+		}
+		if metric.AllowScaleUp != nil && !*metric.AllowScaleUp && metric.AllowScaleDown != nil && !*metric.AllowScaleDown {
+			return fmt.Errorf("a %s metric can't disallow both scaling up and scaling down", metric.Type)
+		}
+		if metric.WatermarkSource != nil && metric.WatermarkSource.ConfigMapName == "" {
+			return fmt.Errorf("the watermarkSource of a %s metric has to reference a ConfigMap", metric.Type)
+		}
+		if metric.WatermarkSource != nil && metric.External != nil && metric.External.TargetValue != nil {
+			return fmt.Errorf("the watermarkSource of External metric %s can't be set along with targetValue", metric.External.MetricName)
+		}
 		switch metric.Type {
 		case "External":
 			if metric.External == nil {
 				return fmt.Errorf("metric.External is nil while metric.Type is '%s'", metric.Type)
 			}
-			if metric.External.LowWatermark == nil || metric.External.HighWatermark == nil {
+			if metric.External.TargetValue != nil && (metric.External.LowWatermark != nil || metric.External.HighWatermark != nil) {
+				return fmt.Errorf("targetValue of External metric %s can't be set along with the watermarks", metric.External.MetricName)
+			}
+			if metric.External.TargetValue != nil && metric.External.TargetValue.MilliValue() <= 0 {
+				return fmt.Errorf("targetValue of External metric %s has to be strictly positive", metric.External.MetricName)
+			}
+			if metric.External.TargetValue != nil && (metric.External.DeadbandPercent < 1 || metric.External.DeadbandPercent > 100) {
+				return fmt.Errorf("deadbandPercent of External metric %s should be between 1 and 100, currently set to : %d", metric.External.MetricName, metric.External.DeadbandPercent)
+			}
+			lowMark, highMark := metric.External.GetWatermarks()
+			if lowMark == nil || highMark == nil {
 				msg := fmt.Sprintf("Watermarks are not set correctly, removing the WPA %s/%s from the Reconciler", wpa.Namespace, wpa.Name)
 				return fmt.Errorf(msg)
 			}
@@ -121,10 +216,34 @@ func checkWPAMetricsValidity(wpa *WatermarkPodAutoscaler) (err error) {
 				msg := fmt.Sprintf("Missing Labels for the External metric %s", metric.External.MetricName)
 				return fmt.Errorf(msg)
 			}
-			if metric.External.HighWatermark.MilliValue() < metric.External.LowWatermark.MilliValue() {
+			if highMark.MilliValue() < lowMark.MilliValue() {
 				msg := fmt.Sprintf("Low WaterMark of External metric %s{%s} has to be strictly inferior to the High Watermark", metric.External.MetricName, metric.External.MetricSelector.MatchLabels)
 				return fmt.Errorf(msg)
 			}
+			if metric.External.IdleWatermark != nil && metric.External.IdleWatermark.MilliValue() >= lowMark.MilliValue() {
+				return fmt.Errorf("idleWatermark of External metric %s{%s} has to be strictly inferior to the Low Watermark", metric.External.MetricName, metric.External.MetricSelector.MatchLabels)
+			}
+			if metric.External.PerReplicaCapacity != nil && metric.External.PerReplicaCapacity.MilliValue() <= 0 {
+				return fmt.Errorf("perReplicaCapacity of External metric %s{%s} has to be strictly positive", metric.External.MetricName, metric.External.MetricSelector.MatchLabels)
+			}
+			if metric.External.MaxUtilization != nil && metric.External.MaxUtilization.MilliValue() <= highMark.MilliValue() {
+				return fmt.Errorf("maxUtilization of External metric %s{%s} has to be strictly superior to the High Watermark", metric.External.MetricName, metric.External.MetricSelector.MatchLabels)
+			}
+			if !isValidAlgorithm(metric.External.Algorithm) {
+				return fmt.Errorf("algorithm of External metric %s{%s} should be either absolute, average, averageByRequest, count or logarithmic, currently set to : %s", metric.External.MetricName, metric.External.MetricSelector.MatchLabels, metric.External.Algorithm)
+			}
+			if !isValidTargetType(metric.External.TargetType) {
+				return fmt.Errorf("targetType of External metric %s{%s} should be either AverageValue or Value, currently set to : %s", metric.External.MetricName, metric.External.MetricSelector.MatchLabels, metric.External.TargetType)
+			}
+			if !isValidAggregatorFunc(metric.External.AggregatorFunc) {
+				return fmt.Errorf("aggregatorFunc of External metric %s{%s} should be one of sum, avg, max, min, p50, p90, p95 or p99, currently set to : %s", metric.External.MetricName, metric.External.MetricSelector.MatchLabels, metric.External.AggregatorFunc)
+			}
+			if metric.External.Tolerance != nil && (metric.External.Tolerance.MilliValue() > 1000 || metric.External.Tolerance.MilliValue() < 0) {
+				return fmt.Errorf("tolerance of External metric %s{%s} should be set as a quantity between 0 and 1, currently set to : %v, which is %.0f%%", metric.External.MetricName, metric.External.MetricSelector.MatchLabels, metric.External.Tolerance.String(), float64(metric.External.Tolerance.MilliValue())/10)
+			}
+			if metric.External.DenominatorMetricName != "" && (len(metric.External.Weights) > 0 || (metric.External.AggregatorFunc != "" && metric.External.AggregatorFunc != "sum")) {
+				return fmt.Errorf("denominatorMetricName of External metric %s{%s} can only be set with the sum aggregatorFunc and without weights", metric.External.MetricName, metric.External.MetricSelector.MatchLabels)
+			}
 		case "Resource":
 			if metric.Resource == nil {
 				return fmt.Errorf("metric.Resource is nil while metric.Type is '%s'", metric.Type)
@@ -141,9 +260,213 @@ func checkWPAMetricsValidity(wpa *WatermarkPodAutoscaler) (err error) {
 				msg := fmt.Sprintf("Low WaterMark of Resource metric %s{%s} has to be strictly inferior to the High Watermark", metric.Resource.Name, metric.Resource.MetricSelector.MatchLabels)
 				return fmt.Errorf(msg)
 			}
+			if !isValidResourceTargetType(metric.Resource.TargetType) {
+				return fmt.Errorf("targetType of Resource metric %s{%s} should be Utilization, currently set to : %s", metric.Resource.Name, metric.Resource.MetricSelector.MatchLabels, metric.Resource.TargetType)
+			}
+			if metric.Resource.TargetType == "Utilization" && (!isValidPercentage(metric.Resource.LowWatermark) || !isValidPercentage(metric.Resource.HighWatermark)) {
+				return fmt.Errorf("watermarks of Resource metric %s{%s} should be percentages between 0 and 100 with the Utilization targetType, currently set to : %s and %s", metric.Resource.Name, metric.Resource.MetricSelector.MatchLabels, metric.Resource.LowWatermark.String(), metric.Resource.HighWatermark.String())
+			}
+			if metric.Resource.Tolerance != nil && (metric.Resource.Tolerance.MilliValue() > 1000 || metric.Resource.Tolerance.MilliValue() < 0) {
+				return fmt.Errorf("tolerance of Resource metric %s{%s} should be set as a quantity between 0 and 1, currently set to : %v, which is %.0f%%", metric.Resource.Name, metric.Resource.MetricSelector.MatchLabels, metric.Resource.Tolerance.String(), float64(metric.Resource.Tolerance.MilliValue())/10)
+			}
+		case "Object":
+			if metric.Object == nil {
+				return fmt.Errorf("metric.Object is nil while metric.Type is '%s'", metric.Type)
+			}
+			if metric.Object.LowWatermark == nil || metric.Object.HighWatermark == nil {
+				msg := fmt.Sprintf("Watermarks are not set correctly, removing the WPA %s/%s from the Reconciler", wpa.Namespace, wpa.Name)
+				return fmt.Errorf(msg)
+			}
+			if metric.Object.DescribedObject.Kind == "" || metric.Object.DescribedObject.Name == "" {
+				return fmt.Errorf("the describedObject of the Object metric %s should be populated, currently Kind:%s and/or Name:%s are not set properly", metric.Object.MetricName, metric.Object.DescribedObject.Kind, metric.Object.DescribedObject.Name)
+			}
+			if metric.Object.HighWatermark.MilliValue() < metric.Object.LowWatermark.MilliValue() {
+				return fmt.Errorf("Low WaterMark of Object metric %s{%s/%s} has to be strictly inferior to the High Watermark", metric.Object.MetricName, metric.Object.DescribedObject.Kind, metric.Object.DescribedObject.Name)
+			}
+			if metric.Object.IdleWatermark != nil && metric.Object.IdleWatermark.MilliValue() >= metric.Object.LowWatermark.MilliValue() {
+				return fmt.Errorf("idleWatermark of Object metric %s{%s/%s} has to be strictly inferior to the Low Watermark", metric.Object.MetricName, metric.Object.DescribedObject.Kind, metric.Object.DescribedObject.Name)
+			}
+			if metric.Object.Tolerance != nil && (metric.Object.Tolerance.MilliValue() > 1000 || metric.Object.Tolerance.MilliValue() < 0) {
+				return fmt.Errorf("tolerance of Object metric %s{%s/%s} should be set as a quantity between 0 and 1, currently set to : %v, which is %.0f%%", metric.Object.MetricName, metric.Object.DescribedObject.Kind, metric.Object.DescribedObject.Name, metric.Object.Tolerance.String(), float64(metric.Object.Tolerance.MilliValue())/10)
+			}
+		case "Pods":
+			if metric.Pods == nil {
+				return fmt.Errorf("metric.Pods is nil while metric.Type is '%s'", metric.Type)
+			}
+			if metric.Pods.LowWatermark == nil || metric.Pods.HighWatermark == nil {
+				msg := fmt.Sprintf("Watermarks are not set correctly, removing the WPA %s/%s from the Reconciler", wpa.Namespace, wpa.Name)
+				return fmt.Errorf(msg)
+			}
+			if metric.Pods.HighWatermark.MilliValue() < metric.Pods.LowWatermark.MilliValue() {
+				return fmt.Errorf("Low WaterMark of Pods metric %s has to be strictly inferior to the High Watermark", metric.Pods.MetricName)
+			}
+			if metric.Pods.Tolerance != nil && (metric.Pods.Tolerance.MilliValue() > 1000 || metric.Pods.Tolerance.MilliValue() < 0) {
+				return fmt.Errorf("tolerance of Pods metric %s should be set as a quantity between 0 and 1, currently set to : %v, which is %.0f%%", metric.Pods.MetricName, metric.Pods.Tolerance.String(), float64(metric.Pods.Tolerance.MilliValue())/10)
+			}
 		default:
 			return fmt.Errorf("incorrect metric.Type: '%s'", metric.Type)
 		}
 	}
 	return err
 }
+
+// algorithms are the ways the value of a metric can be compared to its watermarks.
+var algorithms = []string{"absolute", "average", "averageByRequest", "count", "logarithmic"}
+
+// isValidAlgorithm returns whether the algorithm is supported, an empty algorithm falls back to the default one.
+func isValidAlgorithm(algorithm string) bool {
+	if algorithm == "" {
+		return true
+	}
+	for _, supported := range algorithms {
+		if algorithm == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// targetTypes are the ways the value of an external metric can be compared to its watermarks.
+var targetTypes = []string{"AverageValue", "Value"}
+
+// isValidTargetType returns whether the target type is supported, an empty one falls back to the algorithm.
+func isValidTargetType(targetType string) bool {
+	if targetType == "" {
+		return true
+	}
+	for _, supported := range targetTypes {
+		if targetType == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceTargetTypes are the ways the usage of a resource metric can be compared to its watermarks besides the absolute one.
+var resourceTargetTypes = []string{"Utilization"}
+
+// isValidResourceTargetType returns whether the target type of a resource metric is supported, an empty one keeps absolute watermarks.
+func isValidResourceTargetType(targetType string) bool {
+	if targetType == "" {
+		return true
+	}
+	for _, supported := range resourceTargetTypes {
+		if targetType == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidPercentage returns whether the quantity is a percentage between 0 and 100.
+func isValidPercentage(q *resource.Quantity) bool {
+	return q.MilliValue() >= 0 && q.MilliValue() <= 100000
+}
+
+// aggregatorFuncs are the functions that can combine the values of an external metric.
+var aggregatorFuncs = []string{"sum", "avg", "max", "min", "p50", "p90", "p95", "p99"}
+
+// isValidAggregatorFunc returns whether the aggregator function is supported, an empty one falls back to sum.
+func isValidAggregatorFunc(fn string) bool {
+	if fn == "" {
+		return true
+	}
+	for _, supported := range aggregatorFuncs {
+		if fn == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// toleranceModes are the ways the tolerance widens the watermarks.
+var toleranceModes = []string{"multiplicative", "band"}
+
+// isValidToleranceMode returns whether the tolerance mode is supported, an empty one falls back to multiplicative.
+func isValidToleranceMode(mode string) bool {
+	if mode == "" {
+		return true
+	}
+	for _, supported := range toleranceModes {
+		if mode == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// replicaRoundings are the ways the fractional number of replicas recommended can be rounded.
+var replicaRoundings = []string{"legacy", "ceil", "floor", "nearest"}
+
+// isValidReplicaRounding returns whether the rounding is supported, an empty one falls back to legacy.
+func isValidReplicaRounding(rounding string) bool {
+	if rounding == "" {
+		return true
+	}
+	for _, supported := range replicaRoundings {
+		if rounding == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// metricAggregations are the ways the recommendations of the metrics can be combined.
+var metricAggregations = []string{"max", "weighted-sum"}
+
+// isValidMetricAggregation returns whether the aggregation is supported, an empty one falls back to max.
+func isValidMetricAggregation(aggregation string) bool {
+	if aggregation == "" {
+		return true
+	}
+	for _, supported := range metricAggregations {
+		if aggregation == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// metricErrorPolicies are the ways a WPA can scale when none of its metrics can be retrieved.
+var metricErrorPolicies = []string{"maintain", "scaleToMin", "lastKnownGood"}
+
+// isValidMetricErrorPolicy returns whether the policy is supported, an empty one falls back to maintain.
+func isValidMetricErrorPolicy(policy string) bool {
+	if policy == "" {
+		return true
+	}
+	for _, supported := range metricErrorPolicies {
+		if policy == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidScaleApprovalWebhookURL returns whether the URL is an absolute http or https URL, an empty one disables the approval.
+func isValidScaleApprovalWebhookURL(rawURL string) bool {
+	if rawURL == "" {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// scaleDirections are the directions in which a WPA can be allowed to scale its target.
+var scaleDirections = []string{"both", "up", "down"}
+
+// isValidScaleDirection returns whether the direction is supported, an empty one falls back to both.
+func isValidScaleDirection(direction string) bool {
+	if direction == "" {
+		return true
+	}
+	for _, supported := range scaleDirections {
+		if direction == supported {
+			return true
+		}
+	}
+	return false
+}
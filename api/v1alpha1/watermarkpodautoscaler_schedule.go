@@ -0,0 +1,114 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// scheduleDays are the days of the week that can be used in the days of a WatermarkScheduleEntry.
+var scheduleDays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// IsActive returns whether the window of the entry contains the given time, in the location of the time.
+func (e *WatermarkScheduleEntry) IsActive(now time.Time) (bool, error) {
+	days, err := parseScheduleDays(e.Days)
+	if err != nil {
+		return false, err
+	}
+	start, err := parseScheduleTime(e.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := parseScheduleTime(e.End)
+	if err != nil {
+		return false, err
+	}
+	minutes := now.Hour()*60 + now.Minute()
+	weekday := now.Weekday()
+	if start < end {
+		return days[weekday] && minutes >= start && minutes < end, nil
+	}
+	// the window spans midnight, it started either today or the day before.
+	yesterday := (weekday + 6) % 7
+	return (days[weekday] && minutes >= start) || (days[yesterday] && minutes < end), nil
+}
+
+// parseScheduleDays returns the days of the week matched by a comma-separated list of days or ranges of days
+// (e.g. Mon-Fri or Sat,Sun), every day is matched when it is empty.
+func parseScheduleDays(value string) ([7]bool, error) {
+	var days [7]bool
+	if value == "" {
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	}
+	for _, item := range strings.Split(value, ",") {
+		bounds := strings.Split(strings.TrimSpace(item), "-")
+		if len(bounds) > 2 {
+			return days, fmt.Errorf("invalid range of days %q", item)
+		}
+		first, ok := scheduleDays[strings.ToLower(bounds[0])]
+		if !ok {
+			return days, fmt.Errorf("invalid day %q, should be one of Sun, Mon, Tue, Wed, Thu, Fri or Sat", bounds[0])
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, ok = scheduleDays[strings.ToLower(bounds[1])]; !ok {
+				return days, fmt.Errorf("invalid day %q, should be one of Sun, Mon, Tue, Wed, Thu, Fri or Sat", bounds[1])
+			}
+		}
+		// a range can wrap around the end of the week, e.g. Fri-Mon.
+		for day := first; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseScheduleTime returns the number of minutes since midnight of a time formatted as HH:MM.
+func parseScheduleTime(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, should be formatted as HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// checkWatermarkScheduleValidity returns an error if one of the entries of the watermarkSchedule can't be evaluated.
+func checkWatermarkScheduleValidity(wpa *WatermarkPodAutoscaler) error {
+	if len(wpa.Spec.WatermarkSchedule) == 0 {
+		return nil
+	}
+	if _, err := time.LoadLocation(wpa.Spec.WatermarkScheduleTimezone); err != nil {
+		return fmt.Errorf("watermarkScheduleTimezone should be a valid timezone, currently set to : %s", wpa.Spec.WatermarkScheduleTimezone)
+	}
+	for i, entry := range wpa.Spec.WatermarkSchedule {
+		if _, err := entry.IsActive(time.Now()); err != nil {
+			return fmt.Errorf("the window of the entry %d of the watermarkSchedule is invalid: %v", i, err)
+		}
+		if entry.LowWatermark == nil && entry.HighWatermark == nil {
+			return fmt.Errorf("the entry %d of the watermarkSchedule should override the highWatermark, the lowWatermark or both", i)
+		}
+		if entry.LowWatermark != nil && entry.HighWatermark != nil && entry.LowWatermark.MilliValue() >= entry.HighWatermark.MilliValue() {
+			return fmt.Errorf("the lowWatermark of the entry %d of the watermarkSchedule has to be strictly inferior to its highWatermark", i)
+		}
+	}
+	return nil
+}
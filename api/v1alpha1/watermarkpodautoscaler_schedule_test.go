@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatermarkScheduleEntryIsActive(t *testing.T) {
+	// 2020-06-01 is a Monday.
+	monday := func(hour, minute int) time.Time {
+		return time.Date(2020, time.June, 1, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		entry    WatermarkScheduleEntry
+		now      time.Time
+		expected bool
+	}{
+		{
+			name:     "within the window",
+			entry:    WatermarkScheduleEntry{Start: "09:00", End: "18:00"},
+			now:      monday(12, 0),
+			expected: true,
+		},
+		{
+			name:     "start of the window",
+			entry:    WatermarkScheduleEntry{Start: "09:00", End: "18:00"},
+			now:      monday(9, 0),
+			expected: true,
+		},
+		{
+			name:     "end of the window",
+			entry:    WatermarkScheduleEntry{Start: "09:00", End: "18:00"},
+			now:      monday(18, 0),
+			expected: false,
+		},
+		{
+			name:     "day of the week",
+			entry:    WatermarkScheduleEntry{Days: "Mon-Fri", Start: "09:00", End: "18:00"},
+			now:      monday(12, 0),
+			expected: true,
+		},
+		{
+			name:     "other day of the week",
+			entry:    WatermarkScheduleEntry{Days: "Sat,Sun", Start: "09:00", End: "18:00"},
+			now:      monday(12, 0),
+			expected: false,
+		},
+		{
+			name:     "range of days wrapping around the week",
+			entry:    WatermarkScheduleEntry{Days: "fri-mon", Start: "09:00", End: "18:00"},
+			now:      monday(12, 0),
+			expected: true,
+		},
+		{
+			name:     "window spanning midnight, before midnight",
+			entry:    WatermarkScheduleEntry{Days: "Mon", Start: "22:00", End: "06:00"},
+			now:      monday(23, 0),
+			expected: true,
+		},
+		{
+			name:     "window spanning midnight, after midnight of the day before",
+			entry:    WatermarkScheduleEntry{Days: "Sun", Start: "22:00", End: "06:00"},
+			now:      monday(5, 59),
+			expected: true,
+		},
+		{
+			name:     "window spanning midnight, after midnight of the same day",
+			entry:    WatermarkScheduleEntry{Days: "Mon", Start: "22:00", End: "06:00"},
+			now:      monday(5, 59),
+			expected: false,
+		},
+		{
+			name:     "whole day",
+			entry:    WatermarkScheduleEntry{Days: "Mon", Start: "00:00", End: "00:00"},
+			now:      monday(12, 0),
+			expected: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active, err := tt.entry.IsActive(tt.now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, active)
+		})
+	}
+}
+
+func TestWatermarkScheduleEntryIsActiveInvalid(t *testing.T) {
+	for _, entry := range []WatermarkScheduleEntry{
+		{Days: "Monday", Start: "09:00", End: "18:00"},
+		{Days: "Mon-Wed-Fri", Start: "09:00", End: "18:00"},
+		{Start: "9h", End: "18:00"},
+		{Start: "09:00", End: "24:00"},
+	} {
+		_, err := entry.IsActive(time.Now())
+		assert.Error(t, err, "%+v", entry)
+	}
+}
@@ -0,0 +1,21 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// GetWatermarks returns the watermarks of the external metric. When its targetValue is set, they are derived from it:
+// targetValue * (1 - deadbandPercent / 100) and targetValue * (1 + deadbandPercent / 100), rounded to the milli-unit.
+func (m *ExternalMetricSource) GetWatermarks() (lowMark, highMark *resource.Quantity) {
+	if m.TargetValue == nil {
+		return m.LowWatermark, m.HighWatermark
+	}
+	target := m.TargetValue.MilliValue()
+	deadband := target * int64(m.DeadbandPercent) / 100
+	return resource.NewMilliQuantity(target-deadband, resource.DecimalSI), resource.NewMilliQuantity(target+deadband, resource.DecimalSI)
+}
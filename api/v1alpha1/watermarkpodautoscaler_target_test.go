@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestExternalMetricSourceGetWatermarks(t *testing.T) {
+	tests := []struct {
+		name         string
+		metric       ExternalMetricSource
+		expectedLow  int64
+		expectedHigh int64
+	}{
+		{
+			name: "watermarks",
+			metric: ExternalMetricSource{
+				LowWatermark:  resource.NewQuantity(60, resource.DecimalSI),
+				HighWatermark: resource.NewQuantity(80, resource.DecimalSI),
+			},
+			expectedLow:  60000,
+			expectedHigh: 80000,
+		},
+		{
+			name:         "target with a deadband",
+			metric:       ExternalMetricSource{TargetValue: resource.NewQuantity(70, resource.DecimalSI), DeadbandPercent: 10},
+			expectedLow:  63000,
+			expectedHigh: 77000,
+		},
+		{
+			name:         "fractional target",
+			metric:       ExternalMetricSource{TargetValue: resource.NewMilliQuantity(700, resource.DecimalSI), DeadbandPercent: 5},
+			expectedLow:  665,
+			expectedHigh: 735,
+		},
+		{
+			// the deadband is rounded down to the milli-unit.
+			name:         "rounded deadband",
+			metric:       ExternalMetricSource{TargetValue: resource.NewMilliQuantity(15, resource.DecimalSI), DeadbandPercent: 10},
+			expectedLow:  14,
+			expectedHigh: 16,
+		},
+		{
+			name:         "full deadband",
+			metric:       ExternalMetricSource{TargetValue: resource.NewQuantity(70, resource.DecimalSI), DeadbandPercent: 100},
+			expectedLow:  0,
+			expectedHigh: 140000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lowMark, highMark := tt.metric.GetWatermarks()
+			assert.Equal(t, tt.expectedLow, lowMark.MilliValue())
+			assert.Equal(t, tt.expectedHigh, highMark.MilliValue())
+		})
+	}
+}
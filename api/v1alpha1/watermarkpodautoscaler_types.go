@@ -22,6 +22,10 @@ import (
 // +kubebuilder:printcolumn:name="min replicas",type="integer",JSONPath=".spec.minReplicas"
 // +kubebuilder:printcolumn:name="max replicas",type="integer",JSONPath=".spec.maxReplicas"
 // +kubebuilder:printcolumn:name="dry-run",type="string",JSONPath=".spec.dryRun"
+// +kubebuilder:printcolumn:name="current replicas",type="integer",JSONPath=".status.currentReplicas"
+// +kubebuilder:printcolumn:name="desired replicas",type="integer",JSONPath=".status.desiredReplicas"
+// +kubebuilder:printcolumn:name="scaling metric",type="string",JSONPath=".status.scalingMetricName"
+// +kubebuilder:printcolumn:name="last scale",type="date",JSONPath=".status.lastScaleTime"
 // +kubebuilder:resource:path=watermarkpodautoscalers,shortName=wpa
 // +k8s:openapi-gen=true
 // +genclient
@@ -56,25 +60,187 @@ type WatermarkPodAutoscalerSpec struct {
 	// +kubebuilder:validation:Minimum=1
 	UpscaleForbiddenWindowSeconds int32 `json:"upscaleForbiddenWindowSeconds,omitempty"`
 
+	// Number of seconds of past recommendations considered before scaling down, the highest one is used.
+	// 0 disables the stabilization.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DownscaleStabilizationWindowSeconds int32 `json:"downscaleStabilizationWindowSeconds,omitempty"`
+
+	// Number of seconds of past recommendations considered before scaling up, the lowest one is used.
+	// 0 disables the stabilization.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	UpscaleStabilizationWindowSeconds int32 `json:"upscaleStabilizationWindowSeconds,omitempty"`
+
+	// Number of consecutive reconcile cycles recommending to scale down required before scaling down.
+	// 0 or 1 scales down as soon as the metrics are below the low watermark.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DownscaleDelayCount int32 `json:"downscaleDelayCount,omitempty"`
+
+	// Number of consecutive reconcile cycles recommending to scale up required before scaling up.
+	// 0 or 1 scales up as soon as the metrics are above the high watermark.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	UpscaleDelayCount int32 `json:"upscaleDelayCount,omitempty"`
+
+	// Number of seconds the metrics have to stay below the low watermark before scaling down.
+	// 0 scales down as soon as the metrics are below the low watermark.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DownscaleDelaySeconds int32 `json:"downscaleDelaySeconds,omitempty"`
+
+	// Number of seconds the metrics have to stay above the high watermark before scaling up.
+	// 0 scales up as soon as the metrics are above the high watermark.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	UpscaleDelaySeconds int32 `json:"upscaleDelaySeconds,omitempty"`
+
 	// Percentage of replicas that can be added in an upscale event.
 	// Parameter used to be a float, in order to support the transition seamlessly, we validate that it is [0;100] in the code.
 	// ScaleUpLimitFactor == 0 means that upscaling will not be allowed for the target.
 	ScaleUpLimitFactor *resource.Quantity `json:"scaleUpLimitFactor,omitempty"`
 
+	// Number of replicas that can be added in an upscale event, on top of the limit set by ScaleUpLimitFactor.
+	// 0 means that the number of replicas added is only limited by ScaleUpLimitFactor.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ScaleUpLimit int32 `json:"scaleUpLimit,omitempty"`
+
 	// Percentage of replicas that can be removed in an downscale event.
 	// Parameter used to be a float, in order to support the transition seamlessly, we validate that it is [0;100[ in the code.
 	// ScaleDownLimitFactor == 0 means that downscaling will not be allowed for the target.
 	ScaleDownLimitFactor *resource.Quantity `json:"scaleDownLimitFactor,omitempty"`
 
+	// Number of replicas that can be removed in a downscale event, on top of the limit set by ScaleDownLimitFactor.
+	// 0 means that the number of replicas removed is only limited by ScaleDownLimitFactor.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ScaleDownLimit int32 `json:"scaleDownLimit,omitempty"`
+
 	// Parameter used to be a float, in order to support the transition seamlessly, we validate that it is ]0;1[ in the code.
 	Tolerance resource.Quantity `json:"tolerance,omitempty"`
 
+	// Tolerance applied above the high watermark, takes precedence over Tolerance when set.
+	// We validate that it is [0;1] in the code.
+	// +optional
+	UpscaleTolerance *resource.Quantity `json:"upscaleTolerance,omitempty"`
+
+	// Tolerance applied below the low watermark, takes precedence over Tolerance when set.
+	// We validate that it is [0;1] in the code.
+	// +optional
+	DownscaleTolerance *resource.Quantity `json:"downscaleTolerance,omitempty"`
+
+	// How the tolerance is applied to the watermarks.
+	// Either multiplicative (default) to apply it as a percentage of each watermark,
+	// or band to apply it as a percentage of the band between the low and the high watermarks.
+	// +optional
+	ToleranceMode string `json:"toleranceMode,omitempty"`
+
+	// How the fractional number of replicas recommended when the metrics are beyond the watermarks is rounded.
+	// Either legacy (default) to round up above the high watermark and down below the low watermark,
+	// ceil, floor or nearest to use the same rounding in both directions.
+	// +optional
+	ReplicaRounding string `json:"replicaRounding,omitempty"`
+
+	// Weight of the new value of the metrics in the exponential moving average of their usage, the watermarks being compared to the smoothed usage.
+	// Lower values dampen the variations of the metrics, 1 (default) disables the smoothing.
+	// We validate that it is ]0;1] in the code.
+	// +optional
+	SmoothingFactor *resource.Quantity `json:"smoothingFactor,omitempty"`
+
+	// Number of replicas below which a breach of the watermarks of an external metric adds or removes a single replica,
+	// instead of scaling proportionally to the metric, which is too sensitive to its noise with few replicas.
+	// 0 (default) always scales proportionally.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinReplicasForProportional int32 `json:"minReplicasForProportional,omitempty"`
+
 	// computed values take the # of replicas into account
+	// Either absolute (default) to compare the value of the metrics to the watermarks,
+	// average to divide it by the number of replicas first,
+	// averageByRequest to divide it by the total request of averageByRequestResource of the replicas, in number of replicas of the size of the newest one,
+	// count to compare the number of series returned for the external metrics (e.g. one per partition) instead of their values,
+	// or logarithmic to compare the value like absolute but dampen the number of replicas recommended by a breach with a log curve.
 	Algorithm string `json:"algorithm,omitempty"`
 
+	// Algorithm used instead of the algorithm of the WPA to tell whether the external metrics are above their high watermark
+	// and how many replicas to scale up to. Defaults to the algorithm of the WPA.
+	// The metrics setting their own algorithm or targetType are not affected.
+	// +optional
+	UpscaleAlgorithm string `json:"upscaleAlgorithm,omitempty"`
+
+	// Algorithm used instead of the algorithm of the WPA to tell whether the external metrics are below their low watermark
+	// and how many replicas to scale down to. Defaults to the algorithm of the WPA.
+	// The metrics setting their own algorithm or targetType are not affected.
+	// +optional
+	DownscaleAlgorithm string `json:"downscaleAlgorithm,omitempty"`
+
+	// Resource whose requests are summed across the ready pods with the averageByRequest algorithm. Defaults to cpu.
+	// +optional
+	AverageByRequestResource v1.ResourceName `json:"averageByRequestResource,omitempty"`
+
+	// How the recommendations of the metrics are combined.
+	// Either max (default) to use the highest recommendation,
+	// or weighted-sum to scale the current replicas by the average utilization of the metrics compared to their watermarks,
+	// weighted by the weight of each metric.
+	// +optional
+	MetricAggregation string `json:"metricAggregation,omitempty"`
+
+	// Maximum age in seconds of the external and object metrics, older values are not used to scale.
+	// 0 disables the check.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MetricStalenessWindowSeconds int32 `json:"metricStalenessWindowSeconds,omitempty"`
+
+	// Number of seconds the values of the external metrics are cached for, the cache being shared by the WPAs querying
+	// the same metric with the same selector in the same namespace to reduce the calls to the External Metrics Provider.
+	// 0 (default) always queries the provider.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=300
+	// +optional
+	MetricCacheTTLSeconds int32 `json:"metricCacheTTLSeconds,omitempty"`
+
+	// How the WPA scales when none of its metrics can be retrieved.
+	// Either maintain (default) to keep the current number of replicas, scaleToMin to scale down to minReplicas,
+	// or lastKnownGood to use the last recommendation computed from the metrics.
+	// +optional
+	MetricErrorPolicy string `json:"metricErrorPolicy,omitempty"`
+
+	// Direction in which the WPA is allowed to scale the target.
+	// Either both (default), up to only scale up, or down to only scale down, the other direction being left to manual operations.
+	// +optional
+	ScaleDirection string `json:"scaleDirection,omitempty"`
+
+	// Overrides of the watermarks of the metrics during time windows, e.g. tighter watermarks during business hours.
+	// The first entry whose window contains the current time is used, the watermarks of the metrics apply otherwise.
+	// +optional
+	WatermarkSchedule []WatermarkScheduleEntry `json:"watermarkSchedule,omitempty"`
+
+	// IANA name of the timezone of the windows of the watermarkSchedule, e.g. Europe/Paris. Defaults to UTC.
+	// +optional
+	WatermarkScheduleTimezone string `json:"watermarkScheduleTimezone,omitempty"`
+
+	// Number of seconds between two reconcile cycles of the WPA, it should be at least 5 seconds.
+	// 0 uses the sync period of the controller.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ReconcileIntervalSeconds int32 `json:"reconcileIntervalSeconds,omitempty"`
+
 	// Whether planned scale changes are actually applied
 	DryRun bool `json:"dryRun,omitempty"`
 
+	// URL of an HTTP endpoint approving the scale changes before they are applied, e.g. to check a quota or a budget.
+	// The controller POSTs the current and desired numbers of replicas along with the metric driving the scaling, and
+	// only scales the target if the endpoint approves the change within the scale approval timeout of the controller.
+	// +optional
+	ScaleApprovalWebhookURL string `json:"scaleApprovalWebhookURL,omitempty"`
+
+	// Whether the number of series returned for the external metrics and the range of their values are reported in the status
+	// and the logs, to tell when a single series dominates the aggregated value.
+	// +optional
+	Debug bool `json:"debug,omitempty"`
+
 	// part of HorizontalPodAutoscalerSpec, see comments in the k8s-1.10.8 repo: staging/src/k8s.io/api/autoscaling/v1/types.go
 	// reference to scaled resource; horizontal pod autoscaler will learn the current resource consumption
 	// and will set the desired number of pods by using its Scale subresource.
@@ -82,12 +248,73 @@ type WatermarkPodAutoscalerSpec struct {
 	// specifications that will be used to calculate the desired replica count
 	// +listType=set
 	Metrics []MetricSpec `json:"metrics,omitempty"`
-	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Minimum=0
 	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// Whether the target can be scaled down to zero replicas when the metrics are below the low watermark.
+	// MinReplicas can only be set to 0 when it is enabled.
+	// +optional
+	ScaleDownToZeroEnabled bool `json:"scaleDownToZeroEnabled,omitempty"`
+	// Number of replicas to scale up to when the target is at zero replicas and the metrics are above the high watermark.
+	// Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ScaleUpFromZeroReplicas int32 `json:"scaleUpFromZeroReplicas,omitempty"`
 	// +kubebuilder:validation:Minimum=1
 	MaxReplicas int32 `json:"maxReplicas,omitempty"`
 	// +kubebuilder:validation:Minimum=1
 	ReadinessDelaySeconds int32 `json:"readinessDelaySeconds,omitempty"`
+	// Number of seconds after the start of a pod during which its resource and pods metrics are ignored, even if it is
+	// ready. Once set, the metrics of the unready pods are ignored as well, whatever the resource. 0 (default) only
+	// ignores the CPU metrics of the pods that have never been ready.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	InitialReadinessDelaySeconds int32 `json:"initialReadinessDelaySeconds,omitempty"`
+	// Whether only the ready pods which are not terminating are counted as the current replicas the recommendations
+	// are proportional to, so that the pods still terminating after a downscale don't lower the usage per replica.
+	UseReadyReplicas bool `json:"useReadyReplicas,omitempty"`
+	// Minimum percentage of the pods of the target which have to be ready for the WPA to scale it, the current number
+	// of replicas is kept otherwise, as scaling a target whose pods are crash-looping can make it worse.
+	// 0 (default) disables the check.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	MinReadyPercentage int32 `json:"minReadyPercentage,omitempty"`
+
+	// Minimum difference between the recommendation and the current number of replicas for the target to be scaled,
+	// the current number of replicas is kept otherwise, e.g. 2 to ignore the recommendations of a single replica more or less.
+	// Unlike the tolerance, it applies to the number of replicas rather than to the value of the metrics.
+	// Scaling to and from zero replicas is not affected. 0 (default) disables the check.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinReplicaChange int32 `json:"minReplicaChange,omitempty"`
+
+	// Number of the last recommendations of the WPA kept in the recommendationHistory of its status, 10 by default, at most 50.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=50
+	// +optional
+	RecommendationHistoryLimit int32 `json:"recommendationHistoryLimit,omitempty"`
+}
+
+// WatermarkScheduleEntry overrides the watermarks of the metrics during a time window.
+// +k8s:openapi-gen=true
+type WatermarkScheduleEntry struct {
+	// Days of the week of the window, as a comma-separated list of days or ranges of days, e.g. Mon-Fri or Sat,Sun.
+	// Every day when empty.
+	// +optional
+	Days string `json:"days,omitempty"`
+	// Start of the window on each of the days, formatted as HH:MM.
+	Start string `json:"start"`
+	// End of the window, formatted as HH:MM. A window ending at or before its start spans midnight and ends on the next day.
+	End string `json:"end"`
+	// Name of the external or object metric, or of the resource, whose watermarks are overridden. All of the metrics when empty.
+	// +optional
+	MetricName string `json:"metricName,omitempty"`
+	// HighWatermark used instead of the one of the metrics during the window.
+	// +optional
+	HighWatermark *resource.Quantity `json:"highWatermark,omitempty"`
+	// LowWatermark used instead of the one of the metrics during the window.
+	// +optional
+	LowWatermark *resource.Quantity `json:"lowWatermark,omitempty"`
 }
 
 // ExternalMetricSource indicates how to scale on a metric not associated with
@@ -105,6 +332,71 @@ type ExternalMetricSource struct {
 
 	HighWatermark *resource.Quantity `json:"highWatermark,omitempty"`
 	LowWatermark  *resource.Quantity `json:"lowWatermark,omitempty"`
+
+	// targetValue is the value the metric is kept around, as an alternative to the watermarks: the highWatermark and
+	// the lowWatermark are derived from it and the deadbandPercent. It can't be set along with the watermarks.
+	// +optional
+	TargetValue *resource.Quantity `json:"targetValue,omitempty"`
+	// deadbandPercent is how far from the targetValue the watermarks are, as a percentage of the targetValue: with a
+	// targetValue of 70 and a deadbandPercent of 10, the lowWatermark is 63 and the highWatermark 77.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	DeadbandPercent int32 `json:"deadbandPercent,omitempty"`
+
+	// idleWatermark is the value below which the metric is considered idle, the target is then scaled down to zero replicas.
+	// Only used when scaleDownToZeroEnabled is set, it should be strictly lower than the lowWatermark.
+	// +optional
+	IdleWatermark *resource.Quantity `json:"idleWatermark,omitempty"`
+
+	// perReplicaCapacity is the amount of the metric a single replica can handle.
+	// When set with the absolute algorithm, the recommendation is ceil(value / perReplicaCapacity)
+	// regardless of the current number of replicas.
+	// +optional
+	PerReplicaCapacity *resource.Quantity `json:"perReplicaCapacity,omitempty"`
+
+	// maxUtilization caps the value of the metric compared to the watermarks, once aggregated and averaged with the
+	// algorithm, so that a single absurd value doesn't recommend an absurd number of replicas.
+	// It should be strictly greater than the highWatermark.
+	// +optional
+	MaxUtilization *resource.Quantity `json:"maxUtilization,omitempty"`
+
+	// tolerance overrides the tolerance of the WPA for this metric only.
+	// We validate that it is [0;1] in the code.
+	// +optional
+	Tolerance *resource.Quantity `json:"tolerance,omitempty"`
+
+	// algorithm overrides the algorithm of the WPA for this metric only, targetType is preferred to set it explicitly.
+	// +optional
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// aggregatorFunc is used to combine the values returned for the metric, sum is used by default.
+	// +kubebuilder:validation:Enum=sum;avg;max;min;p50;p90;p95;p99
+	// +optional
+	AggregatorFunc string `json:"aggregatorFunc,omitempty"`
+
+	// targetType is how the value of the metric is compared to the watermarks, it takes precedence over the algorithm.
+	// Either AverageValue to divide it by the current number of replicas first, like the average algorithm,
+	// or Value to compare the value itself, like the absolute algorithm.
+	// +kubebuilder:validation:Enum=AverageValue;Value
+	// +optional
+	TargetType string `json:"targetType,omitempty"`
+
+	// weights are the weights of the values returned for the metric, matched by position, to sum them with a weighted sum,
+	// e.g. to weight the values of a region twice as heavily as another. The values without a weight are weighted by 1.
+	// They are only used with the sum aggregatorFunc.
+	// +optional
+	Weights []resource.Quantity `json:"weights,omitempty"`
+
+	// denominatorMetricName is the name of a second external metric the metric is divided by, e.g. the requests to scale
+	// on the ratio of the errors to the requests. The sum of the values of the metric is divided by the sum of the values
+	// of the denominator and the ratio is compared to the watermarks as is, regardless of the algorithm.
+	// The current number of replicas is kept while the sum of the denominator is zero.
+	// +optional
+	DenominatorMetricName string `json:"denominatorMetricName,omitempty"`
+	// denominatorMetricSelector is used to identify the time series of the denominator, the metricSelector is used by default.
+	// +optional
+	DenominatorMetricSelector *metav1.LabelSelector `json:"denominatorMetricSelector,omitempty"`
 }
 
 // ResourceMetricSource indicates how to scale on a resource metric known to
@@ -125,6 +417,71 @@ type ResourceMetricSource struct {
 
 	HighWatermark *resource.Quantity `json:"highWatermark,omitempty"`
 	LowWatermark  *resource.Quantity `json:"lowWatermark,omitempty"`
+
+	// targetType is how the usage of the resource is compared to the watermarks. With Utilization, the watermarks are
+	// percentages between 0 and 100 of the request of the pods for the resource, e.g. 80 to scale up above 80% of the
+	// request. All the containers of the ready pods need a request for the resource. The watermarks are absolute by default.
+	// +kubebuilder:validation:Enum=Utilization
+	// +optional
+	TargetType string `json:"targetType,omitempty"`
+
+	// tolerance overrides the tolerance of the WPA for this metric only.
+	// We validate that it is [0;1] in the code.
+	// +optional
+	Tolerance *resource.Quantity `json:"tolerance,omitempty"`
+}
+
+// ObjectMetricSource indicates how to scale on a metric describing a
+// kubernetes object (for example, the length of a queue reported in the
+// status of a custom resource). The single value of the metric is compared
+// to the watermarks.
+// +k8s:openapi-gen=true
+type ObjectMetricSource struct {
+	// describedObject is the object the metric describes, it is looked up in the namespace of the WPA.
+	// Objects of other namespaces can't be referenced, a Namespace kind refers to the namespace of the WPA itself.
+	DescribedObject CrossVersionObjectReference `json:"describedObject"`
+	// metricName is the name of the metric in question.
+	MetricName string `json:"metricName"`
+	// metricSelector is used to identify a specific time series
+	// within a given metric.
+	// +optional
+	MetricSelector *metav1.LabelSelector `json:"metricSelector,omitempty"`
+
+	HighWatermark *resource.Quantity `json:"highWatermark,omitempty"`
+	LowWatermark  *resource.Quantity `json:"lowWatermark,omitempty"`
+
+	// idleWatermark is the value below which the metric is considered idle, the target is then scaled down to zero replicas.
+	// Only used when scaleDownToZeroEnabled is set, it should be strictly lower than the lowWatermark.
+	// +optional
+	IdleWatermark *resource.Quantity `json:"idleWatermark,omitempty"`
+
+	// tolerance overrides the tolerance of the WPA for this metric only.
+	// We validate that it is [0;1] in the code.
+	// +optional
+	Tolerance *resource.Quantity `json:"tolerance,omitempty"`
+}
+
+// PodsMetricSource indicates how to scale on a metric describing each pod of
+// the current scale target (for example, transactions-processed-per-second),
+// served by the custom metrics API. The pods are selected with the selector of
+// the target, the values of the ready ones are averaged together before being
+// compared to the watermarks.
+// +k8s:openapi-gen=true
+type PodsMetricSource struct {
+	// metricName is the name of the metric in question.
+	MetricName string `json:"metricName"`
+	// metricSelector is used to identify a specific time series
+	// within a given metric.
+	// +optional
+	MetricSelector *metav1.LabelSelector `json:"metricSelector,omitempty"`
+
+	HighWatermark *resource.Quantity `json:"highWatermark,omitempty"`
+	LowWatermark  *resource.Quantity `json:"lowWatermark,omitempty"`
+
+	// tolerance overrides the tolerance of the WPA for this metric only.
+	// We validate that it is [0;1] in the code.
+	// +optional
+	Tolerance *resource.Quantity `json:"tolerance,omitempty"`
 }
 
 // MetricSourceType indicates the type of metric.
@@ -144,6 +501,17 @@ var (
 	// Kubernetes, and have special scaling options on top of those available
 	// to normal per-pod metrics (the "pods" source).
 	ResourceMetricSourceType MetricSourceType = "Resource"
+
+	// ObjectMetricSourceType is a metric describing a kubernetes object
+	// (for example, hits-per-second on an Ingress object), served by the
+	// custom metrics API.
+	ObjectMetricSourceType MetricSourceType = "Object"
+
+	// PodsMetricSourceType is a metric describing each pod in the current scale
+	// target (for example, transactions-processed-per-second), served by the
+	// custom metrics API. The values are averaged together before being
+	// compared to the watermarks.
+	PodsMetricSourceType MetricSourceType = "Pods"
 )
 
 // MetricSpec specifies how to scale based on a single metric
@@ -167,6 +535,72 @@ type MetricSpec struct {
 	// to normal per-pod metrics using the "pods" source.
 	// +optional
 	Resource *ResourceMetricSource `json:"resource,omitempty"`
+	// object refers to a metric describing a single kubernetes object
+	// (for example, hits-per-second on an Ingress object).
+	// +optional
+	Object *ObjectMetricSource `json:"object,omitempty"`
+	// pods refers to a metric describing each pod in the current scale target
+	// (for example, transactions-processed-per-second), averaged over the ready pods.
+	// +optional
+	Pods *PodsMetricSource `json:"pods,omitempty"`
+	// weight of the utilization of the metric when metricAggregation is weighted-sum, defaults to 1.
+	// We validate that it is strictly positive in the code.
+	// +optional
+	Weight *resource.Quantity `json:"weight,omitempty"`
+	// Whether the metric can drive a scale up, defaults to true.
+	// When false, the current number of replicas is recommended instead while the metric is above its high watermark.
+	// +optional
+	AllowScaleUp *bool `json:"allowScaleUp,omitempty"`
+	// Whether the metric can drive a scale down, defaults to true.
+	// When false, the current number of replicas is recommended instead while the metric is below its low watermark.
+	// +optional
+	AllowScaleDown *bool `json:"allowScaleDown,omitempty"`
+	// watermarkSource references a ConfigMap the watermarks of the metric are read from at each reconcile cycle, to update
+	// them without editing the WPA. The inline watermarks are used when the ConfigMap or one of its keys is missing or invalid.
+	// +optional
+	WatermarkSource *WatermarkSource `json:"watermarkSource,omitempty"`
+}
+
+// WatermarkSource references a ConfigMap holding the watermarks of a metric.
+// +k8s:openapi-gen=true
+type WatermarkSource struct {
+	// configMapName is the name of the ConfigMap holding the watermarks, in the namespace of the WPA.
+	ConfigMapName string `json:"configMapName"`
+	// highWatermarkKey is the key of the high watermark in the ConfigMap, highWatermark by default.
+	// +optional
+	HighWatermarkKey string `json:"highWatermarkKey,omitempty"`
+	// lowWatermarkKey is the key of the low watermark in the ConfigMap, lowWatermark by default.
+	// +optional
+	LowWatermarkKey string `json:"lowWatermarkKey,omitempty"`
+}
+
+// ExternalMetricSeriesStatus summarizes the series returned for an external metric
+// +k8s:openapi-gen=true
+type ExternalMetricSeriesStatus struct {
+	// name of the external metric
+	MetricName string `json:"metricName"`
+	// number of series returned for the metric
+	Count int32 `json:"count"`
+	// lowest value among the series
+	// +optional
+	Min *resource.Quantity `json:"min,omitempty"`
+	// highest value among the series
+	// +optional
+	Max *resource.Quantity `json:"max,omitempty"`
+}
+
+// RecommendationHistoryEntry is a recommendation of the WPA and whether it was applied to the target
+// +k8s:openapi-gen=true
+type RecommendationHistoryEntry struct {
+	// time of the reconcile cycle
+	Timestamp metav1.Time `json:"timestamp"`
+	// value of the metric that drove the recommendation
+	// +optional
+	Utilization *resource.Quantity `json:"utilization,omitempty"`
+	// number of replicas recommended
+	Recommendation int32 `json:"recommendation"`
+	// whether the target was scaled to the recommended number of replicas
+	Applied bool `json:"applied"`
 }
 
 // WatermarkPodAutoscalerStatus defines the observed state of WatermarkPodAutoscaler
@@ -176,6 +610,27 @@ type WatermarkPodAutoscalerStatus struct {
 	LastScaleTime      *metav1.Time `json:"lastScaleTime,omitempty"`
 	CurrentReplicas    int32        `json:"currentReplicas"`
 	DesiredReplicas    int32        `json:"desiredReplicas"`
+	// name of the metric that drove the last recommendation
+	// +optional
+	ScalingMetricName string `json:"scalingMetricName,omitempty"`
+	// value of the metric that drove the last recommendation
+	// +optional
+	ScalingMetricValue *resource.Quantity `json:"scalingMetricValue,omitempty"`
+	// position of the metric that drove the last recommendation relative to its watermarks,
+	// one of AboveHighWatermark, BelowLowWatermark, BelowIdleWatermark or WithinTolerance
+	// +optional
+	ScalingMetricPosition string `json:"scalingMetricPosition,omitempty"`
+	// reason of the last scaling decision, e.g. WithinTolerance, ClampedToMax, InCooldown or MetricStale
+	// +optional
+	LastDecisionReason string `json:"lastDecisionReason,omitempty"`
+	// number of series returned for each external metric and the range of their values, only reported with debug
+	// +optional
+	// +listType=set
+	ExternalMetricSeries []ExternalMetricSeriesStatus `json:"externalMetricSeries,omitempty"`
+	// last recommendations of the WPA, the oldest first, up to the recommendationHistoryLimit
+	// +optional
+	// +listType=set
+	RecommendationHistory []RecommendationHistoryEntry `json:"recommendationHistory,omitempty"`
 	// +listType=set
 	CurrentMetrics []autoscalingv2.MetricStatus `json:"currentMetrics"`
 	// +listType=set
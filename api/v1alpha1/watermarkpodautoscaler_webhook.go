@@ -0,0 +1,297 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package v1alpha1
+
+import (
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers the validating webhook of the WatermarkPodAutoscaler in the manager.
+func (wpa *WatermarkPodAutoscaler) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(wpa).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-datadoghq-com-v1alpha1-watermarkpodautoscaler,mutating=false,failurePolicy=fail,groups=datadoghq.com,resources=watermarkpodautoscalers,versions=v1alpha1,name=vwatermarkpodautoscaler.kb.io
+
+var _ webhook.Validator = &WatermarkPodAutoscaler{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (wpa *WatermarkPodAutoscaler) ValidateCreate() error {
+	return wpa.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (wpa *WatermarkPodAutoscaler) ValidateUpdate(old runtime.Object) error {
+	return wpa.validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (wpa *WatermarkPodAutoscaler) ValidateDelete() error {
+	return nil
+}
+
+func (wpa *WatermarkPodAutoscaler) validate() error {
+	allErrs := validateWatermarkPodAutoscalerSpec(&wpa.Spec, field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("WatermarkPodAutoscaler").GroupKind(), wpa.Name, allErrs)
+}
+
+// validateWatermarkPodAutoscalerSpec rejects the specs that would lead to nonsensical scaling decisions.
+func validateWatermarkPodAutoscalerSpec(spec *WatermarkPodAutoscalerSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if spec.MinReplicas != nil && *spec.MinReplicas > spec.MaxReplicas {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minReplicas"), *spec.MinReplicas, "should be lower than or equal to maxReplicas"))
+	}
+	if spec.MinReplicas != nil && *spec.MinReplicas == 0 && !spec.ScaleDownToZeroEnabled {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minReplicas"), *spec.MinReplicas, "can only be 0 when scaleDownToZeroEnabled is true"))
+	}
+	if spec.ReconcileIntervalSeconds != 0 && spec.ReconcileIntervalSeconds < minReconcileIntervalSeconds {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("reconcileIntervalSeconds"), spec.ReconcileIntervalSeconds, fmt.Sprintf("should be 0 or at least %d seconds", minReconcileIntervalSeconds)))
+	}
+	allErrs = append(allErrs, validateTolerance(&spec.Tolerance, fldPath.Child("tolerance"))...)
+	allErrs = append(allErrs, validateTolerance(spec.UpscaleTolerance, fldPath.Child("upscaleTolerance"))...)
+	allErrs = append(allErrs, validateTolerance(spec.DownscaleTolerance, fldPath.Child("downscaleTolerance"))...)
+	if spec.SmoothingFactor != nil && (spec.SmoothingFactor.MilliValue() <= 0 || spec.SmoothingFactor.MilliValue() > 1000) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("smoothingFactor"), spec.SmoothingFactor.String(), "should be greater than 0 and lower than or equal to 1"))
+	}
+	if spec.MinReplicasForProportional < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minReplicasForProportional"), spec.MinReplicasForProportional, "should be positive"))
+	}
+	if spec.MetricCacheTTLSeconds < 0 || spec.MetricCacheTTLSeconds > MaxMetricCacheTTLSeconds {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("metricCacheTTLSeconds"), spec.MetricCacheTTLSeconds, fmt.Sprintf("should be between 0 and %d seconds", MaxMetricCacheTTLSeconds)))
+	}
+	if spec.MinReadyPercentage < 0 || spec.MinReadyPercentage > 100 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minReadyPercentage"), spec.MinReadyPercentage, "should be between 0 and 100"))
+	}
+	if spec.InitialReadinessDelaySeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("initialReadinessDelaySeconds"), spec.InitialReadinessDelaySeconds, "should be positive"))
+	}
+	if spec.MinReplicaChange < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minReplicaChange"), spec.MinReplicaChange, "should be positive"))
+	}
+	if spec.RecommendationHistoryLimit < 0 || spec.RecommendationHistoryLimit > MaxRecommendationHistoryLimit {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("recommendationHistoryLimit"), spec.RecommendationHistoryLimit, fmt.Sprintf("should be between 0 and %d", MaxRecommendationHistoryLimit)))
+	}
+
+	if !isValidAlgorithm(spec.Algorithm) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("algorithm"), spec.Algorithm, algorithms))
+	}
+	if !isValidAlgorithm(spec.UpscaleAlgorithm) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("upscaleAlgorithm"), spec.UpscaleAlgorithm, algorithms))
+	}
+	if !isValidAlgorithm(spec.DownscaleAlgorithm) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("downscaleAlgorithm"), spec.DownscaleAlgorithm, algorithms))
+	}
+	if !isValidToleranceMode(spec.ToleranceMode) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("toleranceMode"), spec.ToleranceMode, toleranceModes))
+	}
+	if !isValidReplicaRounding(spec.ReplicaRounding) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("replicaRounding"), spec.ReplicaRounding, replicaRoundings))
+	}
+	if !isValidMetricAggregation(spec.MetricAggregation) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("metricAggregation"), spec.MetricAggregation, metricAggregations))
+	}
+	if !isValidMetricErrorPolicy(spec.MetricErrorPolicy) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("metricErrorPolicy"), spec.MetricErrorPolicy, metricErrorPolicies))
+	}
+	if !isValidScaleDirection(spec.ScaleDirection) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("scaleDirection"), spec.ScaleDirection, scaleDirections))
+	}
+	if !isValidScaleApprovalWebhookURL(spec.ScaleApprovalWebhookURL) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("scaleApprovalWebhookURL"), spec.ScaleApprovalWebhookURL, "should be an absolute http or https URL"))
+	}
+	allErrs = append(allErrs, validateWatermarkSchedule(spec, fldPath)...)
+
+	metricsPath := fldPath.Child("metrics")
+	if len(spec.Metrics) == 0 {
+		allErrs = append(allErrs, field.Required(metricsPath, "at least one metric should be set"))
+	}
+	for i, metric := range spec.Metrics {
+		if metric.Weight != nil && metric.Weight.MilliValue() <= 0 {
+			allErrs = append(allErrs, field.Invalid(metricsPath.Index(i).Child("weight"), metric.Weight.String(), "should be strictly positive"))
+		}
+		if metric.AllowScaleUp != nil && !*metric.AllowScaleUp && metric.AllowScaleDown != nil && !*metric.AllowScaleDown {
+			allErrs = append(allErrs, field.Invalid(metricsPath.Index(i).Child("allowScaleDown"), false, "can't be false when allowScaleUp is false"))
+		}
+		if metric.WatermarkSource != nil {
+			sourcePath := metricsPath.Index(i).Child("watermarkSource")
+			if metric.WatermarkSource.ConfigMapName == "" {
+				allErrs = append(allErrs, field.Required(sourcePath.Child("configMapName"), ""))
+			}
+			// the inline watermarks the source falls back to can't be set along with a targetValue.
+			if metric.External != nil && metric.External.TargetValue != nil {
+				allErrs = append(allErrs, field.Invalid(sourcePath, metric.WatermarkSource.ConfigMapName, "can't be set along with targetValue"))
+			}
+		}
+		switch {
+		case metric.External != nil:
+			externalPath := metricsPath.Index(i).Child("external")
+			if metric.External.MetricName == "" {
+				allErrs = append(allErrs, field.Required(externalPath.Child("metricName"), ""))
+			}
+			if !isValidAlgorithm(metric.External.Algorithm) {
+				allErrs = append(allErrs, field.NotSupported(externalPath.Child("algorithm"), metric.External.Algorithm, algorithms))
+			}
+			if metric.External.TargetValue != nil {
+				allErrs = append(allErrs, validateTargetValue(metric.External, externalPath)...)
+			} else {
+				allErrs = append(allErrs, validateWatermarks(metric.External.LowWatermark, metric.External.HighWatermark, externalPath)...)
+			}
+			lowMark, highMark := metric.External.GetWatermarks()
+			allErrs = append(allErrs, validateIdleWatermark(metric.External.IdleWatermark, lowMark, externalPath)...)
+			allErrs = append(allErrs, validateMaxUtilization(metric.External.MaxUtilization, highMark, externalPath)...)
+			allErrs = append(allErrs, validateTolerance(metric.External.Tolerance, externalPath.Child("tolerance"))...)
+			if !isValidAggregatorFunc(metric.External.AggregatorFunc) {
+				allErrs = append(allErrs, field.NotSupported(externalPath.Child("aggregatorFunc"), metric.External.AggregatorFunc, aggregatorFuncs))
+			}
+			if !isValidTargetType(metric.External.TargetType) {
+				allErrs = append(allErrs, field.NotSupported(externalPath.Child("targetType"), metric.External.TargetType, targetTypes))
+			}
+			if len(metric.External.Weights) > 0 && metric.External.AggregatorFunc != "" && metric.External.AggregatorFunc != "sum" {
+				allErrs = append(allErrs, field.Invalid(externalPath.Child("weights"), len(metric.External.Weights), "can only be set with the sum aggregatorFunc"))
+			}
+			for j, weight := range metric.External.Weights {
+				if weight.MilliValue() < 0 {
+					allErrs = append(allErrs, field.Invalid(externalPath.Child("weights").Index(j), weight.String(), "should be positive"))
+				}
+			}
+			if metric.External.DenominatorMetricName != "" && (len(metric.External.Weights) > 0 || (metric.External.AggregatorFunc != "" && metric.External.AggregatorFunc != "sum")) {
+				allErrs = append(allErrs, field.Invalid(externalPath.Child("denominatorMetricName"), metric.External.DenominatorMetricName, "can only be set with the sum aggregatorFunc and without weights"))
+			}
+		case metric.Resource != nil:
+			resourcePath := metricsPath.Index(i).Child("resource")
+			if metric.Resource.Name == "" {
+				allErrs = append(allErrs, field.Required(resourcePath.Child("name"), ""))
+			}
+			allErrs = append(allErrs, validateWatermarks(metric.Resource.LowWatermark, metric.Resource.HighWatermark, resourcePath)...)
+			if !isValidResourceTargetType(metric.Resource.TargetType) {
+				allErrs = append(allErrs, field.NotSupported(resourcePath.Child("targetType"), metric.Resource.TargetType, resourceTargetTypes))
+			}
+			if metric.Resource.TargetType == "Utilization" {
+				allErrs = append(allErrs, validatePercentage(metric.Resource.LowWatermark, resourcePath.Child("lowWatermark"))...)
+				allErrs = append(allErrs, validatePercentage(metric.Resource.HighWatermark, resourcePath.Child("highWatermark"))...)
+			}
+			allErrs = append(allErrs, validateTolerance(metric.Resource.Tolerance, resourcePath.Child("tolerance"))...)
+		case metric.Object != nil:
+			objectPath := metricsPath.Index(i).Child("object")
+			if metric.Object.MetricName == "" {
+				allErrs = append(allErrs, field.Required(objectPath.Child("metricName"), ""))
+			}
+			allErrs = append(allErrs, validateWatermarks(metric.Object.LowWatermark, metric.Object.HighWatermark, objectPath)...)
+			allErrs = append(allErrs, validateIdleWatermark(metric.Object.IdleWatermark, metric.Object.LowWatermark, objectPath)...)
+			allErrs = append(allErrs, validateTolerance(metric.Object.Tolerance, objectPath.Child("tolerance"))...)
+		case metric.Pods != nil:
+			podsPath := metricsPath.Index(i).Child("pods")
+			if metric.Pods.MetricName == "" {
+				allErrs = append(allErrs, field.Required(podsPath.Child("metricName"), ""))
+			}
+			allErrs = append(allErrs, validateWatermarks(metric.Pods.LowWatermark, metric.Pods.HighWatermark, podsPath)...)
+			allErrs = append(allErrs, validateTolerance(metric.Pods.Tolerance, podsPath.Child("tolerance"))...)
+		default:
+			allErrs = append(allErrs, field.Required(metricsPath.Index(i), "one of external, resource, object or pods should be set"))
+		}
+	}
+	return allErrs
+}
+
+func validateWatermarks(lowMark, highMark *resource.Quantity, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if lowMark == nil {
+		allErrs = append(allErrs, field.Required(fldPath.Child("lowWatermark"), ""))
+	}
+	if highMark == nil {
+		allErrs = append(allErrs, field.Required(fldPath.Child("highWatermark"), ""))
+	}
+	if lowMark != nil && highMark != nil && lowMark.MilliValue() >= highMark.MilliValue() {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("lowWatermark"), lowMark.String(), "should be strictly lower than highWatermark"))
+	}
+	return allErrs
+}
+
+// validatePercentage checks that a watermark of a resource metric with the Utilization targetType is a percentage of the request.
+func validatePercentage(watermark *resource.Quantity, fldPath *field.Path) field.ErrorList {
+	if watermark == nil || isValidPercentage(watermark) {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(fldPath, watermark.String(), "should be a percentage between 0 and 100 with the Utilization targetType")}
+}
+
+// validateTargetValue checks that the targetValue of an external metric isn't set along with the watermarks it replaces.
+func validateTargetValue(metric *ExternalMetricSource, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if metric.LowWatermark != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("lowWatermark"), metric.LowWatermark.String(), "can't be set along with targetValue"))
+	}
+	if metric.HighWatermark != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("highWatermark"), metric.HighWatermark.String(), "can't be set along with targetValue"))
+	}
+	if metric.TargetValue.MilliValue() <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("targetValue"), metric.TargetValue.String(), "should be strictly positive"))
+	}
+	if metric.DeadbandPercent < 1 || metric.DeadbandPercent > 100 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("deadbandPercent"), metric.DeadbandPercent, "should be between 1 and 100"))
+	}
+	return allErrs
+}
+
+func validateIdleWatermark(idleMark, lowMark *resource.Quantity, fldPath *field.Path) field.ErrorList {
+	if idleMark == nil || lowMark == nil || idleMark.MilliValue() < lowMark.MilliValue() {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(fldPath.Child("idleWatermark"), idleMark.String(), "should be strictly lower than lowWatermark")}
+}
+
+func validateMaxUtilization(maxUtilization, highMark *resource.Quantity, fldPath *field.Path) field.ErrorList {
+	if maxUtilization == nil || highMark == nil || maxUtilization.MilliValue() > highMark.MilliValue() {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(fldPath.Child("maxUtilization"), maxUtilization.String(), "should be strictly greater than highWatermark")}
+}
+
+func validateWatermarkSchedule(spec *WatermarkPodAutoscalerSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if _, err := time.LoadLocation(spec.WatermarkScheduleTimezone); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("watermarkScheduleTimezone"), spec.WatermarkScheduleTimezone, err.Error()))
+	}
+	for i, entry := range spec.WatermarkSchedule {
+		entryPath := fldPath.Child("watermarkSchedule").Index(i)
+		if _, err := parseScheduleDays(entry.Days); err != nil {
+			allErrs = append(allErrs, field.Invalid(entryPath.Child("days"), entry.Days, err.Error()))
+		}
+		if _, err := parseScheduleTime(entry.Start); err != nil {
+			allErrs = append(allErrs, field.Invalid(entryPath.Child("start"), entry.Start, err.Error()))
+		}
+		if _, err := parseScheduleTime(entry.End); err != nil {
+			allErrs = append(allErrs, field.Invalid(entryPath.Child("end"), entry.End, err.Error()))
+		}
+		switch {
+		case entry.LowWatermark == nil && entry.HighWatermark == nil:
+			allErrs = append(allErrs, field.Required(entryPath, "highWatermark, lowWatermark or both should be set"))
+		case entry.LowWatermark != nil && entry.HighWatermark != nil:
+			allErrs = append(allErrs, validateWatermarks(entry.LowWatermark, entry.HighWatermark, entryPath)...)
+		}
+	}
+	return allErrs
+}
+
+func validateTolerance(tolerance *resource.Quantity, fldPath *field.Path) field.ErrorList {
+	if tolerance == nil || (tolerance.MilliValue() >= 0 && tolerance.MilliValue() <= 1000) {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(fldPath, tolerance.String(), "should be between 0 and 1")}
+}
@@ -0,0 +1,762 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newValidatedWPA(modify func(spec *WatermarkPodAutoscalerSpec)) *WatermarkPodAutoscaler {
+	wpa := &WatermarkPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar"},
+		Spec: WatermarkPodAutoscalerSpec{
+			MinReplicas: NewInt32(2),
+			MaxReplicas: 10,
+			Tolerance:   *resource.NewMilliQuantity(100, resource.DecimalSI),
+			Metrics: []MetricSpec{
+				{
+					Type: ExternalMetricSourceType,
+					External: &ExternalMetricSource{
+						MetricName:     "deadbeef",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+					},
+				},
+			},
+		},
+	}
+	if modify != nil {
+		modify(&wpa.Spec)
+	}
+	return wpa
+}
+
+func TestWatermarkPodAutoscalerValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		wpa       *WatermarkPodAutoscaler
+		wantField string
+	}{
+		{
+			name: "valid spec",
+			wpa:  newValidatedWPA(nil),
+		},
+		{
+			name: "low watermark above the high watermark",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.LowWatermark = resource.NewQuantity(90, resource.DecimalSI)
+			}),
+			wantField: "spec.metrics[0].external.lowWatermark",
+		},
+		{
+			name: "low watermark equal to the high watermark",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.LowWatermark = resource.NewQuantity(80, resource.DecimalSI)
+			}),
+			wantField: "spec.metrics[0].external.lowWatermark",
+		},
+		{
+			name: "aggregator function of an external metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.AggregatorFunc = "max"
+			}),
+		},
+		{
+			name: "unknown aggregator function of an external metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.AggregatorFunc = "median"
+			}),
+			wantField: "spec.metrics[0].external.aggregatorFunc",
+		},
+		{
+			name: "target type of an external metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.TargetType = "AverageValue"
+			}),
+		},
+		{
+			name: "unknown target type of an external metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.TargetType = "Utilization"
+			}),
+			wantField: "spec.metrics[0].external.targetType",
+		},
+		{
+			name: "idle watermark of an external metric below the low watermark",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.IdleWatermark = resource.NewQuantity(5, resource.DecimalSI)
+			}),
+		},
+		{
+			name: "idle watermark of an external metric equal to the low watermark",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.IdleWatermark = resource.NewQuantity(70, resource.DecimalSI)
+			}),
+			wantField: "spec.metrics[0].external.idleWatermark",
+		},
+		{
+			name: "max utilization of an external metric above the high watermark",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.MaxUtilization = resource.NewQuantity(400, resource.DecimalSI)
+			}),
+		},
+		{
+			name: "max utilization of an external metric equal to the high watermark",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.MaxUtilization = resource.NewQuantity(80, resource.DecimalSI)
+			}),
+			wantField: "spec.metrics[0].external.maxUtilization",
+		},
+		{
+			name: "watermark source of a metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].WatermarkSource = &WatermarkSource{ConfigMapName: "watermarks", HighWatermarkKey: "high"}
+			}),
+		},
+		{
+			name: "watermark source without a ConfigMap",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].WatermarkSource = &WatermarkSource{HighWatermarkKey: "high"}
+			}),
+			wantField: "spec.metrics[0].watermarkSource.configMapName",
+		},
+		{
+			name: "watermark source along with a target value",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.LowWatermark = nil
+				spec.Metrics[0].External.HighWatermark = nil
+				spec.Metrics[0].External.TargetValue = resource.NewQuantity(75, resource.DecimalSI)
+				spec.Metrics[0].External.DeadbandPercent = 10
+				spec.Metrics[0].WatermarkSource = &WatermarkSource{ConfigMapName: "watermarks"}
+			}),
+			wantField: "spec.metrics[0].watermarkSource",
+		},
+		{
+			name: "low watermark of a resource metric above the high watermark",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics = []MetricSpec{
+					{
+						Type: ResourceMetricSourceType,
+						Resource: &ResourceMetricSource{
+							Name:           "cpu",
+							MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+							HighWatermark:  resource.NewQuantity(70, resource.DecimalSI),
+							LowWatermark:   resource.NewQuantity(80, resource.DecimalSI),
+						},
+					},
+				}
+			}),
+			wantField: "spec.metrics[0].resource.lowWatermark",
+		},
+		{
+			name: "utilization watermarks of a resource metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics = []MetricSpec{
+					{
+						Type: ResourceMetricSourceType,
+						Resource: &ResourceMetricSource{
+							Name:           "cpu",
+							MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+							HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+							LowWatermark:   resource.NewQuantity(60, resource.DecimalSI),
+							TargetType:     "Utilization",
+						},
+					},
+				}
+			}),
+		},
+		{
+			name: "utilization watermark of a resource metric above 100%",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics = []MetricSpec{
+					{
+						Type: ResourceMetricSourceType,
+						Resource: &ResourceMetricSource{
+							Name:           "cpu",
+							MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+							HighWatermark:  resource.NewQuantity(120, resource.DecimalSI),
+							LowWatermark:   resource.NewQuantity(60, resource.DecimalSI),
+							TargetType:     "Utilization",
+						},
+					},
+				}
+			}),
+			wantField: "spec.metrics[0].resource.highWatermark",
+		},
+		{
+			name: "utilization watermark of a resource metric below 0%",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics = []MetricSpec{
+					{
+						Type: ResourceMetricSourceType,
+						Resource: &ResourceMetricSource{
+							Name:           "cpu",
+							MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+							HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+							LowWatermark:   resource.NewQuantity(-10, resource.DecimalSI),
+							TargetType:     "Utilization",
+						},
+					},
+				}
+			}),
+			wantField: "spec.metrics[0].resource.lowWatermark",
+		},
+		{
+			name: "unknown target type of a resource metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics = []MetricSpec{
+					{
+						Type: ResourceMetricSourceType,
+						Resource: &ResourceMetricSource{
+							Name:           "cpu",
+							MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+							HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+							LowWatermark:   resource.NewQuantity(60, resource.DecimalSI),
+							TargetType:     "AverageValue",
+						},
+					},
+				}
+			}),
+			wantField: "spec.metrics[0].resource.targetType",
+		},
+		{
+			name: "low watermark of an object metric above the high watermark",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics = []MetricSpec{
+					{
+						Type: ObjectMetricSourceType,
+						Object: &ObjectMetricSource{
+							DescribedObject: CrossVersionObjectReference{Kind: "Queue", Name: "jobs", APIVersion: "example.com/v1"},
+							MetricName:      "length",
+							HighWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+							LowWatermark:    resource.NewQuantity(80, resource.DecimalSI),
+						},
+					},
+				}
+			}),
+			wantField: "spec.metrics[0].object.lowWatermark",
+		},
+		{
+			name: "minReplicas above maxReplicas",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.MinReplicas = NewInt32(12)
+			}),
+			wantField: "spec.minReplicas",
+		},
+		{
+			name: "minReplicas set to 0 without scaling down to zero",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.MinReplicas = NewInt32(0)
+			}),
+			wantField: "spec.minReplicas",
+		},
+		{
+			name: "minReplicas set to 0 when scaling down to zero",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.MinReplicas = NewInt32(0)
+				spec.ScaleDownToZeroEnabled = true
+			}),
+		},
+		{
+			name: "tolerance above 1",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Tolerance = *resource.NewMilliQuantity(1500, resource.DecimalSI)
+			}),
+			wantField: "spec.tolerance",
+		},
+		{
+			name: "negative tolerance",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Tolerance = *resource.NewMilliQuantity(-100, resource.DecimalSI)
+			}),
+			wantField: "spec.tolerance",
+		},
+		{
+			name: "smoothing factor",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.SmoothingFactor = resource.NewMilliQuantity(300, resource.DecimalSI)
+			}),
+		},
+		{
+			name: "smoothing factor set to 0",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.SmoothingFactor = resource.NewQuantity(0, resource.DecimalSI)
+			}),
+			wantField: "spec.smoothingFactor",
+		},
+		{
+			name: "smoothing factor above 1",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.SmoothingFactor = resource.NewMilliQuantity(1500, resource.DecimalSI)
+			}),
+			wantField: "spec.smoothingFactor",
+		},
+		{
+			name: "negative min replicas for proportional",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.MinReplicasForProportional = -1
+			}),
+			wantField: "spec.minReplicasForProportional",
+		},
+		{
+			name: "metric cache TTL",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.MetricCacheTTLSeconds = 30
+			}),
+		},
+		{
+			name: "metric cache TTL too long",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.MetricCacheTTLSeconds = 600
+			}),
+			wantField: "spec.metricCacheTTLSeconds",
+		},
+		{
+			name: "min ready percentage",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.MinReadyPercentage = 80
+			}),
+		},
+		{
+			name: "min ready percentage above 100",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.MinReadyPercentage = 120
+			}),
+			wantField: "spec.minReadyPercentage",
+		},
+		{
+			name: "initial readiness delay",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.InitialReadinessDelaySeconds = 30
+			}),
+		},
+		{
+			name: "negative initial readiness delay",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.InitialReadinessDelaySeconds = -1
+			}),
+			wantField: "spec.initialReadinessDelaySeconds",
+		},
+		{
+			name: "min replica change",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.MinReplicaChange = 2
+			}),
+		},
+		{
+			name: "negative min replica change",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.MinReplicaChange = -1
+			}),
+			wantField: "spec.minReplicaChange",
+		},
+		{
+			name: "recommendation history limit above the maximum",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.RecommendationHistoryLimit = MaxRecommendationHistoryLimit + 1
+			}),
+			wantField: "spec.recommendationHistoryLimit",
+		},
+		{
+			name: "reconcile interval",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.ReconcileIntervalSeconds = 5
+			}),
+		},
+		{
+			name: "reconcile interval too short",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.ReconcileIntervalSeconds = 2
+			}),
+			wantField: "spec.reconcileIntervalSeconds",
+		},
+		{
+			name: "weighted-sum aggregation of the metrics",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.MetricAggregation = "weighted-sum"
+				spec.Metrics[0].Weight = resource.NewMilliQuantity(500, resource.DecimalSI)
+			}),
+		},
+		{
+			name: "unknown aggregation of the metrics",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.MetricAggregation = "min"
+			}),
+			wantField: "spec.metricAggregation",
+		},
+		{
+			name: "lastKnownGood metric error policy",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.MetricErrorPolicy = "lastKnownGood"
+			}),
+		},
+		{
+			name: "unknown metric error policy",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.MetricErrorPolicy = "scaleToMax"
+			}),
+			wantField: "spec.metricErrorPolicy",
+		},
+		{
+			name: "scale up only",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.ScaleDirection = "up"
+			}),
+		},
+		{
+			name: "unknown scale direction",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.ScaleDirection = "none"
+			}),
+			wantField: "spec.scaleDirection",
+		},
+		{
+			name: "scale approval webhook",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.ScaleApprovalWebhookURL = "https://approval.example.com/scale"
+			}),
+		},
+		{
+			name: "relative scale approval webhook",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.ScaleApprovalWebhookURL = "/scale"
+			}),
+			wantField: "spec.scaleApprovalWebhookURL",
+		},
+		{
+			name: "watermark schedule",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.WatermarkScheduleTimezone = "Europe/Paris"
+				spec.WatermarkSchedule = []WatermarkScheduleEntry{
+					{Days: "Mon-Fri", Start: "09:00", End: "18:00", HighWatermark: resource.NewQuantity(60, resource.DecimalSI), LowWatermark: resource.NewQuantity(50, resource.DecimalSI)},
+				}
+			}),
+		},
+		{
+			name: "unknown timezone of the watermark schedule",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.WatermarkScheduleTimezone = "Mars/Olympus_Mons"
+			}),
+			wantField: "spec.watermarkScheduleTimezone",
+		},
+		{
+			name: "invalid days of the watermark schedule",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.WatermarkSchedule = []WatermarkScheduleEntry{
+					{Days: "Weekdays", Start: "09:00", End: "18:00", HighWatermark: resource.NewQuantity(60, resource.DecimalSI)},
+				}
+			}),
+			wantField: "spec.watermarkSchedule[0].days",
+		},
+		{
+			name: "invalid start of the watermark schedule",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.WatermarkSchedule = []WatermarkScheduleEntry{
+					{Start: "9am", End: "18:00", HighWatermark: resource.NewQuantity(60, resource.DecimalSI)},
+				}
+			}),
+			wantField: "spec.watermarkSchedule[0].start",
+		},
+		{
+			name: "watermark schedule without watermarks",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.WatermarkSchedule = []WatermarkScheduleEntry{
+					{Start: "09:00", End: "18:00"},
+				}
+			}),
+			wantField: "spec.watermarkSchedule[0]",
+		},
+		{
+			name: "low watermark of the watermark schedule above its high watermark",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.WatermarkSchedule = []WatermarkScheduleEntry{
+					{Start: "09:00", End: "18:00", HighWatermark: resource.NewQuantity(50, resource.DecimalSI), LowWatermark: resource.NewQuantity(60, resource.DecimalSI)},
+				}
+			}),
+			wantField: "spec.watermarkSchedule[0].lowWatermark",
+		},
+		{
+			name: "scale up only metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].AllowScaleDown = NewBool(false)
+			}),
+		},
+		{
+			name: "metric allowed to scale in neither direction",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].AllowScaleUp = NewBool(false)
+				spec.Metrics[0].AllowScaleDown = NewBool(false)
+			}),
+			wantField: "spec.metrics[0].allowScaleDown",
+		},
+		{
+			name: "weight of a metric set to 0",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].Weight = resource.NewQuantity(0, resource.DecimalSI)
+			}),
+			wantField: "spec.metrics[0].weight",
+		},
+		{
+			name: "weights of an external metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.Weights = []resource.Quantity{*resource.NewQuantity(2, resource.DecimalSI), *resource.NewQuantity(1, resource.DecimalSI)}
+			}),
+		},
+		{
+			name: "negative weight of an external metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.Weights = []resource.Quantity{*resource.NewQuantity(2, resource.DecimalSI), *resource.NewQuantity(-1, resource.DecimalSI)}
+			}),
+			wantField: "spec.metrics[0].external.weights[1]",
+		},
+		{
+			name: "weights with the max aggregator function",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.AggregatorFunc = "max"
+				spec.Metrics[0].External.Weights = []resource.Quantity{*resource.NewQuantity(2, resource.DecimalSI)}
+			}),
+			wantField: "spec.metrics[0].external.weights",
+		},
+		{
+			name: "target value with a deadband",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.HighWatermark = nil
+				spec.Metrics[0].External.LowWatermark = nil
+				spec.Metrics[0].External.TargetValue = resource.NewQuantity(70, resource.DecimalSI)
+				spec.Metrics[0].External.DeadbandPercent = 10
+			}),
+		},
+		{
+			name: "target value along with the watermarks",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.LowWatermark = nil
+				spec.Metrics[0].External.TargetValue = resource.NewQuantity(70, resource.DecimalSI)
+				spec.Metrics[0].External.DeadbandPercent = 10
+			}),
+			wantField: "spec.metrics[0].external.highWatermark",
+		},
+		{
+			name: "target value without a deadband",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.HighWatermark = nil
+				spec.Metrics[0].External.LowWatermark = nil
+				spec.Metrics[0].External.TargetValue = resource.NewQuantity(70, resource.DecimalSI)
+			}),
+			wantField: "spec.metrics[0].external.deadbandPercent",
+		},
+		{
+			name: "idle watermark above the low watermark derived from the target value",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.HighWatermark = nil
+				spec.Metrics[0].External.LowWatermark = nil
+				spec.Metrics[0].External.TargetValue = resource.NewQuantity(70, resource.DecimalSI)
+				spec.Metrics[0].External.DeadbandPercent = 10
+				spec.Metrics[0].External.IdleWatermark = resource.NewQuantity(65, resource.DecimalSI)
+			}),
+			wantField: "spec.metrics[0].external.idleWatermark",
+		},
+		{
+			name: "ratio of two external metrics",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.DenominatorMetricName = "requests"
+			}),
+		},
+		{
+			name: "ratio with the max aggregator function",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.DenominatorMetricName = "requests"
+				spec.Metrics[0].External.AggregatorFunc = "max"
+			}),
+			wantField: "spec.metrics[0].external.denominatorMetricName",
+		},
+		{
+			name: "metric without source",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External = nil
+			}),
+			wantField: "spec.metrics[0]",
+		},
+		{
+			name: "empty name of an external metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.MetricName = ""
+			}),
+			wantField: "spec.metrics[0].external.metricName",
+		},
+		{
+			name: "empty name of a resource metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics = []MetricSpec{
+					{
+						Type: ResourceMetricSourceType,
+						Resource: &ResourceMetricSource{
+							HighWatermark: resource.NewQuantity(80, resource.DecimalSI),
+							LowWatermark:  resource.NewQuantity(70, resource.DecimalSI),
+						},
+					},
+				}
+			}),
+			wantField: "spec.metrics[0].resource.name",
+		},
+		{
+			name: "empty name of an object metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics = []MetricSpec{
+					{
+						Type: ObjectMetricSourceType,
+						Object: &ObjectMetricSource{
+							DescribedObject: CrossVersionObjectReference{Kind: "Queue", Name: "jobs", APIVersion: "example.com/v1"},
+							HighWatermark:   resource.NewQuantity(80, resource.DecimalSI),
+							LowWatermark:    resource.NewQuantity(70, resource.DecimalSI),
+						},
+					},
+				}
+			}),
+			wantField: "spec.metrics[0].object.metricName",
+		},
+		{
+			name: "pods metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics = []MetricSpec{
+					{
+						Type: PodsMetricSourceType,
+						Pods: &PodsMetricSource{
+							MetricName:    "requests_per_second",
+							HighWatermark: resource.NewQuantity(80, resource.DecimalSI),
+							LowWatermark:  resource.NewQuantity(70, resource.DecimalSI),
+						},
+					},
+				}
+			}),
+		},
+		{
+			name: "low watermark of a pods metric above the high watermark",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics = []MetricSpec{
+					{
+						Type: PodsMetricSourceType,
+						Pods: &PodsMetricSource{
+							MetricName:    "requests_per_second",
+							HighWatermark: resource.NewQuantity(70, resource.DecimalSI),
+							LowWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+						},
+					},
+				}
+			}),
+			wantField: "spec.metrics[0].pods.lowWatermark",
+		},
+		{
+			name: "empty name of a pods metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics = []MetricSpec{
+					{
+						Type: PodsMetricSourceType,
+						Pods: &PodsMetricSource{
+							HighWatermark: resource.NewQuantity(80, resource.DecimalSI),
+							LowWatermark:  resource.NewQuantity(70, resource.DecimalSI),
+						},
+					},
+				}
+			}),
+			wantField: "spec.metrics[0].pods.metricName",
+		},
+		{
+			name: "algorithm",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Algorithm = "averageByRequest"
+			}),
+		},
+		{
+			name: "logarithmic algorithm",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Algorithm = "logarithmic"
+			}),
+		},
+		{
+			name: "count algorithm of an external metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.Algorithm = "count"
+			}),
+		},
+		{
+			name: "unknown algorithm",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Algorithm = "median"
+			}),
+			wantField: "spec.algorithm",
+		},
+		{
+			name: "upscale and downscale algorithms",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.UpscaleAlgorithm = "average"
+				spec.DownscaleAlgorithm = "absolute"
+			}),
+		},
+		{
+			name: "unknown upscale algorithm",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.UpscaleAlgorithm = "median"
+			}),
+			wantField: "spec.upscaleAlgorithm",
+		},
+		{
+			name: "unknown downscale algorithm",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.DownscaleAlgorithm = "median"
+			}),
+			wantField: "spec.downscaleAlgorithm",
+		},
+		{
+			name: "unknown algorithm of an external metric",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics[0].External.Algorithm = "median"
+			}),
+			wantField: "spec.metrics[0].external.algorithm",
+		},
+		{
+			name: "unknown tolerance mode",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.ToleranceMode = "additive"
+			}),
+			wantField: "spec.toleranceMode",
+		},
+		{
+			name: "unknown replica rounding",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.ReplicaRounding = "up"
+			}),
+			wantField: "spec.replicaRounding",
+		},
+		{
+			name: "empty metrics",
+			wpa: newValidatedWPA(func(spec *WatermarkPodAutoscalerSpec) {
+				spec.Metrics = nil
+			}),
+			wantField: "spec.metrics",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createErr := tt.wpa.ValidateCreate()
+			updateErr := tt.wpa.ValidateUpdate(tt.wpa.DeepCopy())
+			if tt.wantField == "" {
+				assert.NoError(t, createErr)
+				assert.NoError(t, updateErr)
+				return
+			}
+			assert.True(t, apierrors.IsInvalid(createErr))
+			assert.Contains(t, createErr.Error(), tt.wantField)
+			assert.Equal(t, createErr, updateErr)
+		})
+	}
+	assert.NoError(t, newValidatedWPA(nil).ValidateDelete())
+}
@@ -11,6 +11,7 @@ package v1alpha1
 
 import (
 	"k8s.io/api/autoscaling/v2beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -30,6 +31,31 @@ func (in *CrossVersionObjectReference) DeepCopy() *CrossVersionObjectReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalMetricSeriesStatus) DeepCopyInto(out *ExternalMetricSeriesStatus) {
+	*out = *in
+	if in.Min != nil {
+		in, out := &in.Min, &out.Min
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Max != nil {
+		in, out := &in.Max, &out.Max
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalMetricSeriesStatus.
+func (in *ExternalMetricSeriesStatus) DeepCopy() *ExternalMetricSeriesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalMetricSeriesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExternalMetricSource) DeepCopyInto(out *ExternalMetricSource) {
 	*out = *in
@@ -48,6 +74,43 @@ func (in *ExternalMetricSource) DeepCopyInto(out *ExternalMetricSource) {
 		x := (*in).DeepCopy()
 		*out = &x
 	}
+	if in.TargetValue != nil {
+		in, out := &in.TargetValue, &out.TargetValue
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.IdleWatermark != nil {
+		in, out := &in.IdleWatermark, &out.IdleWatermark
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.PerReplicaCapacity != nil {
+		in, out := &in.PerReplicaCapacity, &out.PerReplicaCapacity
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxUtilization != nil {
+		in, out := &in.MaxUtilization, &out.MaxUtilization
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Tolerance != nil {
+		in, out := &in.Tolerance, &out.Tolerance
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Weights != nil {
+		in, out := &in.Weights, &out.Weights
+		*out = make([]resource.Quantity, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DenominatorMetricSelector != nil {
+		in, out := &in.DenominatorMetricSelector, &out.DenominatorMetricSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalMetricSource.
@@ -73,6 +136,36 @@ func (in *MetricSpec) DeepCopyInto(out *MetricSpec) {
 		*out = new(ResourceMetricSource)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Object != nil {
+		in, out := &in.Object, &out.Object
+		*out = new(ObjectMetricSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = new(PodsMetricSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.AllowScaleUp != nil {
+		in, out := &in.AllowScaleUp, &out.AllowScaleUp
+		*out = new(bool)
+		**out = **in
+	}
+	if in.AllowScaleDown != nil {
+		in, out := &in.AllowScaleDown, &out.AllowScaleDown
+		*out = new(bool)
+		**out = **in
+	}
+	if in.WatermarkSource != nil {
+		in, out := &in.WatermarkSource, &out.WatermarkSource
+		*out = new(WatermarkSource)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricSpec.
@@ -85,6 +178,103 @@ func (in *MetricSpec) DeepCopy() *MetricSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectMetricSource) DeepCopyInto(out *ObjectMetricSource) {
+	*out = *in
+	out.DescribedObject = in.DescribedObject
+	if in.MetricSelector != nil {
+		in, out := &in.MetricSelector, &out.MetricSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HighWatermark != nil {
+		in, out := &in.HighWatermark, &out.HighWatermark
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.LowWatermark != nil {
+		in, out := &in.LowWatermark, &out.LowWatermark
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.IdleWatermark != nil {
+		in, out := &in.IdleWatermark, &out.IdleWatermark
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Tolerance != nil {
+		in, out := &in.Tolerance, &out.Tolerance
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectMetricSource.
+func (in *ObjectMetricSource) DeepCopy() *ObjectMetricSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectMetricSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodsMetricSource) DeepCopyInto(out *PodsMetricSource) {
+	*out = *in
+	if in.MetricSelector != nil {
+		in, out := &in.MetricSelector, &out.MetricSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HighWatermark != nil {
+		in, out := &in.HighWatermark, &out.HighWatermark
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.LowWatermark != nil {
+		in, out := &in.LowWatermark, &out.LowWatermark
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Tolerance != nil {
+		in, out := &in.Tolerance, &out.Tolerance
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodsMetricSource.
+func (in *PodsMetricSource) DeepCopy() *PodsMetricSource {
+	if in == nil {
+		return nil
+	}
+	out := new(PodsMetricSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecommendationHistoryEntry) DeepCopyInto(out *RecommendationHistoryEntry) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+	if in.Utilization != nil {
+		in, out := &in.Utilization, &out.Utilization
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecommendationHistoryEntry.
+func (in *RecommendationHistoryEntry) DeepCopy() *RecommendationHistoryEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(RecommendationHistoryEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceMetricSource) DeepCopyInto(out *ResourceMetricSource) {
 	*out = *in
@@ -103,6 +293,11 @@ func (in *ResourceMetricSource) DeepCopyInto(out *ResourceMetricSource) {
 		x := (*in).DeepCopy()
 		*out = &x
 	}
+	if in.Tolerance != nil {
+		in, out := &in.Tolerance, &out.Tolerance
+		x := (*in).DeepCopy()
+		*out = &x
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceMetricSource.
@@ -188,6 +383,28 @@ func (in *WatermarkPodAutoscalerSpec) DeepCopyInto(out *WatermarkPodAutoscalerSp
 		*out = &x
 	}
 	out.Tolerance = in.Tolerance.DeepCopy()
+	if in.UpscaleTolerance != nil {
+		in, out := &in.UpscaleTolerance, &out.UpscaleTolerance
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.DownscaleTolerance != nil {
+		in, out := &in.DownscaleTolerance, &out.DownscaleTolerance
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.SmoothingFactor != nil {
+		in, out := &in.SmoothingFactor, &out.SmoothingFactor
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.WatermarkSchedule != nil {
+		in, out := &in.WatermarkSchedule, &out.WatermarkSchedule
+		*out = make([]WatermarkScheduleEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	out.ScaleTargetRef = in.ScaleTargetRef
 	if in.Metrics != nil {
 		in, out := &in.Metrics, &out.Metrics
@@ -225,6 +442,25 @@ func (in *WatermarkPodAutoscalerStatus) DeepCopyInto(out *WatermarkPodAutoscaler
 		in, out := &in.LastScaleTime, &out.LastScaleTime
 		*out = (*in).DeepCopy()
 	}
+	if in.ScalingMetricValue != nil {
+		in, out := &in.ScalingMetricValue, &out.ScalingMetricValue
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.ExternalMetricSeries != nil {
+		in, out := &in.ExternalMetricSeries, &out.ExternalMetricSeries
+		*out = make([]ExternalMetricSeriesStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RecommendationHistory != nil {
+		in, out := &in.RecommendationHistory, &out.RecommendationHistory
+		*out = make([]RecommendationHistoryEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.CurrentMetrics != nil {
 		in, out := &in.CurrentMetrics, &out.CurrentMetrics
 		*out = make([]v2beta1.MetricStatus, len(*in))
@@ -250,3 +486,43 @@ func (in *WatermarkPodAutoscalerStatus) DeepCopy() *WatermarkPodAutoscalerStatus
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WatermarkSource) DeepCopyInto(out *WatermarkSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WatermarkSource.
+func (in *WatermarkSource) DeepCopy() *WatermarkSource {
+	if in == nil {
+		return nil
+	}
+	out := new(WatermarkSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WatermarkScheduleEntry) DeepCopyInto(out *WatermarkScheduleEntry) {
+	*out = *in
+	if in.HighWatermark != nil {
+		in, out := &in.HighWatermark, &out.HighWatermark
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.LowWatermark != nil {
+		in, out := &in.LowWatermark, &out.LowWatermark
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WatermarkScheduleEntry.
+func (in *WatermarkScheduleEntry) DeepCopy() *WatermarkScheduleEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(WatermarkScheduleEntry)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -19,12 +19,18 @@ import (
 func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
 	return map[string]common.OpenAPIDefinition{
 		"./api/v1alpha1.CrossVersionObjectReference":  schema__api_v1alpha1_CrossVersionObjectReference(ref),
+		"./api/v1alpha1.ExternalMetricSeriesStatus":   schema__api_v1alpha1_ExternalMetricSeriesStatus(ref),
 		"./api/v1alpha1.ExternalMetricSource":         schema__api_v1alpha1_ExternalMetricSource(ref),
 		"./api/v1alpha1.MetricSpec":                   schema__api_v1alpha1_MetricSpec(ref),
+		"./api/v1alpha1.ObjectMetricSource":           schema__api_v1alpha1_ObjectMetricSource(ref),
+		"./api/v1alpha1.PodsMetricSource":             schema__api_v1alpha1_PodsMetricSource(ref),
+		"./api/v1alpha1.RecommendationHistoryEntry":   schema__api_v1alpha1_RecommendationHistoryEntry(ref),
 		"./api/v1alpha1.ResourceMetricSource":         schema__api_v1alpha1_ResourceMetricSource(ref),
 		"./api/v1alpha1.WatermarkPodAutoscaler":       schema__api_v1alpha1_WatermarkPodAutoscaler(ref),
 		"./api/v1alpha1.WatermarkPodAutoscalerSpec":   schema__api_v1alpha1_WatermarkPodAutoscalerSpec(ref),
 		"./api/v1alpha1.WatermarkPodAutoscalerStatus": schema__api_v1alpha1_WatermarkPodAutoscalerStatus(ref),
+		"./api/v1alpha1.WatermarkScheduleEntry":       schema__api_v1alpha1_WatermarkScheduleEntry(ref),
+		"./api/v1alpha1.WatermarkSource":              schema__api_v1alpha1_WatermarkSource(ref),
 	}
 }
 
@@ -63,6 +69,48 @@ func schema__api_v1alpha1_CrossVersionObjectReference(ref common.ReferenceCallba
 	}
 }
 
+func schema__api_v1alpha1_ExternalMetricSeriesStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ExternalMetricSeriesStatus summarizes the series returned for an external metric",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"metricName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "name of the external metric",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"count": {
+						SchemaProps: spec.SchemaProps{
+							Description: "number of series returned for the metric",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"min": {
+						SchemaProps: spec.SchemaProps{
+							Description: "lowest value among the series",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"max": {
+						SchemaProps: spec.SchemaProps{
+							Description: "highest value among the series",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+				},
+				Required: []string{"metricName", "count"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/api/resource.Quantity"},
+	}
+}
+
 func schema__api_v1alpha1_ExternalMetricSource(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -93,6 +141,97 @@ func schema__api_v1alpha1_ExternalMetricSource(ref common.ReferenceCallback) com
 							Ref: ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
 						},
 					},
+					"targetValue": {
+						SchemaProps: spec.SchemaProps{
+							Description: "targetValue is the value the metric is kept around, as an alternative to the watermarks: the highWatermark and the lowWatermark are derived from it and the deadbandPercent. It can't be set along with the watermarks.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"deadbandPercent": {
+						SchemaProps: spec.SchemaProps{
+							Description: "deadbandPercent is how far from the targetValue the watermarks are, as a percentage of the targetValue: with a targetValue of 70 and a deadbandPercent of 10, the lowWatermark is 63 and the highWatermark 77.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"idleWatermark": {
+						SchemaProps: spec.SchemaProps{
+							Description: "idleWatermark is the value below which the metric is considered idle, the target is then scaled down to zero replicas. Only used when scaleDownToZeroEnabled is set, it should be strictly lower than the lowWatermark.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"perReplicaCapacity": {
+						SchemaProps: spec.SchemaProps{
+							Description: "perReplicaCapacity is the amount of the metric a single replica can handle. When set with the absolute algorithm, the recommendation is ceil(value / perReplicaCapacity) regardless of the current number of replicas.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"maxUtilization": {
+						SchemaProps: spec.SchemaProps{
+							Description: "maxUtilization caps the value of the metric compared to the watermarks, once aggregated and averaged with the algorithm, so that a single absurd value doesn't recommend an absurd number of replicas. It should be strictly greater than the highWatermark.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"tolerance": {
+						SchemaProps: spec.SchemaProps{
+							Description: "tolerance overrides the tolerance of the WPA for this metric only. We validate that it is [0;1] in the code.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"replicaRounding": {
+						SchemaProps: spec.SchemaProps{
+							Description: "How the fractional number of replicas recommended when the metrics are beyond the watermarks is rounded. Either legacy (default) to round up above the high watermark and down below the low watermark, ceil, floor or nearest to use the same rounding in both directions.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"algorithm": {
+						SchemaProps: spec.SchemaProps{
+							Description: "algorithm overrides the algorithm of the WPA for this metric only, targetType is preferred to set it explicitly.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"aggregatorFunc": {
+						SchemaProps: spec.SchemaProps{
+							Description: "aggregatorFunc is used to combine the values returned for the metric, sum is used by default.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"targetType": {
+						SchemaProps: spec.SchemaProps{
+							Description: "targetType is how the value of the metric is compared to the watermarks, it takes precedence over the algorithm. Either AverageValue to divide it by the current number of replicas first, like the average algorithm, or Value to compare the value itself, like the absolute algorithm.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"weights": {
+						SchemaProps: spec.SchemaProps{
+							Description: "weights are the weights of the values returned for the metric, matched by position, to sum them with a weighted sum, e.g. to weight the values of a region twice as heavily as another. The values without a weight are weighted by 1. They are only used with the sum aggregatorFunc.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+									},
+								},
+							},
+						},
+					},
+					"denominatorMetricName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "denominatorMetricName is the name of a second external metric the metric is divided by, e.g. the requests to scale on the ratio of the errors to the requests. The sum of the values of the metric is divided by the sum of the values of the denominator and the ratio is compared to the watermarks as is, regardless of the algorithm. The current number of replicas is kept while the sum of the denominator is zero.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"denominatorMetricSelector": {
+						SchemaProps: spec.SchemaProps{
+							Description: "denominatorMetricSelector is used to identify the time series of the denominator, the metricSelector is used by default.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.LabelSelector"),
+						},
+					},
 				},
 				Required: []string{"metricName"},
 			},
@@ -128,12 +267,194 @@ func schema__api_v1alpha1_MetricSpec(ref common.ReferenceCallback) common.OpenAP
 							Ref:         ref("./api/v1alpha1.ResourceMetricSource"),
 						},
 					},
+					"object": {
+						SchemaProps: spec.SchemaProps{
+							Description: "object refers to a metric describing a single kubernetes object (for example, hits-per-second on an Ingress object).",
+							Ref:         ref("./api/v1alpha1.ObjectMetricSource"),
+						},
+					},
+					"pods": {
+						SchemaProps: spec.SchemaProps{
+							Description: "pods refers to a metric describing each pod in the current scale target (for example, transactions-processed-per-second), averaged over the ready pods.",
+							Ref:         ref("./api/v1alpha1.PodsMetricSource"),
+						},
+					},
+					"weight": {
+						SchemaProps: spec.SchemaProps{
+							Description: "weight of the utilization of the metric when metricAggregation is weighted-sum, defaults to 1. We validate that it is strictly positive in the code.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"allowScaleUp": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Whether the metric can drive a scale up, defaults to true. When false, the current number of replicas is recommended instead while the metric is above its high watermark.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"allowScaleDown": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Whether the metric can drive a scale down, defaults to true. When false, the current number of replicas is recommended instead while the metric is below its low watermark.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"watermarkSource": {
+						SchemaProps: spec.SchemaProps{
+							Description: "watermarkSource references a ConfigMap the watermarks of the metric are read from at each reconcile cycle, to update them without editing the WPA. The inline watermarks are used when the ConfigMap or one of its keys is missing or invalid.",
+							Ref:         ref("./api/v1alpha1.WatermarkSource"),
+						},
+					},
 				},
 				Required: []string{"type"},
 			},
 		},
 		Dependencies: []string{
-			"./api/v1alpha1.ExternalMetricSource", "./api/v1alpha1.ResourceMetricSource"},
+			"./api/v1alpha1.ExternalMetricSource", "./api/v1alpha1.ObjectMetricSource", "./api/v1alpha1.PodsMetricSource", "./api/v1alpha1.ResourceMetricSource", "./api/v1alpha1.WatermarkSource", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
+	}
+}
+
+func schema__api_v1alpha1_ObjectMetricSource(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ObjectMetricSource indicates how to scale on a metric describing a kubernetes object (for example, the length of a queue reported in the status of a custom resource). The single value of the metric is compared to the watermarks.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"describedObject": {
+						SchemaProps: spec.SchemaProps{
+							Description: "describedObject is the object the metric describes, it is looked up in the namespace of the WPA. Objects of other namespaces can't be referenced, a Namespace kind refers to the namespace of the WPA itself.",
+							Ref:         ref("./api/v1alpha1.CrossVersionObjectReference"),
+						},
+					},
+					"metricName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "metricName is the name of the metric in question.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metricSelector": {
+						SchemaProps: spec.SchemaProps{
+							Description: "metricSelector is used to identify a specific time series within a given metric.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.LabelSelector"),
+						},
+					},
+					"highWatermark": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"lowWatermark": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"idleWatermark": {
+						SchemaProps: spec.SchemaProps{
+							Description: "idleWatermark is the value below which the metric is considered idle, the target is then scaled down to zero replicas. Only used when scaleDownToZeroEnabled is set, it should be strictly lower than the lowWatermark.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"tolerance": {
+						SchemaProps: spec.SchemaProps{
+							Description: "tolerance overrides the tolerance of the WPA for this metric only. We validate that it is [0;1] in the code.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+				},
+				Required: []string{"describedObject", "metricName"},
+			},
+		},
+		Dependencies: []string{
+			"./api/v1alpha1.CrossVersionObjectReference", "k8s.io/apimachinery/pkg/api/resource.Quantity", "k8s.io/apimachinery/pkg/apis/meta/v1.LabelSelector"},
+	}
+}
+
+func schema__api_v1alpha1_PodsMetricSource(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "PodsMetricSource indicates how to scale on a metric describing each pod of the current scale target (for example, transactions-processed-per-second), served by the custom metrics API. The pods are selected with the selector of the target, the values of the ready ones are averaged together before being compared to the watermarks.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"metricName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "metricName is the name of the metric in question.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metricSelector": {
+						SchemaProps: spec.SchemaProps{
+							Description: "metricSelector is used to identify a specific time series within a given metric.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.LabelSelector"),
+						},
+					},
+					"highWatermark": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"lowWatermark": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"tolerance": {
+						SchemaProps: spec.SchemaProps{
+							Description: "tolerance overrides the tolerance of the WPA for this metric only. We validate that it is [0;1] in the code.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+				},
+				Required: []string{"metricName"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/api/resource.Quantity", "k8s.io/apimachinery/pkg/apis/meta/v1.LabelSelector"},
+	}
+}
+
+func schema__api_v1alpha1_RecommendationHistoryEntry(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RecommendationHistoryEntry is a recommendation of the WPA and whether it was applied to the target",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"timestamp": {
+						SchemaProps: spec.SchemaProps{
+							Description: "time of the reconcile cycle",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"utilization": {
+						SchemaProps: spec.SchemaProps{
+							Description: "value of the metric that drove the recommendation",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"recommendation": {
+						SchemaProps: spec.SchemaProps{
+							Description: "number of replicas recommended",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"applied": {
+						SchemaProps: spec.SchemaProps{
+							Description: "whether the target was scaled to the recommended number of replicas",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"timestamp", "recommendation", "applied"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/api/resource.Quantity", "k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
 	}
 }
 
@@ -167,6 +488,19 @@ func schema__api_v1alpha1_ResourceMetricSource(ref common.ReferenceCallback) com
 							Ref: ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
 						},
 					},
+					"targetType": {
+						SchemaProps: spec.SchemaProps{
+							Description: "targetType is how the usage of the resource is compared to the watermarks. With Utilization, the watermarks are percentages between 0 and 100 of the request of the pods for the resource, e.g. 80 to scale up above 80% of the request. All the containers of the ready pods need a request for the resource. The watermarks are absolute by default.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"tolerance": {
+						SchemaProps: spec.SchemaProps{
+							Description: "tolerance overrides the tolerance of the WPA for this metric only. We validate that it is [0;1] in the code.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
 				},
 				Required: []string{"name"},
 			},
@@ -240,31 +574,202 @@ func schema__api_v1alpha1_WatermarkPodAutoscalerSpec(ref common.ReferenceCallbac
 							Format: "int32",
 						},
 					},
-					"scaleUpLimitFactor": {
+					"downscaleStabilizationWindowSeconds": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Percentage of replicas that can be added in an upscale event. Parameter used to be a float, in order to support the transition seamlessly, we validate that it is [0;100] in the code. ScaleUpLimitFactor == 0 means that upscaling will not be allowed for the target.",
-							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+							Description: "Number of seconds of past recommendations considered before scaling down, the highest one is used. 0 disables the stabilization.",
+							Type:        []string{"integer"},
+							Format:      "int32",
 						},
 					},
-					"scaleDownLimitFactor": {
+					"upscaleStabilizationWindowSeconds": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Percentage of replicas that can be removed in an downscale event. Parameter used to be a float, in order to support the transition seamlessly, we validate that it is [0;100[ in the code. ScaleDownLimitFactor == 0 means that downscaling will not be allowed for the target.",
+							Description: "Number of seconds of past recommendations considered before scaling up, the lowest one is used. 0 disables the stabilization.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"downscaleDelayCount": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Number of consecutive reconcile cycles recommending to scale down required before scaling down. 0 or 1 scales down as soon as the metrics are below the low watermark.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"upscaleDelayCount": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Number of consecutive reconcile cycles recommending to scale up required before scaling up. 0 or 1 scales up as soon as the metrics are above the high watermark.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"downscaleDelaySeconds": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Number of seconds the metrics have to stay below the low watermark before scaling down. 0 scales down as soon as the metrics are below the low watermark.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"upscaleDelaySeconds": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Number of seconds the metrics have to stay above the high watermark before scaling up. 0 scales up as soon as the metrics are above the high watermark.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"scaleUpLimitFactor": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Percentage of replicas that can be added in an upscale event. Parameter used to be a float, in order to support the transition seamlessly, we validate that it is [0;100] in the code. ScaleUpLimitFactor == 0 means that upscaling will not be allowed for the target.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"scaleUpLimit": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Number of replicas that can be added in an upscale event, on top of the limit set by ScaleUpLimitFactor. 0 means that the number of replicas added is only limited by ScaleUpLimitFactor.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"scaleDownLimitFactor": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Percentage of replicas that can be removed in an downscale event. Parameter used to be a float, in order to support the transition seamlessly, we validate that it is [0;100[ in the code. ScaleDownLimitFactor == 0 means that downscaling will not be allowed for the target.",
 							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
 						},
 					},
+					"scaleDownLimit": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Number of replicas that can be removed in a downscale event, on top of the limit set by ScaleDownLimitFactor. 0 means that the number of replicas removed is only limited by ScaleDownLimitFactor.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
 					"tolerance": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Parameter used to be a float, in order to support the transition seamlessly, we validate that it is ]0;1[ in the code.",
 							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
 						},
 					},
+					"upscaleTolerance": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Tolerance applied above the high watermark, takes precedence over Tolerance when set. We validate that it is [0;1] in the code.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"downscaleTolerance": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Tolerance applied below the low watermark, takes precedence over Tolerance when set. We validate that it is [0;1] in the code.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"toleranceMode": {
+						SchemaProps: spec.SchemaProps{
+							Description: "How the tolerance is applied to the watermarks. Either multiplicative (default) to apply it as a percentage of each watermark, or band to apply it as a percentage of the band between the low and the high watermarks.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"smoothingFactor": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Weight of the new value of the metrics in the exponential moving average of their usage, the watermarks being compared to the smoothed usage. Lower values dampen the variations of the metrics, 1 (default) disables the smoothing. We validate that it is ]0;1] in the code.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"minReplicasForProportional": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Number of replicas below which a breach of the watermarks of an external metric adds or removes a single replica, instead of scaling proportionally to the metric, which is too sensitive to its noise with few replicas. 0 (default) always scales proportionally.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
 					"algorithm": {
 						SchemaProps: spec.SchemaProps{
-							Description: "computed values take the # of replicas into account",
+							Description: "computed values take the # of replicas into account Either absolute (default) to compare the value of the metrics to the watermarks, average to divide it by the number of replicas first, averageByRequest to divide it by the total request of averageByRequestResource of the replicas, in number of replicas of the size of the newest one, count to compare the number of series returned for the external metrics (e.g. one per partition) instead of their values, or logarithmic to compare the value like absolute but dampen the number of replicas recommended by a breach with a log curve.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"upscaleAlgorithm": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Algorithm used instead of the algorithm of the WPA to tell whether the external metrics are above their high watermark and how many replicas to scale up to. Defaults to the algorithm of the WPA. The metrics setting their own algorithm or targetType are not affected.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"downscaleAlgorithm": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Algorithm used instead of the algorithm of the WPA to tell whether the external metrics are below their low watermark and how many replicas to scale down to. Defaults to the algorithm of the WPA. The metrics setting their own algorithm or targetType are not affected.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"averageByRequestResource": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Resource whose requests are summed across the ready pods with the averageByRequest algorithm. Defaults to cpu.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metricAggregation": {
+						SchemaProps: spec.SchemaProps{
+							Description: "How the recommendations of the metrics are combined. Either max (default) to use the highest recommendation, or weighted-sum to scale the current replicas by the average utilization of the metrics compared to their watermarks, weighted by the weight of each metric.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metricStalenessWindowSeconds": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Maximum age in seconds of the external and object metrics, older values are not used to scale. 0 disables the check.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"metricCacheTTLSeconds": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Number of seconds the values of the external metrics are cached for, the cache being shared by the WPAs querying the same metric with the same selector in the same namespace to reduce the calls to the External Metrics Provider. 0 (default) always queries the provider.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"metricErrorPolicy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "How the WPA scales when none of its metrics can be retrieved. Either maintain (default) to keep the current number of replicas, scaleToMin to scale down to minReplicas, or lastKnownGood to use the last recommendation computed from the metrics.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"scaleDirection": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Direction in which the WPA is allowed to scale the target. Either both (default), up to only scale up, or down to only scale down, the other direction being left to manual operations.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"watermarkSchedule": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Overrides of the watermarks of the metrics during time windows, e.g. tighter watermarks during business hours. The first entry whose window contains the current time is used, the watermarks of the metrics apply otherwise.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("./api/v1alpha1.WatermarkScheduleEntry"),
+									},
+								},
+							},
+						},
+					},
+					"watermarkScheduleTimezone": {
+						SchemaProps: spec.SchemaProps{
+							Description: "IANA name of the timezone of the windows of the watermarkSchedule, e.g. Europe/Paris. Defaults to UTC.",
 							Type:        []string{"string"},
 							Format:      "",
 						},
 					},
+					"reconcileIntervalSeconds": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Number of seconds between two reconcile cycles of the WPA, it should be at least 5 seconds. 0 uses the sync period of the controller.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
 					"dryRun": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Whether planned scale changes are actually applied",
@@ -272,6 +777,20 @@ func schema__api_v1alpha1_WatermarkPodAutoscalerSpec(ref common.ReferenceCallbac
 							Format:      "",
 						},
 					},
+					"scaleApprovalWebhookURL": {
+						SchemaProps: spec.SchemaProps{
+							Description: "URL of an HTTP endpoint approving the scale changes before they are applied, e.g. to check a quota or a budget. The controller POSTs the current and desired numbers of replicas along with the metric driving the scaling, and only scales the target if the endpoint approves the change within the scale approval timeout of the controller.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"debug": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Whether the number of series returned for the external metrics and the range of their values are reported in the status and the logs, to tell when a single series dominates the aggregated value.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
 					"scaleTargetRef": {
 						SchemaProps: spec.SchemaProps{
 							Description: "part of HorizontalPodAutoscalerSpec, see comments in the k8s-1.10.8 repo: staging/src/k8s.io/api/autoscaling/v1/types.go reference to scaled resource; horizontal pod autoscaler will learn the current resource consumption and will set the desired number of pods by using its Scale subresource.",
@@ -302,6 +821,20 @@ func schema__api_v1alpha1_WatermarkPodAutoscalerSpec(ref common.ReferenceCallbac
 							Format: "int32",
 						},
 					},
+					"scaleDownToZeroEnabled": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Whether the target can be scaled down to zero replicas when the metrics are below the low watermark. MinReplicas can only be set to 0 when it is enabled.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"scaleUpFromZeroReplicas": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Number of replicas to scale up to when the target is at zero replicas and the metrics are above the high watermark. Defaults to 1.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
 					"maxReplicas": {
 						SchemaProps: spec.SchemaProps{
 							Type:   []string{"integer"},
@@ -314,12 +847,47 @@ func schema__api_v1alpha1_WatermarkPodAutoscalerSpec(ref common.ReferenceCallbac
 							Format: "int32",
 						},
 					},
+					"initialReadinessDelaySeconds": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Number of seconds after the start of a pod during which its resource and pods metrics are ignored, even if it is ready. Once set, the metrics of the unready pods are ignored as well, whatever the resource. 0 (default) only ignores the CPU metrics of the pods that have never been ready.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"useReadyReplicas": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Whether only the ready pods which are not terminating are counted as the current replicas the recommendations are proportional to, so that the pods still terminating after a downscale don't lower the usage per replica.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"minReadyPercentage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Minimum percentage of the pods of the target which have to be ready for the WPA to scale it, the current number of replicas is kept otherwise, as scaling a target whose pods are crash-looping can make it worse. 0 (default) disables the check.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"minReplicaChange": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Minimum difference between the recommendation and the current number of replicas for the target to be scaled, the current number of replicas is kept otherwise, e.g. 2 to ignore the recommendations of a single replica more or less. Unlike the tolerance, it applies to the number of replicas rather than to the value of the metrics. Scaling to and from zero replicas is not affected. 0 (default) disables the check.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"recommendationHistoryLimit": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Number of the last recommendations of the WPA kept in the recommendationHistory of its status, 10 by default, at most 50.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
 				},
 				Required: []string{"scaleTargetRef"},
 			},
 		},
 		Dependencies: []string{
-			"./api/v1alpha1.CrossVersionObjectReference", "./api/v1alpha1.MetricSpec", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
+			"./api/v1alpha1.CrossVersionObjectReference", "./api/v1alpha1.MetricSpec", "./api/v1alpha1.WatermarkScheduleEntry", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
 	}
 }
 
@@ -353,6 +921,69 @@ func schema__api_v1alpha1_WatermarkPodAutoscalerStatus(ref common.ReferenceCallb
 							Format: "int32",
 						},
 					},
+					"scalingMetricName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "name of the metric that drove the last recommendation",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"scalingMetricValue": {
+						SchemaProps: spec.SchemaProps{
+							Description: "value of the metric that drove the last recommendation",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"scalingMetricPosition": {
+						SchemaProps: spec.SchemaProps{
+							Description: "position of the metric that drove the last recommendation relative to its watermarks, one of AboveHighWatermark, BelowLowWatermark, BelowIdleWatermark or WithinTolerance",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"lastDecisionReason": {
+						SchemaProps: spec.SchemaProps{
+							Description: "reason of the last scaling decision, e.g. WithinTolerance, ClampedToMax, InCooldown or MetricStale",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"externalMetricSeries": {
+						VendorExtensible: spec.VendorExtensible{
+							Extensions: spec.Extensions{
+								"x-kubernetes-list-type": "set",
+							},
+						},
+						SchemaProps: spec.SchemaProps{
+							Description: "number of series returned for each external metric and the range of their values, only reported with debug",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("./api/v1alpha1.ExternalMetricSeriesStatus"),
+									},
+								},
+							},
+						},
+					},
+					"recommendationHistory": {
+						VendorExtensible: spec.VendorExtensible{
+							Extensions: spec.Extensions{
+								"x-kubernetes-list-type": "set",
+							},
+						},
+						SchemaProps: spec.SchemaProps{
+							Description: "last recommendations of the WPA, the oldest first, up to the recommendationHistoryLimit",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("./api/v1alpha1.RecommendationHistoryEntry"),
+									},
+								},
+							},
+						},
+					},
 					"currentMetrics": {
 						VendorExtensible: spec.VendorExtensible{
 							Extensions: spec.Extensions{
@@ -392,6 +1023,97 @@ func schema__api_v1alpha1_WatermarkPodAutoscalerStatus(ref common.ReferenceCallb
 			},
 		},
 		Dependencies: []string{
-			"k8s.io/api/autoscaling/v2beta1.HorizontalPodAutoscalerCondition", "k8s.io/api/autoscaling/v2beta1.MetricStatus", "k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+			"./api/v1alpha1.ExternalMetricSeriesStatus", "./api/v1alpha1.RecommendationHistoryEntry", "k8s.io/api/autoscaling/v2beta1.HorizontalPodAutoscalerCondition", "k8s.io/api/autoscaling/v2beta1.MetricStatus", "k8s.io/apimachinery/pkg/api/resource.Quantity", "k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
+func schema__api_v1alpha1_WatermarkScheduleEntry(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "WatermarkScheduleEntry overrides the watermarks of the metrics during a time window.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"days": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Days of the week of the window, as a comma-separated list of days or ranges of days, e.g. Mon-Fri or Sat,Sun. Every day when empty.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"start": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Start of the window on each of the days, formatted as HH:MM.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"end": {
+						SchemaProps: spec.SchemaProps{
+							Description: "End of the window, formatted as HH:MM. A window ending at or before its start spans midnight and ends on the next day.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metricName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the external or object metric, or of the resource, whose watermarks are overridden. All of the metrics when empty.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"highWatermark": {
+						SchemaProps: spec.SchemaProps{
+							Description: "HighWatermark used instead of the one of the metrics during the window.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"lowWatermark": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LowWatermark used instead of the one of the metrics during the window.",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+				},
+				Required: []string{"start", "end"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/api/resource.Quantity"},
+	}
+}
+
+func schema__api_v1alpha1_WatermarkSource(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "WatermarkSource references a ConfigMap holding the watermarks of a metric.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"configMapName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "configMapName is the name of the ConfigMap holding the watermarks, in the namespace of the WPA.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"highWatermarkKey": {
+						SchemaProps: spec.SchemaProps{
+							Description: "highWatermarkKey is the key of the high watermark in the ConfigMap, highWatermark by default.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"lowWatermarkKey": {
+						SchemaProps: spec.SchemaProps{
+							Description: "lowWatermarkKey is the key of the low watermark in the ConfigMap, lowWatermark by default.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"configMapName"},
+			},
+		},
 	}
 }
@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// maxMetricErrorBackoff caps the interval between two reconcile cycles of a WPA whose metrics can't be retrieved.
+	maxMetricErrorBackoff = 5 * time.Minute
+	// maxRequeueJitterPercent caps the jitter applied to the interval between two reconcile cycles of a WPA.
+	maxRequeueJitterPercent = 100
+	// minJitteredRequeueInterval is the shortest interval the jitter can bring the requeue interval down to, the minimum
+	// reconcileIntervalSeconds of a WPA.
+	minJitteredRequeueInterval = 5 * time.Second
+)
+
+// metricErrorBackoff keeps the number of consecutive reconcile cycles for which none of the metrics of each WPA
+// could be retrieved, to requeue it less often during an outage of the metrics provider.
+type metricErrorBackoff struct {
+	sync.Mutex
+	failures map[types.NamespacedName]int32
+}
+
+// failure records a reconcile cycle for which none of the metrics could be retrieved.
+func (b *metricErrorBackoff) failure(key types.NamespacedName) {
+	b.Lock()
+	defer b.Unlock()
+	if b.failures == nil {
+		b.failures = make(map[types.NamespacedName]int32)
+	}
+	b.failures[key]++
+}
+
+// success resets the backoff once the metrics could be retrieved.
+func (b *metricErrorBackoff) success(key types.NamespacedName) {
+	b.delete(key)
+}
+
+// requeueAfter returns the sync period doubled for each consecutive failure, capped at maxMetricErrorBackoff.
+// The sync period is returned as is without failures, or when it is already above the cap.
+func (b *metricErrorBackoff) requeueAfter(key types.NamespacedName, syncPeriod time.Duration) time.Duration {
+	b.Lock()
+	defer b.Unlock()
+	interval := syncPeriod
+	for i := int32(0); i < b.failures[key] && interval < maxMetricErrorBackoff; i++ {
+		interval *= 2
+	}
+	if interval > maxMetricErrorBackoff && syncPeriod < maxMetricErrorBackoff {
+		return maxMetricErrorBackoff
+	}
+	return interval
+}
+
+// delete frees the failures of a WPA.
+func (b *metricErrorBackoff) delete(key types.NamespacedName) {
+	b.Lock()
+	defer b.Unlock()
+	delete(b.failures, key)
+}
+
+// addRequeueJitter returns the interval increased or decreased by a random fraction of up to jitterPercent percent of
+// it, so that the WPAs sharing the same interval don't all query the metrics provider at once. random returns a number
+// in [0, 1). The jitter never brings the interval below minJitteredRequeueInterval, nor shortens an interval that is
+// already below it.
+func addRequeueJitter(interval time.Duration, jitterPercent int, random func() float64) time.Duration {
+	if jitterPercent <= 0 || random == nil {
+		return interval
+	}
+	if jitterPercent > maxRequeueJitterPercent {
+		jitterPercent = maxRequeueJitterPercent
+	}
+	jittered := interval + time.Duration((2*random()-1)*float64(interval)*float64(jitterPercent)/100)
+	floor := minJitteredRequeueInterval
+	if interval < floor {
+		floor = interval
+	}
+	if jittered < floor {
+		return floor
+	}
+	return jittered
+}
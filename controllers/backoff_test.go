@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestMetricErrorBackoffRequeueAfter(t *testing.T) {
+	key := types.NamespacedName{Namespace: testingNamespace, Name: testingWPAName}
+
+	tests := []struct {
+		name       string
+		failures   int
+		syncPeriod time.Duration
+		expected   time.Duration
+	}{
+		{
+			name:       "no failure",
+			syncPeriod: 15 * time.Second,
+			expected:   15 * time.Second,
+		},
+		{
+			name:       "single failure",
+			failures:   1,
+			syncPeriod: 15 * time.Second,
+			expected:   30 * time.Second,
+		},
+		{
+			name:       "consecutive failures",
+			failures:   3,
+			syncPeriod: 15 * time.Second,
+			expected:   2 * time.Minute,
+		},
+		{
+			name:       "capped",
+			failures:   5,
+			syncPeriod: 15 * time.Second,
+			expected:   maxMetricErrorBackoff,
+		},
+		{
+			name:       "capped after many failures",
+			failures:   100,
+			syncPeriod: 15 * time.Second,
+			expected:   maxMetricErrorBackoff,
+		},
+		{
+			name:       "sync period above the cap",
+			failures:   2,
+			syncPeriod: 10 * time.Minute,
+			expected:   10 * time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backoff := &metricErrorBackoff{}
+			for i := 0; i < tt.failures; i++ {
+				backoff.failure(key)
+			}
+			assert.Equal(t, tt.expected, backoff.requeueAfter(key, tt.syncPeriod))
+		})
+	}
+}
+
+func TestMetricErrorBackoffSuccess(t *testing.T) {
+	key := types.NamespacedName{Namespace: testingNamespace, Name: testingWPAName}
+	other := types.NamespacedName{Namespace: testingNamespace, Name: "other"}
+	backoff := &metricErrorBackoff{}
+	backoff.failure(key)
+	backoff.failure(key)
+	backoff.failure(other)
+	assert.Equal(t, 60*time.Second, backoff.requeueAfter(key, 15*time.Second))
+
+	// the first success resets the backoff of the WPA only.
+	backoff.success(key)
+	assert.Equal(t, 15*time.Second, backoff.requeueAfter(key, 15*time.Second))
+	assert.Equal(t, 30*time.Second, backoff.requeueAfter(other, 15*time.Second))
+
+	backoff.delete(other)
+	_, found := backoff.failures[other]
+	assert.False(t, found)
+}
+
+func TestAddRequeueJitter(t *testing.T) {
+	tests := []struct {
+		name          string
+		interval      time.Duration
+		jitterPercent int
+		random        func() float64
+		expected      time.Duration
+	}{
+		{
+			name:          "no jitter",
+			interval:      15 * time.Second,
+			jitterPercent: 0,
+			random:        func() float64 { return 0.5 },
+			expected:      15 * time.Second,
+		},
+		{
+			name:          "no random source",
+			interval:      15 * time.Second,
+			jitterPercent: 10,
+			expected:      15 * time.Second,
+		},
+		{
+			name:          "lowest jitter",
+			interval:      15 * time.Second,
+			jitterPercent: 10,
+			random:        func() float64 { return 0 },
+			expected:      13*time.Second + 500*time.Millisecond,
+		},
+		{
+			name:          "middle of the jitter",
+			interval:      15 * time.Second,
+			jitterPercent: 10,
+			random:        func() float64 { return 0.5 },
+			expected:      15 * time.Second,
+		},
+		{
+			name:          "half of the jitter",
+			interval:      15 * time.Second,
+			jitterPercent: 10,
+			random:        func() float64 { return 0.75 },
+			expected:      15*time.Second + 750*time.Millisecond,
+		},
+		{
+			name:          "jitter capped at the interval",
+			interval:      15 * time.Second,
+			jitterPercent: 300,
+			random:        func() float64 { return 0.75 },
+			expected:      22*time.Second + 500*time.Millisecond,
+		},
+		{
+			name:          "interval not shortened below the minimum",
+			interval:      15 * time.Second,
+			jitterPercent: 100,
+			random:        func() float64 { return 0.1 },
+			expected:      5 * time.Second,
+		},
+		{
+			name:          "interval below the minimum not shortened",
+			interval:      2 * time.Second,
+			jitterPercent: 10,
+			random:        func() float64 { return 0 },
+			expected:      2 * time.Second,
+		},
+		{
+			name:          "interval below the minimum lengthened",
+			interval:      2 * time.Second,
+			jitterPercent: 10,
+			random:        func() float64 { return 0.75 },
+			expected:      2*time.Second + 100*time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, addRequeueJitter(tt.interval, tt.jitterPercent, tt.random))
+		})
+	}
+}
+
+func TestAddRequeueJitterBounds(t *testing.T) {
+	seeded := rand.New(rand.NewSource(42))
+	shorter, longer := false, false
+	for i := 0; i < 1000; i++ {
+		interval := addRequeueJitter(15*time.Second, 20, seeded.Float64)
+		assert.True(t, interval >= 12*time.Second && interval < 18*time.Second, "interval %s out of bounds", interval)
+		shorter = shorter || interval < 15*time.Second
+		longer = longer || interval > 15*time.Second
+	}
+	// the intervals are spread on both sides of the interval.
+	assert.True(t, shorter)
+	assert.True(t, longer)
+}
@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// breach is an ongoing breach of the watermarks in a single direction.
+type breach struct {
+	// count is positive for upscale recommendations and negative for downscale ones.
+	count int32
+	// start is the time the first recommendation of the breach was observed.
+	start time.Time
+}
+
+// breachCounter keeps the ongoing breach of each WPA, to only scale once the recommendation was seen in the same direction
+// for enough consecutive reconcile cycles and for long enough.
+type breachCounter struct {
+	sync.Mutex
+	breaches map[types.NamespacedName]breach
+}
+
+// observe records the direction of the recommendation and returns it once it was seen for enough consecutive cycles
+// and for long enough, the current number of replicas is returned until then. A delay count of 0 or 1 and a delay of 0
+// return the recommendation right away.
+func (b *breachCounter) observe(key types.NamespacedName, now time.Time, currentReplicas, recommendation, upscaleDelayCount, downscaleDelayCount int32, upscaleDelay, downscaleDelay time.Duration) int32 {
+	b.Lock()
+	defer b.Unlock()
+	if b.breaches == nil {
+		b.breaches = make(map[types.NamespacedName]breach)
+	}
+
+	current, found := b.breaches[key]
+	switch {
+	case recommendation > currentReplicas:
+		if !found || current.count < 0 {
+			current = breach{start: now}
+		}
+		current.count++
+		if current.count < upscaleDelayCount || now.Sub(current.start) < upscaleDelay {
+			b.breaches[key] = current
+			return currentReplicas
+		}
+	case recommendation < currentReplicas:
+		if !found || current.count > 0 {
+			current = breach{start: now}
+		}
+		current.count--
+		if -current.count < downscaleDelayCount || now.Sub(current.start) < downscaleDelay {
+			b.breaches[key] = current
+			return currentReplicas
+		}
+	}
+	// the breach starts over once the recommendation is returned or when the metrics are back within the watermarks.
+	delete(b.breaches, key)
+	return recommendation
+}
+
+// delete frees the breach of a WPA.
+func (b *breachCounter) delete(key types.NamespacedName) {
+	b.Lock()
+	defer b.Unlock()
+	delete(b.breaches, key)
+}
@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestBreachCounterObserve(t *testing.T) {
+	key := types.NamespacedName{Namespace: testingNamespace, Name: testingWPAName}
+
+	type step struct {
+		// elapsed is the time since the first step.
+		elapsed         time.Duration
+		currentReplicas int32
+		recommendation  int32
+		expected        int32
+	}
+	tests := []struct {
+		name                string
+		upscaleDelayCount   int32
+		downscaleDelayCount int32
+		upscaleDelay        time.Duration
+		downscaleDelay      time.Duration
+		steps               []step
+	}{
+		{
+			name: "no delay",
+			steps: []step{
+				{currentReplicas: 5, recommendation: 8, expected: 8},
+				{currentReplicas: 8, recommendation: 4, expected: 4},
+			},
+		},
+		{
+			name:              "upscale after consecutive breaches",
+			upscaleDelayCount: 3,
+			steps: []step{
+				{currentReplicas: 5, recommendation: 8, expected: 5},
+				{currentReplicas: 5, recommendation: 7, expected: 5},
+				{currentReplicas: 5, recommendation: 9, expected: 9},
+				{currentReplicas: 9, recommendation: 10, expected: 9},
+			},
+		},
+		{
+			name:                "downscale after consecutive breaches",
+			downscaleDelayCount: 2,
+			steps: []step{
+				{currentReplicas: 5, recommendation: 3, expected: 5},
+				{currentReplicas: 5, recommendation: 3, expected: 3},
+			},
+		},
+		{
+			name:                "flapping series never scales",
+			upscaleDelayCount:   2,
+			downscaleDelayCount: 2,
+			steps: []step{
+				{currentReplicas: 5, recommendation: 8, expected: 5},
+				{currentReplicas: 5, recommendation: 3, expected: 5},
+				{currentReplicas: 5, recommendation: 8, expected: 5},
+				{currentReplicas: 5, recommendation: 3, expected: 5},
+			},
+		},
+		{
+			name:              "back within bounds resets the count",
+			upscaleDelayCount: 2,
+			steps: []step{
+				{currentReplicas: 5, recommendation: 8, expected: 5},
+				{currentReplicas: 5, recommendation: 5, expected: 5},
+				{currentReplicas: 5, recommendation: 8, expected: 5},
+				{currentReplicas: 5, recommendation: 8, expected: 8},
+			},
+		},
+		{
+			name:         "upscale once the breach lasted long enough",
+			upscaleDelay: 60 * time.Second,
+			steps: []step{
+				{elapsed: 0, currentReplicas: 5, recommendation: 8, expected: 5},
+				{elapsed: 30 * time.Second, currentReplicas: 5, recommendation: 8, expected: 5},
+				{elapsed: 60 * time.Second, currentReplicas: 5, recommendation: 9, expected: 9},
+			},
+		},
+		{
+			name:           "downscale once the breach lasted long enough",
+			downscaleDelay: 30 * time.Second,
+			steps: []step{
+				{elapsed: 0, currentReplicas: 5, recommendation: 3, expected: 5},
+				{elapsed: 15 * time.Second, currentReplicas: 5, recommendation: 3, expected: 5},
+				{elapsed: 45 * time.Second, currentReplicas: 5, recommendation: 3, expected: 3},
+			},
+		},
+		{
+			name:         "back within bounds restarts the delay",
+			upscaleDelay: 60 * time.Second,
+			steps: []step{
+				{elapsed: 0, currentReplicas: 5, recommendation: 8, expected: 5},
+				{elapsed: 45 * time.Second, currentReplicas: 5, recommendation: 5, expected: 5},
+				{elapsed: 60 * time.Second, currentReplicas: 5, recommendation: 8, expected: 5},
+				{elapsed: 105 * time.Second, currentReplicas: 5, recommendation: 8, expected: 5},
+				{elapsed: 120 * time.Second, currentReplicas: 5, recommendation: 8, expected: 8},
+			},
+		},
+		{
+			name:           "change of direction restarts the delay",
+			upscaleDelay:   30 * time.Second,
+			downscaleDelay: 30 * time.Second,
+			steps: []step{
+				{elapsed: 0, currentReplicas: 5, recommendation: 8, expected: 5},
+				{elapsed: 20 * time.Second, currentReplicas: 5, recommendation: 3, expected: 5},
+				{elapsed: 40 * time.Second, currentReplicas: 5, recommendation: 3, expected: 5},
+				{elapsed: 50 * time.Second, currentReplicas: 5, recommendation: 3, expected: 3},
+			},
+		},
+		{
+			name:              "both the delay count and the delay are required",
+			upscaleDelayCount: 3,
+			upscaleDelay:      10 * time.Second,
+			steps: []step{
+				{elapsed: 0, currentReplicas: 5, recommendation: 8, expected: 5},
+				{elapsed: 20 * time.Second, currentReplicas: 5, recommendation: 8, expected: 5},
+				{elapsed: 25 * time.Second, currentReplicas: 5, recommendation: 8, expected: 8},
+			},
+		},
+	}
+	start := time.Now()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := &breachCounter{}
+			for i, s := range tt.steps {
+				got := counter.observe(key, start.Add(s.elapsed), s.currentReplicas, s.recommendation, tt.upscaleDelayCount, tt.downscaleDelayCount, tt.upscaleDelay, tt.downscaleDelay)
+				assert.Equal(t, s.expected, got, "step %d", i)
+			}
+		})
+	}
+}
+
+func TestBreachCounterDelete(t *testing.T) {
+	key := types.NamespacedName{Namespace: testingNamespace, Name: testingWPAName}
+	counter := &breachCounter{}
+	counter.observe(key, time.Now(), 3, 5, 2, 2, 0, 0)
+	assert.Equal(t, int32(1), counter.breaches[key].count)
+
+	counter.delete(key)
+	_, found := counter.breaches[key]
+	assert.False(t, found)
+}
@@ -0,0 +1,206 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"math"
+
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// RecommendationInput is what the recommendation of an external metric is computed from, without access to a cluster.
+type RecommendationInput struct {
+	// Spec of the WPA, for its algorithm, tolerances, rounding, smoothing and scaling to zero.
+	Spec v1alpha1.WatermarkPodAutoscalerSpec
+	// Metric is the external metric the values were returned for, for its watermarks, aggregation and algorithm.
+	Metric v1alpha1.ExternalMetricSource
+	// Values of the series returned for the metric, as milli-values.
+	Values []int64
+	// DenominatorValues are the values of the series returned for the denominatorMetricName of the metric, as milli-values.
+	DenominatorValues []int64
+	// CurrentReplicas is the number of replicas of the target.
+	CurrentReplicas int32
+	// ReadyCapacity is the number of ready replicas of the target, or with the averageByRequest algorithm
+	// the total request of the ready pods in number of replicas of the size of the newest one.
+	ReadyCapacity float64
+	// ReadyCapacities are the ready capacities by algorithm for the upscaleAlgorithm and downscaleAlgorithm of the WPA,
+	// ReadyCapacity is used for the algorithms missing.
+	ReadyCapacities map[string]float64
+	// PreviousUsage is the usage of the previous recommendation once smoothed, nil for the first one.
+	PreviousUsage *float64
+}
+
+// RecommendationResult is the number of replicas recommended by the watermarks of an external metric, before it is
+// restricted to the directions the metric is allowed to scale in and clamped to the minReplicas and maxReplicas of the WPA.
+type RecommendationResult struct {
+	// ReplicaCount is the recommended number of replicas.
+	ReplicaCount int32
+	// ProportionalReplicaCount is the number of replicas proportional to the usage, before it is stepped below minReplicasForProportional.
+	ProportionalReplicaCount int32
+	// Stepped is whether a single replica is added or removed instead of scaling proportionally.
+	Stepped bool
+	// Reason is the branch that fired: AboveHighWatermark, BelowLowWatermark, BelowIdleWatermark or WithinTolerance.
+	Reason string
+	// RawUsage is the usage of the metric as a milli-value, aggregated and averaged with the algorithm.
+	RawUsage float64
+	// Usage is the RawUsage capped to the maxUtilization of the metric and smoothed with the smoothingFactor of the WPA,
+	// compared to the watermarks.
+	Usage float64
+	// Clamped is whether the RawUsage was above the maxUtilization of the metric.
+	Clamped bool
+	// UpscaleTolerance and DownscaleTolerance are the tolerances applied to the watermarks, as milli-values.
+	UpscaleTolerance   int64
+	DownscaleTolerance int64
+	// AdjustedLowWatermark and AdjustedHighWatermark are the watermarks widened by the tolerances, as milli-values.
+	AdjustedLowWatermark  float64
+	AdjustedHighWatermark float64
+	// Distance is how far the usage is from the breached watermark, as a fraction of the watermark.
+	Distance float64
+}
+
+// ComputeRecommendation returns the number of replicas recommended by the watermarks of an external metric for the
+// given values, with the same math as the controller but without any metric, log or call to the cluster, e.g. to
+// replay the history of a metric. The error reports a usage or a number of replicas that is NaN or Inf, the usage
+// is then still returned. With an upscaleAlgorithm or a downscaleAlgorithm, the usage is the one of the algorithm
+// the recommendation comes from, see getRecommendationAlgorithm.
+func ComputeRecommendation(input RecommendationInput) (RecommendationResult, error) {
+	wpa := &v1alpha1.WatermarkPodAutoscaler{Spec: input.Spec}
+	metric := input.Metric
+	metric.LowWatermark, metric.HighWatermark = metric.GetWatermarks()
+	metricSpec := v1alpha1.MetricSpec{External: &metric}
+	algorithm := getExternalMetricAlgorithm(wpa, metricSpec)
+	upscaleAlgorithm, downscaleAlgorithm := getExternalMetricDirectionAlgorithms(wpa, metricSpec)
+	if upscaleAlgorithm == algorithm && downscaleAlgorithm == algorithm {
+		return computeAlgorithmRecommendation(wpa, metric, input, algorithm, input.ReadyCapacity)
+	}
+
+	// the upscaleAlgorithm only decides whether the metric is above its high watermark,
+	// and the downscaleAlgorithm whether it is below its low watermark.
+	upscale, err := computeAlgorithmRecommendation(wpa, metric, input, upscaleAlgorithm, getAlgorithmReadyCapacity(input.ReadyCapacity, input.ReadyCapacities, upscaleAlgorithm))
+	if err != nil || upscale.Reason == v1alpha1.DecisionReasonAboveHighWatermark {
+		return upscale, err
+	}
+	downscale, err := computeAlgorithmRecommendation(wpa, metric, input, downscaleAlgorithm, getAlgorithmReadyCapacity(input.ReadyCapacity, input.ReadyCapacities, downscaleAlgorithm))
+	if err != nil || downscale.Reason == v1alpha1.DecisionReasonBelowLowWatermark || downscale.Reason == v1alpha1.DecisionReasonBelowIdleWatermark {
+		return downscale, err
+	}
+	// neither direction is breached with its own algorithm, the current number of replicas is kept.
+	downscale.ReplicaCount = input.CurrentReplicas
+	downscale.ProportionalReplicaCount = input.CurrentReplicas
+	downscale.Stepped = false
+	downscale.Reason = v1alpha1.DecisionReasonWithinTolerance
+	downscale.Distance = 0
+	return downscale, nil
+}
+
+// getRecommendationAlgorithm returns the algorithm the recommendation of the external metric with the reason was computed
+// with: the upscaleAlgorithm above the high watermark and the downscaleAlgorithm otherwise.
+func getRecommendationAlgorithm(wpa *v1alpha1.WatermarkPodAutoscaler, metric v1alpha1.MetricSpec, reason string) string {
+	upscaleAlgorithm, downscaleAlgorithm := getExternalMetricDirectionAlgorithms(wpa, metric)
+	if reason == v1alpha1.DecisionReasonAboveHighWatermark {
+		return upscaleAlgorithm
+	}
+	return downscaleAlgorithm
+}
+
+// getAlgorithmReadyCapacity returns the ready capacity for the algorithm from the ready capacities by algorithm,
+// the default ready capacity when it is missing.
+func getAlgorithmReadyCapacity(readyCapacity float64, readyCapacities map[string]float64, algorithm string) float64 {
+	if algorithmCapacity, ok := readyCapacities[algorithm]; ok {
+		return algorithmCapacity
+	}
+	return readyCapacity
+}
+
+// computeAlgorithmRecommendation returns the number of replicas recommended by the watermarks of the external metric
+// when its usage is computed with the algorithm and the ready capacity.
+func computeAlgorithmRecommendation(wpa *v1alpha1.WatermarkPodAutoscaler, metric v1alpha1.ExternalMetricSource, input RecommendationInput, algorithm string, readyCapacity float64) (RecommendationResult, error) {
+	var aggregated float64
+	switch {
+	case metric.DenominatorMetricName != "":
+		aggregated = getRatio(input.Values, input.DenominatorValues)
+	case algorithm == "count":
+		// the number of series returned (e.g. one per partition) is compared to the watermarks, as a milli-value like the values.
+		aggregated = float64(len(input.Values)) * 1000
+	case len(metric.Weights) > 0 && (metric.AggregatorFunc == "" || metric.AggregatorFunc == "sum"):
+		aggregated = weightedSum(input.Values, metric.Weights)
+	default:
+		aggregated = aggregate(input.Values, metric.AggregatorFunc)
+	}
+
+	// if the average algorithm is used, the metrics retrieved has to be divided by the number of available replicas.
+	// the usage is then capped to the maxUtilization of the metric and smoothed with the smoothingFactor of the WPA.
+	rawUsage := aggregated / getAveragedCapacity(algorithm, readyCapacity)
+	clampedUsage, clamped := clampUsage(rawUsage, metric.MaxUtilization)
+	usage := getSmoothedUsage(clampedUsage, input.PreviousUsage, getSmoothingFactor(wpa))
+	// the capacity of a replica is only meaningful when the raw value is compared to the watermarks, not a ratio.
+	var perReplicaCapacity *resource.Quantity
+	if algorithm == "absolute" && metric.DenominatorMetricName == "" {
+		perReplicaCapacity = metric.PerReplicaCapacity
+	}
+	result, err := getWatermarkRecommendation(wpa, algorithm, metric.MetricName, input.CurrentReplicas, readyCapacity, usage, metric.LowWatermark, metric.HighWatermark, metric.Tolerance, perReplicaCapacity, metric.IdleWatermark)
+	result.RawUsage = rawUsage
+	result.Usage = usage
+	result.Clamped = clamped
+	if err != nil {
+		return result, err
+	}
+
+	result.ProportionalReplicaCount = result.ReplicaCount
+	// with few replicas, a breach of the watermarks adds or removes a single replica.
+	if input.CurrentReplicas < input.Spec.MinReplicasForProportional {
+		result.ReplicaCount, result.Stepped = getSteppedReplicaCount(wpa, input.CurrentReplicas, result.ReplicaCount, result.Reason)
+	}
+	return result, nil
+}
+
+// getRatio returns the sum of the numerator divided by the sum of the denominator, as a milli-value like the values.
+// It is NaN or Inf when the sum of the denominator is zero.
+func getRatio(numerator, denominator []int64) float64 {
+	return aggregate(numerator, "sum") * 1000 / aggregate(denominator, "sum")
+}
+
+// clampUsage returns the usage capped to the maxUtilization of the metric, and whether it was above it.
+// The usage is kept as is when there is no maxUtilization.
+func clampUsage(usage float64, maxUtilization *resource.Quantity) (float64, bool) {
+	if maxUtilization == nil || !(usage > float64(maxUtilization.MilliValue())) {
+		return usage, false
+	}
+	return float64(maxUtilization.MilliValue()), true
+}
+
+// getAveragedCapacity returns the number the aggregated value of an external metric is divided by with the algorithm,
+// the ready capacity with the average algorithms and 1 otherwise. At zero replicas, the first replica would get all of the load.
+func getAveragedCapacity(algorithm string, readyCapacity float64) float64 {
+	if algorithm == "average" || algorithm == "averageByRequest" {
+		return math.Max(readyCapacity, 1)
+	}
+	return 1
+}
+
+// getSteppedReplicaCount returns the number of replicas recommended below the minReplicasForProportional of the WPA:
+// one more replica above the high watermark and one less below the low watermark, and whether it was stepped.
+// The other recommendations are kept.
+func getSteppedReplicaCount(wpa *v1alpha1.WatermarkPodAutoscaler, currentReplicas, replicaCount int32, reason string) (int32, bool) {
+	switch reason {
+	case v1alpha1.DecisionReasonAboveHighWatermark:
+		return currentReplicas + 1, true
+	case v1alpha1.DecisionReasonBelowLowWatermark:
+		// Keep a minimum of 1 replica, unless the target can be scaled down to zero
+		minReplicas := int32(1)
+		if wpa.Spec.ScaleDownToZeroEnabled {
+			minReplicas = 0
+		}
+		if currentReplicas > minReplicas {
+			return currentReplicas - 1, true
+		}
+		return currentReplicas, true
+	default:
+		return replicaCount, false
+	}
+}
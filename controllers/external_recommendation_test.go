@@ -0,0 +1,433 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"math"
+	"testing"
+
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestComputeRecommendation(t *testing.T) {
+	spec := func(modify func(spec *v1alpha1.WatermarkPodAutoscalerSpec)) v1alpha1.WatermarkPodAutoscalerSpec {
+		spec := v1alpha1.WatermarkPodAutoscalerSpec{
+			Algorithm: "absolute",
+			Tolerance: *resource.NewMilliQuantity(100, resource.DecimalSI), // 10%
+		}
+		if modify != nil {
+			modify(&spec)
+		}
+		return spec
+	}
+	metric := func(modify func(metric *v1alpha1.ExternalMetricSource)) v1alpha1.ExternalMetricSource {
+		metric := v1alpha1.ExternalMetricSource{
+			MetricName:    "queue",
+			HighWatermark: resource.NewQuantity(8, resource.DecimalSI),
+			LowWatermark:  resource.NewQuantity(3, resource.DecimalSI),
+		}
+		if modify != nil {
+			modify(&metric)
+		}
+		return metric
+	}
+	previousUsage := 2000.0
+
+	tests := []struct {
+		name     string
+		input    RecommendationInput
+		expected RecommendationResult
+	}{
+		{
+			name: "within the watermarks",
+			input: RecommendationInput{
+				Spec: spec(nil), Metric: metric(nil), Values: []int64{2000, 3000}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 4, ProportionalReplicaCount: 4, Reason: v1alpha1.DecisionReasonWithinTolerance,
+				RawUsage: 5000, Usage: 5000, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800,
+			},
+		},
+		{
+			// 4 * 10000 / 8000 = 5.
+			name: "above the high watermark",
+			input: RecommendationInput{
+				Spec: spec(nil), Metric: metric(nil), Values: []int64{3000, 7000}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 5, ProportionalReplicaCount: 5, Reason: v1alpha1.DecisionReasonAboveHighWatermark,
+				RawUsage: 10000, Usage: 10000, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800, Distance: -0.25,
+			},
+		},
+		{
+			// 4 * 1500 / 3000 = 2.
+			name: "below the low watermark",
+			input: RecommendationInput{
+				Spec: spec(nil), Metric: metric(nil), Values: []int64{1500}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 2, ProportionalReplicaCount: 2, Reason: v1alpha1.DecisionReasonBelowLowWatermark,
+				RawUsage: 1500, Usage: 1500, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800, Distance: 0.5,
+			},
+		},
+		{
+			name: "below the low watermark keeps a replica",
+			input: RecommendationInput{
+				Spec: spec(nil), Metric: metric(nil), Values: []int64{100}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 1, ProportionalReplicaCount: 1, Reason: v1alpha1.DecisionReasonBelowLowWatermark,
+				RawUsage: 100, Usage: 100, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800, Distance: float64(2900) / 3000,
+			},
+		},
+		{
+			name: "below the idle watermark",
+			input: RecommendationInput{
+				Spec: spec(func(spec *v1alpha1.WatermarkPodAutoscalerSpec) { spec.ScaleDownToZeroEnabled = true }),
+				Metric: metric(func(metric *v1alpha1.ExternalMetricSource) {
+					metric.IdleWatermark = resource.NewQuantity(1, resource.DecimalSI)
+				}),
+				Values: []int64{600}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 0, ProportionalReplicaCount: 0, Reason: v1alpha1.DecisionReasonBelowIdleWatermark,
+				RawUsage: 600, Usage: 600, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800, Distance: 0.8,
+			},
+		},
+		{
+			// 40000 / 4 replicas = 10000, above the high watermark: 4 * 10000 / 8000 = 5.
+			name: "average algorithm",
+			input: RecommendationInput{
+				Spec: spec(func(spec *v1alpha1.WatermarkPodAutoscalerSpec) { spec.Algorithm = "average" }), Metric: metric(nil),
+				Values: []int64{40000}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 5, ProportionalReplicaCount: 5, Reason: v1alpha1.DecisionReasonAboveHighWatermark,
+				RawUsage: 10000, Usage: 10000, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800, Distance: -0.25,
+			},
+		},
+		{
+			// the algorithm of the metric is preferred to the one of the WPA, and without ready replica the value isn't divided.
+			name: "average algorithm of the metric without ready replica",
+			input: RecommendationInput{
+				Spec:   spec(nil),
+				Metric: metric(func(metric *v1alpha1.ExternalMetricSource) { metric.Algorithm = "average" }),
+				Values: []int64{5000}, CurrentReplicas: 0, ReadyCapacity: 0,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 0, ProportionalReplicaCount: 0, Reason: v1alpha1.DecisionReasonWithinTolerance,
+				RawUsage: 5000, Usage: 5000, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800,
+			},
+		},
+		{
+			// the values are ignored, the 9 series are compared to the watermarks: 4 * 9000 / 8000 = 4.5.
+			name: "count algorithm",
+			input: RecommendationInput{
+				Spec:   spec(nil),
+				Metric: metric(func(metric *v1alpha1.ExternalMetricSource) { metric.Algorithm = "count" }),
+				Values: []int64{1, 1, 1, 1, 1, 1, 1, 1, 1}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 5, ProportionalReplicaCount: 5, Reason: v1alpha1.DecisionReasonAboveHighWatermark,
+				RawUsage: 9000, Usage: 9000, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800, Distance: -0.125,
+			},
+		},
+		{
+			name: "max aggregator",
+			input: RecommendationInput{
+				Spec:   spec(nil),
+				Metric: metric(func(metric *v1alpha1.ExternalMetricSource) { metric.AggregatorFunc = "max" }),
+				Values: []int64{3000, 2500, 3500}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 4, ProportionalReplicaCount: 4, Reason: v1alpha1.DecisionReasonWithinTolerance,
+				RawUsage: 3500, Usage: 3500, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800,
+			},
+		},
+		{
+			// 2 * 4000 + 1000 = 9000, 4 * 9000 / 8000 = 4.5.
+			name: "weighted values",
+			input: RecommendationInput{
+				Spec: spec(nil),
+				Metric: metric(func(metric *v1alpha1.ExternalMetricSource) {
+					metric.Weights = []resource.Quantity{*resource.NewQuantity(2, resource.DecimalSI)}
+				}),
+				Values: []int64{4000, 1000}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 5, ProportionalReplicaCount: 5, Reason: v1alpha1.DecisionReasonAboveHighWatermark,
+				RawUsage: 9000, Usage: 9000, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800, Distance: -0.125,
+			},
+		},
+		{
+			// 12000 / 5000 = 2.4, rounded up.
+			name: "per replica capacity",
+			input: RecommendationInput{
+				Spec: spec(nil),
+				Metric: metric(func(metric *v1alpha1.ExternalMetricSource) {
+					metric.PerReplicaCapacity = resource.NewQuantity(5, resource.DecimalSI)
+				}),
+				Values: []int64{12000}, CurrentReplicas: 1, ReadyCapacity: 1,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 3, ProportionalReplicaCount: 3, Reason: v1alpha1.DecisionReasonAboveHighWatermark,
+				RawUsage: 12000, Usage: 12000, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800, Distance: -0.5,
+			},
+		},
+		{
+			// 1 error for 10 requests is 0.1, compared as is despite the average algorithm: 4 * 0.1 / 0.08 = 5.
+			name: "ratio",
+			input: RecommendationInput{
+				Spec: spec(func(spec *v1alpha1.WatermarkPodAutoscalerSpec) { spec.Algorithm = "average" }),
+				Metric: metric(func(metric *v1alpha1.ExternalMetricSource) {
+					metric.DenominatorMetricName = "requests"
+					metric.HighWatermark = resource.NewMilliQuantity(80, resource.DecimalSI)
+					metric.LowWatermark = resource.NewMilliQuantity(30, resource.DecimalSI)
+				}),
+				Values: []int64{600, 400}, DenominatorValues: []int64{5000, 5000}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 5, ProportionalReplicaCount: 5, Reason: v1alpha1.DecisionReasonAboveHighWatermark,
+				RawUsage: 100, Usage: 100, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 27, AdjustedHighWatermark: 88, Distance: -0.25,
+			},
+		},
+		{
+			// 0.5 * 14000 + 0.5 * 2000 = 8000, within the tolerance of the high watermark.
+			name: "smoothed usage",
+			input: RecommendationInput{
+				Spec: spec(func(spec *v1alpha1.WatermarkPodAutoscalerSpec) {
+					spec.SmoothingFactor = resource.NewMilliQuantity(500, resource.DecimalSI)
+				}),
+				Metric: metric(nil), Values: []int64{14000}, CurrentReplicas: 4, ReadyCapacity: 4, PreviousUsage: &previousUsage,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 4, ProportionalReplicaCount: 4, Reason: v1alpha1.DecisionReasonWithinTolerance,
+				RawUsage: 14000, Usage: 8000, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800,
+			},
+		},
+		{
+			name: "first smoothed usage",
+			input: RecommendationInput{
+				Spec: spec(func(spec *v1alpha1.WatermarkPodAutoscalerSpec) {
+					spec.SmoothingFactor = resource.NewMilliQuantity(500, resource.DecimalSI)
+				}),
+				Metric: metric(nil), Values: []int64{14000}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 7, ProportionalReplicaCount: 7, Reason: v1alpha1.DecisionReasonAboveHighWatermark,
+				RawUsage: 14000, Usage: 14000, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800, Distance: -0.75,
+			},
+		},
+		{
+			// 4 * 16000 / 8000 = 8, stepped to a single replica more.
+			name: "stepped above the high watermark",
+			input: RecommendationInput{
+				Spec:   spec(func(spec *v1alpha1.WatermarkPodAutoscalerSpec) { spec.MinReplicasForProportional = 5 }),
+				Metric: metric(nil), Values: []int64{16000}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 5, ProportionalReplicaCount: 8, Stepped: true, Reason: v1alpha1.DecisionReasonAboveHighWatermark,
+				RawUsage: 16000, Usage: 16000, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800, Distance: -1,
+			},
+		},
+		{
+			name: "stepped below the low watermark",
+			input: RecommendationInput{
+				Spec:   spec(func(spec *v1alpha1.WatermarkPodAutoscalerSpec) { spec.MinReplicasForProportional = 5 }),
+				Metric: metric(nil), Values: []int64{100}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 3, ProportionalReplicaCount: 1, Stepped: true, Reason: v1alpha1.DecisionReasonBelowLowWatermark,
+				RawUsage: 100, Usage: 100, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800, Distance: float64(2900) / 3000,
+			},
+		},
+		{
+			name: "not stepped within the watermarks",
+			input: RecommendationInput{
+				Spec:   spec(func(spec *v1alpha1.WatermarkPodAutoscalerSpec) { spec.MinReplicasForProportional = 5 }),
+				Metric: metric(nil), Values: []int64{5000}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 4, ProportionalReplicaCount: 4, Reason: v1alpha1.DecisionReasonWithinTolerance,
+				RawUsage: 5000, Usage: 5000, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800,
+			},
+		},
+		{
+			// the tolerances of the WPA and of the metric are both overridden by the upscale and downscale ones.
+			name: "upscale and downscale tolerances",
+			input: RecommendationInput{
+				Spec: spec(func(spec *v1alpha1.WatermarkPodAutoscalerSpec) {
+					spec.UpscaleTolerance = resource.NewMilliQuantity(200, resource.DecimalSI)
+					spec.DownscaleTolerance = resource.NewMilliQuantity(0, resource.DecimalSI)
+				}),
+				Metric: metric(func(metric *v1alpha1.ExternalMetricSource) {
+					metric.Tolerance = resource.NewMilliQuantity(50, resource.DecimalSI)
+				}),
+				Values: []int64{9000}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 4, ProportionalReplicaCount: 4, Reason: v1alpha1.DecisionReasonWithinTolerance,
+				RawUsage: 9000, Usage: 9000, UpscaleTolerance: 200, DownscaleTolerance: 0,
+				AdjustedLowWatermark: 3000, AdjustedHighWatermark: 9600,
+			},
+		},
+		{
+			// 4 * 10000 / 8000 = 5.
+			name: "below the maxUtilization",
+			input: RecommendationInput{
+				Spec: spec(nil),
+				Metric: metric(func(metric *v1alpha1.ExternalMetricSource) {
+					metric.MaxUtilization = resource.NewQuantity(16, resource.DecimalSI)
+				}),
+				Values: []int64{10000}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 5, ProportionalReplicaCount: 5, Reason: v1alpha1.DecisionReasonAboveHighWatermark,
+				RawUsage: 10000, Usage: 10000, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800, Distance: -0.25,
+			},
+		},
+		{
+			// 4 * 16000 / 8000 = 8.
+			name: "at the maxUtilization",
+			input: RecommendationInput{
+				Spec: spec(nil),
+				Metric: metric(func(metric *v1alpha1.ExternalMetricSource) {
+					metric.MaxUtilization = resource.NewQuantity(16, resource.DecimalSI)
+				}),
+				Values: []int64{16000}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 8, ProportionalReplicaCount: 8, Reason: v1alpha1.DecisionReasonAboveHighWatermark,
+				RawUsage: 16000, Usage: 16000, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800, Distance: -1,
+			},
+		},
+		{
+			// 80000 is capped to the maxUtilization: 4 * 16000 / 8000 = 8.
+			name: "above the maxUtilization",
+			input: RecommendationInput{
+				Spec: spec(nil),
+				Metric: metric(func(metric *v1alpha1.ExternalMetricSource) {
+					metric.MaxUtilization = resource.NewQuantity(16, resource.DecimalSI)
+				}),
+				Values: []int64{80000}, CurrentReplicas: 4, ReadyCapacity: 4,
+			},
+			expected: RecommendationResult{
+				ReplicaCount: 8, ProportionalReplicaCount: 8, Reason: v1alpha1.DecisionReasonAboveHighWatermark,
+				RawUsage: 80000, Usage: 16000, Clamped: true, UpscaleTolerance: 100, DownscaleTolerance: 100,
+				AdjustedLowWatermark: 2700, AdjustedHighWatermark: 8800, Distance: -1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ComputeRecommendation(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestComputeRecommendation_invalidValue(t *testing.T) {
+	input := RecommendationInput{
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{Algorithm: "absolute"},
+		Metric: v1alpha1.ExternalMetricSource{
+			MetricName:    "queue",
+			HighWatermark: resource.NewQuantity(0, resource.DecimalSI),
+			LowWatermark:  resource.NewQuantity(-1, resource.DecimalSI),
+		},
+		Values:          []int64{5000},
+		CurrentReplicas: 4,
+		ReadyCapacity:   4,
+	}
+	// with a high watermark of 0, the number of replicas is infinite.
+	result, err := ComputeRecommendation(input)
+	require.Error(t, err)
+	assert.Equal(t, "invalid replica count computed for the metric queue: +Inf", err.Error())
+	// the usage is still returned, e.g. to be smoothed.
+	assert.Equal(t, 5000.0, result.Usage)
+
+	previousUsage := math.Inf(1)
+	input.Spec.SmoothingFactor = resource.NewMilliQuantity(500, resource.DecimalSI)
+	input.PreviousUsage = &previousUsage
+	_, err = ComputeRecommendation(input)
+	require.Error(t, err)
+	assert.Equal(t, "invalid usage computed for the metric queue: +Inf", err.Error())
+
+	// the ratio is undefined while the sum of the denominator is zero.
+	input.Spec.SmoothingFactor = nil
+	input.PreviousUsage = nil
+	input.Metric.DenominatorMetricName = "requests"
+	input.DenominatorValues = []int64{0, 0}
+	_, err = ComputeRecommendation(input)
+	require.Error(t, err)
+	assert.Equal(t, "invalid usage computed for the metric queue: +Inf", err.Error())
+}
+
+func TestComputeRecommendation_overlappingWatermarks(t *testing.T) {
+	input := RecommendationInput{
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			Algorithm: "absolute",
+			Tolerance: *resource.NewMilliQuantity(500, resource.DecimalSI),
+		},
+		Metric: v1alpha1.ExternalMetricSource{
+			MetricName:    "queue",
+			HighWatermark: resource.NewQuantity(-5, resource.DecimalSI),
+			LowWatermark:  resource.NewQuantity(-10, resource.DecimalSI),
+		},
+		Values:          []int64{-6000},
+		CurrentReplicas: 4,
+		ReadyCapacity:   4,
+	}
+	// the tolerance of 50% moves the low watermark up to -5 and the high watermark down to -7.5: -6 is both above the
+	// adjusted high watermark and below the adjusted low watermark.
+	_, err := ComputeRecommendation(input)
+	require.Error(t, err)
+	assert.Equal(t, "the watermarks of the metric queue overlap once adjusted with the tolerances: the adjusted low watermark -5000 is not below the adjusted high watermark -7500", err.Error())
+}
+
+func TestComputeRecommendation_noSideEffect(t *testing.T) {
+	input := RecommendationInput{
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{Algorithm: "absolute"},
+		Metric: v1alpha1.ExternalMetricSource{
+			MetricName:    "queue",
+			HighWatermark: resource.NewQuantity(8, resource.DecimalSI),
+			LowWatermark:  resource.NewQuantity(3, resource.DecimalSI),
+		},
+		Values:          []int64{10000, 6000},
+		CurrentReplicas: 4,
+		ReadyCapacity:   4,
+	}
+	// the values are not sorted or modified, and the same input always gives the same result.
+	first, err := ComputeRecommendation(input)
+	require.NoError(t, err)
+	second, err := ComputeRecommendation(input)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, []int64{10000, 6000}, input.Values)
+}
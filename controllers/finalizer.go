@@ -11,6 +11,7 @@ import (
 	datadoghqv1alpha1 "github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
 	"github.com/DataDog/watermarkpodautoscaler/pkg/util"
 	logr "github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 const (
@@ -51,6 +52,11 @@ func (r *WatermarkPodAutoscalerReconciler) handleFinalizer(reqLogger logr.Logger
 
 func (r *WatermarkPodAutoscalerReconciler) finalizeWPA(reqLogger logr.Logger, wpa *datadoghqv1alpha1.WatermarkPodAutoscaler) {
 	cleanupAssociatedMetrics(wpa, false)
+	r.recommendations.delete(types.NamespacedName{Namespace: wpa.Namespace, Name: wpa.Name})
+	r.breaches.delete(types.NamespacedName{Namespace: wpa.Namespace, Name: wpa.Name})
+	r.metricErrors.delete(types.NamespacedName{Namespace: wpa.Namespace, Name: wpa.Name})
+	r.lastRecommendations.delete(types.NamespacedName{Namespace: wpa.Namespace, Name: wpa.Name})
+	r.deleteSmoothedUsages(types.NamespacedName{Namespace: wpa.Namespace, Name: wpa.Name})
 	reqLogger.Info("Successfully finalized WatermarkPodAutoscaler")
 }
 
@@ -0,0 +1,59 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
+)
+
+// externalMetricKey identifies the values returned by the External Metrics Provider for a query.
+type externalMetricKey struct {
+	metricName string
+	namespace  string
+	selector   string
+}
+
+// cachedExternalMetric is a response of the External Metrics Provider, with the time it was fetched at.
+type cachedExternalMetric struct {
+	values    []int64
+	timestamp time.Time
+	fetchedAt time.Time
+}
+
+// externalMetricCache keeps the last values of the external metrics, shared by the WPAs running the same query.
+type externalMetricCache struct {
+	sync.Mutex
+	entries map[externalMetricKey]cachedExternalMetric
+}
+
+// get returns the values of the metric and their timestamp if they were fetched less than the ttl ago.
+func (c *externalMetricCache) get(key externalMetricKey, now time.Time, ttl time.Duration) ([]int64, time.Time, bool) {
+	c.Lock()
+	defer c.Unlock()
+	entry, found := c.entries[key]
+	if !found || now.Sub(entry.fetchedAt) >= ttl {
+		return nil, time.Time{}, false
+	}
+	return entry.values, entry.timestamp, true
+}
+
+// set keeps the values of the metric fetched now, and frees the entries too old to be served to any WPA.
+func (c *externalMetricCache) set(key externalMetricKey, values []int64, timestamp, now time.Time) {
+	c.Lock()
+	defer c.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[externalMetricKey]cachedExternalMetric)
+	}
+	for k, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) >= v1alpha1.MaxMetricCacheTTLSeconds*time.Second {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cachedExternalMetric{values: values, timestamp: timestamp, fetchedAt: now}
+}
@@ -0,0 +1,181 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestExternalMetricCache(t *testing.T) {
+	now := time.Now()
+	timestamp := now.Add(-time.Minute)
+	key := externalMetricKey{metricName: "queue", namespace: testingNamespace, selector: "foo=bar"}
+	cache := &externalMetricCache{}
+
+	// miss
+	_, _, found := cache.get(key, now, 10*time.Second)
+	assert.False(t, found)
+
+	// hit, with the timestamp of the values rather than the time they were fetched at.
+	cache.set(key, []int64{1000, 2000}, timestamp, now)
+	values, cachedTimestamp, found := cache.get(key, now.Add(5*time.Second), 10*time.Second)
+	require.True(t, found)
+	assert.Equal(t, []int64{1000, 2000}, values)
+	assert.Equal(t, timestamp, cachedTimestamp)
+
+	// the same metric with another selector or in another namespace is another query.
+	_, _, found = cache.get(externalMetricKey{metricName: "queue", namespace: testingNamespace, selector: "foo=baz"}, now, 10*time.Second)
+	assert.False(t, found)
+	_, _, found = cache.get(externalMetricKey{metricName: "queue", namespace: "other", selector: "foo=bar"}, now, 10*time.Second)
+	assert.False(t, found)
+
+	// expiry, which depends on the TTL of each WPA.
+	_, _, found = cache.get(key, now.Add(10*time.Second), 10*time.Second)
+	assert.False(t, found)
+	_, _, found = cache.get(key, now.Add(10*time.Second), 30*time.Second)
+	assert.True(t, found)
+
+	// the entries older than the longest TTL are freed.
+	other := externalMetricKey{metricName: "latency", namespace: testingNamespace, selector: "foo=bar"}
+	cache.set(other, []int64{3000}, now, now.Add(v1alpha1.MaxMetricCacheTTLSeconds*time.Second))
+	assert.Len(t, cache.entries, 1)
+}
+
+func TestReplicaCalculator_getExternalMetric(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "metric-cache", Namespace: testNamespace},
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			ScaleTargetRef:        v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+			MetricCacheTTLSeconds: 10,
+		},
+	}
+	promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: testDeploymentName, resourceKindPromLabel: "Deployment", metricNamePromLabel: "deadbeef"}
+	defer metricCacheHits.Delete(promLabels)
+	defer metricCacheMisses.Delete(promLabels)
+	defer metricsFetchDuration.Delete(promLabels)
+
+	fakeClock := clock.NewFakeClock(time.Now())
+	timestamp := fakeClock.Now().Add(-30 * time.Second)
+	calls := 0
+	var providerErr error
+	provider := fakeExternalMetricsProvider{
+		getExternalMetric: func(_ context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+			calls++
+			return []int64{int64(calls * 1000)}, timestamp, providerErr
+		},
+	}
+	replicaCalculator := NewReplicaCalculator(nil, provider, nil)
+	replicaCalculator.clock = fakeClock
+	selector := labels.SelectorFromSet(labels.Set{"foo": "bar"})
+
+	tests := []struct {
+		name           string
+		step           time.Duration
+		wpa            *v1alpha1.WatermarkPodAutoscaler
+		providerErr    error
+		expectedValues []int64
+		expectedCalls  int
+		expectedHits   float64
+		expectedMisses float64
+	}{
+		{
+			name:           "miss",
+			wpa:            wpa,
+			expectedValues: []int64{1000},
+			expectedCalls:  1,
+			expectedMisses: 1,
+		},
+		{
+			name:           "hit",
+			step:           5 * time.Second,
+			wpa:            wpa,
+			expectedValues: []int64{1000},
+			expectedCalls:  1,
+			expectedHits:   1,
+			expectedMisses: 1,
+		},
+		{
+			name:           "expiry",
+			step:           5 * time.Second,
+			wpa:            wpa,
+			expectedValues: []int64{2000},
+			expectedCalls:  2,
+			expectedHits:   1,
+			expectedMisses: 2,
+		},
+		{
+			// a WPA without metricCacheTTLSeconds always queries the provider.
+			name: "cache disabled",
+			wpa: func() *v1alpha1.WatermarkPodAutoscaler {
+				disabled := wpa.DeepCopy()
+				disabled.Spec.MetricCacheTTLSeconds = 0
+				return disabled
+			}(),
+			expectedValues: []int64{3000},
+			expectedCalls:  3,
+			expectedHits:   1,
+			expectedMisses: 2,
+		},
+		{
+			// the values fetched for the WPA without metricCacheTTLSeconds are served to the other ones.
+			name:           "hit on the values fetched without the cache",
+			wpa:            wpa,
+			expectedValues: []int64{3000},
+			expectedCalls:  3,
+			expectedHits:   2,
+			expectedMisses: 2,
+		},
+		{
+			// the errors of the provider are not cached.
+			name:          "error",
+			step:          10 * time.Second,
+			wpa:           wpa,
+			providerErr:   fmt.Errorf("provider unavailable"),
+			expectedCalls: 4,
+			expectedHits:  2,
+		},
+		{
+			name:           "miss after an error",
+			wpa:            wpa,
+			expectedValues: []int64{5000},
+			expectedCalls:  5,
+			expectedHits:   2,
+			expectedMisses: 4,
+		},
+	}
+	for _, tt := range tests {
+		fakeClock.Step(tt.step)
+		providerErr = tt.providerErr
+		values, cachedTimestamp, err := replicaCalculator.getExternalMetric(context.TODO(), logf.Log.WithName(tt.name), tt.wpa, "deadbeef", selector)
+		if tt.providerErr != nil {
+			require.Error(t, err, tt.name)
+			assert.Equal(t, tt.expectedCalls, calls, tt.name)
+			continue
+		}
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.expectedValues, values, tt.name)
+		// the timestamp of the values is kept for the staleness check.
+		assert.Equal(t, timestamp, cachedTimestamp, tt.name)
+		assert.Equal(t, tt.expectedCalls, calls, tt.name)
+		assert.Equal(t, tt.expectedHits, testutil.ToFloat64(metricCacheHits.With(promLabels)), tt.name)
+		assert.Equal(t, tt.expectedMisses, testutil.ToFloat64(metricCacheMisses.With(promLabels)), tt.name)
+	}
+}
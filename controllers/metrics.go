@@ -26,15 +26,32 @@ const (
 	metricNamePromLabel        = "metric_name"
 	reasonPromLabel            = "reason"
 	transitionPromLabel        = "transition"
+	boundPromLabel             = "bound"
+	directionPromLabel         = "direction"
 	// Label values
 	downscaleCappingPromLabelVal = "downscale_capping"
 	upscaleCappingPromLabelVal   = "upscale_capping"
 	withinBoundsPromLabelVal     = "within_bounds"
+	minReplicasPromLabelVal      = "min_replicas"
+	maxReplicasPromLabelVal      = "max_replicas"
+	upperPromLabelVal            = "upper"
+	lowerPromLabelVal            = "lower"
+	upPromLabelVal               = "up"
+	downPromLabelVal             = "down"
 )
 
 // reasonValues contains the 3 possible values of the 'reason' label
 var reasonValues = []string{downscaleCappingPromLabelVal, upscaleCappingPromLabelVal, withinBoundsPromLabelVal}
 
+// boundValues contains the 2 possible values of the 'bound' label
+var boundValues = []string{minReplicasPromLabelVal, maxReplicasPromLabelVal}
+
+// directionValues contains the 2 possible values of the 'direction' label
+var directionValues = []string{upperPromLabelVal, lowerPromLabelVal}
+
+// scaleDirectionValues contains the 2 possible values of the 'direction' label of scale_blocked_total and transition_count_total
+var scaleDirectionValues = []string{upPromLabelVal, downPromLabelVal}
+
 // Labels to add to an info metric and join on (with wpaNamePromLabel) in the Datadog prometheus check
 var extraPromLabels = strings.Fields(os.Getenv("DD_LABELS_AS_TAGS"))
 
@@ -52,6 +69,58 @@ var (
 			resourceNamePromLabel,
 			resourceKindPromLabel,
 		})
+	rawValue = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "raw_value",
+			Help:      "Gauge of the value of a metric before it is smoothed with the smoothingFactor of the WPA",
+		},
+		[]string{
+			wpaNamePromLabel,
+			metricNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	utilization = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "utilization",
+			Help:      "Gauge of the utilization of a metric compared to the watermarks, set whether the WPA scales or not",
+		},
+		[]string{
+			wpaNamePromLabel,
+			metricNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	metricUnavailable = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "metric_unavailable",
+			Help:      "Gauge set to 1 when a metric of a given WPA can't be retrieved or has no value, 0 otherwise",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+			metricNamePromLabel,
+		})
+	winningMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "winning_metric",
+			Help:      "Gauge set to 1 for the metric with the highest recommendation of a given WPA, which drives the scaling, 0 for the other ones",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+			metricNamePromLabel,
+		})
 	highwm = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Subsystem: subsystem,
@@ -91,6 +160,19 @@ var (
 			resourceNamePromLabel,
 			resourceKindPromLabel,
 		})
+	forbiddenWindowActive = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "forbidden_window_active",
+			Help:      "Gauge set to 1 while the time since the last scaling event is within the forbidden window of the transition, 0 otherwise",
+		},
+		[]string{
+			wpaNamePromLabel,
+			transitionPromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
 	lowwm = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Subsystem: subsystem,
@@ -117,6 +199,32 @@ var (
 			resourceKindPromLabel,
 			metricNamePromLabel,
 		})
+	adjustedHighwm = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "adjusted_high_watermark",
+			Help:      "Gauge for the high watermark of a given metric once widened by the tolerance, the value has to be above it to scale up",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+			metricNamePromLabel,
+		})
+	adjustedLowwm = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "adjusted_low_watermark",
+			Help:      "Gauge for the low watermark of a given metric once widened by the tolerance, the value has to be below it to scale down",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+			metricNamePromLabel,
+		})
 	replicaProposal = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Subsystem: subsystem,
@@ -130,6 +238,32 @@ var (
 			resourceKindPromLabel,
 			metricNamePromLabel,
 		})
+	watermarkDistance = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "watermark_distance",
+			Help:      "Gauge for the distance of the value of a metric to its nearest watermark, as a fraction of the watermark: negative above the high watermark, positive below the low watermark and 0 in between",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+			metricNamePromLabel,
+		})
+	replicaRecommendation = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "replicas_recommendation",
+			Help:      "Gauge for the number of replicas recommended for a given metric, before the minReplicas and maxReplicas bounds are applied",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+			metricNamePromLabel,
+		})
 	replicaEffective = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Subsystem: subsystem,
@@ -142,11 +276,71 @@ var (
 			resourceNamePromLabel,
 			resourceKindPromLabel,
 		})
+	replicaCurrent = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "current_replicas",
+			Help:      "Gauge for the current number of replicas of the target of a given WPA",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	replicaDesired = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "desired_replicas",
+			Help:      "Gauge for the desired number of replicas of the target of a given WPA",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	dryRunReplicas = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "dry_run_replicas",
+			Help:      "Gauge for the number of replicas the WPA would scale to if the dry-run mode was disabled",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	dryRun = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "dry_run",
+			Help:      "Gauge indicating whether the dry-run mode is enabled for a given WPA",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	paused = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "paused",
+			Help:      "Gauge indicating whether a given WPA is paused with the wpa.datadoghq.com/paused annotation",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
 	restrictedScaling = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Subsystem: subsystem,
 			Name:      "restricted_scaling",
-			Help:      "Gauge indicating whether the metric is within the watermarks bounds",
+			Help:      "Gauge indicating whether the metric driving the scaling is within the watermarks bounds, or whether the scaling is capped. The capping series have no metric name",
 		},
 		[]string{
 			wpaNamePromLabel,
@@ -154,6 +348,7 @@ var (
 			resourceNamespacePromLabel,
 			resourceNamePromLabel,
 			resourceKindPromLabel,
+			metricNamePromLabel,
 		})
 	replicaMin = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -179,6 +374,236 @@ var (
 			resourceNamePromLabel,
 			resourceKindPromLabel,
 		})
+	replicaClamped = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "replicas_clamped",
+			Help:      "Gauge indicating whether the number of replicas recommended by a metric was clamped to the minReplicas or maxReplicas of a given WPA",
+		},
+		[]string{
+			wpaNamePromLabel,
+			boundPromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+			metricNamePromLabel,
+		})
+	clampedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "clamped_total",
+			Help:      "Counter of the recommendations of a metric clamped to the minReplicas (lower) or maxReplicas (upper) of a given WPA",
+		},
+		[]string{
+			wpaNamePromLabel,
+			directionPromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+			metricNamePromLabel,
+		})
+	scaleUpLimited = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "scale_up_limited_total",
+			Help:      "Counter of the upscales capped by the scaleUpLimitFactor or the scaleUpLimit of a given WPA",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	scaleDownLimited = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "scale_down_limited_total",
+			Help:      "Counter of the downscales capped by the scaleDownLimitFactor or the scaleDownLimit of a given WPA",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	lastScaleTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "last_scale_timestamp_seconds",
+			Help:      "Gauge for the unix time of the last change of the number of replicas of the target applied by a given WPA",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	scaleTransitions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "transition_count_total",
+			Help:      "Counter of the changes of the number of replicas of the target applied by a given WPA, by direction (up or down)",
+		},
+		[]string{
+			wpaNamePromLabel,
+			directionPromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	scaleBlocked = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "scale_blocked_total",
+			Help:      "Counter of the recommendations not applied because they scale in a direction (up or down) disallowed by the scaleDirection of a given WPA",
+		},
+		[]string{
+			wpaNamePromLabel,
+			directionPromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	invalidMetricValue = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "invalid_metric_value_total",
+			Help:      "Counter of the NaN or Inf values computed for a metric, which are not used to scale",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+			metricNamePromLabel,
+		})
+	staleMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "stale_metric_total",
+			Help:      "Counter of the external metric values older than the metricStalenessWindowSeconds of a given WPA",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+			metricNamePromLabel,
+		})
+	utilizationClamped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "utilization_clamped_total",
+			Help:      "Counter of the external metric values capped to the maxUtilization of the metric of a given WPA",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+			metricNamePromLabel,
+		})
+	metricFetchErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "metric_fetch_errors_total",
+			Help:      "Counter of the errors returned by the External Metrics Provider for the metrics of a given WPA",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	metricErrorTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "metric_error_total",
+			Help:      "Counter of the reconcile cycles for which none of the metrics of a given WPA could be retrieved and its metric error policy was applied",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	changeSuppressed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "change_suppressed_total",
+			Help:      "Counter of the recommendations of a given WPA held back because they differ from the current number of replicas by less than its minReplicaChange",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	scaleVetoed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "scale_vetoed_total",
+			Help:      "Counter of the scale changes of a given WPA not approved by its scale approval webhook, including the ones it didn't answer in time",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	reconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "reconcile_duration_seconds",
+			Help:      "Histogram of the time taken to fetch the metrics of a given WPA and compute its recommendation",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+		})
+	metricsFetchDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "metrics_fetch_duration_seconds",
+			Help:      "Histogram of the time taken by the External Metrics Provider to return the values of a metric of a given WPA",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+			metricNamePromLabel,
+		})
+	metricCacheHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "metric_cache_hits_total",
+			Help:      "Counter of the values of an external metric of a given WPA served from the cache",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+			metricNamePromLabel,
+		})
+	metricCacheMisses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "metric_cache_misses_total",
+			Help:      "Counter of the values of an external metric of a given WPA missing from the cache or expired",
+		},
+		[]string{
+			wpaNamePromLabel,
+			resourceNamespacePromLabel,
+			resourceNamePromLabel,
+			resourceKindPromLabel,
+			metricNamePromLabel,
+		})
 	labelsInfo = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Subsystem: subsystem,
@@ -189,19 +614,124 @@ var (
 	)
 )
 
+// collectors are the metrics of the controller, registered in the registry of controller-runtime.
+var collectors = []prometheus.Collector{
+	value,
+	rawValue,
+	utilization,
+	metricUnavailable,
+	winningMetric,
+	highwm,
+	highwmV2,
+	lowwm,
+	lowwmV2,
+	adjustedHighwm,
+	adjustedLowwm,
+	replicaProposal,
+	replicaRecommendation,
+	watermarkDistance,
+	replicaEffective,
+	replicaCurrent,
+	replicaDesired,
+	dryRunReplicas,
+	dryRun,
+	paused,
+	restrictedScaling,
+	transitionCountdown,
+	forbiddenWindowActive,
+	replicaMin,
+	replicaMax,
+	replicaClamped,
+	clampedTotal,
+	scaleUpLimited,
+	scaleDownLimited,
+	scaleBlocked,
+	lastScaleTimestamp,
+	scaleTransitions,
+	invalidMetricValue,
+	staleMetric,
+	utilizationClamped,
+	metricFetchErrors,
+	metricErrorTotal,
+	changeSuppressed,
+	scaleVetoed,
+	reconcileDuration,
+	metricsFetchDuration,
+	metricCacheHits,
+	metricCacheMisses,
+	labelsInfo,
+}
+
 func init() {
-	sigmetrics.Registry.MustRegister(value)
-	sigmetrics.Registry.MustRegister(highwm)
-	sigmetrics.Registry.MustRegister(highwmV2)
-	sigmetrics.Registry.MustRegister(lowwm)
-	sigmetrics.Registry.MustRegister(lowwmV2)
-	sigmetrics.Registry.MustRegister(replicaProposal)
-	sigmetrics.Registry.MustRegister(replicaEffective)
-	sigmetrics.Registry.MustRegister(restrictedScaling)
-	sigmetrics.Registry.MustRegister(transitionCountdown)
-	sigmetrics.Registry.MustRegister(replicaMin)
-	sigmetrics.Registry.MustRegister(replicaMax)
-	sigmetrics.Registry.MustRegister(labelsInfo)
+	if err := registerMetrics(sigmetrics.Registry); err != nil {
+		panic(err)
+	}
+}
+
+// registerMetrics registers the metrics of the controller. The ones already registered are skipped, so that it can run
+// again, e.g. when the controller is set up again in the same process after losing the leader election.
+func registerMetrics(registerer prometheus.Registerer) error {
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cleanupRestrictedScalingMetrics removes the restricted_scaling series of a WPA, used when none of its metrics are available.
+func cleanupRestrictedScalingMetrics(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler) {
+	promLabelsForWpa := prometheus.Labels{
+		wpaNamePromLabel:           wpa.Name,
+		resourceNamespacePromLabel: wpa.Namespace,
+		resourceNamePromLabel:      wpa.Spec.ScaleTargetRef.Name,
+		resourceKindPromLabel:      wpa.Spec.ScaleTargetRef.Kind,
+		metricNamePromLabel:        "",
+	}
+	for _, reason := range reasonValues {
+		promLabelsForWpa[reasonPromLabel] = reason
+		restrictedScaling.Delete(promLabelsForWpa)
+	}
+	cleanupWithinBoundsMetrics(wpa, "")
+}
+
+// cleanupWithinBoundsMetrics removes the within_bounds series of restricted_scaling of the metrics of a WPA but the
+// one of the metric driving the scaling, whose metric name label is kept.
+func cleanupWithinBoundsMetrics(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, keptMetricLabel string) {
+	promLabelsForWpa := prometheus.Labels{
+		wpaNamePromLabel:           wpa.Name,
+		resourceNamespacePromLabel: wpa.Namespace,
+		resourceNamePromLabel:      wpa.Spec.ScaleTargetRef.Name,
+		resourceKindPromLabel:      wpa.Spec.ScaleTargetRef.Kind,
+		reasonPromLabel:            withinBoundsPromLabelVal,
+	}
+	for _, metricSpec := range wpa.Spec.Metrics {
+		metricLabel, ok := getMetricNamePromLabelValue(metricSpec)
+		if !ok || (keptMetricLabel != "" && metricLabel == keptMetricLabel) {
+			continue
+		}
+		promLabelsForWpa[metricNamePromLabel] = metricLabel
+		restrictedScaling.Delete(promLabelsForWpa)
+	}
+}
+
+// getMetricNamePromLabelValue returns the value of the metric name label of the series of a metric, false if the
+// metric has no source.
+func getMetricNamePromLabelValue(metricSpec datadoghqv1alpha1.MetricSpec) (string, bool) {
+	switch {
+	case metricSpec.Type == datadoghqv1alpha1.ResourceMetricSourceType && metricSpec.Resource != nil:
+		return string(metricSpec.Resource.Name), true
+	case metricSpec.Type == datadoghqv1alpha1.ObjectMetricSourceType && metricSpec.Object != nil:
+		return metricSpec.Object.MetricName, true
+	case metricSpec.Type == datadoghqv1alpha1.PodsMetricSourceType && metricSpec.Pods != nil:
+		return metricSpec.Pods.MetricName, true
+	case metricSpec.External != nil:
+		return metricSpec.External.MetricName, true
+	default:
+		return "", false
+	}
 }
 
 func cleanupAssociatedMetrics(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, onlyMetricsSpecific bool) {
@@ -214,19 +744,43 @@ func cleanupAssociatedMetrics(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, onl
 
 	if !onlyMetricsSpecific {
 		replicaEffective.Delete(promLabelsForWpa)
+		replicaCurrent.Delete(promLabelsForWpa)
+		replicaDesired.Delete(promLabelsForWpa)
+		dryRunReplicas.Delete(promLabelsForWpa)
+		dryRun.Delete(promLabelsForWpa)
+		paused.Delete(promLabelsForWpa)
 		replicaMin.Delete(promLabelsForWpa)
 		replicaMax.Delete(promLabelsForWpa)
+		scaleUpLimited.Delete(promLabelsForWpa)
+		scaleDownLimited.Delete(promLabelsForWpa)
+		lastScaleTimestamp.Delete(promLabelsForWpa)
+		metricFetchErrors.Delete(promLabelsForWpa)
+		metricErrorTotal.Delete(promLabelsForWpa)
+		changeSuppressed.Delete(promLabelsForWpa)
+		scaleVetoed.Delete(promLabelsForWpa)
+		reconcileDuration.Delete(promLabelsForWpa)
 
+		promLabelsForWpa[metricNamePromLabel] = ""
 		for _, reason := range reasonValues {
 			promLabelsForWpa[reasonPromLabel] = reason
 			restrictedScaling.Delete(promLabelsForWpa)
 		}
 		delete(promLabelsForWpa, reasonPromLabel)
+		delete(promLabelsForWpa, metricNamePromLabel)
+
+		for _, direction := range scaleDirectionValues {
+			promLabelsForWpa[directionPromLabel] = direction
+			scaleBlocked.Delete(promLabelsForWpa)
+			scaleTransitions.Delete(promLabelsForWpa)
+		}
+		delete(promLabelsForWpa, directionPromLabel)
 
 		promLabelsForWpa[transitionPromLabel] = "downscale"
 		transitionCountdown.Delete(promLabelsForWpa)
+		forbiddenWindowActive.Delete(promLabelsForWpa)
 		promLabelsForWpa[transitionPromLabel] = "upscale"
 		transitionCountdown.Delete(promLabelsForWpa)
+		forbiddenWindowActive.Delete(promLabelsForWpa)
 		delete(promLabelsForWpa, transitionPromLabel)
 
 		promLabelsInfo := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace}
@@ -237,18 +791,44 @@ func cleanupAssociatedMetrics(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, onl
 		labelsInfo.Delete(promLabelsInfo)
 	}
 
+	cleanupWithinBoundsMetrics(wpa, "")
 	for _, metricSpec := range wpa.Spec.Metrics {
-		if metricSpec.Type == datadoghqv1alpha1.ResourceMetricSourceType {
-			promLabelsForWpa[metricNamePromLabel] = string(metricSpec.Resource.Name)
-		} else {
-			promLabelsForWpa[metricNamePromLabel] = metricSpec.External.MetricName
+		metricLabel, ok := getMetricNamePromLabelValue(metricSpec)
+		if !ok {
+			continue
 		}
+		promLabelsForWpa[metricNamePromLabel] = metricLabel
 
 		lowwm.Delete(promLabelsForWpa)
 		lowwmV2.Delete(promLabelsForWpa)
+		adjustedLowwm.Delete(promLabelsForWpa)
+		adjustedHighwm.Delete(promLabelsForWpa)
 		replicaProposal.Delete(promLabelsForWpa)
+		replicaRecommendation.Delete(promLabelsForWpa)
+		watermarkDistance.Delete(promLabelsForWpa)
+		invalidMetricValue.Delete(promLabelsForWpa)
+		staleMetric.Delete(promLabelsForWpa)
+		utilizationClamped.Delete(promLabelsForWpa)
+		metricsFetchDuration.Delete(promLabelsForWpa)
+		metricCacheHits.Delete(promLabelsForWpa)
+		metricCacheMisses.Delete(promLabelsForWpa)
 		highwm.Delete(promLabelsForWpa)
 		highwmV2.Delete(promLabelsForWpa)
 		value.Delete(promLabelsForWpa)
+		rawValue.Delete(promLabelsForWpa)
+		utilization.Delete(promLabelsForWpa)
+		metricUnavailable.Delete(promLabelsForWpa)
+		winningMetric.Delete(promLabelsForWpa)
+
+		for _, bound := range boundValues {
+			promLabelsForWpa[boundPromLabel] = bound
+			replicaClamped.Delete(promLabelsForWpa)
+		}
+		delete(promLabelsForWpa, boundPromLabel)
+		for _, direction := range directionValues {
+			promLabelsForWpa[directionPromLabel] = direction
+			clampedTotal.Delete(promLabelsForWpa)
+		}
+		delete(promLabelsForWpa, directionPromLabel)
 	}
 }
@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	metricsclient "k8s.io/kubernetes/pkg/controller/podautoscaler/metrics"
+)
+
+// ExternalMetricsProvider returns the values of the external metrics, it is the only part of the metrics client
+// needed to scale on external metrics and can be implemented by another provider than the External Metrics API.
+type ExternalMetricsProvider interface {
+	// GetExternalMetric gets all the values of a given external metric that match the specified selector.
+	// The provider should give up once the context is done.
+	GetExternalMetric(ctx context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error)
+}
+
+// MetricsClient gets the resource, pods, object and external metrics, it should give up once the context is done.
+type MetricsClient interface {
+	ExternalMetricsProvider
+	// GetResourceMetric gets the given resource metric (and an associated oldest timestamp)
+	// for all pods matching the specified selector in the given namespace
+	GetResourceMetric(ctx context.Context, resource corev1.ResourceName, namespace string, selector labels.Selector) (metricsclient.PodMetricsInfo, time.Time, error)
+	// GetRawMetric gets the given metric (and an associated oldest timestamp)
+	// for all pods matching the specified selector in the given namespace
+	GetRawMetric(ctx context.Context, metricName string, namespace string, selector labels.Selector, metricSelector labels.Selector) (metricsclient.PodMetricsInfo, time.Time, error)
+	// GetObjectMetric gets the given metric (and an associated timestamp) for the given
+	// object in the given namespace
+	GetObjectMetric(ctx context.Context, metricName string, namespace string, objectRef *autoscalingv2beta2.CrossVersionObjectReference, metricSelector labels.Selector) (int64, time.Time, error)
+}
+
+// restMetricsClient gets the metrics with the REST metrics client of the HPA controller, whose queries don't take a
+// context. The context is only checked before a query is sent: a query isn't sent once the context is done, but a query
+// in flight isn't interrupted when the deadline of the context is passed. Only the timeout of the REST clients applies
+// to it, and it applies to each request on its own, e.g. the discovery of the custom metrics API versions is not counted.
+type restMetricsClient struct {
+	client metricsclient.MetricsClient
+}
+
+// NewMetricsClient returns a MetricsClient getting the metrics with the REST metrics client of the HPA controller.
+// The deadline of the context isn't applied to the queries in flight, the REST clients should set a timeout.
+func NewMetricsClient(client metricsclient.MetricsClient) MetricsClient {
+	return &restMetricsClient{client: client}
+}
+
+// GetResourceMetric gets the given resource metric for all pods matching the specified selector in the given namespace.
+func (c *restMetricsClient) GetResourceMetric(ctx context.Context, resource corev1.ResourceName, namespace string, selector labels.Selector) (metricsclient.PodMetricsInfo, time.Time, error) {
+	if err := checkMetricsQueryContext(ctx); err != nil {
+		return nil, time.Time{}, err
+	}
+	return c.client.GetResourceMetric(resource, namespace, selector)
+}
+
+// GetRawMetric gets the given metric for all pods matching the specified selector in the given namespace.
+func (c *restMetricsClient) GetRawMetric(ctx context.Context, metricName string, namespace string, selector labels.Selector, metricSelector labels.Selector) (metricsclient.PodMetricsInfo, time.Time, error) {
+	if err := checkMetricsQueryContext(ctx); err != nil {
+		return nil, time.Time{}, err
+	}
+	return c.client.GetRawMetric(metricName, namespace, selector, metricSelector)
+}
+
+// GetObjectMetric gets the given metric for the given object in the given namespace.
+func (c *restMetricsClient) GetObjectMetric(ctx context.Context, metricName string, namespace string, objectRef *autoscalingv2beta2.CrossVersionObjectReference, metricSelector labels.Selector) (int64, time.Time, error) {
+	if err := checkMetricsQueryContext(ctx); err != nil {
+		return 0, time.Time{}, err
+	}
+	return c.client.GetObjectMetric(metricName, namespace, objectRef, metricSelector)
+}
+
+// GetExternalMetric gets all the values of a given external metric that match the specified selector.
+func (c *restMetricsClient) GetExternalMetric(ctx context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+	if err := checkMetricsQueryContext(ctx); err != nil {
+		return nil, time.Time{}, err
+	}
+	return c.client.GetExternalMetric(metricName, namespace, selector)
+}
+
+// checkMetricsQueryContext returns an error once the context of a query is done, for the query not to be sent.
+func checkMetricsQueryContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("no answer from the metrics API in time: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestRESTMetricsClientContext(t *testing.T) {
+	calls := 0
+	client := NewMetricsClient(fakeMetricsClient{
+		getExternalMetrics: func(metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+			calls++
+			return []int64{3000}, time.Now(), nil
+		},
+	})
+	selector := labels.SelectorFromSet(labels.Set{"foo": "bar"})
+
+	values, _, err := client.GetExternalMetric(context.Background(), "deadbeef", testingNamespace, selector)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3000}, values)
+	assert.Equal(t, 1, calls)
+
+	// the query isn't sent once the deadline of the context is passed.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	_, _, err = client.GetExternalMetric(ctx, "deadbeef", testingNamespace, selector)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), context.DeadlineExceeded.Error())
+	_, _, err = client.GetResourceMetric(ctx, "cpu", testingNamespace, selector)
+	assert.Error(t, err)
+	_, _, err = client.GetRawMetric(ctx, "deadbeef", testingNamespace, selector, labels.Everything())
+	assert.Error(t, err)
+	_, _, err = client.GetObjectMetric(ctx, "deadbeef", testingNamespace, nil, selector)
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
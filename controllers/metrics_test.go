@@ -0,0 +1,32 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sigmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+func TestRegisterMetrics(t *testing.T) {
+	// the metrics were already registered by init, registering them again neither panics nor fails.
+	assert.NotPanics(t, func() {
+		require.NoError(t, registerMetrics(sigmetrics.Registry))
+	})
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registerMetrics(registry))
+	require.NoError(t, registerMetrics(registry))
+	assert.IsType(t, prometheus.AlreadyRegisteredError{}, registry.Register(dryRun))
+
+	// another metric with the same name is still rejected.
+	conflicting := prometheus.NewRegistry()
+	conflicting.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Subsystem: subsystem, Name: "dry_run", Help: "Another gauge"}))
+	assert.Error(t, registerMetrics(conflicting))
+}
@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// timestampedRecommendation is a replica recommendation and the time it was computed at.
+type timestampedRecommendation struct {
+	recommendation int32
+	timestamp      time.Time
+}
+
+// recommendationStore keeps the recent recommendations of each WPA, used to stabilize the scaling decisions.
+type recommendationStore struct {
+	sync.Mutex
+	recommendations map[types.NamespacedName][]timestampedRecommendation
+}
+
+// stabilize records the recommendation and returns the most conservative one over the stabilization windows:
+// the minimum recommendation of the upscale window when scaling up and the maximum of the downscale window when scaling down.
+func (s *recommendationStore) stabilize(key types.NamespacedName, now time.Time, currentReplicas, recommendation int32, upscaleWindow, downscaleWindow time.Duration) int32 {
+	s.Lock()
+	defer s.Unlock()
+	if s.recommendations == nil {
+		s.recommendations = make(map[types.NamespacedName][]timestampedRecommendation)
+	}
+
+	longestWindow := upscaleWindow
+	if downscaleWindow > longestWindow {
+		longestWindow = downscaleWindow
+	}
+
+	upRecommendation := recommendation
+	downRecommendation := recommendation
+	// only keep the recommendations that are still within one of the windows
+	kept := make([]timestampedRecommendation, 0, len(s.recommendations[key])+1)
+	for _, r := range s.recommendations[key] {
+		age := now.Sub(r.timestamp)
+		if age > longestWindow {
+			continue
+		}
+		kept = append(kept, r)
+		if age <= upscaleWindow && r.recommendation < upRecommendation {
+			upRecommendation = r.recommendation
+		}
+		if age <= downscaleWindow && r.recommendation > downRecommendation {
+			downRecommendation = r.recommendation
+		}
+	}
+	s.recommendations[key] = append(kept, timestampedRecommendation{recommendation: recommendation, timestamp: now})
+
+	stabilized := currentReplicas
+	if stabilized < upRecommendation {
+		stabilized = upRecommendation
+	}
+	if stabilized > downRecommendation {
+		stabilized = downRecommendation
+	}
+	return stabilized
+}
+
+// delete frees the recommendations of a WPA.
+func (s *recommendationStore) delete(key types.NamespacedName) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.recommendations, key)
+}
+
+// lastRecommendationStore keeps the last recommendation computed from the metrics of each WPA,
+// used by the lastKnownGood metric error policy when none of the metrics can be retrieved.
+type lastRecommendationStore struct {
+	sync.Mutex
+	recommendations map[types.NamespacedName]int32
+}
+
+// set records the recommendation computed from the metrics of a WPA.
+func (s *lastRecommendationStore) set(key types.NamespacedName, recommendation int32) {
+	s.Lock()
+	defer s.Unlock()
+	if s.recommendations == nil {
+		s.recommendations = make(map[types.NamespacedName]int32)
+	}
+	s.recommendations[key] = recommendation
+}
+
+// get returns the last recommendation computed from the metrics of a WPA, if any.
+func (s *lastRecommendationStore) get(key types.NamespacedName) (int32, bool) {
+	s.Lock()
+	defer s.Unlock()
+	recommendation, found := s.recommendations[key]
+	return recommendation, found
+}
+
+// delete frees the last recommendation of a WPA.
+func (s *lastRecommendationStore) delete(key types.NamespacedName) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.recommendations, key)
+}
@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestRecommendationStoreStabilize(t *testing.T) {
+	key := types.NamespacedName{Namespace: testingNamespace, Name: testingWPAName}
+	start := time.Unix(1232000, 0)
+
+	type step struct {
+		offset          time.Duration
+		currentReplicas int32
+		recommendation  int32
+		expected        int32
+	}
+	tests := []struct {
+		name            string
+		upscaleWindow   time.Duration
+		downscaleWindow time.Duration
+		steps           []step
+	}{
+		{
+			name: "no window",
+			steps: []step{
+				{offset: 0, currentReplicas: 5, recommendation: 10, expected: 10},
+				{offset: 15 * time.Second, currentReplicas: 10, recommendation: 3, expected: 3},
+			},
+		},
+		{
+			name:            "downscale uses the max recommendation of the window",
+			downscaleWindow: time.Minute,
+			steps: []step{
+				{offset: 0, currentReplicas: 10, recommendation: 10, expected: 10},
+				{offset: 15 * time.Second, currentReplicas: 10, recommendation: 4, expected: 10},
+				{offset: 30 * time.Second, currentReplicas: 10, recommendation: 6, expected: 10},
+				// the recommendation of 10 is now out of the window
+				{offset: 75 * time.Second, currentReplicas: 10, recommendation: 5, expected: 6},
+				{offset: 95 * time.Second, currentReplicas: 6, recommendation: 5, expected: 5},
+			},
+		},
+		{
+			name:          "upscale uses the min recommendation of the window",
+			upscaleWindow: time.Minute,
+			steps: []step{
+				{offset: 0, currentReplicas: 4, recommendation: 4, expected: 4},
+				{offset: 15 * time.Second, currentReplicas: 4, recommendation: 12, expected: 4},
+				{offset: 30 * time.Second, currentReplicas: 4, recommendation: 8, expected: 4},
+				// the recommendation of 4 is now out of the window
+				{offset: 75 * time.Second, currentReplicas: 4, recommendation: 9, expected: 8},
+			},
+		},
+		{
+			name:            "flapping recommendations within both windows",
+			upscaleWindow:   time.Minute,
+			downscaleWindow: time.Minute,
+			steps: []step{
+				{offset: 0, currentReplicas: 6, recommendation: 6, expected: 6},
+				{offset: 15 * time.Second, currentReplicas: 6, recommendation: 9, expected: 6},
+				{offset: 30 * time.Second, currentReplicas: 6, recommendation: 3, expected: 6},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &recommendationStore{}
+			for i, s := range tt.steps {
+				got := store.stabilize(key, start.Add(s.offset), s.currentReplicas, s.recommendation, tt.upscaleWindow, tt.downscaleWindow)
+				assert.Equal(t, s.expected, got, "step %d", i)
+			}
+		})
+	}
+}
+
+func TestRecommendationStoreDelete(t *testing.T) {
+	key := types.NamespacedName{Namespace: testingNamespace, Name: testingWPAName}
+	store := &recommendationStore{}
+	store.stabilize(key, time.Now(), 3, 5, time.Minute, time.Minute)
+	assert.Len(t, store.recommendations[key], 1)
+
+	store.delete(key)
+	_, found := store.recommendations[key]
+	assert.False(t, found)
+}
+
+func TestLastRecommendationStore(t *testing.T) {
+	key := types.NamespacedName{Namespace: testingNamespace, Name: testingWPAName}
+	store := &lastRecommendationStore{}
+	_, found := store.get(key)
+	assert.False(t, found)
+
+	store.set(key, 4)
+	store.set(key, 6)
+	recommendation, found := store.get(key)
+	assert.True(t, found)
+	assert.Equal(t, int32(6), recommendation)
+
+	store.delete(key)
+	_, found = store.get(key)
+	assert.False(t, found)
+}
@@ -6,8 +6,11 @@
 package controllers
 
 import (
+	"context"
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,10 +19,13 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/prometheus/client_golang/prometheus"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/apimachinery/pkg/util/sets"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	metricsclient "k8s.io/kubernetes/pkg/controller/podautoscaler/metrics"
@@ -30,139 +36,482 @@ type ReplicaCalculation struct {
 	replicaCount int32
 	utilization  int64
 	timestamp    time.Time
+	// reason is why replicaCount was recommended, one of the DecisionReason values of the API.
+	reason string
+	// position is where the usage stands relative to the watermarks, before the recommendation is restricted or clamped.
+	position string
+	// series summarizes the series returned for an external metric, nil for the other metrics.
+	series *metricSeries
+}
+
+// metricSeries is the number of series returned for an external metric and the range of their values, to tell when one of
+// them dominates the aggregated value.
+type metricSeries struct {
+	count int32
+	min   int64
+	max   int64
 }
 
 // ReplicaCalculatorItf interface for ReplicaCalculator
+// The context bounds the time given to the metrics providers to answer.
 type ReplicaCalculatorItf interface {
-	GetExternalMetricReplicas(logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error)
-	GetResourceReplicas(logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error)
+	GetExternalMetricReplicas(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error)
+	GetResourceMetricReplicas(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error)
+	GetObjectMetricReplicas(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error)
+	GetPodsMetricReplicas(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error)
+	GetPodReadiness(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, wpa *v1alpha1.WatermarkPodAutoscaler) (readyPodCount, podCount int32, err error)
 }
 
 // ReplicaCalculator is responsible for calculation of the number of replicas
 // It contains all the needed information
 type ReplicaCalculator struct {
-	metricsClient metricsclient.MetricsClient
-	podLister     corelisters.PodLister
+	// metricsClient gets the resource, object and pods metrics
+	metricsClient MetricsClient
+	// externalMetricsProvider gets the external metrics
+	externalMetricsProvider ExternalMetricsProvider
+	podLister               corelisters.PodLister
+	// clock measures the time taken by the metrics provider and the age of the cached external metrics
+	clock clock.Clock
+	// smoothedUsages keeps the moving average of the usage of the metrics of each WPA to apply its smoothingFactor
+	smoothedUsages smoothedUsageStore
+	// externalMetrics caches the values of the external metrics for the WPAs setting a metricCacheTTLSeconds
+	externalMetrics externalMetricCache
 }
 
 // NewReplicaCalculator returns a ReplicaCalculator object reference
-func NewReplicaCalculator(metricsClient metricsclient.MetricsClient, podLister corelisters.PodLister) *ReplicaCalculator {
-
+// The external metrics are retrieved with the metricsClient when externalMetricsProvider is nil.
+// The metricsClient can be nil if only external metrics are used.
+func NewReplicaCalculator(metricsClient MetricsClient, externalMetricsProvider ExternalMetricsProvider, podLister corelisters.PodLister) *ReplicaCalculator {
+	if externalMetricsProvider == nil && metricsClient != nil {
+		externalMetricsProvider = metricsClient
+	}
 	return &ReplicaCalculator{
-		metricsClient: metricsClient,
-		podLister:     podLister,
+		metricsClient:           metricsClient,
+		externalMetricsProvider: externalMetricsProvider,
+		podLister:               podLister,
+		clock:                   clock.RealClock{},
 	}
 }
 
 // GetExternalMetricReplicas calculates the desired replica count based on a
 // target metric value (as a milli-value) for the external metric in the given
 // namespace, and the current replica count.
-func (c *ReplicaCalculator) GetExternalMetricReplicas(logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (ReplicaCalculation, error) {
+func (c *ReplicaCalculator) GetExternalMetricReplicas(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (ReplicaCalculation, error) {
 	lbl, err := labels.Parse(target.Status.Selector)
 	if err != nil {
 		logger.Error(err, "Could not parse the labels of the target")
 	}
-	currentReadyReplicas, err := c.getReadyPodsCount(logger, target, lbl, time.Duration(wpa.Spec.ReadinessDelaySeconds)*time.Second)
+	var currentReadyReplicas int32
+	// there is no pod to count once the target was scaled down to zero.
+	if !isScaledToZero(wpa, target.Status.Replicas) {
+		currentReadyReplicas, err = c.getReadyPodsCount(logger, target, lbl, time.Duration(wpa.Spec.ReadinessDelaySeconds)*time.Second, wpa.Spec.UseReadyReplicas)
+		if err != nil {
+			return ReplicaCalculation{}, fmt.Errorf("unable to get the number of ready pods across all namespaces for %v: %s", lbl, err.Error())
+		}
+	}
+	metricName := metric.External.MetricName
+	algorithm := getExternalMetricAlgorithm(wpa, metric)
+	upscaleAlgorithm, downscaleAlgorithm := getExternalMetricDirectionAlgorithms(wpa, metric)
+	logger.Info("Using algorithm for the external metric", "metricName", metricName, "algorithm", algorithm, "upscaleAlgorithm", upscaleAlgorithm, "downscaleAlgorithm", downscaleAlgorithm)
+	readyCapacity, _, err := c.getReadyCapacity(logger, target, lbl, wpa, algorithm, currentReadyReplicas)
 	if err != nil {
-		return ReplicaCalculation{}, fmt.Errorf("unable to get the number of ready pods across all namespaces for %v: %s", lbl, err.Error())
+		return ReplicaCalculation{}, err
 	}
-	averaged := 1.0
-	if wpa.Spec.Algorithm == "average" {
-		averaged = float64(currentReadyReplicas)
+	// the upscaleAlgorithm and downscaleAlgorithm of the WPA can average the metric over a different capacity.
+	var readyCapacities map[string]float64
+	for _, directionAlgorithm := range []string{upscaleAlgorithm, downscaleAlgorithm} {
+		if _, found := readyCapacities[directionAlgorithm]; found || directionAlgorithm == algorithm {
+			continue
+		}
+		var directionCapacity float64
+		directionCapacity, _, err = c.getReadyCapacity(logger, target, lbl, wpa, directionAlgorithm, currentReadyReplicas)
+		if err != nil {
+			return ReplicaCalculation{}, err
+		}
+		if readyCapacities == nil {
+			readyCapacities = map[string]float64{}
+		}
+		readyCapacities[directionAlgorithm] = directionCapacity
 	}
 
-	metricName := metric.External.MetricName
 	selector := metric.External.MetricSelector
 	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
 	if err != nil {
 		return ReplicaCalculation{}, err
 	}
 
-	metrics, timestamp, err := c.metricsClient.GetExternalMetric(metricName, wpa.Namespace, labelSelector)
+	if c.externalMetricsProvider == nil {
+		return ReplicaCalculation{}, fmt.Errorf("no external metrics provider to get the external metric %s", metricName)
+	}
+	metrics, timestamp, err := c.getExternalMetric(ctx, logger, wpa, metricName, labelSelector)
 	if err != nil {
-		// When we add official support for several metrics, move this Delete to only occur if no metric is available at all.
-		labelsWithReason := prometheus.Labels{
-			wpaNamePromLabel:           wpa.Name,
-			resourceNamespacePromLabel: wpa.Namespace,
-			resourceNamePromLabel:      wpa.Spec.ScaleTargetRef.Name,
-			resourceKindPromLabel:      wpa.Spec.ScaleTargetRef.Kind,
-			reasonPromLabel:            upscaleCappingPromLabelVal}
-		restrictedScaling.Delete(labelsWithReason)
-		labelsWithReason[reasonPromLabel] = downscaleCappingPromLabelVal
-		restrictedScaling.Delete(labelsWithReason)
-		labelsWithReason[reasonPromLabel] = withinBoundsPromLabelVal
-		restrictedScaling.Delete(labelsWithReason)
-		value.Delete(prometheus.Labels{wpaNamePromLabel: wpa.Name, metricNamePromLabel: metricName})
-		return ReplicaCalculation{0, 0, time.Time{}}, fmt.Errorf("unable to get external metric %s/%s/%+v: %s", wpa.Namespace, metricName, selector, err)
-	}
-	logger.Info("Metrics from the External Metrics Provider", "metrics", metrics)
+		metricFetchErrors.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Inc()
+		deleteMetricGauges(wpa, metricName)
+		return ReplicaCalculation{}, fmt.Errorf("unable to get external metric %s/%s/%+v: %s", wpa.Namespace, metricName, selector, err)
+	}
+	logger.Info("Metrics from the External Metrics Provider", "metricName", metricName, "metrics", metrics)
 
-	var sum int64
-	for _, val := range metrics {
-		sum += val
+	// without any value, the sum would be 0 and the target could be scaled down, the metric is considered unavailable instead.
+	// with the count algorithm, no series is a count of 0.
+	if len(metrics) == 0 && algorithm != "count" {
+		deleteMetricGauges(wpa, metricName)
+		return ReplicaCalculation{}, fmt.Errorf("no value returned for the external metric %s/%s/%+v", wpa.Namespace, metricName, selector)
 	}
 
-	// if the average algorithm is used, the metrics retrieved has to be divided by the number of available replicas.
-	adjustedUsage := float64(sum) / averaged
-	replicaCount, utilizationQuantity := getReplicaCount(logger, target.Status.Replicas, currentReadyReplicas, wpa, metricName, adjustedUsage, metric.External.LowWatermark, metric.External.HighWatermark)
-	return ReplicaCalculation{replicaCount, utilizationQuantity, timestamp}, nil
+	var denominatorMetrics []int64
+	denominatorName := metric.External.DenominatorMetricName
+	if denominatorName != "" {
+		denominatorSelector := metric.External.DenominatorMetricSelector
+		if denominatorSelector == nil {
+			denominatorSelector = selector
+		}
+		denominatorLabelSelector, err := metav1.LabelSelectorAsSelector(denominatorSelector)
+		if err != nil {
+			return ReplicaCalculation{}, err
+		}
+		var denominatorTimestamp time.Time
+		denominatorMetrics, denominatorTimestamp, err = c.getExternalMetric(ctx, logger, wpa, denominatorName, denominatorLabelSelector)
+		if err != nil {
+			metricFetchErrors.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Inc()
+			deleteMetricGauges(wpa, metricName)
+			return ReplicaCalculation{}, fmt.Errorf("unable to get the denominator %s/%s/%+v of the external metric %s: %s", wpa.Namespace, denominatorName, denominatorSelector, metricName, err)
+		}
+		logger.Info("Metrics of the denominator from the External Metrics Provider", "metricName", metricName, "denominatorMetricName", denominatorName, "metrics", denominatorMetrics)
+		// the ratio is as stale as the oldest of its metrics.
+		if len(denominatorMetrics) > 0 && denominatorTimestamp.Before(timestamp) {
+			timestamp = denominatorTimestamp
+		}
+	}
+
+	stalenessWindow := time.Duration(wpa.Spec.MetricStalenessWindowSeconds) * time.Second
+	// there is no value to be stale when no series is returned.
+	if len(metrics) > 0 && isMetricStale(timestamp, c.clock.Now(), stalenessWindow) {
+		promLabelsForWpaWithMetricName := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind, metricNamePromLabel: metricName}
+		staleMetric.With(promLabelsForWpaWithMetricName).Inc()
+		deleteMetricGauges(wpa, metricName)
+		return ReplicaCalculation{reason: v1alpha1.DecisionReasonMetricStale}, fmt.Errorf("external metric %s/%s/%+v is stale: last value from %v, older than %v", wpa.Namespace, metricName, selector, timestamp, stalenessWindow)
+	}
+
+	// without any request, a ratio such as the errors per request is undefined rather than 0, the current number of replicas is kept.
+	if denominatorName != "" && aggregate(denominatorMetrics, "sum") == 0 {
+		logger.Info("The denominator of the ratio is zero, keeping the current number of replicas", "metricName", metricName, "denominatorMetricName", denominatorName, "currentReplicas", target.Status.Replicas)
+		deleteMetricGauges(wpa, metricName)
+		return getReplicaCalculation(logger, target, wpa, metric, metricName, target.Status.Replicas, 0, timestamp, v1alpha1.DecisionReasonRatioUndefined)
+	}
+
+	if len(metric.External.Weights) > 0 && len(metric.External.Weights) < len(metrics) && algorithm != "count" && (metric.External.AggregatorFunc == "" || metric.External.AggregatorFunc == "sum") {
+		logger.Info("Fewer weights than values for the metric, weighting the remaining values by 1", "metricName", metricName, "weightCount", len(metric.External.Weights), "valueCount", len(metrics))
+	}
+	key := types.NamespacedName{Namespace: wpa.Namespace, Name: wpa.Name}
+	recommendation, err := ComputeRecommendation(RecommendationInput{
+		Spec:              wpa.Spec,
+		Metric:            *metric.External,
+		Values:            metrics,
+		DenominatorValues: denominatorMetrics,
+		CurrentReplicas:   target.Status.Replicas,
+		ReadyCapacity:     readyCapacity,
+		ReadyCapacities:   readyCapacities,
+		PreviousUsage:     c.smoothedUsages.get(key, metricName),
+	})
+	// the smoothed usage is kept for the next recommendation, even when the number of replicas can't be computed.
+	factor := getSmoothingFactor(wpa)
+	c.smoothedUsages.set(key, metricName, recommendation.Usage, factor)
+	if factor < 1 {
+		logger.Info("Smoothing the usage of the metric", "metricName", metricName, "usage", recommendation.RawUsage, "smoothedUsage", recommendation.Usage, "smoothingFactor", factor)
+	}
+	promLabelsForWpaWithMetricName := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind, metricNamePromLabel: metricName}
+	if err != nil {
+		return ReplicaCalculation{}, handleInvalidMetricValue(wpa, metricName, err)
+	}
+	recordRawUsage(wpa, metricName, recommendation.RawUsage)
+	if recommendation.Clamped {
+		logger.Info("Capping the usage of the metric to its maxUtilization", "metricName", metricName, "usage", recommendation.RawUsage, "maxUtilization", metric.External.MaxUtilization)
+		utilizationClamped.With(promLabelsForWpaWithMetricName).Inc()
+	}
+	proportional := recommendation
+	proportional.ReplicaCount = recommendation.ProportionalReplicaCount
+	recordWatermarkRecommendation(logger, wpa, metricName, getAlgorithmReadyCapacity(readyCapacity, readyCapacities, getRecommendationAlgorithm(wpa, metric, recommendation.Reason)), metric.External.LowWatermark, metric.External.HighWatermark, metric.External.IdleWatermark, proportional)
+	if recommendation.Stepped {
+		replicaRecommendation.With(promLabelsForWpaWithMetricName).Set(float64(recommendation.ReplicaCount))
+		logger.Info("Stepping the replicas instead of scaling proportionally", "metricName", metricName, "currentReplicas", target.Status.Replicas, "proportionalReplicaCount", recommendation.ProportionalReplicaCount, "replicaCount", recommendation.ReplicaCount, "minReplicasForProportional", wpa.Spec.MinReplicasForProportional)
+	}
+	replicaCalculation, err := getReplicaCalculation(logger, target, wpa, metric, metricName, recommendation.ReplicaCount, getUtilization(recommendation.Usage), timestamp, recommendation.Reason)
+	if err != nil {
+		return ReplicaCalculation{}, err
+	}
+	replicaCalculation.series = getMetricSeries(metrics)
+	if wpa.Spec.Debug {
+		logger.Info("Series of the external metric", "metricName", metricName, "seriesCount", replicaCalculation.series.count, "seriesMin", replicaCalculation.series.min, "seriesMax", replicaCalculation.series.max)
+	}
+	return replicaCalculation, nil
 }
 
-// GetResourceReplicas calculates the desired replica count based on a target resource utilization percentage
-// of the given resource for pods matching the given selector in the given namespace, and the current replica count
-func (c *ReplicaCalculator) GetResourceReplicas(logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (ReplicaCalculation, error) {
+// getExternalMetric returns the values of an external metric, served from the cache when they were fetched less than
+// the metricCacheTTLSeconds of the WPA ago. The timestamp of the cached values is returned so that they still go through
+// the staleness check. The values fetched from the provider are cached for the other WPAs even when the WPA doesn't use the cache.
+// The provider should give up once the context is done.
+func (c *ReplicaCalculator) getExternalMetric(ctx context.Context, logger logr.Logger, wpa *v1alpha1.WatermarkPodAutoscaler, metricName string, selector labels.Selector) ([]int64, time.Time, error) {
+	promLabelsForWpaWithMetricName := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind, metricNamePromLabel: metricName}
+	key := externalMetricKey{metricName: metricName, namespace: wpa.Namespace, selector: selector.String()}
+	ttl := time.Duration(wpa.Spec.MetricCacheTTLSeconds) * time.Second
+	if ttl > 0 {
+		if values, timestamp, found := c.externalMetrics.get(key, c.clock.Now(), ttl); found {
+			metricCacheHits.With(promLabelsForWpaWithMetricName).Inc()
+			logger.Info("Using the cached values of the external metric", "metricName", metricName, "timestamp", timestamp, "metricCacheTTLSeconds", wpa.Spec.MetricCacheTTLSeconds)
+			return values, timestamp, nil
+		}
+		metricCacheMisses.With(promLabelsForWpaWithMetricName).Inc()
+	}
+
+	fetchStart := c.clock.Now()
+	values, timestamp, err := c.externalMetricsProvider.GetExternalMetric(ctx, metricName, wpa.Namespace, selector)
+	metricsFetchDuration.With(promLabelsForWpaWithMetricName).Observe(c.clock.Since(fetchStart).Seconds())
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	c.externalMetrics.set(key, values, timestamp, c.clock.Now())
+	return values, timestamp, nil
+}
+
+// getMetricSeries returns the number of series and the lowest and highest of their values.
+// Without any series, the range is left at 0.
+func getMetricSeries(values []int64) *metricSeries {
+	series := &metricSeries{count: int32(len(values))}
+	for i, v := range values {
+		if i == 0 || v < series.min {
+			series.min = v
+		}
+		if i == 0 || v > series.max {
+			series.max = v
+		}
+	}
+	return series
+}
+
+// getReplicaCalculation returns the recommendation of a metric from the number of replicas recommended by its watermarks,
+// restricted to the directions the metric is allowed to scale in and clamped to the bounds of the WPA.
+// The reason given by the watermarks is kept as the position of the metric.
+func getReplicaCalculation(logger logr.Logger, target *autoscalingv1.Scale, wpa *v1alpha1.WatermarkPodAutoscaler, metric v1alpha1.MetricSpec, name string, replicaCount int32, utilizationValue int64, timestamp time.Time, reason string) (ReplicaCalculation, error) {
+	position := reason
+	replicaCount, reason = restrictMetricDirection(logger, metric, name, target.Status.Replicas, replicaCount, reason)
+	clampedReplicaCount, err := clampReplicaCount(logger, wpa, name, replicaCount)
+	if err != nil {
+		return ReplicaCalculation{}, err
+	}
+	return ReplicaCalculation{clampedReplicaCount, utilizationValue, timestamp, getClampedReason(reason, replicaCount, clampedReplicaCount), position, nil}, nil
+}
+
+// aggregate combines the values of a metric with the given function, the values are summed by default.
+// Percentiles (e.g. p90) use the nearest-rank method.
+func aggregate(values []int64, fn string) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	switch fn {
+	case "avg":
+		return aggregate(values, "sum") / float64(len(values))
+	case "max":
+		highest := values[0]
+		for _, v := range values[1:] {
+			if v > highest {
+				highest = v
+			}
+		}
+		return float64(highest)
+	case "min":
+		lowest := values[0]
+		for _, v := range values[1:] {
+			if v < lowest {
+				lowest = v
+			}
+		}
+		return float64(lowest)
+	case "p50", "p90", "p95", "p99":
+		percentile, _ := strconv.Atoi(strings.TrimPrefix(fn, "p"))
+		sorted := make([]int64, len(values))
+		copy(sorted, values)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		rank := (percentile*len(sorted) + 99) / 100
+		return float64(sorted[rank-1])
+	default:
+		var sum int64
+		for _, v := range values {
+			sum += v
+		}
+		return float64(sum)
+	}
+}
+
+// weightedSum sums the values of a metric weighted by the weights matched by position.
+// The values without a weight, when there are fewer weights than values, are weighted by 1.
+func weightedSum(values []int64, weights []resource.Quantity) float64 {
+	var sum float64
+	for i, v := range values {
+		weight := 1.0
+		if i < len(weights) {
+			weight = float64(weights[i].MilliValue()) / 1000
+		}
+		sum += weight * float64(v)
+	}
+	return sum
+}
+
+// isMetricStale returns whether the metric is older than the staleness window, a window of 0 disables the check.
+func isMetricStale(timestamp, now time.Time, stalenessWindow time.Duration) bool {
+	return stalenessWindow > 0 && now.Sub(timestamp) > stalenessWindow
+}
+
+// getExternalMetricAlgorithm returns the algorithm matching the target type of the external metric if it is set,
+// otherwise the algorithm of the external metric if it is set, and the one of the WPA as a last resort.
+// A ratio of two external metrics is compared to the watermarks as is, with the absolute algorithm.
+func getExternalMetricAlgorithm(wpa *v1alpha1.WatermarkPodAutoscaler, metric v1alpha1.MetricSpec) string {
+	if metric.External.DenominatorMetricName != "" {
+		return "absolute"
+	}
+	switch metric.External.TargetType {
+	case "AverageValue":
+		return "average"
+	case "Value":
+		return "absolute"
+	}
+	if metric.External.Algorithm != "" {
+		return metric.External.Algorithm
+	}
+	return wpa.Spec.Algorithm
+}
+
+// getExternalMetricDirectionAlgorithms returns the algorithms used to tell whether the external metric is above its high
+// watermark and below its low watermark: the upscaleAlgorithm and downscaleAlgorithm of the WPA when they are set, unless
+// the metric sets its own algorithm, and the algorithm of the metric otherwise.
+func getExternalMetricDirectionAlgorithms(wpa *v1alpha1.WatermarkPodAutoscaler, metric v1alpha1.MetricSpec) (upscaleAlgorithm, downscaleAlgorithm string) {
+	algorithm := getExternalMetricAlgorithm(wpa, metric)
+	if metric.External.DenominatorMetricName != "" || metric.External.TargetType != "" || metric.External.Algorithm != "" {
+		return algorithm, algorithm
+	}
+	upscaleAlgorithm, downscaleAlgorithm = algorithm, algorithm
+	if wpa.Spec.UpscaleAlgorithm != "" {
+		upscaleAlgorithm = wpa.Spec.UpscaleAlgorithm
+	}
+	if wpa.Spec.DownscaleAlgorithm != "" {
+		downscaleAlgorithm = wpa.Spec.DownscaleAlgorithm
+	}
+	return upscaleAlgorithm, downscaleAlgorithm
+}
+
+// GetObjectMetricReplicas calculates the desired replica count based on the value of a metric describing a single
+// Kubernetes object (e.g. the length of a queue or the requests per second of an ingress), served by the custom
+// metrics API, and the current replica count.
+func (c *ReplicaCalculator) GetObjectMetricReplicas(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (ReplicaCalculation, error) {
+	lbl, err := labels.Parse(target.Status.Selector)
+	if err != nil {
+		logger.Error(err, "Could not parse the labels of the target")
+	}
+	var currentReadyReplicas int32
+	// there is no pod to count once the target was scaled down to zero.
+	if !isScaledToZero(wpa, target.Status.Replicas) {
+		currentReadyReplicas, err = c.getReadyPodsCount(logger, target, lbl, time.Duration(wpa.Spec.ReadinessDelaySeconds)*time.Second, wpa.Spec.UseReadyReplicas)
+		if err != nil {
+			return ReplicaCalculation{}, fmt.Errorf("unable to get the number of ready pods across all namespaces for %v: %s", lbl, err.Error())
+		}
+	}
+	metricName := metric.Object.MetricName
+	readyCapacity, averaged, err := c.getReadyCapacity(logger, target, lbl, wpa, wpa.Spec.Algorithm, currentReadyReplicas)
+	if err != nil {
+		return ReplicaCalculation{}, err
+	}
+
+	labelSelector := labels.Everything()
+	if metric.Object.MetricSelector != nil {
+		labelSelector, err = metav1.LabelSelectorAsSelector(metric.Object.MetricSelector)
+		if err != nil {
+			return ReplicaCalculation{}, err
+		}
+	}
+
+	if c.metricsClient == nil {
+		return ReplicaCalculation{}, fmt.Errorf("no metrics client to get the object metric %s", metricName)
+	}
+	objectRef := &autoscalingv2beta2.CrossVersionObjectReference{
+		Kind:       metric.Object.DescribedObject.Kind,
+		Name:       metric.Object.DescribedObject.Name,
+		APIVersion: metric.Object.DescribedObject.APIVersion,
+	}
+	usage, timestamp, err := c.metricsClient.GetObjectMetric(ctx, metricName, wpa.Namespace, objectRef, labelSelector)
+	if err != nil {
+		deleteMetricGauges(wpa, metricName)
+		return ReplicaCalculation{}, fmt.Errorf("unable to get object metric %s/%s/%s/%s: %s", wpa.Namespace, objectRef.Kind, objectRef.Name, metricName, err)
+	}
+	logger.Info("Metric from the Custom Metrics Provider", "metricName", metricName, "object", objectRef, "value", usage)
+
+	stalenessWindow := time.Duration(wpa.Spec.MetricStalenessWindowSeconds) * time.Second
+	if isMetricStale(timestamp, c.clock.Now(), stalenessWindow) {
+		promLabelsForWpaWithMetricName := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind, metricNamePromLabel: metricName}
+		staleMetric.With(promLabelsForWpaWithMetricName).Inc()
+		deleteMetricGauges(wpa, metricName)
+		return ReplicaCalculation{reason: v1alpha1.DecisionReasonMetricStale}, fmt.Errorf("object metric %s/%s/%s/%s is stale: last value from %v, older than %v", wpa.Namespace, objectRef.Kind, objectRef.Name, metricName, timestamp, stalenessWindow)
+	}
+
+	// if the average algorithm is used, the metric retrieved has to be divided by the number of available replicas.
+	// the usage is then smoothed with the smoothingFactor of the WPA.
+	adjustedUsage := c.smoothUsage(logger, wpa, metricName, float64(usage)/averaged)
+	replicaCount, utilizationQuantity, reason, err := getReplicaCount(logger, target.Status.Replicas, readyCapacity, wpa, metricName, adjustedUsage, metric.Object.LowWatermark, metric.Object.HighWatermark, metric.Object.Tolerance, nil, metric.Object.IdleWatermark)
+	if err != nil {
+		return ReplicaCalculation{}, err
+	}
+	return getReplicaCalculation(logger, target, wpa, metric, metricName, replicaCount, utilizationQuantity, timestamp, reason)
+}
+
+// GetResourceMetricReplicas calculates the desired replica count based on the watermarks of the given resource (CPU or memory)
+// for pods matching the given selector in the given namespace, and the current replica count.
+// Pods that are pending, failed or missing metrics are left out of the computation, as well as the unready pods as
+// described in groupPods.
+func (c *ReplicaCalculator) GetResourceMetricReplicas(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (ReplicaCalculation, error) {
 
 	resourceName := metric.Resource.Name
 	selector := metric.Resource.MetricSelector
 	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
 	if err != nil {
-		return ReplicaCalculation{0, 0, time.Time{}}, err
+		return ReplicaCalculation{}, err
 	}
 
+	if c.metricsClient == nil {
+		return ReplicaCalculation{}, fmt.Errorf("no metrics client to get the resource metric %s", resourceName)
+	}
 	namespace := wpa.Namespace
-	metrics, timestamp, err := c.metricsClient.GetResourceMetric(resourceName, namespace, labelSelector)
+	metrics, timestamp, err := c.metricsClient.GetResourceMetric(ctx, resourceName, namespace, labelSelector)
 	if err != nil {
-		// When we add official support for several metrics, move this Delete to only occur if no metric is available at all.
-		labelsWithReason := prometheus.Labels{
-			wpaNamePromLabel:           wpa.Name,
-			resourceNamespacePromLabel: wpa.Namespace,
-			resourceNamePromLabel:      wpa.Spec.ScaleTargetRef.Name,
-			resourceKindPromLabel:      wpa.Spec.ScaleTargetRef.Kind,
-			reasonPromLabel:            upscaleCappingPromLabelVal}
-		restrictedScaling.Delete(labelsWithReason)
-		labelsWithReason[reasonPromLabel] = downscaleCappingPromLabelVal
-		restrictedScaling.Delete(labelsWithReason)
-		labelsWithReason[reasonPromLabel] = withinBoundsPromLabelVal
-		restrictedScaling.Delete(labelsWithReason)
-		value.Delete(prometheus.Labels{wpaNamePromLabel: wpa.Name, metricNamePromLabel: string(resourceName)})
-		return ReplicaCalculation{0, 0, time.Time{}}, fmt.Errorf("unable to get resource metric %s/%s/%+v: %s", wpa.Namespace, resourceName, selector, err)
-	}
-	logger.Info("Metrics from the Resource Client", "metrics", metrics)
+		deleteMetricGauges(wpa, string(resourceName))
+		return ReplicaCalculation{}, fmt.Errorf("unable to get resource metric %s/%s/%+v: %s", wpa.Namespace, resourceName, selector, err)
+	}
+	logger.Info("Metrics from the Resource Client", "resource", resourceName, "metrics", metrics)
 
 	lbl, err := labels.Parse(target.Status.Selector)
 	if err != nil {
-		return ReplicaCalculation{0, 0, time.Time{}}, fmt.Errorf("could not parse the labels of the target: %v", err)
+		return ReplicaCalculation{}, fmt.Errorf("could not parse the labels of the target: %v", err)
 	}
 
 	podList, err := c.podLister.Pods(namespace).List(lbl)
 	if err != nil {
-		return ReplicaCalculation{0, 0, time.Time{}}, fmt.Errorf("unable to get pods while calculating replica count: %v", err)
+		return ReplicaCalculation{}, fmt.Errorf("unable to get pods while calculating replica count: %v", err)
 	}
 
 	if len(podList) == 0 {
-		return ReplicaCalculation{0, 0, time.Time{}}, fmt.Errorf("no pods returned by selector while calculating replica count")
+		return ReplicaCalculation{}, fmt.Errorf("no pods returned by selector while calculating replica count")
 	}
 	readiness := time.Duration(wpa.Spec.ReadinessDelaySeconds) * time.Second
-	readyPods, ignoredPods := groupPods(logger, podList, target.Name, metrics, resourceName, readiness)
+	initialReadiness := time.Duration(wpa.Spec.InitialReadinessDelaySeconds) * time.Second
+	readyPods, ignoredPods := groupPods(logger, podList, target.Name, metrics, resourceName, readiness, initialReadiness, wpa.Spec.UseReadyReplicas)
 	readyPodCount := len(readyPods)
 
 	removeMetricsForPods(metrics, ignoredPods)
 	if len(metrics) == 0 {
-		return ReplicaCalculation{0, 0, time.Time{}}, fmt.Errorf("did not receive metrics for any ready pods")
+		return ReplicaCalculation{}, fmt.Errorf("did not receive metrics for any ready pods")
 	}
 
 	averaged := 1.0
-	if wpa.Spec.Algorithm == "average" {
+	// the resource metrics are already compared to the requests of the pods by the averageByRequest algorithm of the
+	// other metrics, they are averaged over the ready pods.
+	if wpa.Spec.Algorithm == "average" || wpa.Spec.Algorithm == "averageByRequest" {
 		averaged = float64(readyPodCount)
 	}
 
@@ -170,45 +519,426 @@ func (c *ReplicaCalculator) GetResourceReplicas(logger logr.Logger, target *auto
 	for _, podMetric := range metrics {
 		sum += podMetric.Value
 	}
-	adjustedUsage := float64(sum) / averaged
+	adjustedUsage := c.smoothUsage(logger, wpa, string(resourceName), float64(sum)/averaged)
+
+	lowMark, highMark := metric.Resource.LowWatermark, metric.Resource.HighWatermark
+	if metric.Resource.TargetType == "Utilization" {
+		var request int64
+		request, err = getReadyPodsRequest(podList, readyPods, resourceName)
+		if err != nil {
+			return ReplicaCalculation{}, fmt.Errorf("unable to get the requests of the pods for the utilization of %s: %v", resourceName, err)
+		}
+		// the usage is compared to the request of a pod when it is averaged, to the total request otherwise.
+		reference := float64(request) * averaged / float64(readyPodCount)
+		lowMark, highMark = getUtilizationWatermark(lowMark, reference), getUtilizationWatermark(highMark, reference)
+		logger.Info("Computed the watermarks from the requests of the pods", "resource", resourceName, "request", reference, "lowWatermark", lowMark, "highWatermark", highMark)
+	}
 
-	replicaCount, utilizationQuantity := getReplicaCount(logger, target.Status.Replicas, int32(readyPodCount), wpa, string(resourceName), adjustedUsage, metric.Resource.LowWatermark, metric.Resource.HighWatermark)
-	return ReplicaCalculation{replicaCount, utilizationQuantity, timestamp}, nil
+	replicaCount, utilizationQuantity, reason, err := getReplicaCount(logger, target.Status.Replicas, float64(readyPodCount), wpa, string(resourceName), adjustedUsage, lowMark, highMark, metric.Resource.Tolerance, nil, nil)
+	if err != nil {
+		return ReplicaCalculation{}, err
+	}
+	return getReplicaCalculation(logger, target, wpa, metric, string(resourceName), replicaCount, utilizationQuantity, timestamp, reason)
 }
 
-func getReplicaCount(logger logr.Logger, currentReplicas, currentReadyReplicas int32, wpa *v1alpha1.WatermarkPodAutoscaler, name string, adjustedUsage float64, lowMark, highMark *resource.Quantity) (replicaCount int32, utilization int64) {
-	utilizationQuantity := resource.NewMilliQuantity(int64(adjustedUsage), resource.DecimalSI)
-	adjustedHM := float64(highMark.MilliValue() + highMark.MilliValue()*wpa.Spec.Tolerance.MilliValue()/1000)
-	adjustedLM := float64(lowMark.MilliValue() - lowMark.MilliValue()*wpa.Spec.Tolerance.MilliValue()/1000)
+// GetPodsMetricReplicas calculates the desired replica count based on the average value of a metric describing each pod
+// of the target (e.g. the requests per second served by each pod), served by the custom metrics API, and the current
+// replica count. Pods that are pending, failed or missing metrics are left out of the average, as well as the unready
+// pods as described in groupPods.
+func (c *ReplicaCalculator) GetPodsMetricReplicas(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (ReplicaCalculation, error) {
+	metricName := metric.Pods.MetricName
+	metricSelector := labels.Everything()
+	if metric.Pods.MetricSelector != nil {
+		var err error
+		metricSelector, err = metav1.LabelSelectorAsSelector(metric.Pods.MetricSelector)
+		if err != nil {
+			return ReplicaCalculation{}, err
+		}
+	}
+	lbl, err := labels.Parse(target.Status.Selector)
+	if err != nil {
+		return ReplicaCalculation{}, fmt.Errorf("could not parse the labels of the target: %v", err)
+	}
 
-	labelsWithReason := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind, reasonPromLabel: withinBoundsPromLabelVal}
-	labelsWithMetricName := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind, metricNamePromLabel: name}
+	if c.metricsClient == nil {
+		return ReplicaCalculation{}, fmt.Errorf("no metrics client to get the pods metric %s", metricName)
+	}
+	namespace := wpa.Namespace
+	metrics, timestamp, err := c.metricsClient.GetRawMetric(ctx, metricName, namespace, lbl, metricSelector)
+	if err != nil {
+		deleteMetricGauges(wpa, metricName)
+		return ReplicaCalculation{}, fmt.Errorf("unable to get pods metric %s/%s/%v: %s", namespace, metricName, lbl, err)
+	}
+	logger.Info("Metrics from the Custom Metrics Provider", "metricName", metricName, "metrics", metrics)
+
+	stalenessWindow := time.Duration(wpa.Spec.MetricStalenessWindowSeconds) * time.Second
+	if isMetricStale(timestamp, c.clock.Now(), stalenessWindow) {
+		promLabelsForWpaWithMetricName := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind, metricNamePromLabel: metricName}
+		staleMetric.With(promLabelsForWpaWithMetricName).Inc()
+		deleteMetricGauges(wpa, metricName)
+		return ReplicaCalculation{reason: v1alpha1.DecisionReasonMetricStale}, fmt.Errorf("pods metric %s/%s/%v is stale: last value from %v, older than %v", namespace, metricName, lbl, timestamp, stalenessWindow)
+	}
+
+	podList, err := c.podLister.Pods(namespace).List(lbl)
+	if err != nil {
+		return ReplicaCalculation{}, fmt.Errorf("unable to get pods while calculating replica count: %v", err)
+	}
+	if len(podList) == 0 {
+		return ReplicaCalculation{}, fmt.Errorf("no pods returned by selector while calculating replica count")
+	}
+	readiness := time.Duration(wpa.Spec.ReadinessDelaySeconds) * time.Second
+	initialReadiness := time.Duration(wpa.Spec.InitialReadinessDelaySeconds) * time.Second
+	readyPods, ignoredPods := groupPods(logger, podList, target.Name, metrics, corev1.ResourceName(metricName), readiness, initialReadiness, wpa.Spec.UseReadyReplicas)
+	readyPodCount := len(readyPods)
+
+	removeMetricsForPods(metrics, ignoredPods)
+	if len(metrics) == 0 || readyPodCount == 0 {
+		return ReplicaCalculation{}, fmt.Errorf("did not receive metrics for any ready pods")
+	}
+
+	// the value of the metric is averaged over the ready pods, whatever the algorithm of the WPA.
+	var sum int64
+	for podName, podMetric := range metrics {
+		if readyPods.Has(podName) {
+			sum += podMetric.Value
+		}
+	}
+	adjustedUsage := c.smoothUsage(logger, wpa, metricName, float64(sum)/float64(readyPodCount))
+
+	replicaCount, utilizationQuantity, reason, err := getReplicaCount(logger, target.Status.Replicas, float64(readyPodCount), wpa, metricName, adjustedUsage, metric.Pods.LowWatermark, metric.Pods.HighWatermark, metric.Pods.Tolerance, nil, nil)
+	if err != nil {
+		return ReplicaCalculation{}, err
+	}
+	return getReplicaCalculation(logger, target, wpa, metric, metricName, replicaCount, utilizationQuantity, timestamp, reason)
+}
+
+// getReplicaCount returns the number of replicas recommended by the usage of a metric compared to its watermarks.
+// currentReadyReplicas is the capacity of the ready replicas, in number of replicas of the size of the newest one with the
+// averageByRequest algorithm.
+func getReplicaCount(logger logr.Logger, currentReplicas int32, currentReadyReplicas float64, wpa *v1alpha1.WatermarkPodAutoscaler, name string, adjustedUsage float64, lowMark, highMark, tolerance, perReplicaCapacity, idleMark *resource.Quantity) (replicaCount int32, utilizationValue int64, reason string, err error) {
+	recommendation, err := getWatermarkRecommendation(wpa, wpa.Spec.Algorithm, name, currentReplicas, currentReadyReplicas, adjustedUsage, lowMark, highMark, tolerance, perReplicaCapacity, idleMark)
+	if err != nil {
+		return 0, 0, "", handleInvalidMetricValue(wpa, name, err)
+	}
+	recordWatermarkRecommendation(logger, wpa, name, currentReadyReplicas, lowMark, highMark, idleMark, recommendation)
+	return recommendation.ReplicaCount, getUtilization(recommendation.Usage), recommendation.Reason, nil
+}
+
+// getWatermarkRecommendation compares the usage of a metric to its watermarks widened by the tolerances, and returns the
+// number of replicas they recommend along with the branch that fired. It has no side effect, the errors are a usage
+// or a number of replicas that is NaN or Inf, and watermarks overlapping once widened by the tolerances.
+// The algorithm of the metric dampens the response to the breach with the logarithmic algorithm.
+func getWatermarkRecommendation(wpa *v1alpha1.WatermarkPodAutoscaler, algorithm string, name string, currentReplicas int32, currentReadyReplicas float64, adjustedUsage float64, lowMark, highMark, tolerance, perReplicaCapacity, idleMark *resource.Quantity) (RecommendationResult, error) {
+	// a NaN or Inf can't be converted to a number of replicas, the current one is kept.
+	if !isValidMetricValue(adjustedUsage) {
+		return RecommendationResult{}, fmt.Errorf("invalid usage computed for the metric %s: %v", name, adjustedUsage)
+	}
+
+	result := RecommendationResult{
+		Usage:              adjustedUsage,
+		UpscaleTolerance:   getUpscaleTolerance(wpa, tolerance),
+		DownscaleTolerance: getDownscaleTolerance(wpa, tolerance),
+	}
+	result.AdjustedLowWatermark, result.AdjustedHighWatermark = getAdjustedWatermarks(wpa, lowMark, highMark, result.UpscaleTolerance, result.DownscaleTolerance)
+	// the multiplicative tolerance moves negative watermarks towards each other, the usage could then be both above the
+	// high watermark and below the low watermark: there is no sensible recommendation, the current one is kept.
+	if result.AdjustedLowWatermark >= result.AdjustedHighWatermark {
+		return RecommendationResult{}, fmt.Errorf("the watermarks of the metric %s overlap once adjusted with the tolerances: the adjusted low watermark %v is not below the adjusted high watermark %v", name, result.AdjustedLowWatermark, result.AdjustedHighWatermark)
+	}
 
 	switch {
-	case adjustedUsage > adjustedHM:
-		replicaCount = int32(math.Ceil(float64(currentReadyReplicas) * adjustedUsage / (float64(highMark.MilliValue()))))
-		// tolerance: milliValue/10 to represent the %.
-		logger.Info("Value is above highMark", "usage", utilizationQuantity.String(), "replicaCount", replicaCount, "currentReadyReplicas", currentReadyReplicas, "tolerance (%):", float64(wpa.Spec.Tolerance.MilliValue())/10, "adjustedHM", adjustedHM, "adjustedUsage", adjustedUsage)
-	case adjustedUsage < adjustedLM:
-		replicaCount = int32(math.Floor(float64(currentReadyReplicas) * adjustedUsage / (float64(lowMark.MilliValue()))))
-		// Keep a minimum of 1 replica
-		replicaCount = int32(math.Max(float64(replicaCount), 1))
-		logger.Info("Value is below lowMark", "usage", utilizationQuantity.String(), "replicaCount", replicaCount, "currentReadyReplicas", currentReadyReplicas, "tolerance (%):", float64(wpa.Spec.Tolerance.MilliValue())/10, "adjustedLM", adjustedLM, "adjustedUsage", adjustedUsage)
+	case wpa.Spec.ScaleDownToZeroEnabled && idleMark != nil && adjustedUsage < getMilliValue(idleMark):
+		result.ReplicaCount = 0
+		result.Reason = v1alpha1.DecisionReasonBelowIdleWatermark
+		result.Distance = getWatermarkDistance(adjustedUsage, lowMark)
+	case adjustedUsage > result.AdjustedHighWatermark:
+		rawReplicaCount := currentReadyReplicas * getScalingRatio(algorithm, adjustedUsage, highMark)
+		if perReplicaCapacity != nil {
+			rawReplicaCount = float64(getCapacityReplicaCount(adjustedUsage, perReplicaCapacity))
+		}
+		if currentReadyReplicas == 0 && wpa.Spec.ScaleDownToZeroEnabled {
+			if perReplicaCapacity == nil {
+				// there is no replica to scale from, the first one would get all of the load.
+				rawReplicaCount = adjustedUsage / getMilliValue(highMark)
+			}
+			rawReplicaCount = math.Max(rawReplicaCount, float64(getScaleUpFromZeroReplicas(wpa)))
+		}
+		if !isValidMetricValue(rawReplicaCount) {
+			return RecommendationResult{}, fmt.Errorf("invalid replica count computed for the metric %s: %v", name, rawReplicaCount)
+		}
+		result.ReplicaCount = roundReplicas(rawReplicaCount, getReplicaRounding(wpa, "ceil"))
+		result.Reason = v1alpha1.DecisionReasonAboveHighWatermark
+		result.Distance = getWatermarkDistance(adjustedUsage, highMark)
+	case adjustedUsage < result.AdjustedLowWatermark:
+		rawReplicaCount := currentReadyReplicas * getScalingRatio(algorithm, adjustedUsage, lowMark)
+		if perReplicaCapacity != nil {
+			rawReplicaCount = float64(getCapacityReplicaCount(adjustedUsage, perReplicaCapacity))
+		}
+		if !isValidMetricValue(rawReplicaCount) {
+			return RecommendationResult{}, fmt.Errorf("invalid replica count computed for the metric %s: %v", name, rawReplicaCount)
+		}
+		result.ReplicaCount = roundReplicas(rawReplicaCount, getReplicaRounding(wpa, "floor"))
+		result.Reason = v1alpha1.DecisionReasonBelowLowWatermark
+		result.Distance = getWatermarkDistance(adjustedUsage, lowMark)
+		// Keep a minimum of 1 replica, unless the target can be scaled down to zero
+		if !wpa.Spec.ScaleDownToZeroEnabled {
+			result.ReplicaCount = int32(math.Max(float64(result.ReplicaCount), 1))
+		}
 	default:
-		restrictedScaling.With(labelsWithReason).Set(1)
-		value.With(labelsWithMetricName).Set(adjustedUsage)
-		logger.Info("Within bounds of the watermarks", "value", utilizationQuantity.String(), "currentReadyReplicas", currentReadyReplicas, "tolerance (%):", float64(wpa.Spec.Tolerance.MilliValue())/10, "adjustedLM", adjustedLM, "adjustedHM", adjustedHM, "adjustedUsage", adjustedUsage)
 		// returning the currentReplicas instead of the count of healthy ones to be consistent with the upstream behavior.
-		return currentReplicas, utilizationQuantity.MilliValue()
+		result.ReplicaCount = currentReplicas
+		result.Reason = v1alpha1.DecisionReasonWithinTolerance
 	}
+	return result, nil
+}
 
-	restrictedScaling.With(labelsWithReason).Set(0)
-	value.With(labelsWithMetricName).Set(adjustedUsage)
+// getScalingRatio returns the factor the ready replicas are multiplied by when the usage breaches the watermark: the ratio
+// usage / watermark, dampened with the logarithmic algorithm to 1 + ln(ratio) above 1 and 1 / (1 + ln(1 / ratio)) below 1.
+// A usage ten times the high watermark then multiplies the replicas by 3.3 instead of 10.
+// A ratio that is not positive can't be dampened and is kept as is.
+func getScalingRatio(algorithm string, usage float64, watermark *resource.Quantity) float64 {
+	ratio := usage / getMilliValue(watermark)
+	if algorithm != "logarithmic" || !(ratio > 0) {
+		return ratio
+	}
+	if ratio >= 1 {
+		return 1 + math.Log(ratio)
+	}
+	return 1 / (1 + math.Log(1/ratio))
+}
 
-	return replicaCount, utilizationQuantity.MilliValue()
+// recordWatermarkRecommendation logs the recommendation of the watermarks of a metric and exposes it with the metrics of the WPA.
+func recordWatermarkRecommendation(logger logr.Logger, wpa *v1alpha1.WatermarkPodAutoscaler, name string, currentReadyReplicas float64, lowMark, highMark, idleMark *resource.Quantity, recommendation RecommendationResult) {
+	labelsWithMetricName := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind, metricNamePromLabel: name}
+	utilizationQuantity := resource.NewMilliQuantity(getUtilization(recommendation.Usage), resource.DecimalSI)
+	// tolerance: milliValue/10 to represent the %.
+	upscaleTolerancePercent, downscaleTolerancePercent := float64(recommendation.UpscaleTolerance)/10, float64(recommendation.DownscaleTolerance)/10
+
+	switch recommendation.Reason {
+	case v1alpha1.DecisionReasonBelowIdleWatermark:
+		logger.Info("Value is below idleMark", "metricName", name, "usage", utilizationQuantity.String(), "replicaCount", recommendation.ReplicaCount, "currentReadyReplicas", currentReadyReplicas, "idleMark", getMilliValue(idleMark), "adjustedUsage", recommendation.Usage)
+	case v1alpha1.DecisionReasonAboveHighWatermark:
+		logger.Info("Value is above highMark", "metricName", name, "usage", utilizationQuantity.String(), "replicaCount", recommendation.ReplicaCount, "currentReadyReplicas", currentReadyReplicas, "highMark", getMilliValue(highMark), "upscaleTolerancePercent", upscaleTolerancePercent, "adjustedHM", recommendation.AdjustedHighWatermark, "adjustedUsage", recommendation.Usage)
+	case v1alpha1.DecisionReasonBelowLowWatermark:
+		logger.Info("Value is below lowMark", "metricName", name, "usage", utilizationQuantity.String(), "replicaCount", recommendation.ReplicaCount, "currentReadyReplicas", currentReadyReplicas, "lowMark", getMilliValue(lowMark), "downscaleTolerancePercent", downscaleTolerancePercent, "adjustedLM", recommendation.AdjustedLowWatermark, "adjustedUsage", recommendation.Usage)
+	default:
+		logger.Info("Within bounds of the watermarks", "metricName", name, "usage", utilizationQuantity.String(), "replicaCount", recommendation.ReplicaCount, "currentReadyReplicas", currentReadyReplicas, "lowMark", getMilliValue(lowMark), "highMark", getMilliValue(highMark), "upscaleTolerancePercent", upscaleTolerancePercent, "downscaleTolerancePercent", downscaleTolerancePercent, "adjustedLM", recommendation.AdjustedLowWatermark, "adjustedHM", recommendation.AdjustedHighWatermark, "adjustedUsage", recommendation.Usage)
+	}
+
+	value.With(labelsWithMetricName).Set(recommendation.Usage)
+	utilization.With(labelsWithMetricName).Set(float64(utilizationQuantity.MilliValue()))
+	replicaRecommendation.With(labelsWithMetricName).Set(float64(recommendation.ReplicaCount))
+	watermarkDistance.With(labelsWithMetricName).Set(recommendation.Distance)
+	adjustedLowwm.With(labelsWithMetricName).Set(recommendation.AdjustedLowWatermark)
+	adjustedHighwm.With(labelsWithMetricName).Set(recommendation.AdjustedHighWatermark)
+}
+
+// getUtilization returns the usage of a metric as the milli-value reported in the status, truncated.
+func getUtilization(usage float64) int64 {
+	return int64(usage)
+}
+
+// getWatermarkDistance returns how far the usage is from the watermark, as a fraction of the watermark.
+// It is negative above the watermark and positive below it, and 0 for a watermark of 0.
+func getWatermarkDistance(adjustedUsage float64, watermark *resource.Quantity) float64 {
+	milliValue := getMilliValue(watermark)
+	if milliValue == 0 {
+		return 0
+	}
+	return (milliValue - adjustedUsage) / milliValue
+}
+
+// getMilliValue returns the milliValue of a quantity as a float, to be compared to the usage of the metrics.
+// MilliValue overflows above 9.2e15 (e.g. a watermark of 10Pi bytes), the milliValue is then computed from Value,
+// which only loses the precision below the unit.
+func getMilliValue(q *resource.Quantity) float64 {
+	if v := q.Value(); v > math.MaxInt64/1000 || v < math.MinInt64/1000 {
+		return float64(v) * 1000
+	}
+	return float64(q.MilliValue())
+}
+
+// getReplicaRounding returns the rounding of the WPA, the legacy one rounds with the given rounding of the direction of the breach.
+func getReplicaRounding(wpa *v1alpha1.WatermarkPodAutoscaler, legacyRounding string) string {
+	if wpa.Spec.ReplicaRounding == "" || wpa.Spec.ReplicaRounding == "legacy" {
+		return legacyRounding
+	}
+	return wpa.Spec.ReplicaRounding
 }
 
-func (c *ReplicaCalculator) getReadyPodsCount(log logr.Logger, target *autoscalingv1.Scale, selector labels.Selector, readinessDelay time.Duration) (int32, error) {
+// roundReplicas converts a fractional number of replicas with the given rounding, half replicas are rounded up by nearest.
+func roundReplicas(x float64, rounding string) int32 {
+	switch rounding {
+	case "floor":
+		return int32(math.Floor(x))
+	case "nearest":
+		return int32(math.Round(x))
+	default:
+		return int32(math.Ceil(x))
+	}
+}
+
+// isValidMetricValue returns false for the NaN and Inf values, which can't be used to compute a number of replicas.
+func isValidMetricValue(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// handleInvalidMetricValue counts the invalid value, removes the stale gauges of the metric and returns the error to surface.
+func handleInvalidMetricValue(wpa *v1alpha1.WatermarkPodAutoscaler, metricName string, err error) error {
+	invalidMetricValue.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind, metricNamePromLabel: metricName}).Inc()
+	deleteMetricGauges(wpa, metricName)
+	return err
+}
+
+// deleteMetricGauges removes the gauges computed from the value of a metric, so that they don't expose a value
+// which is no longer used to scale the target.
+func deleteMetricGauges(wpa *v1alpha1.WatermarkPodAutoscaler, metricName string) {
+	promLabelsForWpaWithMetricName := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind, metricNamePromLabel: metricName}
+	value.Delete(promLabelsForWpaWithMetricName)
+	rawValue.Delete(promLabelsForWpaWithMetricName)
+	utilization.Delete(promLabelsForWpaWithMetricName)
+	replicaRecommendation.Delete(promLabelsForWpaWithMetricName)
+	watermarkDistance.Delete(promLabelsForWpaWithMetricName)
+	adjustedLowwm.Delete(promLabelsForWpaWithMetricName)
+	adjustedHighwm.Delete(promLabelsForWpaWithMetricName)
+}
+
+// clampReplicaCount keeps the recommendation of the metric within [getMinReplicas, MaxReplicas].
+// MaxReplicas is only enforced when it is set, as an unset value would clamp every recommendation to 0.
+// The clamping is exposed per metric, as each metric of the WPA is clamped on its own.
+func clampReplicaCount(logger logr.Logger, wpa *v1alpha1.WatermarkPodAutoscaler, name string, replicaCount int32) (int32, error) {
+	minReplicas, maxReplicas := getMinReplicas(wpa), wpa.Spec.MaxReplicas
+	if maxReplicas > 0 && minReplicas > maxReplicas {
+		return 0, fmt.Errorf("minReplicas (%d) is greater than maxReplicas (%d)", minReplicas, maxReplicas)
+	}
+	labels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind, metricNamePromLabel: name}
+
+	clampedReplicaCount := replicaCount
+	clampedToMin, clampedToMax := 0.0, 0.0
+	switch {
+	case maxReplicas > 0 && replicaCount > maxReplicas:
+		logger.Info("Recommendation above maxReplicas", "metricName", name, "replicaCount", replicaCount, "maxReplicas", maxReplicas)
+		clampedReplicaCount = maxReplicas
+		clampedToMax = 1
+		labels[directionPromLabel] = upperPromLabelVal
+		clampedTotal.With(labels).Inc()
+	case replicaCount < minReplicas:
+		logger.Info("Recommendation below minReplicas", "metricName", name, "replicaCount", replicaCount, "minReplicas", minReplicas)
+		clampedReplicaCount = minReplicas
+		clampedToMin = 1
+		labels[directionPromLabel] = lowerPromLabelVal
+		clampedTotal.With(labels).Inc()
+	}
+	delete(labels, directionPromLabel)
+	labels[boundPromLabel] = minReplicasPromLabelVal
+	replicaClamped.With(labels).Set(clampedToMin)
+	labels[boundPromLabel] = maxReplicasPromLabelVal
+	replicaClamped.With(labels).Set(clampedToMax)
+
+	return clampedReplicaCount, nil
+}
+
+// getClampedReason returns the reason of a recommendation once clamped to the minReplicas and maxReplicas bounds.
+func getClampedReason(reason string, replicaCount, clampedReplicaCount int32) string {
+	switch {
+	case clampedReplicaCount < replicaCount:
+		return v1alpha1.DecisionReasonClampedToMax
+	case clampedReplicaCount > replicaCount:
+		return v1alpha1.DecisionReasonClampedToMin
+	default:
+		return reason
+	}
+}
+
+// restrictMetricDirection returns the current number of replicas instead of the recommendation of a metric
+// if the metric is not allowed to scale the target in that direction.
+func restrictMetricDirection(logger logr.Logger, metric v1alpha1.MetricSpec, name string, currentReplicas, replicaCount int32, reason string) (int32, string) {
+	switch {
+	case replicaCount > currentReplicas && metric.AllowScaleUp != nil && !*metric.AllowScaleUp:
+		logger.Info("Ignoring the upscale recommendation, the metric is not allowed to scale up", "metricName", name, "replicaCount", replicaCount, "currentReplicas", currentReplicas)
+	case replicaCount < currentReplicas && metric.AllowScaleDown != nil && !*metric.AllowScaleDown:
+		logger.Info("Ignoring the downscale recommendation, the metric is not allowed to scale down", "metricName", name, "replicaCount", replicaCount, "currentReplicas", currentReplicas)
+	default:
+		return replicaCount, reason
+	}
+	return currentReplicas, v1alpha1.DecisionReasonDirectionBlocked
+}
+
+// getMinReplicas returns the lowest number of replicas a metric can recommend: the minReplicas of the WPA, which can't
+// be 0 unless the target can be scaled down to zero, as in convertDesiredReplicasWithRules. It defaults to 1, or to 0
+// when the target can be scaled down to zero.
+func getMinReplicas(wpa *v1alpha1.WatermarkPodAutoscaler) int32 {
+	switch {
+	case wpa.Spec.MinReplicas != nil && (*wpa.Spec.MinReplicas > 0 || wpa.Spec.ScaleDownToZeroEnabled):
+		return *wpa.Spec.MinReplicas
+	case wpa.Spec.MinReplicas == nil && wpa.Spec.ScaleDownToZeroEnabled:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// isScaledToZero returns true when the target was scaled down to zero replicas by a WPA allowed to do so.
+func isScaledToZero(wpa *v1alpha1.WatermarkPodAutoscaler, currentReplicas int32) bool {
+	return wpa.Spec.ScaleDownToZeroEnabled && currentReplicas == 0
+}
+
+// getScaleUpFromZeroReplicas returns the number of replicas to scale up to from zero, 1 if it is unset.
+func getScaleUpFromZeroReplicas(wpa *v1alpha1.WatermarkPodAutoscaler) int32 {
+	if wpa.Spec.ScaleUpFromZeroReplicas > 0 {
+		return wpa.Spec.ScaleUpFromZeroReplicas
+	}
+	return 1
+}
+
+// getCapacityReplicaCount returns the number of replicas needed to handle the usage, given what a single replica can handle.
+// We round up in both directions as we don't want to be under-provisioned.
+func getCapacityReplicaCount(usage float64, perReplicaCapacity *resource.Quantity) int32 {
+	return int32(math.Max(math.Ceil(usage/getMilliValue(perReplicaCapacity)), 1))
+}
+
+// getAdjustedWatermarks returns the low and high watermarks widened by the tolerances (as milliValues).
+// By default, each tolerance is a percentage of its watermark. With the band mode, it is a percentage of the band between the watermarks,
+// which keeps the dead zones symmetric when the watermarks differ greatly in magnitude.
+// The tolerances are truncated to a milliValue, they are computed with floats not to overflow with large watermarks (e.g. in bytes).
+func getAdjustedWatermarks(wpa *v1alpha1.WatermarkPodAutoscaler, lowMark, highMark *resource.Quantity, upscaleTolerance, downscaleTolerance int64) (adjustedLM, adjustedHM float64) {
+	lm, hm := getMilliValue(lowMark), getMilliValue(highMark)
+	if wpa.Spec.ToleranceMode == "band" {
+		band := hm - lm
+		return lm - math.Trunc(band*float64(downscaleTolerance)/1000), hm + math.Trunc(band*float64(upscaleTolerance)/1000)
+	}
+	return lm - math.Trunc(lm*float64(downscaleTolerance)/1000), hm + math.Trunc(hm*float64(upscaleTolerance)/1000)
+}
+
+// getUpscaleTolerance returns the tolerance (as a milliValue) applied above the high watermark.
+// UpscaleTolerance is preferred, then the tolerance of the metric, Tolerance is used for backward compatibility when both are unset.
+func getUpscaleTolerance(wpa *v1alpha1.WatermarkPodAutoscaler, metricTolerance *resource.Quantity) int64 {
+	if wpa.Spec.UpscaleTolerance != nil {
+		return wpa.Spec.UpscaleTolerance.MilliValue()
+	}
+	return getMetricTolerance(wpa, metricTolerance)
+}
+
+// getDownscaleTolerance returns the tolerance (as a milliValue) applied below the low watermark.
+// DownscaleTolerance is preferred, then the tolerance of the metric, Tolerance is used for backward compatibility when both are unset.
+func getDownscaleTolerance(wpa *v1alpha1.WatermarkPodAutoscaler, metricTolerance *resource.Quantity) int64 {
+	if wpa.Spec.DownscaleTolerance != nil {
+		return wpa.Spec.DownscaleTolerance.MilliValue()
+	}
+	return getMetricTolerance(wpa, metricTolerance)
+}
+
+// getMetricTolerance returns the tolerance (as a milliValue) of the metric if it is set, the one of the WPA otherwise.
+func getMetricTolerance(wpa *v1alpha1.WatermarkPodAutoscaler, metricTolerance *resource.Quantity) int64 {
+	if metricTolerance != nil {
+		return metricTolerance.MilliValue()
+	}
+	return wpa.Spec.Tolerance.MilliValue()
+}
+
+// getReadyPodsCount returns the number of ready pods of the target, tolerating the pending pods within the readinessDelay.
+// With excludeTerminating, the pods being deleted are not counted, e.g. the ones still terminating after a downscale.
+func (c *ReplicaCalculator) getReadyPodsCount(log logr.Logger, target *autoscalingv1.Scale, selector labels.Selector, readinessDelay time.Duration, excludeTerminating bool) (int32, error) {
 	podList, err := c.podLister.Pods(target.Namespace).List(selector)
 	if err != nil {
 		return 0, fmt.Errorf("unable to get pods while calculating replica count: %v", err)
@@ -218,13 +948,18 @@ func (c *ReplicaCalculator) getReadyPodsCount(log logr.Logger, target *autoscali
 	}
 
 	toleratedAsReadyPodCount := 0
-	var incorrectTargetPodsCount int
+	var incorrectTargetPodsCount, terminatingPodsCount int
+	now := time.Now()
 	for _, pod := range podList {
 		// matchLabel might be too broad, use the OwnerRef to scope over the actual target
 		if ok := checkOwnerRef(pod.OwnerReferences, target.Name); !ok {
 			incorrectTargetPodsCount++
 			continue
 		}
+		if excludeTerminating && isTerminating(pod) {
+			terminatingPodsCount++
+			continue
+		}
 		_, condition := getPodCondition(&pod.Status, corev1.PodReady)
 		// We can't distinguish pods that are past the Readiness in the lifecycle but have not reached it
 		// and pods that are still Unschedulable but we don't need this level of granularity.
@@ -235,16 +970,184 @@ func (c *ReplicaCalculator) getReadyPodsCount(log logr.Logger, target *autoscali
 		if pod.Status.Phase == corev1.PodRunning && condition.Status == corev1.ConditionTrue ||
 			// Pending includes the time spent pulling images onto the host.
 			// If the pod is stuck in a ContainerCreating state for more than readinessDelay we want to discard it.
-			pod.Status.Phase == corev1.PodPending && metav1.Now().Sub((condition.LastTransitionTime).Time) < readinessDelay {
+			pod.Status.Phase == corev1.PodPending && now.Sub(condition.LastTransitionTime.Time) < readinessDelay {
 			toleratedAsReadyPodCount++
 		}
 	}
-	log.Info("getReadyPodsCount", "full podList length", len(podList), "toleratedAsReadyPodCount", toleratedAsReadyPodCount, "incorrectly targeted pods", incorrectTargetPodsCount)
+	log.Info("Counted the ready pods of the target", "podCount", len(podList), "toleratedAsReadyPodCount", toleratedAsReadyPodCount, "incorrectTargetPodCount", incorrectTargetPodsCount, "terminatingPodCount", terminatingPodsCount)
 	if toleratedAsReadyPodCount == 0 {
 		return 0, fmt.Errorf("among the %d pods, none is ready. Skipping recommendation", len(podList))
 	}
 	return int32(toleratedAsReadyPodCount), nil
 }
+
+// getAveragingPodsCount returns the number of running and ready pods of the target, used to average the metrics.
+// The pending pods tolerated by getReadyPodsCount are not serving yet, counting them would lower the average right
+// after an upscale. The current number of replicas is returned if the pods can't be listed, and toleratedAsReadyPodCount
+// if none of the pods is ready yet, as the first ready replica would otherwise get all of the load.
+// With excludeTerminating, the pods being deleted are not averaged over.
+func (c *ReplicaCalculator) getAveragingPodsCount(log logr.Logger, target *autoscalingv1.Scale, selector labels.Selector, toleratedAsReadyPodCount int32, excludeTerminating bool) int32 {
+	podList, err := c.podLister.Pods(target.Namespace).List(selector)
+	if err != nil {
+		log.Info("Unable to list the pods of the target, averaging over the current replicas", "currentReplicas", target.Status.Replicas, "error", err)
+		return target.Status.Replicas
+	}
+	var readyPodCount int32
+	for _, pod := range podList {
+		if ok := checkOwnerRef(pod.OwnerReferences, target.Name); !ok {
+			continue
+		}
+		if excludeTerminating && isTerminating(pod) {
+			continue
+		}
+		_, condition := getPodCondition(&pod.Status, corev1.PodReady)
+		if pod.Status.Phase == corev1.PodRunning && condition != nil && condition.Status == corev1.ConditionTrue {
+			readyPodCount++
+		}
+	}
+	log.Info("Counted the pods to average over", "readyPodCount", readyPodCount, "toleratedAsReadyPodCount", toleratedAsReadyPodCount)
+	if readyPodCount == 0 {
+		return toleratedAsReadyPodCount
+	}
+	return readyPodCount
+}
+
+// GetPodReadiness returns the number of running and ready pods of the target and its total number of pods.
+// The pods being deleted are not counted, e.g. the ones still terminating after a downscale.
+func (c *ReplicaCalculator) GetPodReadiness(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, wpa *v1alpha1.WatermarkPodAutoscaler) (readyPodCount, podCount int32, err error) {
+	selector, err := labels.Parse(target.Status.Selector)
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not parse the labels of the target: %v", err)
+	}
+	podList, err := c.podLister.Pods(wpa.Namespace).List(selector)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to get the pods of the target: %v", err)
+	}
+	for _, pod := range podList {
+		// matchLabel might be too broad, use the OwnerRef to scope over the actual target
+		if ok := checkOwnerRef(pod.OwnerReferences, target.Name); !ok {
+			continue
+		}
+		if isTerminating(pod) {
+			continue
+		}
+		podCount++
+		_, condition := getPodCondition(&pod.Status, corev1.PodReady)
+		if pod.Status.Phase == corev1.PodRunning && condition != nil && condition.Status == corev1.ConditionTrue {
+			readyPodCount++
+		}
+	}
+	logger.Info("Counted the ready pods of the target", "podCount", podCount, "readyPodCount", readyPodCount)
+	return readyPodCount, podCount, nil
+}
+
+// getReadyCapacity returns the capacity of the ready replicas of the target and the number the usage of a metric is divided
+// by with the algorithm. The capacity is the number of ready replicas, except with the averageByRequest algorithm where it
+// is the total request of the ready pods in number of replicas of the size of the newest one.
+func (c *ReplicaCalculator) getReadyCapacity(logger logr.Logger, target *autoscalingv1.Scale, selector labels.Selector, wpa *v1alpha1.WatermarkPodAutoscaler, algorithm string, currentReadyReplicas int32) (readyCapacity, averaged float64, err error) {
+	switch algorithm {
+	case "average":
+		if currentReadyReplicas > 0 {
+			currentReadyReplicas = c.getAveragingPodsCount(logger, target, selector, currentReadyReplicas, wpa.Spec.UseReadyReplicas)
+		}
+		// at zero replicas, the first replica would get all of the load.
+		return float64(currentReadyReplicas), math.Max(float64(currentReadyReplicas), 1), nil
+	case "averageByRequest":
+		if currentReadyReplicas == 0 {
+			return 0, 1, nil
+		}
+		readyCapacity, err = c.getRequestCapacity(logger, target, selector, getAverageByRequestResource(wpa), wpa.Spec.UseReadyReplicas)
+		if err != nil {
+			return 0, 0, fmt.Errorf("unable to get the requests of the pods for %v: %v", selector, err)
+		}
+		// the newest pod accounts for 1 and the other ones for at least 0, the capacity is at least 1.
+		return readyCapacity, readyCapacity, nil
+	default:
+		return float64(currentReadyReplicas), 1, nil
+	}
+}
+
+// getAverageByRequestResource returns the resource whose requests are used by the averageByRequest algorithm, cpu by default.
+func getAverageByRequestResource(wpa *v1alpha1.WatermarkPodAutoscaler) corev1.ResourceName {
+	if wpa.Spec.AverageByRequestResource == "" {
+		return corev1.ResourceCPU
+	}
+	return wpa.Spec.AverageByRequestResource
+}
+
+// getRequestCapacity returns the total request of the resource of the running and ready pods of the target, in number of
+// replicas of the size of the newest one. The newest pod is the most likely to have the requests of the next replicas,
+// e.g. the ones recommended by the Vertical Pod Autoscaler. With excludeTerminating, the pods being deleted are not accounted for.
+func (c *ReplicaCalculator) getRequestCapacity(log logr.Logger, target *autoscalingv1.Scale, selector labels.Selector, resourceName corev1.ResourceName, excludeTerminating bool) (float64, error) {
+	podList, err := c.podLister.Pods(target.Namespace).List(selector)
+	if err != nil {
+		return 0, err
+	}
+	var totalRequest, newestRequest int64
+	var newestPod *corev1.Pod
+	for _, pod := range podList {
+		if ok := checkOwnerRef(pod.OwnerReferences, target.Name); !ok {
+			continue
+		}
+		if excludeTerminating && isTerminating(pod) {
+			continue
+		}
+		_, condition := getPodCondition(&pod.Status, corev1.PodReady)
+		if pod.Status.Phase != corev1.PodRunning || condition == nil || condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		var request int64
+		for _, container := range pod.Spec.Containers {
+			containerRequest, found := container.Resources.Requests[resourceName]
+			if !found {
+				return 0, fmt.Errorf("missing request for %s in container %s of pod %s/%s", resourceName, container.Name, pod.Namespace, pod.Name)
+			}
+			request += containerRequest.MilliValue()
+		}
+		if request == 0 {
+			return 0, fmt.Errorf("no request for %s in pod %s/%s", resourceName, pod.Namespace, pod.Name)
+		}
+		totalRequest += request
+		if newestPod == nil || newestPod.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			newestPod = pod
+			newestRequest = request
+		}
+	}
+	if newestPod == nil {
+		return 0, fmt.Errorf("none of the %d pods is running and ready", len(podList))
+	}
+	capacity := float64(totalRequest) / float64(newestRequest)
+	log.Info("Computed the capacity of the pods from their requests", "resource", resourceName, "totalRequest", totalRequest, "newestPod", newestPod.Name, "newestPodRequest", newestRequest, "capacity", capacity)
+	return capacity, nil
+}
+
+// getReadyPodsRequest returns the total request of the resource of the ready pods, in milli-units.
+// All the containers of the ready pods need a request for the resource.
+func getReadyPodsRequest(podList []*corev1.Pod, readyPods sets.String, resourceName corev1.ResourceName) (int64, error) {
+	var totalRequest int64
+	for _, pod := range podList {
+		if !readyPods.Has(pod.Name) {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			containerRequest, found := container.Resources.Requests[resourceName]
+			if !found {
+				return 0, fmt.Errorf("missing request for %s in container %s of pod %s/%s", resourceName, container.Name, pod.Namespace, pod.Name)
+			}
+			totalRequest += containerRequest.MilliValue()
+		}
+	}
+	if totalRequest == 0 {
+		return 0, fmt.Errorf("no request for %s in the ready pods", resourceName)
+	}
+	return totalRequest, nil
+}
+
+// getUtilizationWatermark returns the watermark matching a percentage of the request, in milli-units.
+func getUtilizationWatermark(percentage *resource.Quantity, request float64) *resource.Quantity {
+	return resource.NewMilliQuantity(int64(float64(percentage.MilliValue())*request/100000), resource.DecimalSI)
+}
+
 func checkOwnerRef(ownerRef []metav1.OwnerReference, targetName string) bool {
 	for _, o := range ownerRef {
 		if o.Kind != "ReplicaSet" && o.Kind != "StatefulSet" {
@@ -257,17 +1160,32 @@ func checkOwnerRef(ownerRef []metav1.OwnerReference, targetName string) bool {
 	return false
 }
 
-func groupPods(logger logr.Logger, podList []*corev1.Pod, targetName string, metrics metricsclient.PodMetricsInfo, resource corev1.ResourceName, delayOfInitialReadinessStatus time.Duration) (readyPods, ignoredPods sets.String) {
+// isTerminating returns whether the pod is being deleted. A terminating pod can still be running and ready while its
+// containers are stopping, but it is not part of the current replicas of its target anymore.
+func isTerminating(pod *corev1.Pod) bool {
+	return pod.DeletionTimestamp != nil
+}
+
+// groupPods returns the pods of the target whose metrics are used, and the ones whose metrics are ignored. Unless the
+// initialReadinessDelay is set, only the unready CPU pods that have never been ready are ignored. Once it is set, the
+// pods started less than initialReadinessDelay ago and the unready pods are ignored whatever the resource.
+func groupPods(logger logr.Logger, podList []*corev1.Pod, targetName string, metrics metricsclient.PodMetricsInfo, resource corev1.ResourceName, delayOfInitialReadinessStatus, initialReadinessDelay time.Duration, excludeTerminating bool) (readyPods, ignoredPods sets.String) {
 	readyPods = sets.NewString()
 	ignoredPods = sets.NewString()
 	missing := sets.NewString()
 	var incorrectTargetPodsCount int
+	now := time.Now()
 	for _, pod := range podList {
 		// matchLabel might be too broad, use the OwnerRef to scope over the actual target
 		if ok := checkOwnerRef(pod.OwnerReferences, targetName); !ok {
 			incorrectTargetPodsCount++
 			continue
 		}
+		// Terminating pods are ignored with useReadyReplicas, their usage is leaving with them.
+		if excludeTerminating && isTerminating(pod) {
+			ignoredPods.Insert(pod.Name)
+			continue
+		}
 		// Failed pods shouldn't produce metrics, but add to ignoredPods to be safe
 		if pod.Status.Phase == corev1.PodFailed {
 			ignoredPods.Insert(pod.Name)
@@ -285,11 +1203,21 @@ func groupPods(logger logr.Logger, podList []*corev1.Pod, targetName string, met
 			continue
 		}
 
+		_, condition := getPodCondition(&pod.Status, corev1.PodReady)
+		if initialReadinessDelay > 0 {
+			// Pods within their initialization window and unready pods are ignored, their usage is not representative.
+			if pod.Status.StartTime == nil || pod.Status.StartTime.Add(initialReadinessDelay).After(now) ||
+				condition == nil || condition.Status != corev1.ConditionTrue {
+				ignoredPods.Insert(pod.Name)
+				continue
+			}
+			readyPods.Insert(pod.Name)
+			continue
+		}
+
 		// Unready pods are ignored.
 		if resource == corev1.ResourceCPU {
 			var ignorePod bool
-			_, condition := getPodCondition(&pod.Status, corev1.PodReady)
-
 			if condition == nil || pod.Status.StartTime == nil {
 				ignorePod = true
 			} else {
@@ -303,7 +1231,7 @@ func groupPods(logger logr.Logger, podList []*corev1.Pod, targetName string, met
 		}
 		readyPods.Insert(pod.Name)
 	}
-	logger.Info("groupPods", "ready", len(readyPods), "missing", len(missing), "ignored", len(ignoredPods), "incorrect target", incorrectTargetPodsCount)
+	logger.Info("Grouped the pods of the target", "resource", resource, "readyPodCount", len(readyPods), "missingPodCount", len(missing), "ignoredPodCount", len(ignoredPods), "incorrectTargetPodCount", incorrectTargetPodsCount)
 	return readyPods, ignoredPods
 }
 
@@ -6,12 +6,17 @@
 package controllers
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
@@ -20,16 +25,20 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	core "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/kubernetes/pkg/controller/podautoscaler/metrics"
+	cmapi "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
 	emapi "k8s.io/metrics/pkg/apis/external_metrics/v1beta1"
 	metricsapi "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+	cmfake "k8s.io/metrics/pkg/client/custom_metrics/fake"
 	emfake "k8s.io/metrics/pkg/client/external_metrics/fake"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 )
@@ -48,6 +57,8 @@ type metricInfo struct {
 type replicaCalcTestCase struct {
 	expectedReplicas int32
 	expectedError    error
+	expectedReason   string
+	expectedPosition string
 	timestamp        time.Time
 
 	namespace string
@@ -153,6 +164,62 @@ func (tc *replicaCalcTestCase) getFakeEMClient(t *testing.T) *emfake.FakeExterna
 	return fakeEMClient
 }
 
+func (tc *replicaCalcTestCase) getFakeCMClient(t *testing.T) *cmfake.FakeCustomMetricsClient {
+	fakeCMClient := &cmfake.FakeCustomMetricsClient{}
+	fakeCMClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		getForAction, wasGetFor := action.(cmfake.GetForAction)
+		if !wasGetFor {
+			return true, nil, fmt.Errorf("expected a get-for action, got %v instead", action)
+		}
+
+		if tc.metric.spec.Pods != nil {
+			assert.Equal(t, tc.metric.spec.Pods.MetricName, getForAction.GetMetricName(), "the metric requested should have matched the one specified")
+			metrics := &cmapi.MetricValueList{}
+			for i, level := range tc.metric.levels {
+				metrics.Items = append(metrics.Items, cmapi.MetricValue{
+					DescribedObject: corev1.ObjectReference{
+						Kind:      "Pod",
+						Name:      fmt.Sprintf("%s-%d", podNamePrefix, i),
+						Namespace: tc.namespace,
+					},
+					Timestamp: metav1.Time{Time: tc.timestamp},
+					Metric:    cmapi.MetricIdentifier{Name: tc.metric.spec.Pods.MetricName},
+					Value:     *resource.NewMilliQuantity(level, resource.DecimalSI),
+				})
+			}
+			return true, metrics, nil
+		}
+
+		if tc.metric.spec.Object == nil {
+			return true, nil, fmt.Errorf("no object metrics specified in test client")
+		}
+
+		assert.Equal(t, tc.metric.spec.Object.MetricName, getForAction.GetMetricName(), "the metric requested should have matched the one specified")
+		if tc.metric.spec.Object.DescribedObject.Kind == "Namespace" {
+			// the metrics of a namespace are root scoped, the namespace of the WPA is used in place of the name of the object.
+			assert.Equal(t, tc.wpa.Namespace, getForAction.GetName(), "the namespace of the WPA should have been requested")
+		} else {
+			assert.Equal(t, tc.metric.spec.Object.DescribedObject.Name, getForAction.GetName(), "the object requested should have matched the one specified")
+			assert.Equal(t, tc.wpa.Namespace, getForAction.GetNamespace(), "the object should have been looked up in the namespace of the WPA")
+		}
+
+		metrics := &cmapi.MetricValueList{}
+		metric := cmapi.MetricValue{
+			DescribedObject: corev1.ObjectReference{
+				Kind:       tc.metric.spec.Object.DescribedObject.Kind,
+				APIVersion: tc.metric.spec.Object.DescribedObject.APIVersion,
+				Name:       tc.metric.spec.Object.DescribedObject.Name,
+			},
+			Timestamp: metav1.Time{Time: tc.timestamp},
+			Metric:    cmapi.MetricIdentifier{Name: tc.metric.spec.Object.MetricName},
+			Value:     *resource.NewMilliQuantity(tc.metric.levels[0], resource.DecimalSI),
+		}
+		metrics.Items = []cmapi.MetricValue{metric}
+		return true, metrics, nil
+	})
+	return fakeCMClient
+}
+
 func (tc *replicaCalcTestCase) prepareTestClientSet() *fake.Clientset {
 	fakeClient := &fake.Clientset{}
 	fakeClient.AddWatchReactor("pods", func(action core.Action) (handled bool, ret watch.Interface, err error) { return false, nil, nil })
@@ -247,9 +314,11 @@ func (tc *replicaCalcTestCase) runTest(t *testing.T) {
 
 	rClient := tc.getFakeResourceClient()
 
-	mClient := metrics.NewRESTMetricsClient(rClient.MetricsV1beta1(), nil, emClient)
+	cmClient := tc.getFakeCMClient(t)
+
+	mClient := NewMetricsClient(metrics.NewRESTMetricsClient(rClient.MetricsV1beta1(), cmClient, emClient))
 
-	replicaCalculator := NewReplicaCalculator(mClient, informer.Lister())
+	replicaCalculator := NewReplicaCalculator(mClient, nil, informer.Lister())
 
 	stop := make(chan struct{})
 	defer close(stop)
@@ -263,21 +332,28 @@ func (tc *replicaCalcTestCase) runTest(t *testing.T) {
 	if tc.metric.spec.Resource != nil {
 		// Resource metric tests
 		// Update with the correct labels.
-		replicaCalculation, err = replicaCalculator.GetResourceReplicas(logf.Log, tc.scale, tc.metric.spec, tc.wpa)
+		replicaCalculation, err = replicaCalculator.GetResourceMetricReplicas(context.TODO(), logf.Log, tc.scale, tc.metric.spec, tc.wpa)
 
-		if tc.expectedError != nil {
-			require.Error(t, err, "there should be an error calculating the replica count")
-			assert.Contains(t, err.Error(), tc.expectedError.Error(), "the error message should have contained the expected error message")
-			return
-		}
 	} else if tc.metric.spec.External != nil {
 		// External metric tests
-		replicaCalculation, err = replicaCalculator.GetExternalMetricReplicas(logf.Log, tc.scale, tc.metric.spec, tc.wpa)
-		if tc.expectedError != nil {
-			require.Error(t, err, "there should be an error calculating the replica count")
-			assert.Contains(t, err.Error(), tc.expectedError.Error(), "the error message should have contained the expected error message")
-			return
-		}
+		replicaCalculation, err = replicaCalculator.GetExternalMetricReplicas(context.TODO(), logf.Log, tc.scale, tc.metric.spec, tc.wpa)
+	} else if tc.metric.spec.Object != nil {
+		// Object metric tests
+		replicaCalculation, err = replicaCalculator.GetObjectMetricReplicas(context.TODO(), logf.Log, tc.scale, tc.metric.spec, tc.wpa)
+	} else if tc.metric.spec.Pods != nil {
+		// Pods metric tests
+		replicaCalculation, err = replicaCalculator.GetPodsMetricReplicas(context.TODO(), logf.Log, tc.scale, tc.metric.spec, tc.wpa)
+	}
+	if tc.expectedReason != "" {
+		assert.Equal(t, tc.expectedReason, replicaCalculation.reason, "the reason should be as expected")
+	}
+	if tc.expectedPosition != "" {
+		assert.Equal(t, tc.expectedPosition, replicaCalculation.position, "the position should be as expected")
+	}
+	if tc.expectedError != nil {
+		require.Error(t, err, "there should be an error calculating the replica count")
+		assert.Contains(t, err.Error(), tc.expectedError.Error(), "the error message should have contained the expected error message")
+		return
 	}
 
 	require.NoError(t, err, "there should not have been an error calculating the replica count")
@@ -899,6 +975,131 @@ func TestReplicaCalcAverageScaleUpUnreadyLessScale(t *testing.T) {
 	tc.runTest(t)
 }
 
+func TestReplicaCalcAverageIgnoresUnreadyAndMissingPods(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ResourceMetricSourceType,
+		Resource: &v1alpha1.ResourceMetricSource{
+			Name:           corev1.ResourceCPU,
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": "test-pod"}},
+			HighWatermark:  resource.NewMilliQuantity(40000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(20000, resource.DecimalSI),
+		},
+	}
+
+	tc := replicaCalcTestCase{
+		// 2 ready pods with metrics: (60 + 30) / 2 = 45 > 40 so we scale to ceil(2 * 45 / 40) replicas.
+		expectedReplicas: 3,
+		scale:            makeScale(testDeploymentName, 4, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm:                    "average",
+				Tolerance:                    *resource.NewMilliQuantity(20, resource.DecimalSI),
+				Metrics:                      []v1alpha1.MetricSpec{metric1},
+				ReadinessDelaySeconds:        readinessDelay,
+				InitialReadinessDelaySeconds: readinessDelay,
+			},
+		},
+		podCondition: []corev1.PodCondition{
+			{
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.Now(),
+			},
+			{
+				Status:             corev1.ConditionFalse,
+				LastTransitionTime: metav1.Now(),
+			},
+			{
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.Now(),
+			},
+			{
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.Now(),
+			},
+		},
+		metric: &metricInfo{
+			spec: metric1,
+			// the second pod is unready and the last one is missing metrics.
+			levels:              []int64{60000, 90000, 30000},
+			expectedUtilization: 45000,
+		},
+	}
+	tc.runTest(t)
+}
+
+func TestReplicaCalcInitialReadinessDelay(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ResourceMetricSourceType,
+		Resource: &v1alpha1.ResourceMetricSource{
+			Name:           corev1.ResourceCPU,
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": "test-pod"}},
+			HighWatermark:  resource.NewMilliQuantity(40000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(20000, resource.DecimalSI),
+		},
+	}
+	tests := []struct {
+		name                string
+		podStartTime        []metav1.Time
+		expectedReplicas    int32
+		expectedUtilization int64
+	}{
+		{
+			// the unready pod counted by TestReplicaCalcAverageScaleUpUnreadyLessScale is ignored: (50 + 60) / 2 = 55.
+			name:                "unready pod ignored",
+			expectedReplicas:    3,
+			expectedUtilization: 55000,
+		},
+		{
+			// the last pod is ready but started within the initialization window: 50 / 1 = 50.
+			name:                "ready pod within its initialization window ignored",
+			podStartTime:        []metav1.Time{{}, {}, metav1.Now()},
+			expectedReplicas:    2,
+			expectedUtilization: 50000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := replicaCalcTestCase{
+				expectedReplicas: tt.expectedReplicas,
+				scale:            makeScale(testDeploymentName, 3, map[string]string{"name": "test-pod"}),
+				wpa: &v1alpha1.WatermarkPodAutoscaler{
+					Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+						Algorithm:                    "average",
+						Tolerance:                    *resource.NewMilliQuantity(20, resource.DecimalSI),
+						Metrics:                      []v1alpha1.MetricSpec{metric1},
+						ReadinessDelaySeconds:        readinessDelay,
+						InitialReadinessDelaySeconds: readinessDelay,
+					},
+				},
+				podCondition: []corev1.PodCondition{
+					{
+						Status:             corev1.ConditionFalse,
+						LastTransitionTime: metav1.Now(),
+					},
+					{
+						Status:             corev1.ConditionTrue,
+						LastTransitionTime: metav1.Now(),
+					},
+					{
+						Status:             corev1.ConditionTrue,
+						LastTransitionTime: metav1.Now(),
+					},
+				},
+				podStartTime: tt.podStartTime,
+				metric: &metricInfo{
+					spec:                metric1,
+					levels:              []int64{100000, 50000, 60000},
+					expectedUtilization: tt.expectedUtilization,
+				},
+			}
+			tc.runTest(t)
+		})
+	}
+}
+
 // Start of External Metric Tests
 // Test Upscale1, Upscale2 and Upscale3 showcase the absolute algorithm.
 // Use case is: "My application should run between LM to HM on average"
@@ -906,6 +1107,108 @@ func TestReplicaCalcAverageScaleUpUnreadyLessScale(t *testing.T) {
 // This is a good use case for the average CPU usage of an application for instance.
 // Here one replicas can handle between 20% and 40 % of CPU usage and we currently have 4.
 // If we see that the application is running at 86% of CPU we need to at least double the number of replicas. (Upscale1 and Upscale2)
+func TestReplicaCalcResource_Utilization(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	metric := v1alpha1.MetricSpec{
+		Type: v1alpha1.ResourceMetricSourceType,
+		Resource: &v1alpha1.ResourceMetricSource{
+			Name:           corev1.ResourceCPU,
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": "test-pod"}},
+			HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+			LowWatermark:   resource.NewQuantity(40, resource.DecimalSI),
+			TargetType:     "Utilization",
+		},
+	}
+	// each pod has two containers requesting 500m, the request of a pod is 1000m.
+	requests := []resource.Quantity{resource.MustParse("500m"), resource.MustParse("500m"), resource.MustParse("500m")}
+	ready := corev1.PodCondition{Status: corev1.ConditionTrue, LastTransitionTime: metav1.Now()}
+	unready := corev1.PodCondition{Status: corev1.ConditionFalse, LastTransitionTime: metav1.Now()}
+
+	tests := []struct {
+		name                string
+		algorithm           string
+		requests            []resource.Quantity
+		podCondition        []corev1.PodCondition
+		initialReadiness    int32
+		levels              []int64
+		expectedReplicas    int32
+		expectedUtilization int64
+		expectedError       error
+	}{
+		{
+			// the watermarks are 400m and 800m, 3 * 1000 / 800 = 3.75.
+			name:                "average above the high watermark",
+			algorithm:           "average",
+			requests:            requests,
+			levels:              []int64{1000, 1000, 1000},
+			expectedReplicas:    4,
+			expectedUtilization: 1000,
+		},
+		{
+			name:                "average within the watermarks",
+			algorithm:           "average",
+			requests:            requests,
+			levels:              []int64{600, 600, 600},
+			expectedReplicas:    3,
+			expectedUtilization: 600,
+		},
+		{
+			// the usage is summed and compared to the total request, the watermarks are 1200m and 2400m, 3 * 3000 / 2400 = 3.75.
+			name:                "absolute above the high watermark",
+			algorithm:           "absolute",
+			requests:            requests,
+			levels:              []int64{1000, 1000, 1000},
+			expectedReplicas:    4,
+			expectedUtilization: 3000,
+		},
+		{
+			// the request of the unready pod ignored with the initialReadinessDelaySeconds isn't needed, 2 * 1000 / 800 = 2.5.
+			name:                "unready pod without request",
+			algorithm:           "average",
+			requests:            requests[:2],
+			podCondition:        []corev1.PodCondition{ready, ready, unready},
+			initialReadiness:    readinessDelay,
+			levels:              []int64{1000, 1000, 5000},
+			expectedReplicas:    3,
+			expectedUtilization: 1000,
+		},
+		{
+			name:          "pods without request",
+			algorithm:     "average",
+			levels:        []int64{1000, 1000, 1000},
+			expectedError: fmt.Errorf("missing request for cpu"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := replicaCalcTestCase{
+				expectedReplicas: tt.expectedReplicas,
+				expectedError:    tt.expectedError,
+				scale:            makeScale(testDeploymentName, 3, map[string]string{"name": "test-pod"}),
+				wpa: &v1alpha1.WatermarkPodAutoscaler{
+					Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+						Algorithm:                    tt.algorithm,
+						Tolerance:                    *resource.NewMilliQuantity(20, resource.DecimalSI),
+						Metrics:                      []v1alpha1.MetricSpec{metric},
+						ReadinessDelaySeconds:        readinessDelay,
+						InitialReadinessDelaySeconds: tt.initialReadiness,
+					},
+				},
+				podCondition: tt.podCondition,
+				metric: &metricInfo{
+					spec:                metric,
+					levels:              tt.levels,
+					expectedUtilization: tt.expectedUtilization,
+				},
+			}
+			if tt.requests != nil {
+				tc.resource = &resourceInfo{name: corev1.ResourceCPU, requests: tt.requests}
+			}
+			tc.runTest(t)
+		})
+	}
+}
+
 func TestReplicaCalcAboveAbsoluteExternal_Upscale1(t *testing.T) {
 	logf.SetLogger(logf.ZapLogger(true))
 
@@ -980,63 +1283,3127 @@ func TestReplicaCalcAboveAbsoluteExternal_Upscale3(t *testing.T) {
 			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
 			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
 		},
-	}
-	tc := replicaCalcTestCase{
-		expectedReplicas: 2,
-		scale:            makeScale(testDeploymentName, 20, map[string]string{"name": "test-pod"}),
-		wpa: &v1alpha1.WatermarkPodAutoscaler{
-			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
-				Algorithm: "absolute",
-				Tolerance: *resource.NewMilliQuantity(20, resource.DecimalSI),
-				Metrics:   []v1alpha1.MetricSpec{metric1},
-			},
+	}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 2,
+		scale:            makeScale(testDeploymentName, 20, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm: "absolute",
+				Tolerance: *resource.NewMilliQuantity(20, resource.DecimalSI),
+				Metrics:   []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{200}, // We are 10 times lower than the LowWatermark, we divide by 10 the # of replicas.
+			expectedUtilization: 200,
+		},
+	}
+	tc.runTest(t)
+}
+
+// TestReplicaCalcWithinAbsoluteExternal shows the impact of the tolerance. Here we are just below the Adjusted High Watermark
+func TestReplicaCalcWithinAbsoluteExternal(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 9,
+		scale:            makeScale(testDeploymentName, 9, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm: "absolute",
+				Tolerance: *resource.NewMilliQuantity(200, resource.DecimalSI),
+				Metrics:   []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{4799}, // We are between the Watermarks
+			expectedUtilization: 4799,
+		},
+	}
+	tc.runTest(t)
+}
+
+// TestReplicaCalcAboveAbsoluteExternal_UpscaleTolerance shows that the UpscaleTolerance takes precedence over the Tolerance.
+// With the Tolerance only, we would be within the bounds (see TestReplicaCalcWithinAbsoluteExternal).
+func TestReplicaCalcAboveAbsoluteExternal_UpscaleTolerance(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 11,
+		scale:            makeScale(testDeploymentName, 9, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm:        "absolute",
+				Tolerance:        *resource.NewMilliQuantity(200, resource.DecimalSI),
+				UpscaleTolerance: resource.NewMilliQuantity(10, resource.DecimalSI),
+				Metrics:          []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{4799}, // We are above the High Watermark adjusted with the UpscaleTolerance
+			expectedUtilization: 4799,
+		},
+	}
+	tc.runTest(t)
+}
+
+// TestReplicaCalcWithinAbsoluteExternal_DownscaleTolerance shows that a wide DownscaleTolerance prevents a downscale
+// that the Tolerance alone would have allowed.
+func TestReplicaCalcWithinAbsoluteExternal_MetricTolerance(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+			Tolerance:      resource.NewMilliQuantity(300, resource.DecimalSI),
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 2, // the tolerance of the WPA would have recommended 3 replicas.
+		scale:            makeScale(testDeploymentName, 2, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm: "absolute",
+				Tolerance: *resource.NewMilliQuantity(20, resource.DecimalSI),
+				Metrics:   []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{5000}, // within the 30% tolerance of the metric.
+			expectedUtilization: 5000,
+		},
+	}
+	tc.runTest(t)
+}
+
+func TestReplicaCalcWithinAbsoluteExternal_DownscaleTolerance(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 9,
+		scale:            makeScale(testDeploymentName, 9, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm:          "absolute",
+				Tolerance:          *resource.NewMilliQuantity(20, resource.DecimalSI),
+				DownscaleTolerance: resource.NewMilliQuantity(500, resource.DecimalSI),
+				Metrics:            []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{1500}, // We are below the Low Watermark but above the adjusted one
+			expectedUtilization: 1500,
+		},
+	}
+	tc.runTest(t)
+}
+
+// TestReplicaCalcAbsoluteExternal_PerReplicaCapacity* showcase the queue draining use case: a replica can process a fixed amount of messages.
+// The recommendation only depends on the value of the metric, so it recovers even if the current number of replicas is way off.
+func TestReplicaCalcAbsoluteExternal_PerReplicaCapacityUpscale(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:         "deadbeef",
+			MetricSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:      resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:       resource.NewMilliQuantity(2000, resource.DecimalSI),
+			PerReplicaCapacity: resource.NewMilliQuantity(1000, resource.DecimalSI),
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 10, // the proportional computation would have recommended 20 replicas.
+		scale:            makeScale(testDeploymentName, 2, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm: "absolute",
+				Tolerance: *resource.NewMilliQuantity(20, resource.DecimalSI),
+				Metrics:   []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{6000, 4000}, // 10 messages in the queue.
+			expectedUtilization: 10000,
+		},
+	}
+	tc.runTest(t)
+}
+
+func TestReplicaCalcAbsoluteExternal_PerReplicaCapacityDownscale(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:         "deadbeef",
+			MetricSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:      resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:       resource.NewMilliQuantity(2000, resource.DecimalSI),
+			PerReplicaCapacity: resource.NewMilliQuantity(1000, resource.DecimalSI),
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 2, // the proportional computation would have recommended 7 replicas.
+		scale:            makeScale(testDeploymentName, 10, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm: "absolute",
+				Tolerance: *resource.NewMilliQuantity(20, resource.DecimalSI),
+				Metrics:   []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{1500}, // We are below the LowWatermark, one and a half replica is enough.
+			expectedUtilization: 1500,
+		},
+	}
+	tc.runTest(t)
+}
+
+func TestReplicaCalcAbsoluteExternal_ScaleDownToZero(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 0,
+		scale:            makeScale(testDeploymentName, 3, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm:              "absolute",
+				Tolerance:              *resource.NewMilliQuantity(20, resource.DecimalSI),
+				Metrics:                []v1alpha1.MetricSpec{metric1},
+				MinReplicas:            v1alpha1.NewInt32(0),
+				ScaleDownToZeroEnabled: true,
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{500}, // floor(3 * 500 / 2000) = 0, the metric is idle.
+			expectedUtilization: 500,
+		},
+	}
+	tc.runTest(t)
+
+	// without scaling down to zero, a replica is kept.
+	tc.wpa.Spec.ScaleDownToZeroEnabled = false
+	tc.wpa.Spec.MinReplicas = nil
+	tc.expectedReplicas = 1
+	tc.runTest(t)
+}
+
+func TestReplicaCalcExternal_ScaleUpFromZero(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+	for _, algorithm := range []string{"absolute", "average"} {
+		t.Run(algorithm, func(t *testing.T) {
+			tc := replicaCalcTestCase{
+				expectedReplicas: 3,
+				// the target was scaled down to zero, there is no pod to count.
+				scale: makeScale(testDeploymentName, 0, map[string]string{"name": "test-pod"}),
+				wpa: &v1alpha1.WatermarkPodAutoscaler{
+					Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+						Algorithm:               algorithm,
+						Tolerance:               *resource.NewMilliQuantity(20, resource.DecimalSI),
+						Metrics:                 []v1alpha1.MetricSpec{metric1},
+						MinReplicas:             v1alpha1.NewInt32(0),
+						MaxReplicas:             10,
+						ScaleDownToZeroEnabled:  true,
+						ScaleUpFromZeroReplicas: 3,
+					},
+				},
+				metric: &metricInfo{
+					spec:                metric1,
+					levels:              []int64{5000}, // We are above the HighWatermark.
+					expectedUtilization: 5000,
+				},
+			}
+			tc.runTest(t)
+
+			// the recommendation is based on the raw value when it is above scaleUpFromZeroReplicas.
+			tc.metric.levels = []int64{20000}
+			tc.metric.expectedUtilization = 20000
+			tc.expectedReplicas = 5
+			tc.runTest(t)
+
+			// the target stays at zero while the metric is within the watermarks.
+			tc.metric.levels = []int64{3000}
+			tc.metric.expectedUtilization = 3000
+			tc.expectedReplicas = 0
+			tc.runTest(t)
+		})
+	}
+}
+
+func TestReplicaCalcExternal_IdleWatermark(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+			IdleWatermark:  resource.NewMilliQuantity(1600, resource.DecimalSI),
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 0,
+		scale:            makeScale(testDeploymentName, 3, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm:              "absolute",
+				Tolerance:              *resource.NewMilliQuantity(20, resource.DecimalSI),
+				Metrics:                []v1alpha1.MetricSpec{metric1},
+				MinReplicas:            v1alpha1.NewInt32(0),
+				ScaleDownToZeroEnabled: true,
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{1500}, // below the idleWatermark, floor(3 * 1500 / 2000) = 2 is ignored.
+			expectedUtilization: 1500,
+		},
+	}
+	tc.runTest(t)
+
+	// above the idleWatermark, the target is scaled down as usual.
+	tc.metric.levels = []int64{1700}
+	tc.metric.expectedUtilization = 1700
+	tc.expectedReplicas = 2
+	tc.runTest(t)
+
+	// the idleWatermark is ignored without scaling down to zero.
+	tc.metric.levels = []int64{1500}
+	tc.metric.expectedUtilization = 1500
+	tc.wpa.Spec.ScaleDownToZeroEnabled = false
+	tc.wpa.Spec.MinReplicas = nil
+	tc.expectedReplicas = 2
+	tc.runTest(t)
+}
+
+// TestReplicaCalcAbsoluteExternal_ScaleUpOnlyMetric shows that a metric that is not allowed to scale down keeps the current
+// number of replicas when its value drops below the low watermark, while it can still scale up.
+func TestReplicaCalcAbsoluteExternal_ScaleUpOnlyMetric(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+		AllowScaleDown: v1alpha1.NewBool(false),
+	}
+	tests := []struct {
+		name             string
+		level            int64
+		expectedReplicas int32
+		expectedReason   string
+		expectedPosition string
+	}{
+		{
+			name:             "value below the low watermark",
+			level:            1000, // 4.5 replicas without the restriction
+			expectedReplicas: 9,
+			expectedReason:   v1alpha1.DecisionReasonDirectionBlocked,
+			expectedPosition: v1alpha1.DecisionReasonBelowLowWatermark,
+		},
+		{
+			name:             "value above the high watermark",
+			level:            8600,
+			expectedReplicas: 20,
+			expectedReason:   v1alpha1.DecisionReasonAboveHighWatermark,
+			expectedPosition: v1alpha1.DecisionReasonAboveHighWatermark,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := replicaCalcTestCase{
+				expectedReplicas: tt.expectedReplicas,
+				expectedReason:   tt.expectedReason,
+				expectedPosition: tt.expectedPosition,
+				scale:            makeScale(testDeploymentName, 9, map[string]string{"name": "test-pod"}),
+				wpa: &v1alpha1.WatermarkPodAutoscaler{
+					Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+						Algorithm: "absolute",
+						Tolerance: *resource.NewMilliQuantity(20, resource.DecimalSI),
+						Metrics:   []v1alpha1.MetricSpec{metric1},
+					},
+				},
+				metric: &metricInfo{
+					spec:                metric1,
+					levels:              []int64{tt.level},
+					expectedUtilization: tt.level,
+				},
+			}
+			tc.runTest(t)
+		})
+	}
+}
+
+func TestRestrictMetricDirection(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	tests := []struct {
+		name             string
+		allowScaleUp     *bool
+		allowScaleDown   *bool
+		replicaCount     int32
+		expectedReplicas int32
+		expectedReason   string
+	}{
+		{
+			name:             "both directions by default",
+			replicaCount:     3,
+			expectedReplicas: 3,
+			expectedReason:   v1alpha1.DecisionReasonBelowLowWatermark,
+		},
+		{
+			name:             "scale down allowed",
+			allowScaleUp:     v1alpha1.NewBool(false),
+			replicaCount:     3,
+			expectedReplicas: 3,
+			expectedReason:   v1alpha1.DecisionReasonBelowLowWatermark,
+		},
+		{
+			name:             "scale down blocked",
+			allowScaleDown:   v1alpha1.NewBool(false),
+			replicaCount:     3,
+			expectedReplicas: 5,
+			expectedReason:   v1alpha1.DecisionReasonDirectionBlocked,
+		},
+		{
+			name:             "scale up blocked",
+			allowScaleUp:     v1alpha1.NewBool(false),
+			replicaCount:     8,
+			expectedReplicas: 5,
+			expectedReason:   v1alpha1.DecisionReasonDirectionBlocked,
+		},
+		{
+			name:             "no scaling",
+			allowScaleUp:     v1alpha1.NewBool(false),
+			allowScaleDown:   v1alpha1.NewBool(true),
+			replicaCount:     5,
+			expectedReplicas: 5,
+			expectedReason:   v1alpha1.DecisionReasonBelowLowWatermark,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metric := v1alpha1.MetricSpec{AllowScaleUp: tt.allowScaleUp, AllowScaleDown: tt.allowScaleDown}
+			replicas, reason := restrictMetricDirection(logf.Log, metric, "deadbeef", 5, tt.replicaCount, v1alpha1.DecisionReasonBelowLowWatermark)
+			assert.Equal(t, tt.expectedReplicas, replicas)
+			assert.Equal(t, tt.expectedReason, reason)
+		})
+	}
+}
+
+func TestReplicaCalcAverageExternal_MetricAlgorithmOverride(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(85000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(75000, resource.DecimalSI),
+			Algorithm:      "average",
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 5, // the absolute algorithm of the WPA would have recommended 24 replicas.
+		scale:            makeScale(testDeploymentName, 5, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm: "absolute",
+				Tolerance: *resource.NewMilliQuantity(20, resource.DecimalSI),
+				Metrics:   []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{400000}, // 80 per replica, within the watermarks.
+			expectedUtilization: 80000,
+		},
+	}
+	tc.runTest(t)
+}
+
+func TestReplicaCalcAverageExternal_TargetType(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(85000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(75000, resource.DecimalSI),
+			TargetType:     "AverageValue",
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 5, // comparing the total value would have recommended 24 replicas.
+		scale:            makeScale(testDeploymentName, 5, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm: "absolute",
+				Tolerance: *resource.NewMilliQuantity(20, resource.DecimalSI),
+				Metrics:   []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{400000}, // 80 per replica, within the watermarks.
+			expectedUtilization: 80000,
+		},
+	}
+	tc.runTest(t)
+}
+
+func TestGetExternalMetricAlgorithm(t *testing.T) {
+	valueMetric := v1alpha1.MetricSpec{
+		Type:     v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{MetricName: "queue.length"},
+	}
+	averageMetric := v1alpha1.MetricSpec{
+		Type:     v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{MetricName: "requests.per.second", Algorithm: "average"},
+	}
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			Algorithm: "absolute",
+			Metrics:   []v1alpha1.MetricSpec{valueMetric, averageMetric},
+		},
+	}
+	assert.Equal(t, "absolute", getExternalMetricAlgorithm(wpa, valueMetric))
+	assert.Equal(t, "average", getExternalMetricAlgorithm(wpa, averageMetric))
+
+	// the target type takes precedence over the algorithms of the metric and of the WPA.
+	averageValueMetric := v1alpha1.MetricSpec{
+		Type:     v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{MetricName: "queue.length", TargetType: "AverageValue"},
+	}
+	totalValueMetric := v1alpha1.MetricSpec{
+		Type:     v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{MetricName: "requests.per.second", Algorithm: "average", TargetType: "Value"},
+	}
+	assert.Equal(t, "average", getExternalMetricAlgorithm(wpa, averageValueMetric))
+	assert.Equal(t, "absolute", getExternalMetricAlgorithm(wpa, totalValueMetric))
+}
+
+func TestReplicaCalcExternal_DirectionAlgorithms(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(85000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(75000, resource.DecimalSI),
+		},
+	}
+	tests := []struct {
+		name                string
+		level               int64
+		expectedReplicas    int32
+		expectedUtilization int64
+	}{
+		{
+			// 100 per replica with the average upscaleAlgorithm: 5 * 100 / 85 = 5.88.
+			name:                "above the high watermark per replica",
+			level:               500000,
+			expectedReplicas:    6,
+			expectedUtilization: 100000,
+		},
+		{
+			// 80 per replica is within the watermarks, 400 in total is not below the low watermark.
+			name:                "within the watermarks per replica and above them in total",
+			level:               400000,
+			expectedReplicas:    5,
+			expectedUtilization: 400000,
+		},
+		{
+			// 50 in total with the absolute downscaleAlgorithm: 5 * 50 / 75 = 3.33.
+			name:                "below the low watermark in total",
+			level:               50000,
+			expectedReplicas:    3,
+			expectedUtilization: 50000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := replicaCalcTestCase{
+				expectedReplicas: tt.expectedReplicas,
+				scale:            makeScale(testDeploymentName, 5, map[string]string{"name": "test-pod"}),
+				wpa: &v1alpha1.WatermarkPodAutoscaler{
+					Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+						Algorithm:          "absolute",
+						UpscaleAlgorithm:   "average",
+						DownscaleAlgorithm: "absolute",
+						Tolerance:          *resource.NewMilliQuantity(20, resource.DecimalSI),
+						Metrics:            []v1alpha1.MetricSpec{metric1},
+					},
+				},
+				metric: &metricInfo{
+					spec:                metric1,
+					levels:              []int64{tt.level},
+					expectedUtilization: tt.expectedUtilization,
+				},
+			}
+			tc.runTest(t)
+		})
+	}
+}
+
+func TestGetExternalMetricDirectionAlgorithms(t *testing.T) {
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			Algorithm:          "absolute",
+			UpscaleAlgorithm:   "average",
+			DownscaleAlgorithm: "count",
+		},
+	}
+	valueMetric := v1alpha1.MetricSpec{
+		Type:     v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{MetricName: "queue.length"},
+	}
+	upscaleAlgorithm, downscaleAlgorithm := getExternalMetricDirectionAlgorithms(wpa, valueMetric)
+	assert.Equal(t, "average", upscaleAlgorithm)
+	assert.Equal(t, "count", downscaleAlgorithm)
+
+	// an unset direction falls back to the algorithm of the WPA.
+	wpa.Spec.DownscaleAlgorithm = ""
+	upscaleAlgorithm, downscaleAlgorithm = getExternalMetricDirectionAlgorithms(wpa, valueMetric)
+	assert.Equal(t, "average", upscaleAlgorithm)
+	assert.Equal(t, "absolute", downscaleAlgorithm)
+
+	// the metrics setting their own algorithm, target type or denominator use it in both directions.
+	for _, external := range []*v1alpha1.ExternalMetricSource{
+		{MetricName: "requests.per.second", Algorithm: "averageByRequest"},
+		{MetricName: "requests.per.second", TargetType: "Value"},
+		{MetricName: "errors", DenominatorMetricName: "requests"},
+	} {
+		metric := v1alpha1.MetricSpec{Type: v1alpha1.ExternalMetricSourceType, External: external}
+		algorithm := getExternalMetricAlgorithm(wpa, metric)
+		upscaleAlgorithm, downscaleAlgorithm = getExternalMetricDirectionAlgorithms(wpa, metric)
+		assert.Equal(t, algorithm, upscaleAlgorithm)
+		assert.Equal(t, algorithm, downscaleAlgorithm)
+	}
+}
+
+func TestReplicaCalcExternal_LogarithmicSpike(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(10000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(5000, resource.DecimalSI),
+		},
+	}
+	tests := []struct {
+		name             string
+		algorithm        string
+		replicas         int32
+		level            int64
+		minReplicas      int32
+		maxReplicas      int32
+		expectedReplicas int32
+		expectedReason   string
+	}{
+		{
+			// 4 * 100 / 10 = 40.
+			name:             "absolute above the high watermark",
+			algorithm:        "absolute",
+			replicas:         4,
+			level:            100000,
+			minReplicas:      1,
+			maxReplicas:      50,
+			expectedReplicas: 40,
+			expectedReason:   v1alpha1.DecisionReasonAboveHighWatermark,
+		},
+		{
+			// 4 * (1 + ln(10)) = 13.2.
+			name:             "logarithmic above the high watermark",
+			algorithm:        "logarithmic",
+			replicas:         4,
+			level:            100000,
+			minReplicas:      1,
+			maxReplicas:      50,
+			expectedReplicas: 14,
+			expectedReason:   v1alpha1.DecisionReasonAboveHighWatermark,
+		},
+		{
+			name:             "logarithmic clamped to the maxReplicas",
+			algorithm:        "logarithmic",
+			replicas:         4,
+			level:            100000,
+			minReplicas:      1,
+			maxReplicas:      12,
+			expectedReplicas: 12,
+			expectedReason:   v1alpha1.DecisionReasonClampedToMax,
+		},
+		{
+			// 8 * 0.5 / 5 = 0.8, and at least 1 replica is kept.
+			name:             "absolute below the low watermark",
+			algorithm:        "absolute",
+			replicas:         8,
+			level:            500,
+			minReplicas:      1,
+			maxReplicas:      50,
+			expectedReplicas: 1,
+			expectedReason:   v1alpha1.DecisionReasonBelowLowWatermark,
+		},
+		{
+			// 8 / (1 + ln(10)) = 2.42.
+			name:             "logarithmic below the low watermark",
+			algorithm:        "logarithmic",
+			replicas:         8,
+			level:            500,
+			minReplicas:      1,
+			maxReplicas:      50,
+			expectedReplicas: 2,
+			expectedReason:   v1alpha1.DecisionReasonBelowLowWatermark,
+		},
+		{
+			name:             "logarithmic clamped to the minReplicas",
+			algorithm:        "logarithmic",
+			replicas:         8,
+			level:            500,
+			minReplicas:      3,
+			maxReplicas:      50,
+			expectedReplicas: 3,
+			expectedReason:   v1alpha1.DecisionReasonClampedToMin,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := replicaCalcTestCase{
+				expectedReplicas: tt.expectedReplicas,
+				expectedReason:   tt.expectedReason,
+				scale:            makeScale(testDeploymentName, tt.replicas, map[string]string{"name": "test-pod"}),
+				wpa: &v1alpha1.WatermarkPodAutoscaler{
+					Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+						Algorithm:   tt.algorithm,
+						Tolerance:   *resource.NewMilliQuantity(20, resource.DecimalSI),
+						MinReplicas: v1alpha1.NewInt32(tt.minReplicas),
+						MaxReplicas: tt.maxReplicas,
+						Metrics:     []v1alpha1.MetricSpec{metric1},
+					},
+				},
+				metric: &metricInfo{
+					spec:                metric1,
+					levels:              []int64{tt.level},
+					expectedUtilization: tt.level,
+				},
+			}
+			tc.runTest(t)
+		})
+	}
+}
+
+func TestGetScalingRatio(t *testing.T) {
+	watermark := resource.NewQuantity(10, resource.DecimalSI)
+	tests := []struct {
+		name      string
+		algorithm string
+		usage     float64
+		expected  float64
+	}{
+		{name: "linear", algorithm: "absolute", usage: 100000, expected: 10},
+		{name: "logarithmic above the watermark", algorithm: "logarithmic", usage: 100000, expected: 1 + math.Log(10)},
+		{name: "logarithmic at the watermark", algorithm: "logarithmic", usage: 10000, expected: 1},
+		{name: "logarithmic below the watermark", algorithm: "logarithmic", usage: 1000, expected: 1 / (1 + math.Log(10))},
+		{name: "logarithmic without usage", algorithm: "logarithmic", usage: 0, expected: 0},
+		{name: "logarithmic negative ratio", algorithm: "logarithmic", usage: -5000, expected: -0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, getScalingRatio(tt.algorithm, tt.usage, watermark), 1e-9)
+		})
+	}
+}
+
+func TestReplicaCalcAbsoluteExternal_ClampedToMaxReplicas(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 6, // the computation recommends 20 replicas.
+		expectedReason:   v1alpha1.DecisionReasonClampedToMax,
+		expectedPosition: v1alpha1.DecisionReasonAboveHighWatermark,
+		scale:            makeScale(testDeploymentName, 2, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm:   "absolute",
+				Tolerance:   *resource.NewMilliQuantity(20, resource.DecimalSI),
+				MinReplicas: v1alpha1.NewInt32(1),
+				MaxReplicas: 6,
+				Metrics:     []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{6000, 4000},
+			expectedUtilization: 10000,
+		},
+	}
+	tc.runTest(t)
+}
+
+func TestReplicaCalcAbsoluteExternal_MaxUtilization(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	metric := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+			MaxUtilization: resource.NewMilliQuantity(12000, resource.DecimalSI),
+		},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for i := 0; i < 2; i++ {
+		_ = indexer.Add(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            fmt.Sprintf("%s-%d", podNamePrefix, i),
+				Namespace:       testNamespace,
+				Labels:          map[string]string{"name": podNamePrefix},
+				OwnerReferences: []metav1.OwnerReference{{Kind: replicaSetKind, Name: testReplicaSetName}},
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				StartTime:  &metav1.Time{Time: time.Now()},
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		})
+	}
+
+	tests := []struct {
+		name                string
+		value               int64
+		expectedReplicas    int32
+		expectedUtilization int64
+		expectedClamped     float64
+	}{
+		{
+			// 2 * 8000 / 4000 = 4.
+			name:                "below the maxUtilization",
+			value:               8000,
+			expectedReplicas:    4,
+			expectedUtilization: 8000,
+		},
+		{
+			// 2 * 12000 / 4000 = 6.
+			name:                "at the maxUtilization",
+			value:               12000,
+			expectedReplicas:    6,
+			expectedUtilization: 12000,
+		},
+		{
+			// a spike at 100 times the high watermark recommends 6 replicas instead of 100.
+			name:                "above the maxUtilization",
+			value:               400000,
+			expectedReplicas:    6,
+			expectedUtilization: 12000,
+			expectedClamped:     1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa := &v1alpha1.WatermarkPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "max-utilization", Namespace: testNamespace},
+				Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+					Algorithm:      "absolute",
+					Tolerance:      *resource.NewMilliQuantity(20, resource.DecimalSI),
+					ScaleTargetRef: v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+					MaxReplicas:    200,
+					Metrics:        []v1alpha1.MetricSpec{metric},
+				},
+			}
+			defer cleanupAssociatedMetrics(wpa, false)
+			provider := fakeExternalMetricsProvider{
+				getExternalMetric: func(_ context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+					return []int64{tt.value}, time.Now(), nil
+				},
+			}
+			replicaCalculator := NewReplicaCalculator(nil, provider, corelisters.NewPodLister(indexer))
+			scale := makeScale(testDeploymentName, 2, map[string]string{"name": podNamePrefix})
+
+			replicaCalculation, err := replicaCalculator.GetExternalMetricReplicas(context.TODO(), logf.Log.WithName(tt.name), scale, metric, wpa)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedReplicas, replicaCalculation.replicaCount)
+			assert.Equal(t, tt.expectedUtilization, replicaCalculation.utilization)
+			promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: testDeploymentName, resourceKindPromLabel: "Deployment", metricNamePromLabel: "deadbeef"}
+			assert.Equal(t, tt.expectedClamped, testutil.ToFloat64(utilizationClamped.With(promLabels)))
+			// the raw value is exposed as returned by the External Metrics Provider.
+			assert.Equal(t, float64(tt.value), testutil.ToFloat64(rawValue.With(promLabels)))
+		})
+	}
+}
+
+func TestClampReplicaCount(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	tests := []struct {
+		name            string
+		minReplicas     *int32
+		maxReplicas     int32
+		replicaCount    int32
+		scaleDownToZero bool
+		expected        int32
+		expectedErr     bool
+	}{
+		{
+			name:         "above max",
+			minReplicas:  v1alpha1.NewInt32(2),
+			maxReplicas:  10,
+			replicaCount: 15,
+			expected:     10,
+		},
+		{
+			name:         "below min",
+			minReplicas:  v1alpha1.NewInt32(2),
+			maxReplicas:  10,
+			replicaCount: 1,
+			expected:     2,
+		},
+		{
+			name:         "within bounds",
+			minReplicas:  v1alpha1.NewInt32(2),
+			maxReplicas:  10,
+			replicaCount: 5,
+			expected:     5,
+		},
+		{
+			name:         "at min",
+			minReplicas:  v1alpha1.NewInt32(2),
+			maxReplicas:  10,
+			replicaCount: 2,
+			expected:     2,
+		},
+		{
+			name:         "at max",
+			minReplicas:  v1alpha1.NewInt32(2),
+			maxReplicas:  10,
+			replicaCount: 10,
+			expected:     10,
+		},
+		{
+			name:         "min above max",
+			minReplicas:  v1alpha1.NewInt32(10),
+			maxReplicas:  2,
+			replicaCount: 5,
+			expectedErr:  true,
+		},
+		{
+			name:         "min defaults to 1",
+			maxReplicas:  10,
+			replicaCount: 0,
+			expected:     1,
+		},
+		{
+			name:         "max unset",
+			minReplicas:  v1alpha1.NewInt32(2),
+			replicaCount: 15,
+			expected:     15,
+		},
+		{
+			name:         "min of 0 without scaling down to zero",
+			minReplicas:  v1alpha1.NewInt32(0),
+			maxReplicas:  10,
+			replicaCount: 0,
+			expected:     1,
+		},
+		{
+			name:            "scaling down to zero",
+			minReplicas:     v1alpha1.NewInt32(0),
+			maxReplicas:     10,
+			replicaCount:    0,
+			scaleDownToZero: true,
+			expected:        0,
+		},
+		{
+			name:            "min unset when scaling down to zero",
+			maxReplicas:     10,
+			replicaCount:    0,
+			scaleDownToZero: true,
+			expected:        0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa := &v1alpha1.WatermarkPodAutoscaler{
+				Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+					MinReplicas:            tt.minReplicas,
+					MaxReplicas:            tt.maxReplicas,
+					ScaleDownToZeroEnabled: tt.scaleDownToZero,
+				},
+			}
+			replicaCount, err := clampReplicaCount(logf.Log, wpa, "deadbeef", tt.replicaCount)
+			if tt.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, replicaCount)
+		})
+	}
+}
+
+func TestClampReplicaCountClampedTotal(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "clamped-total", Namespace: testNamespace},
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			MinReplicas: v1alpha1.NewInt32(2),
+			MaxReplicas: 10,
+		},
+	}
+	defer cleanupAssociatedMetrics(wpa, false)
+	wpa.Spec.Metrics = []v1alpha1.MetricSpec{
+		{Type: v1alpha1.ExternalMetricSourceType, External: &v1alpha1.ExternalMetricSource{MetricName: "deadbeef"}},
+		{Type: v1alpha1.ExternalMetricSourceType, External: &v1alpha1.ExternalMetricSource{MetricName: "cafebabe"}},
+	}
+	getLabels := func(metricName, direction string) prometheus.Labels {
+		return prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind, metricNamePromLabel: metricName, directionPromLabel: direction}
+	}
+
+	for _, replicaCount := range []int32{15, 12, 1, 5} {
+		_, err := clampReplicaCount(logf.Log, wpa, "deadbeef", replicaCount)
+		require.NoError(t, err)
+	}
+	// each metric is clamped on its own, the other metrics of the WPA don't change the series of a metric.
+	_, err := clampReplicaCount(logf.Log, wpa, "cafebabe", 15)
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), testutil.ToFloat64(clampedTotal.With(getLabels("deadbeef", upperPromLabelVal))))
+	assert.Equal(t, float64(1), testutil.ToFloat64(clampedTotal.With(getLabels("deadbeef", lowerPromLabelVal))))
+	assert.Equal(t, float64(1), testutil.ToFloat64(clampedTotal.With(getLabels("cafebabe", upperPromLabelVal))))
+	boundLabels := getLabels("deadbeef", "")
+	delete(boundLabels, directionPromLabel)
+	boundLabels[boundPromLabel] = maxReplicasPromLabelVal
+	assert.Equal(t, float64(0), testutil.ToFloat64(replicaClamped.With(boundLabels)))
+	boundLabels[metricNamePromLabel] = "cafebabe"
+	assert.Equal(t, float64(1), testutil.ToFloat64(replicaClamped.With(boundLabels)))
+
+	wpa.Spec.MinReplicas = v1alpha1.NewInt32(12)
+	_, err = clampReplicaCount(logf.Log, wpa, "deadbeef", 5)
+	require.Error(t, err)
+}
+
+func TestGetReplicaCountRecommendationGauge(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "recommendation-gauge", Namespace: testNamespace},
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			Tolerance:      *resource.NewMilliQuantity(20, resource.DecimalSI),
+			ScaleTargetRef: v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+		},
+	}
+	lowMark := resource.NewMilliQuantity(2000, resource.DecimalSI)
+	highMark := resource.NewMilliQuantity(4000, resource.DecimalSI)
+	promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: testDeploymentName, resourceKindPromLabel: "Deployment", metricNamePromLabel: "deadbeef"}
+	defer replicaRecommendation.Delete(promLabels)
+
+	tests := []struct {
+		name     string
+		usage    float64
+		expected int32
+	}{
+		{
+			name:     "above high watermark",
+			usage:    8000,
+			expected: 10,
+		},
+		{
+			name:     "below low watermark",
+			usage:    1000,
+			expected: 2,
+		},
+		{
+			name:     "within bounds",
+			usage:    3000,
+			expected: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replicaCount, _, _, err := getReplicaCount(logf.Log, 5, 5, wpa, "deadbeef", tt.usage, lowMark, highMark, nil, nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, replicaCount)
+			assert.Equal(t, float64(replicaCount), testutil.ToFloat64(replicaRecommendation.With(promLabels)))
+		})
+	}
+}
+
+func TestGetReplicaCountReason(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "reason", Namespace: testNamespace},
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			Tolerance:              *resource.NewMilliQuantity(20, resource.DecimalSI),
+			ScaleTargetRef:         v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+			ScaleDownToZeroEnabled: true,
+		},
+	}
+	lowMark := resource.NewMilliQuantity(2000, resource.DecimalSI)
+	highMark := resource.NewMilliQuantity(4000, resource.DecimalSI)
+	idleMark := resource.NewMilliQuantity(500, resource.DecimalSI)
+	defer cleanupAssociatedMetrics(wpa, false)
+
+	tests := []struct {
+		name     string
+		usage    float64
+		expected string
+	}{
+		{
+			name:     "above high watermark",
+			usage:    8000,
+			expected: v1alpha1.DecisionReasonAboveHighWatermark,
+		},
+		{
+			name:     "below low watermark",
+			usage:    1000,
+			expected: v1alpha1.DecisionReasonBelowLowWatermark,
+		},
+		{
+			name:     "below idle watermark",
+			usage:    100,
+			expected: v1alpha1.DecisionReasonBelowIdleWatermark,
+		},
+		{
+			name:     "within bounds",
+			usage:    3000,
+			expected: v1alpha1.DecisionReasonWithinTolerance,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, reason, err := getReplicaCount(logf.Log, 5, 5, wpa, "deadbeef", tt.usage, lowMark, highMark, nil, nil, idleMark)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, reason)
+		})
+	}
+}
+
+func TestGetReplicaCountWatermarkDistance(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "watermark-distance", Namespace: testNamespace},
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			Tolerance:              *resource.NewMilliQuantity(20, resource.DecimalSI),
+			ScaleTargetRef:         v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+			ScaleDownToZeroEnabled: true,
+		},
+	}
+	lowMark := resource.NewMilliQuantity(2000, resource.DecimalSI)
+	highMark := resource.NewMilliQuantity(4000, resource.DecimalSI)
+	idleMark := resource.NewMilliQuantity(500, resource.DecimalSI)
+	promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: testDeploymentName, resourceKindPromLabel: "Deployment", metricNamePromLabel: "deadbeef"}
+	defer watermarkDistance.Delete(promLabels)
+
+	tests := []struct {
+		name     string
+		usage    float64
+		expected float64
+	}{
+		{
+			name:     "twice the high watermark",
+			usage:    8000,
+			expected: -1,
+		},
+		{
+			name:     "slightly above the high watermark",
+			usage:    5000,
+			expected: -0.25,
+		},
+		{
+			name:     "above the high watermark within the tolerance",
+			usage:    4050,
+			expected: 0,
+		},
+		{
+			name:     "within bounds",
+			usage:    3000,
+			expected: 0,
+		},
+		{
+			name:     "half of the low watermark",
+			usage:    1000,
+			expected: 0.5,
+		},
+		{
+			name:     "below the idle watermark",
+			usage:    100,
+			expected: 0.95,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := getReplicaCount(logf.Log, 5, 5, wpa, "deadbeef", tt.usage, lowMark, highMark, nil, nil, idleMark)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.expected, testutil.ToFloat64(watermarkDistance.With(promLabels)), 0.0001)
+		})
+	}
+
+	// the gauge is removed with the other ones of the metric when its value is invalid.
+	_, _, _, err := getReplicaCount(logf.Log, 5, 5, wpa, "deadbeef", math.NaN(), lowMark, highMark, nil, nil, idleMark)
+	require.Error(t, err)
+	assert.False(t, watermarkDistance.Delete(promLabels))
+}
+
+func TestGetReplicaCountAdjustedWatermarks(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "adjusted-watermarks", Namespace: testNamespace},
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			ScaleTargetRef: v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+			Metrics: []v1alpha1.MetricSpec{
+				{
+					Type:     v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{MetricName: "deadbeef"},
+				},
+			},
+		},
+	}
+	lowMark := resource.NewMilliQuantity(2000, resource.DecimalSI)
+	highMark := resource.NewMilliQuantity(4000, resource.DecimalSI)
+	promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: testDeploymentName, resourceKindPromLabel: "Deployment", metricNamePromLabel: "deadbeef"}
+	defer cleanupAssociatedMetrics(wpa, false)
+
+	tests := []struct {
+		name         string
+		tolerance    int64
+		expectedLow  float64
+		expectedHigh float64
+	}{
+		{
+			name:         "without tolerance",
+			expectedLow:  2000,
+			expectedHigh: 4000,
+		},
+		{
+			// the watermarks are widened by 10% of their value.
+			name:         "tolerance",
+			tolerance:    100,
+			expectedLow:  1800,
+			expectedHigh: 4400,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa.Spec.Tolerance = *resource.NewMilliQuantity(tt.tolerance, resource.DecimalSI)
+			_, _, _, err := getReplicaCount(logf.Log, 5, 5, wpa, "deadbeef", 3000, lowMark, highMark, nil, nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedLow, testutil.ToFloat64(adjustedLowwm.With(promLabels)))
+			assert.Equal(t, tt.expectedHigh, testutil.ToFloat64(adjustedHighwm.With(promLabels)))
+		})
+	}
+
+	// the gauges are removed along with the other gauges of the metric when its value can't be used.
+	_ = handleInvalidMetricValue(wpa, "deadbeef", fmt.Errorf("invalid value"))
+	assert.False(t, adjustedLowwm.Delete(promLabels))
+	assert.False(t, adjustedHighwm.Delete(promLabels))
+
+	// the gauges are removed with the WPA.
+	_, _, _, err := getReplicaCount(logf.Log, 5, 5, wpa, "deadbeef", 3000, lowMark, highMark, nil, nil, nil)
+	require.NoError(t, err)
+	cleanupAssociatedMetrics(wpa, false)
+	assert.False(t, adjustedLowwm.Delete(promLabels))
+	assert.False(t, adjustedHighwm.Delete(promLabels))
+}
+
+func TestGetReplicaCountOverlappingWatermarks(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "overlapping-watermarks", Namespace: testNamespace},
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			ScaleTargetRef: v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+			Tolerance:      *resource.NewMilliQuantity(500, resource.DecimalSI),
+		},
+	}
+	defer cleanupAssociatedMetrics(wpa, false)
+	// with negative watermarks, the tolerance of 50% moves the low watermark up to -5 and the high watermark down to -7.5.
+	lowMark := resource.NewQuantity(-10, resource.DecimalSI)
+	highMark := resource.NewQuantity(-5, resource.DecimalSI)
+
+	for _, usage := range []float64{-12000, -6000, 0} {
+		_, _, _, err := getReplicaCount(logf.Log, 5, 5, wpa, "deadbeef", usage, lowMark, highMark, nil, nil, nil)
+		require.Error(t, err, "usage %v", usage)
+		assert.Contains(t, err.Error(), "overlap")
+	}
+
+	// the same watermarks don't overlap with a tolerance of 10%, widened to -9 and -5.5.
+	wpa.Spec.Tolerance = *resource.NewMilliQuantity(100, resource.DecimalSI)
+	replicaCount, _, reason, err := getReplicaCount(logf.Log, 5, 5, wpa, "deadbeef", -7000, lowMark, highMark, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(5), replicaCount)
+	assert.Equal(t, v1alpha1.DecisionReasonWithinTolerance, reason)
+}
+
+func TestGetClampedReason(t *testing.T) {
+	assert.Equal(t, v1alpha1.DecisionReasonAboveHighWatermark, getClampedReason(v1alpha1.DecisionReasonAboveHighWatermark, 6, 6))
+	assert.Equal(t, v1alpha1.DecisionReasonClampedToMax, getClampedReason(v1alpha1.DecisionReasonAboveHighWatermark, 20, 6))
+	assert.Equal(t, v1alpha1.DecisionReasonClampedToMin, getClampedReason(v1alpha1.DecisionReasonBelowLowWatermark, 1, 2))
+}
+
+func TestGetReplicaCountUtilizationGauge(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "utilization-gauge", Namespace: testNamespace},
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			Tolerance:      *resource.NewMilliQuantity(20, resource.DecimalSI),
+			ScaleTargetRef: v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+		},
+	}
+	lowMark := resource.NewMilliQuantity(2000, resource.DecimalSI)
+	highMark := resource.NewMilliQuantity(4000, resource.DecimalSI)
+	promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: testDeploymentName, resourceKindPromLabel: "Deployment", metricNamePromLabel: "deadbeef"}
+	defer utilization.Delete(promLabels)
+
+	tests := []struct {
+		name     string
+		usage    float64
+		expected int64
+	}{
+		{
+			name:     "above high watermark",
+			usage:    8000.6,
+			expected: 8000,
+		},
+		{
+			name:     "below low watermark",
+			usage:    1000,
+			expected: 1000,
+		},
+		{
+			name:     "within bounds",
+			usage:    3000,
+			expected: 3000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, utilizationValue, _, err := getReplicaCount(logf.Log, 5, 5, wpa, "deadbeef", tt.usage, lowMark, highMark, nil, nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, utilizationValue)
+			assert.Equal(t, float64(tt.expected), testutil.ToFloat64(utilization.With(promLabels)))
+		})
+	}
+}
+
+// fakeExternalMetricsProvider serves the external metrics with getExternalMetric.
+type fakeExternalMetricsProvider struct {
+	getExternalMetric func(ctx context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error)
+}
+
+// GetExternalMetric gets all the values of a given external metric that match the specified selector.
+func (f fakeExternalMetricsProvider) GetExternalMetric(ctx context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+	return f.getExternalMetric(ctx, metricName, namespace, selector)
+}
+
+func TestReplicaCalcExternalMetricsProvider(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "metrics-provider", Namespace: testNamespace},
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			Algorithm:      "absolute",
+			Tolerance:      *resource.NewMilliQuantity(20, resource.DecimalSI),
+			ScaleTargetRef: v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+			MaxReplicas:    10,
+		},
+	}
+	externalMetric := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+	resourceMetric := v1alpha1.MetricSpec{
+		Type: v1alpha1.ResourceMetricSourceType,
+		Resource: &v1alpha1.ResourceMetricSource{
+			Name:           corev1.ResourceCPU,
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": podNamePrefix}},
+			HighWatermark:  resource.NewMilliQuantity(400, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(200, resource.DecimalSI),
+		},
+	}
+	wpa.Spec.Metrics = []v1alpha1.MetricSpec{externalMetric, resourceMetric}
+	defer cleanupAssociatedMetrics(wpa, false)
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for i := 0; i < 2; i++ {
+		_ = indexer.Add(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            fmt.Sprintf("%s-%d", podNamePrefix, i),
+				Namespace:       testNamespace,
+				Labels:          map[string]string{"name": podNamePrefix},
+				OwnerReferences: []metav1.OwnerReference{{Kind: replicaSetKind, Name: testReplicaSetName}},
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				StartTime:  &metav1.Time{Time: time.Now()},
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		})
+	}
+	var requestedMetric string
+	provider := fakeExternalMetricsProvider{
+		getExternalMetric: func(_ context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+			requestedMetric = metricName
+			return []int64{3000, 3000}, time.Now(), nil
+		},
+	}
+	// only the external metrics provider is set, without the metrics client of the metrics APIs.
+	replicaCalculator := NewReplicaCalculator(nil, provider, corelisters.NewPodLister(indexer))
+	scale := makeScale(testDeploymentName, 2, map[string]string{"name": podNamePrefix})
+
+	replicaCalculation, err := replicaCalculator.GetExternalMetricReplicas(context.TODO(), logf.Log, scale, externalMetric, wpa)
+	require.NoError(t, err)
+	assert.Equal(t, "deadbeef", requestedMetric)
+	// 2 * 6000 / 4000 = 3
+	assert.Equal(t, int32(3), replicaCalculation.replicaCount)
+	assert.Equal(t, int64(6000), replicaCalculation.utilization)
+
+	_, err = replicaCalculator.GetResourceMetricReplicas(context.TODO(), logf.Log, scale, resourceMetric, wpa)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no metrics client")
+}
+
+func TestReplicaCalcExternal_Count(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	metric := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "kafka.consumer_lag",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"consumer_group": "foo"}},
+			HighWatermark:  resource.NewQuantity(6, resource.DecimalSI),
+			LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+			Algorithm:      "count",
+		},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for i := 0; i < 4; i++ {
+		_ = indexer.Add(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            fmt.Sprintf("%s-%d", podNamePrefix, i),
+				Namespace:       testNamespace,
+				Labels:          map[string]string{"name": podNamePrefix},
+				OwnerReferences: []metav1.OwnerReference{{Kind: replicaSetKind, Name: testReplicaSetName}},
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				StartTime:  &metav1.Time{Time: time.Now()},
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		})
+	}
+
+	tests := []struct {
+		name                string
+		seriesCount         int
+		expectedReplicas    int32
+		expectedUtilization int64
+		expectedPosition    string
+	}{
+		{
+			// no series counts as 0 instead of making the metric unavailable.
+			name:                "no series",
+			seriesCount:         0,
+			expectedReplicas:    1,
+			expectedUtilization: 0,
+			expectedPosition:    v1alpha1.DecisionReasonBelowLowWatermark,
+		},
+		{
+			// 4 * 2 / 3 = 2.67, rounded down.
+			name:                "below the low watermark",
+			seriesCount:         2,
+			expectedReplicas:    2,
+			expectedUtilization: 2000,
+			expectedPosition:    v1alpha1.DecisionReasonBelowLowWatermark,
+		},
+		{
+			name:                "within the watermarks",
+			seriesCount:         5,
+			expectedReplicas:    4,
+			expectedUtilization: 5000,
+			expectedPosition:    v1alpha1.DecisionReasonWithinTolerance,
+		},
+		{
+			// 4 * 9 / 6 = 6.
+			name:                "above the high watermark",
+			seriesCount:         9,
+			expectedReplicas:    6,
+			expectedUtilization: 9000,
+			expectedPosition:    v1alpha1.DecisionReasonAboveHighWatermark,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa := &v1alpha1.WatermarkPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "count", Namespace: testNamespace},
+				Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+					Algorithm:                    "absolute",
+					Tolerance:                    *resource.NewMilliQuantity(10, resource.DecimalSI),
+					ScaleTargetRef:               v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+					MaxReplicas:                  20,
+					MetricStalenessWindowSeconds: 60,
+					Metrics:                      []v1alpha1.MetricSpec{metric},
+				},
+			}
+			defer cleanupAssociatedMetrics(wpa, false)
+			provider := fakeExternalMetricsProvider{
+				getExternalMetric: func(_ context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+					// the values of the series are ignored, only their number is compared to the watermarks.
+					values := make([]int64, tt.seriesCount)
+					for i := range values {
+						values[i] = 100000
+					}
+					// without any series, there is no timestamp either.
+					if tt.seriesCount == 0 {
+						return values, time.Time{}, nil
+					}
+					return values, time.Now(), nil
+				},
+			}
+			replicaCalculator := NewReplicaCalculator(nil, provider, corelisters.NewPodLister(indexer))
+			scale := makeScale(testDeploymentName, 4, map[string]string{"name": podNamePrefix})
+
+			replicaCalculation, err := replicaCalculator.GetExternalMetricReplicas(context.TODO(), logf.Log.WithName(tt.name), scale, metric, wpa)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedReplicas, replicaCalculation.replicaCount)
+			assert.Equal(t, tt.expectedUtilization, replicaCalculation.utilization)
+			assert.Equal(t, tt.expectedPosition, replicaCalculation.position)
+			require.NotNil(t, replicaCalculation.series)
+			assert.Equal(t, int32(tt.seriesCount), replicaCalculation.series.count)
+		})
+	}
+}
+
+func TestReplicaCalcExternal_Ratio(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	metric := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:            "errors",
+			MetricSelector:        &metav1.LabelSelector{MatchLabels: map[string]string{"service": "foo"}},
+			HighWatermark:         resource.NewMilliQuantity(80, resource.DecimalSI),
+			LowWatermark:          resource.NewMilliQuantity(30, resource.DecimalSI),
+			DenominatorMetricName: "requests",
+		},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for i := 0; i < 4; i++ {
+		_ = indexer.Add(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            fmt.Sprintf("%s-%d", podNamePrefix, i),
+				Namespace:       testNamespace,
+				Labels:          map[string]string{"name": podNamePrefix},
+				OwnerReferences: []metav1.OwnerReference{{Kind: replicaSetKind, Name: testReplicaSetName}},
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				StartTime:  &metav1.Time{Time: time.Now()},
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		})
+	}
+
+	tests := []struct {
+		name                string
+		errors              []int64
+		requests            []int64
+		expectedReplicas    int32
+		expectedUtilization int64
+		expectedPosition    string
+	}{
+		{
+			// 1 error for 10 requests is 0.1: 4 * 0.1 / 0.08 = 5.
+			name:                "above the high watermark",
+			errors:              []int64{600, 400},
+			requests:            []int64{5000, 5000},
+			expectedReplicas:    5,
+			expectedUtilization: 100,
+			expectedPosition:    v1alpha1.DecisionReasonAboveHighWatermark,
+		},
+		{
+			name:                "within the watermarks",
+			errors:              []int64{500},
+			requests:            []int64{4000, 6000},
+			expectedReplicas:    4,
+			expectedUtilization: 50,
+			expectedPosition:    v1alpha1.DecisionReasonWithinTolerance,
+		},
+		{
+			// without any request, the ratio is undefined and the current number of replicas is kept.
+			name:             "zero denominator",
+			errors:           []int64{1000},
+			requests:         []int64{0, 0},
+			expectedReplicas: 4,
+			expectedPosition: v1alpha1.DecisionReasonRatioUndefined,
+		},
+		{
+			name:             "no series for the denominator",
+			errors:           []int64{1000},
+			requests:         []int64{},
+			expectedReplicas: 4,
+			expectedPosition: v1alpha1.DecisionReasonRatioUndefined,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa := &v1alpha1.WatermarkPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "ratio", Namespace: testNamespace},
+				Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+					Algorithm:                    "absolute",
+					Tolerance:                    *resource.NewMilliQuantity(10, resource.DecimalSI),
+					ScaleTargetRef:               v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+					MaxReplicas:                  20,
+					MetricStalenessWindowSeconds: 60,
+					Metrics:                      []v1alpha1.MetricSpec{metric},
+				},
+			}
+			defer cleanupAssociatedMetrics(wpa, false)
+			provider := fakeExternalMetricsProvider{
+				getExternalMetric: func(_ context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+					// the denominator is queried with the metricSelector when it has none.
+					assert.Equal(t, "service=foo", selector.String())
+					if metricName == "requests" {
+						return tt.requests, time.Now(), nil
+					}
+					return tt.errors, time.Now(), nil
+				},
+			}
+			replicaCalculator := NewReplicaCalculator(nil, provider, corelisters.NewPodLister(indexer))
+			scale := makeScale(testDeploymentName, 4, map[string]string{"name": podNamePrefix})
+
+			replicaCalculation, err := replicaCalculator.GetExternalMetricReplicas(context.TODO(), logf.Log.WithName(tt.name), scale, metric, wpa)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedReplicas, replicaCalculation.replicaCount)
+			assert.Equal(t, tt.expectedUtilization, replicaCalculation.utilization)
+			assert.Equal(t, tt.expectedPosition, replicaCalculation.position)
+		})
+	}
+}
+
+func TestReplicaCalcExternal_AverageByRequest(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	metric := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(2000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(1000, resource.DecimalSI),
+		},
+	}
+	newPod := func(i int, created time.Time, requests ...int64) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              fmt.Sprintf("%s-%d", podNamePrefix, i),
+				Namespace:         testNamespace,
+				Labels:            map[string]string{"name": podNamePrefix},
+				OwnerReferences:   []metav1.OwnerReference{{Kind: replicaSetKind, Name: testReplicaSetName}},
+				CreationTimestamp: metav1.Time{Time: created},
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				StartTime:  &metav1.Time{Time: created},
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		}
+		for j, request := range requests {
+			container := corev1.Container{Name: fmt.Sprintf("container-%d", j)}
+			if request > 0 {
+				container.Resources.Requests = corev1.ResourceList{corev1.ResourceCPU: *resource.NewMilliQuantity(request, resource.DecimalSI)}
+			}
+			pod.Spec.Containers = append(pod.Spec.Containers, container)
+		}
+		return pod
+	}
+	now := time.Now()
+	// the oldest pod was given twice the request of the newer ones, e.g. by the VPA.
+	unevenPods := []*corev1.Pod{
+		newPod(0, now.Add(-time.Hour), 1500, 500),
+		newPod(1, now.Add(-2*time.Minute), 1000),
+		newPod(2, now.Add(-time.Minute), 1000),
+	}
+
+	tests := []struct {
+		name                string
+		algorithm           string
+		pods                []*corev1.Pod
+		value               int64
+		expectedReplicas    int32
+		expectedUtilization int64
+		expectedError       string
+	}{
+		{
+			// 7000 / 3 replicas = 2333 is above the high watermark.
+			name:                "average over the replicas",
+			algorithm:           "average",
+			pods:                unevenPods,
+			value:               7000,
+			expectedReplicas:    4,
+			expectedUtilization: 2333,
+		},
+		{
+			// the capacity is (2000 + 1000 + 1000) / 1000 = 4 replicas of the size of the newest one, 7000 / 4 = 1750.
+			name:                "average over the requests within the watermarks",
+			algorithm:           "averageByRequest",
+			pods:                unevenPods,
+			value:               7000,
+			expectedReplicas:    3,
+			expectedUtilization: 1750,
+		},
+		{
+			// 10000 / 4 = 2500, 4 * 2500 / 2000 = 5 replicas of the size of the newest one.
+			name:                "average over the requests above the high watermark",
+			algorithm:           "averageByRequest",
+			pods:                unevenPods,
+			value:               10000,
+			expectedReplicas:    5,
+			expectedUtilization: 2500,
+		},
+		{
+			name:                "average over the requests of even pods",
+			algorithm:           "averageByRequest",
+			pods:                []*corev1.Pod{newPod(0, now.Add(-time.Hour), 1000), newPod(1, now.Add(-time.Minute), 1000)},
+			value:               6000,
+			expectedReplicas:    3,
+			expectedUtilization: 3000,
+		},
+		{
+			name:          "missing request",
+			algorithm:     "averageByRequest",
+			pods:          []*corev1.Pod{newPod(0, now.Add(-time.Hour), 1000), newPod(1, now.Add(-time.Minute), 1000, 0)},
+			value:         6000,
+			expectedError: "missing request for cpu in container container-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa := &v1alpha1.WatermarkPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "average-by-request", Namespace: testNamespace},
+				Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+					Algorithm:      tt.algorithm,
+					Tolerance:      *resource.NewMilliQuantity(20, resource.DecimalSI),
+					ScaleTargetRef: v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+					MaxReplicas:    10,
+					Metrics:        []v1alpha1.MetricSpec{metric},
+				},
+			}
+			defer cleanupAssociatedMetrics(wpa, false)
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			for _, pod := range tt.pods {
+				_ = indexer.Add(pod)
+			}
+			provider := fakeExternalMetricsProvider{
+				getExternalMetric: func(_ context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+					return []int64{tt.value}, time.Now(), nil
+				},
+			}
+			replicaCalculator := NewReplicaCalculator(nil, provider, corelisters.NewPodLister(indexer))
+			scale := makeScale(testDeploymentName, int32(len(tt.pods)), map[string]string{"name": podNamePrefix})
+
+			replicaCalculation, err := replicaCalculator.GetExternalMetricReplicas(context.TODO(), logf.Log.WithName(tt.name), scale, metric, wpa)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedReplicas, replicaCalculation.replicaCount)
+			assert.Equal(t, tt.expectedUtilization, replicaCalculation.utilization)
+		})
+	}
+}
+
+func TestReplicaCalcExternal_UseReadyReplicas(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	metric := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(3000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+	// the target was scaled down from 6 to 4 replicas, 2 pods are still running and ready while terminating.
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for i := 0; i < 6; i++ {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            fmt.Sprintf("%s-%d", podNamePrefix, i),
+				Namespace:       testNamespace,
+				Labels:          map[string]string{"name": podNamePrefix},
+				OwnerReferences: []metav1.OwnerReference{{Kind: replicaSetKind, Name: testReplicaSetName}},
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				StartTime:  &metav1.Time{Time: time.Now().Add(-time.Hour)},
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		}
+		if i >= 4 {
+			pod.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		}
+		_ = indexer.Add(pod)
+	}
+
+	tests := []struct {
+		name                string
+		algorithm           string
+		useReadyReplicas    bool
+		value               int64
+		expectedReplicas    int32
+		expectedUtilization int64
+		expectedReason      string
+	}{
+		{
+			// 9000 / 6 pods = 1500 is below the low watermark.
+			name:                "average over the terminating pods",
+			algorithm:           "average",
+			value:               9000,
+			expectedReplicas:    4,
+			expectedUtilization: 1500,
+			expectedReason:      v1alpha1.DecisionReasonBelowLowWatermark,
+		},
+		{
+			// 9000 / 4 pods = 2250 is within the watermarks.
+			name:                "average over the ready replicas",
+			algorithm:           "average",
+			useReadyReplicas:    true,
+			value:               9000,
+			expectedReplicas:    4,
+			expectedUtilization: 2250,
+			expectedReason:      v1alpha1.DecisionReasonWithinTolerance,
+		},
+		{
+			// 6 * 3500 / 3000 = 7 replicas.
+			name:                "scale from the terminating pods",
+			algorithm:           "absolute",
+			value:               3500,
+			expectedReplicas:    7,
+			expectedUtilization: 3500,
+			expectedReason:      v1alpha1.DecisionReasonAboveHighWatermark,
+		},
+		{
+			// 4 * 3500 / 3000 = 4.67 replicas.
+			name:                "scale from the ready replicas",
+			algorithm:           "absolute",
+			useReadyReplicas:    true,
+			value:               3500,
+			expectedReplicas:    5,
+			expectedUtilization: 3500,
+			expectedReason:      v1alpha1.DecisionReasonAboveHighWatermark,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa := &v1alpha1.WatermarkPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "use-ready-replicas", Namespace: testNamespace},
+				Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+					Algorithm:        tt.algorithm,
+					Tolerance:        *resource.NewMilliQuantity(20, resource.DecimalSI),
+					ScaleTargetRef:   v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+					MaxReplicas:      10,
+					UseReadyReplicas: tt.useReadyReplicas,
+					Metrics:          []v1alpha1.MetricSpec{metric},
+				},
+			}
+			defer cleanupAssociatedMetrics(wpa, false)
+			provider := fakeExternalMetricsProvider{
+				getExternalMetric: func(_ context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+					return []int64{tt.value}, time.Now(), nil
+				},
+			}
+			replicaCalculator := NewReplicaCalculator(nil, provider, corelisters.NewPodLister(indexer))
+			scale := makeScale(testDeploymentName, 4, map[string]string{"name": podNamePrefix})
+
+			replicaCalculation, err := replicaCalculator.GetExternalMetricReplicas(context.TODO(), logf.Log.WithName(tt.name), scale, metric, wpa)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedReplicas, replicaCalculation.replicaCount)
+			assert.Equal(t, tt.expectedUtilization, replicaCalculation.utilization)
+			assert.Equal(t, tt.expectedReason, replicaCalculation.reason)
+		})
+	}
+}
+
+func TestReplicaCalcExternal_MinReplicasForProportional(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	metric := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(3000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+
+	tests := []struct {
+		name                       string
+		minReplicasForProportional int32
+		scaleDownToZeroEnabled     bool
+		currentReplicas            int32
+		value                      int64
+		expectedReplicas           int32
+	}{
+		{
+			name:             "proportional by default",
+			currentReplicas:  1,
+			value:            9000,
+			expectedReplicas: 3,
+		},
+		{
+			name:                       "one more replica below the threshold",
+			minReplicasForProportional: 3,
+			currentReplicas:            1,
+			value:                      9000,
+			expectedReplicas:           2,
+		},
+		{
+			name:                       "one more replica right below the threshold",
+			minReplicasForProportional: 3,
+			currentReplicas:            2,
+			value:                      9000,
+			expectedReplicas:           3,
+		},
+		{
+			// 3 * 9000 / 3000 = 9 replicas.
+			name:                       "proportional from the threshold",
+			minReplicasForProportional: 3,
+			currentReplicas:            3,
+			value:                      9000,
+			expectedReplicas:           9,
+		},
+		{
+			name:                       "one less replica below the threshold",
+			minReplicasForProportional: 3,
+			currentReplicas:            2,
+			value:                      100,
+			expectedReplicas:           1,
+		},
+		{
+			name:                       "keep a replica below the threshold",
+			minReplicasForProportional: 3,
+			currentReplicas:            1,
+			value:                      100,
+			expectedReplicas:           1,
+		},
+		{
+			name:                       "scale down to zero below the threshold",
+			minReplicasForProportional: 3,
+			scaleDownToZeroEnabled:     true,
+			currentReplicas:            1,
+			value:                      100,
+			expectedReplicas:           0,
+		},
+		{
+			// 4 * 100 / 2000 = 0.2, at least 1 replica.
+			name:                       "proportional above the threshold",
+			minReplicasForProportional: 3,
+			currentReplicas:            4,
+			value:                      100,
+			expectedReplicas:           1,
+		},
+		{
+			name:                       "within the watermarks below the threshold",
+			minReplicasForProportional: 3,
+			currentReplicas:            2,
+			value:                      2500,
+			expectedReplicas:           2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minReplicas := int32(1)
+			if tt.scaleDownToZeroEnabled {
+				minReplicas = 0
+			}
+			wpa := &v1alpha1.WatermarkPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "min-replicas-for-proportional", Namespace: testNamespace},
+				Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+					Algorithm:                  "absolute",
+					Tolerance:                  *resource.NewMilliQuantity(20, resource.DecimalSI),
+					ScaleTargetRef:             v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+					MinReplicas:                &minReplicas,
+					MaxReplicas:                10,
+					ScaleDownToZeroEnabled:     tt.scaleDownToZeroEnabled,
+					MinReplicasForProportional: tt.minReplicasForProportional,
+					Metrics:                    []v1alpha1.MetricSpec{metric},
+				},
+			}
+			defer cleanupAssociatedMetrics(wpa, false)
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			for i := int32(0); i < tt.currentReplicas; i++ {
+				_ = indexer.Add(&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            fmt.Sprintf("%s-%d", podNamePrefix, i),
+						Namespace:       testNamespace,
+						Labels:          map[string]string{"name": podNamePrefix},
+						OwnerReferences: []metav1.OwnerReference{{Kind: replicaSetKind, Name: testReplicaSetName}},
+					},
+					Status: corev1.PodStatus{
+						Phase:      corev1.PodRunning,
+						StartTime:  &metav1.Time{Time: time.Now()},
+						Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+					},
+				})
+			}
+			provider := fakeExternalMetricsProvider{
+				getExternalMetric: func(_ context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+					return []int64{tt.value}, time.Now(), nil
+				},
+			}
+			replicaCalculator := NewReplicaCalculator(nil, provider, corelisters.NewPodLister(indexer))
+			scale := makeScale(testDeploymentName, tt.currentReplicas, map[string]string{"name": podNamePrefix})
+
+			replicaCalculation, err := replicaCalculator.GetExternalMetricReplicas(context.TODO(), logf.Log.WithName(tt.name), scale, metric, wpa)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedReplicas, replicaCalculation.replicaCount)
+		})
+	}
+}
+
+func TestReplicaCalcExternal_FetchFailureDeletesGauges(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "fetch-failure", Namespace: testNamespace},
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			Algorithm:      "absolute",
+			Tolerance:      *resource.NewMilliQuantity(20, resource.DecimalSI),
+			ScaleTargetRef: v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+		},
+	}
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+	promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: testDeploymentName, resourceKindPromLabel: "Deployment", metricNamePromLabel: "deadbeef"}
+	promLabelsForWpa := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: testDeploymentName, resourceKindPromLabel: "Deployment"}
+	defer metricFetchErrors.Delete(promLabelsForWpa)
+	utilization.With(promLabels).Set(3000)
+	value.With(promLabels).Set(3000)
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	_ = indexer.Add(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            fmt.Sprintf("%s-0", podNamePrefix),
+			Namespace:       testNamespace,
+			Labels:          map[string]string{"name": podNamePrefix},
+			OwnerReferences: []metav1.OwnerReference{{Kind: replicaSetKind, Name: testReplicaSetName}},
+		},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			StartTime:  &metav1.Time{Time: time.Now()},
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	})
+	provider := fakeExternalMetricsProvider{
+		getExternalMetric: func(_ context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+			return nil, time.Time{}, fmt.Errorf("unable to fetch metrics from external metrics API")
+		},
+	}
+	replicaCalculator := NewReplicaCalculator(nil, provider, corelisters.NewPodLister(indexer))
+
+	_, err := replicaCalculator.GetExternalMetricReplicas(context.TODO(), logf.Log, makeScale(testDeploymentName, 1, map[string]string{"name": podNamePrefix}), metric1, wpa)
+	require.Error(t, err)
+	// the series were already removed by the failure.
+	assert.False(t, utilization.Delete(promLabels))
+	assert.False(t, value.Delete(promLabels))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metricFetchErrors.With(promLabelsForWpa)))
+}
+
+func TestReplicaCalcExternal_FetchTimeout(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "fetch-timeout", Namespace: testNamespace},
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			Algorithm:      "absolute",
+			Tolerance:      *resource.NewMilliQuantity(20, resource.DecimalSI),
+			ScaleTargetRef: v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+		},
+	}
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+	promLabelsForWpa := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: testDeploymentName, resourceKindPromLabel: "Deployment"}
+	defer cleanupAssociatedMetrics(wpa, false)
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	_ = indexer.Add(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            fmt.Sprintf("%s-0", podNamePrefix),
+			Namespace:       testNamespace,
+			Labels:          map[string]string{"name": podNamePrefix},
+			OwnerReferences: []metav1.OwnerReference{{Kind: replicaSetKind, Name: testReplicaSetName}},
+		},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			StartTime:  &metav1.Time{Time: time.Now()},
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	})
+	// the provider hangs until the deadline of the context is passed.
+	provider := fakeExternalMetricsProvider{
+		getExternalMetric: func(ctx context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+			select {
+			case <-ctx.Done():
+				return nil, time.Time{}, ctx.Err()
+			case <-time.After(time.Second):
+				return []int64{3000}, time.Now(), nil
+			}
+		},
+	}
+	replicaCalculator := NewReplicaCalculator(nil, provider, corelisters.NewPodLister(indexer))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := replicaCalculator.GetExternalMetricReplicas(ctx, logf.Log, makeScale(testDeploymentName, 1, map[string]string{"name": podNamePrefix}), metric1, wpa)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), context.DeadlineExceeded.Error())
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metricFetchErrors.With(promLabelsForWpa)))
+}
+
+func TestReplicaCalcExternal_FetchDuration(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "fetch-duration", Namespace: testNamespace},
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			Algorithm:      "absolute",
+			Tolerance:      *resource.NewMilliQuantity(20, resource.DecimalSI),
+			ScaleTargetRef: v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+		},
+	}
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+	wpa.Spec.Metrics = []v1alpha1.MetricSpec{metric1}
+	defer cleanupAssociatedMetrics(wpa, false)
+	promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: testDeploymentName, resourceKindPromLabel: "Deployment", metricNamePromLabel: "deadbeef"}
+
+	fakeClock := clock.NewFakeClock(time.Now())
+	provider := fakeExternalMetricsProvider{
+		getExternalMetric: func(_ context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+			// the provider is slow to answer.
+			fakeClock.Step(2 * time.Second)
+			return []int64{3000}, fakeClock.Now(), nil
+		},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	_ = indexer.Add(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            fmt.Sprintf("%s-0", podNamePrefix),
+			Namespace:       testNamespace,
+			Labels:          map[string]string{"name": podNamePrefix},
+			OwnerReferences: []metav1.OwnerReference{{Kind: replicaSetKind, Name: testReplicaSetName}},
+		},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			StartTime:  &metav1.Time{Time: time.Now()},
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	})
+	replicaCalculator := NewReplicaCalculator(nil, provider, corelisters.NewPodLister(indexer))
+	replicaCalculator.clock = fakeClock
+
+	for i := 1; i <= 2; i++ {
+		_, err := replicaCalculator.GetExternalMetricReplicas(context.TODO(), logf.Log, makeScale(testDeploymentName, 1, map[string]string{"name": podNamePrefix}), metric1, wpa)
+		require.NoError(t, err)
+		count, sum := getHistogram(t, metricsFetchDuration.With(promLabels))
+		assert.Equal(t, uint64(i), count)
+		assert.Equal(t, float64(2*i), sum)
+	}
+}
+
+func TestRoundReplicas(t *testing.T) {
+	tests := []struct {
+		x        float64
+		rounding string
+		expected int32
+	}{
+		{x: 4, rounding: "ceil", expected: 4},
+		{x: 4.01, rounding: "ceil", expected: 5},
+		{x: 4.5, rounding: "ceil", expected: 5},
+		{x: 4, rounding: "floor", expected: 4},
+		{x: 4.5, rounding: "floor", expected: 4},
+		{x: 4.99, rounding: "floor", expected: 4},
+		{x: 4.49, rounding: "nearest", expected: 4},
+		{x: 4.5, rounding: "nearest", expected: 5},
+		{x: 5.5, rounding: "nearest", expected: 6},
+		{x: 0.5, rounding: "nearest", expected: 1},
+		{x: 0.49, rounding: "nearest", expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s of %v", tt.rounding, tt.x), func(t *testing.T) {
+			assert.Equal(t, tt.expected, roundReplicas(tt.x, tt.rounding))
+		})
+	}
+}
+
+func TestGetReplicaCountRounding(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	lowMark := resource.NewMilliQuantity(2000, resource.DecimalSI)
+	highMark := resource.NewMilliQuantity(4000, resource.DecimalSI)
+
+	tests := []struct {
+		name     string
+		rounding string
+		usage    float64
+		expected int32
+	}{
+		{
+			name:     "legacy rounds up above the high watermark",
+			usage:    4200, // 5 * 4200 / 4000 = 5.25
+			expected: 6,
+		},
+		{
+			name:     "legacy rounds down below the low watermark",
+			rounding: "legacy",
+			usage:    1900, // 5 * 1900 / 2000 = 4.75
+			expected: 4,
+		},
+		{
+			name:     "floor above the high watermark",
+			rounding: "floor",
+			usage:    4200,
+			expected: 5,
+		},
+		{
+			name:     "ceil below the low watermark",
+			rounding: "ceil",
+			usage:    1900,
+			expected: 5,
+		},
+		{
+			name:     "nearest above the high watermark",
+			rounding: "nearest",
+			usage:    4400, // 5 * 4400 / 4000 = 5.5
+			expected: 6,
+		},
+		{
+			name:     "nearest below the low watermark",
+			rounding: "nearest",
+			usage:    1700, // 5 * 1700 / 2000 = 4.25
+			expected: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa := &v1alpha1.WatermarkPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "replica-rounding", Namespace: testNamespace},
+				Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+					ReplicaRounding: tt.rounding,
+					ScaleTargetRef:  v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+				},
+			}
+			replicaCount, _, _, err := getReplicaCount(logf.Log, 5, 5, wpa, "deadbeef", tt.usage, lowMark, highMark, nil, nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, replicaCount)
+		})
+	}
+}
+
+func TestGetReplicaCountInvalidValues(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "invalid-values", Namespace: testNamespace},
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			Tolerance:      *resource.NewMilliQuantity(20, resource.DecimalSI),
+			ScaleTargetRef: v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+		},
+	}
+	promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: testDeploymentName, resourceKindPromLabel: "Deployment", metricNamePromLabel: "deadbeef"}
+	defer invalidMetricValue.Delete(promLabels)
+
+	tests := []struct {
+		name     string
+		usage    float64
+		lowMark  *resource.Quantity
+		highMark *resource.Quantity
+	}{
+		{
+			name:     "Inf usage",
+			usage:    math.Inf(1),
+			lowMark:  resource.NewMilliQuantity(2000, resource.DecimalSI),
+			highMark: resource.NewMilliQuantity(4000, resource.DecimalSI),
+		},
+		{
+			name:     "NaN usage",
+			usage:    math.NaN(),
+			lowMark:  resource.NewMilliQuantity(2000, resource.DecimalSI),
+			highMark: resource.NewMilliQuantity(4000, resource.DecimalSI),
+		},
+		{
+			name:     "zero watermarks above",
+			usage:    1000,
+			lowMark:  resource.NewMilliQuantity(0, resource.DecimalSI),
+			highMark: resource.NewMilliQuantity(0, resource.DecimalSI),
+		},
+		{
+			name:     "zero watermarks below",
+			usage:    -1000,
+			lowMark:  resource.NewMilliQuantity(0, resource.DecimalSI),
+			highMark: resource.NewMilliQuantity(0, resource.DecimalSI),
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := getReplicaCount(logf.Log, 5, 5, wpa, "deadbeef", tt.usage, tt.lowMark, tt.highMark, nil, nil, nil)
+			require.Error(t, err)
+			assert.Equal(t, float64(i+1), testutil.ToFloat64(invalidMetricValue.With(promLabels)))
+		})
+	}
+}
+
+func TestReplicaCalcAbsoluteExternal_StaleMetric(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedError:  fmt.Errorf("is stale"),
+		expectedReason: v1alpha1.DecisionReasonMetricStale,
+		timestamp:      time.Now().Add(-2 * time.Minute),
+		scale:          makeScale(testDeploymentName, 2, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm:                    "absolute",
+				Tolerance:                    *resource.NewMilliQuantity(20, resource.DecimalSI),
+				MetricStalenessWindowSeconds: 60,
+				Metrics:                      []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{6000, 4000},
+			expectedUtilization: 10000,
+		},
+	}
+	tc.runTest(t)
+}
+
+func TestReplicaCalcAbsoluteExternal_EmptyMetric(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedError: fmt.Errorf("no value returned for the external metric"),
+		scale:         makeScale(testDeploymentName, 3, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm:              "absolute",
+				Tolerance:              *resource.NewMilliQuantity(20, resource.DecimalSI),
+				Metrics:                []v1alpha1.MetricSpec{metric1},
+				MinReplicas:            v1alpha1.NewInt32(0),
+				ScaleDownToZeroEnabled: true,
+			},
+		},
+		metric: &metricInfo{
+			spec:   metric1,
+			levels: []int64{}, // a sum of 0 would scale the target down to zero.
+		},
+	}
+	tc.runTest(t)
+}
+
+func TestAggregate(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []int64
+		fn       string
+		expected float64
+	}{
+		{name: "default is sum", values: []int64{1000, 3000, 2000}, fn: "", expected: 6000},
+		{name: "sum", values: []int64{1000, 3000, 2000}, fn: "sum", expected: 6000},
+		{name: "avg", values: []int64{1000, 3000, 2000, 1000}, fn: "avg", expected: 1750},
+		{name: "max", values: []int64{1000, 3000, 2000}, fn: "max", expected: 3000},
+		{name: "max with ties", values: []int64{3000, 1000, 3000}, fn: "max", expected: 3000},
+		{name: "min", values: []int64{2000, 1000, 3000}, fn: "min", expected: 1000},
+		{name: "min with ties", values: []int64{1000, 3000, 1000}, fn: "min", expected: 1000},
+		{name: "p50", values: []int64{5000, 1000, 4000, 2000, 3000}, fn: "p50", expected: 3000},
+		{name: "p90", values: []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}, fn: "p90", expected: 90},
+		{name: "p99", values: []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}, fn: "p99", expected: 100},
+		{name: "p95 with ties", values: []int64{100, 100, 100, 10}, fn: "p95", expected: 100},
+		{name: "single element sum", values: []int64{4200}, fn: "sum", expected: 4200},
+		{name: "single element avg", values: []int64{4200}, fn: "avg", expected: 4200},
+		{name: "single element max", values: []int64{4200}, fn: "max", expected: 4200},
+		{name: "single element min", values: []int64{4200}, fn: "min", expected: 4200},
+		{name: "single element p50", values: []int64{4200}, fn: "p50", expected: 4200},
+		{name: "no values", values: []int64{}, fn: "avg", expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, aggregate(tt.values, tt.fn))
+		})
+	}
+}
+
+func TestGetMetricSeries(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []int64
+		expected metricSeries
+	}{
+		{name: "no series", values: []int64{}, expected: metricSeries{}},
+		{name: "single series", values: []int64{4200}, expected: metricSeries{count: 1, min: 4200, max: 4200}},
+		{name: "several series", values: []int64{2000, 1000, 30000, 1500}, expected: metricSeries{count: 4, min: 1000, max: 30000}},
+		{name: "negative values", values: []int64{-1000, 0, 500}, expected: metricSeries{count: 3, min: -1000, max: 500}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, *getMetricSeries(tt.values))
+		})
+	}
+}
+
+func TestReplicaCalcAbsoluteExternal_MaxAggregator(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+			AggregatorFunc: "max",
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 2, // the sum of the values would have recommended 5 replicas.
+		scale:            makeScale(testDeploymentName, 2, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm: "absolute",
+				Tolerance: *resource.NewMilliQuantity(20, resource.DecimalSI),
+				Metrics:   []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{3000, 2500, 3500}, // per-shard latencies, the highest one is within the watermarks.
+			expectedUtilization: 3500,
+		},
+	}
+	tc.runTest(t)
+}
+
+func TestWeightedSum(t *testing.T) {
+	weights := func(milliValues ...int64) []resource.Quantity {
+		quantities := make([]resource.Quantity, 0, len(milliValues))
+		for _, v := range milliValues {
+			quantities = append(quantities, *resource.NewMilliQuantity(v, resource.DecimalSI))
+		}
+		return quantities
+	}
+	tests := []struct {
+		name     string
+		values   []int64
+		weights  []resource.Quantity
+		expected float64
+	}{
+		{name: "weight of 1", values: []int64{1000, 3000}, weights: weights(1000, 1000), expected: 4000},
+		{name: "first value weighted twice", values: []int64{1000, 3000}, weights: weights(2000, 1000), expected: 5000},
+		{name: "fractional weights", values: []int64{1000, 3000}, weights: weights(500, 250), expected: 1250},
+		{name: "fewer weights than values", values: []int64{1000, 3000, 2000}, weights: weights(2000), expected: 7000},
+		{name: "more weights than values", values: []int64{1000}, weights: weights(2000, 3000), expected: 2000},
+		{name: "no values", values: []int64{}, weights: weights(2000), expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, weightedSum(tt.values, tt.weights))
+		})
+	}
+}
+
+func TestReplicaCalcAbsoluteExternal_Weights(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+			Weights:        []resource.Quantity{*resource.NewQuantity(2, resource.DecimalSI)},
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 3, // the sum of the values would have been within the watermarks.
+		scale:            makeScale(testDeploymentName, 2, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm: "absolute",
+				Tolerance: *resource.NewMilliQuantity(20, resource.DecimalSI),
+				Metrics:   []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{2000, 1000}, // per-region values, the first region weighs twice as much.
+			expectedUtilization: 5000,
+		},
+	}
+	tc.runTest(t)
+}
+
+func TestIsMetricStale(t *testing.T) {
+	now := time.Unix(1232000, 0)
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		window    time.Duration
+		expected  bool
+	}{
+		{
+			name:      "check disabled",
+			timestamp: now.Add(-time.Hour),
+			window:    0,
+			expected:  false,
+		},
+		{
+			name:      "within the window",
+			timestamp: now.Add(-59 * time.Second),
+			window:    time.Minute,
+			expected:  false,
+		},
+		{
+			name:      "at the boundary of the window",
+			timestamp: now.Add(-time.Minute),
+			window:    time.Minute,
+			expected:  false,
+		},
+		{
+			name:      "older than the window",
+			timestamp: now.Add(-time.Minute - time.Second),
+			window:    time.Minute,
+			expected:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isMetricStale(tt.timestamp, now, tt.window))
+		})
+	}
+}
+
+func TestGetTolerances(t *testing.T) {
+	tests := []struct {
+		name              string
+		spec              v1alpha1.WatermarkPodAutoscalerSpec
+		metricTolerance   *resource.Quantity
+		expectedUpscale   int64
+		expectedDownscale int64
+	}{
+		{
+			name: "fallback on tolerance",
+			spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Tolerance: *resource.NewMilliQuantity(100, resource.DecimalSI),
+			},
+			expectedUpscale:   100,
+			expectedDownscale: 100,
+		},
+		{
+			name: "upscale tolerance only",
+			spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Tolerance:        *resource.NewMilliQuantity(100, resource.DecimalSI),
+				UpscaleTolerance: resource.NewMilliQuantity(20, resource.DecimalSI),
+			},
+			expectedUpscale:   20,
+			expectedDownscale: 100,
+		},
+		{
+			name: "downscale tolerance only",
+			spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Tolerance:          *resource.NewMilliQuantity(100, resource.DecimalSI),
+				DownscaleTolerance: resource.NewMilliQuantity(300, resource.DecimalSI),
+			},
+			expectedUpscale:   100,
+			expectedDownscale: 300,
+		},
+		{
+			name: "both set to zero",
+			spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Tolerance:          *resource.NewMilliQuantity(100, resource.DecimalSI),
+				UpscaleTolerance:   resource.NewMilliQuantity(0, resource.DecimalSI),
+				DownscaleTolerance: resource.NewMilliQuantity(0, resource.DecimalSI),
+			},
+			expectedUpscale:   0,
+			expectedDownscale: 0,
+		},
+		{
+			name: "metric tolerance overrides tolerance",
+			spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Tolerance: *resource.NewMilliQuantity(100, resource.DecimalSI),
+			},
+			metricTolerance:   resource.NewMilliQuantity(250, resource.DecimalSI),
+			expectedUpscale:   250,
+			expectedDownscale: 250,
+		},
+		{
+			name: "directional tolerance overrides metric tolerance",
+			spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Tolerance:        *resource.NewMilliQuantity(100, resource.DecimalSI),
+				UpscaleTolerance: resource.NewMilliQuantity(20, resource.DecimalSI),
+			},
+			metricTolerance:   resource.NewMilliQuantity(250, resource.DecimalSI),
+			expectedUpscale:   20,
+			expectedDownscale: 250,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa := &v1alpha1.WatermarkPodAutoscaler{Spec: tt.spec}
+			assert.Equal(t, tt.expectedUpscale, getUpscaleTolerance(wpa, tt.metricTolerance))
+			assert.Equal(t, tt.expectedDownscale, getDownscaleTolerance(wpa, tt.metricTolerance))
+		})
+	}
+}
+
+func TestGetAdjustedWatermarks(t *testing.T) {
+	tests := []struct {
+		name          string
+		toleranceMode string
+		lowMark       int64
+		highMark      int64
+		expectedLM    float64
+		expectedHM    float64
+	}{
+		{
+			name:       "multiplicative by default",
+			lowMark:    10000,
+			highMark:   100000,
+			expectedLM: 9000,
+			expectedHM: 110000,
+		},
+		{
+			name:          "multiplicative",
+			toleranceMode: "multiplicative",
+			lowMark:       10000,
+			highMark:      100000,
+			expectedLM:    9000,
+			expectedHM:    110000,
+		},
+		{
+			name:          "band",
+			toleranceMode: "band",
+			lowMark:       10000,
+			highMark:      100000,
+			expectedLM:    1000,
+			expectedHM:    109000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa := &v1alpha1.WatermarkPodAutoscaler{Spec: v1alpha1.WatermarkPodAutoscalerSpec{ToleranceMode: tt.toleranceMode}}
+			adjustedLM, adjustedHM := getAdjustedWatermarks(wpa, resource.NewMilliQuantity(tt.lowMark, resource.DecimalSI), resource.NewMilliQuantity(tt.highMark, resource.DecimalSI), 100, 100)
+			assert.Equal(t, tt.expectedLM, adjustedLM)
+			assert.Equal(t, tt.expectedHM, adjustedHM)
+		})
+	}
+}
+
+func TestGetAdjustedWatermarksDeadZones(t *testing.T) {
+	lowMark := resource.NewMilliQuantity(10000, resource.DecimalSI)
+	highMark := resource.NewMilliQuantity(100000, resource.DecimalSI)
+	multiplicative := &v1alpha1.WatermarkPodAutoscaler{}
+	band := &v1alpha1.WatermarkPodAutoscaler{Spec: v1alpha1.WatermarkPodAutoscalerSpec{ToleranceMode: "band"}}
+
+	// with a 10% tolerance, the dead zones of the multiplicative mode follow the magnitude of each watermark.
+	multiplicativeLM, multiplicativeHM := getAdjustedWatermarks(multiplicative, lowMark, highMark, 100, 100)
+	assert.Equal(t, float64(1000), float64(lowMark.MilliValue())-multiplicativeLM)
+	assert.Equal(t, float64(10000), multiplicativeHM-float64(highMark.MilliValue()))
+
+	// the dead zones of the band mode are the same on both sides.
+	bandLM, bandHM := getAdjustedWatermarks(band, lowMark, highMark, 100, 100)
+	assert.Equal(t, float64(9000), float64(lowMark.MilliValue())-bandLM)
+	assert.Equal(t, float64(9000), bandHM-float64(highMark.MilliValue()))
+
+	// a value just below the low watermark is within the dead zone of the band mode only.
+	usage := float64(8000)
+	assert.True(t, usage < multiplicativeLM)
+	assert.False(t, usage < bandLM)
+}
+
+func TestGetMilliValue(t *testing.T) {
+	assert.Equal(t, float64(1500), getMilliValue(resource.NewMilliQuantity(1500, resource.DecimalSI)))
+	assert.Equal(t, float64(8<<30)*1000, getMilliValue(resource.NewQuantity(8<<30, resource.BinarySI)))
+	// the milliValue of 16Pi doesn't fit in an int64.
+	assert.Equal(t, float64(16<<50)*1000, getMilliValue(resource.NewQuantity(16<<50, resource.BinarySI)))
+}
+
+func TestGetAdjustedWatermarksLargeWatermarks(t *testing.T) {
+	wpa := &v1alpha1.WatermarkPodAutoscaler{Spec: v1alpha1.WatermarkPodAutoscalerSpec{ToleranceMode: "band"}}
+	// the band of 32Ti times a tolerance of 100% overflows an int64 of milliValues.
+	adjustedLM, adjustedHM := getAdjustedWatermarks(wpa, resource.NewQuantity(16<<40, resource.BinarySI), resource.NewQuantity(48<<40, resource.BinarySI), 1000, 1000)
+	assert.Equal(t, -float64(16<<40)*1000, adjustedLM)
+	assert.Equal(t, float64(80<<40)*1000, adjustedHM)
+}
+
+func TestGetReplicaCountLargeWatermarks(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "large-watermarks", Namespace: testNamespace},
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			Tolerance:      *resource.NewMilliQuantity(20, resource.DecimalSI),
+			ScaleTargetRef: v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+		},
+	}
+	defer cleanupAssociatedMetrics(wpa, false)
+	// the watermarks of a metric in bytes, the usage is a milliValue like the values of the metrics.
+	lowMark := resource.MustParse("4Gi")
+	highMark := resource.MustParse("8Gi")
+
+	tests := []struct {
+		name             string
+		usage            float64
+		expectedReplicas int32
+		expectedReason   string
+	}{
+		{
+			name:             "twice the high watermark",
+			usage:            float64(16<<30) * 1000,
+			expectedReplicas: 8,
+			expectedReason:   v1alpha1.DecisionReasonAboveHighWatermark,
+		},
+		{
+			name:             "within bounds",
+			usage:            float64(6<<30) * 1000,
+			expectedReplicas: 4,
+			expectedReason:   v1alpha1.DecisionReasonWithinTolerance,
+		},
+		{
+			name:             "half of the low watermark",
+			usage:            float64(2<<30) * 1000,
+			expectedReplicas: 2,
+			expectedReason:   v1alpha1.DecisionReasonBelowLowWatermark,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replicaCount, utilizationValue, reason, err := getReplicaCount(logf.Log, 4, 4, wpa, "deadbeef", tt.usage, &lowMark, &highMark, nil, nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedReplicas, replicaCount)
+			assert.Equal(t, int64(tt.usage), utilizationValue)
+			assert.Equal(t, tt.expectedReason, reason)
+		})
+	}
+}
+
+func TestReplicaCalcAbsoluteExternal_BandToleranceMode(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(100000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(10000, resource.DecimalSI),
+		},
+	}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 5, // within the band dead zone, the current number of replicas is kept.
+		scale:            makeScale(testDeploymentName, 5, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm:     "absolute",
+				Tolerance:     *resource.NewMilliQuantity(100, resource.DecimalSI),
+				ToleranceMode: "band",
+				Metrics:       []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{8000}, // below the low watermark minus 10% of it, but not minus 10% of the band.
+			expectedUtilization: 8000,
+		},
+	}
+	tc.runTest(t)
+
+	tc.wpa.Spec.ToleranceMode = "multiplicative"
+	tc.expectedReplicas = 4 // floor(5 * 8000 / 10000)
+	tc.runTest(t)
+}
+
+// Test Downscale1, Downscale2, Downscale3 and Downscale4 showcase the average algorithm.
+// Use case is: "1 replica of my application can handle LM to HM"
+// We show that going from X to Y to X again, we end up with the same number of replicas.
+// Here one replicas can handle between 75 and 85 qps and we currently have 5 (which means we should serve between 375-425 qps at the LB level)
+// Going to 370 we only need 4 replicas and we can handle between 300-340 qps.
+func newObjectMetricSpec(highWatermark, lowWatermark int64) v1alpha1.MetricSpec {
+	return v1alpha1.MetricSpec{
+		Type: v1alpha1.ObjectMetricSourceType,
+		Object: &v1alpha1.ObjectMetricSource{
+			DescribedObject: v1alpha1.CrossVersionObjectReference{Kind: "Ingress", Name: "frontend", APIVersion: "extensions/v1beta1"},
+			MetricName:      "requests-per-second",
+			HighWatermark:   resource.NewMilliQuantity(highWatermark, resource.DecimalSI),
+			LowWatermark:    resource.NewMilliQuantity(lowWatermark, resource.DecimalSI),
+		},
+	}
+}
+
+func TestReplicaCalcAbsoluteObject(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := newObjectMetricSpec(4000, 2000)
+	tests := []struct {
+		name             string
+		level            int64
+		expectedReplicas int32
+	}{
+		{
+			name:             "above the high watermark",
+			level:            8600, // ceil(4 * 8600 / 4000) = 9
+			expectedReplicas: 9,
+		},
+		{
+			name:             "within the watermarks",
+			level:            3000,
+			expectedReplicas: 4,
+		},
+		{
+			name:             "below the low watermark",
+			level:            1000, // floor(4 * 1000 / 2000) = 2
+			expectedReplicas: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := replicaCalcTestCase{
+				expectedReplicas: tt.expectedReplicas,
+				scale:            makeScale(testDeploymentName, 4, map[string]string{"name": "test-pod"}),
+				wpa: &v1alpha1.WatermarkPodAutoscaler{
+					ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: testNamespace},
+					Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+						Algorithm: "absolute",
+						Tolerance: *resource.NewMilliQuantity(20, resource.DecimalSI),
+						Metrics:   []v1alpha1.MetricSpec{metric1},
+					},
+				},
+				metric: &metricInfo{
+					spec:                metric1,
+					levels:              []int64{tt.level},
+					expectedUtilization: tt.level,
+				},
+			}
+			tc.runTest(t)
+		})
+	}
+}
+
+func TestReplicaCalcAbsoluteObject_StaleMetric(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := newObjectMetricSpec(4000, 2000)
+	tc := replicaCalcTestCase{
+		expectedReplicas: 9,
+		timestamp:        time.Now().Add(-10 * time.Second),
+		scale:            makeScale(testDeploymentName, 4, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: testNamespace},
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm:                    "absolute",
+				Tolerance:                    *resource.NewMilliQuantity(20, resource.DecimalSI),
+				MetricStalenessWindowSeconds: 60,
+				Metrics:                      []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{8600}, // ceil(4 * 8600 / 4000) = 9
+			expectedUtilization: 8600,
+		},
+	}
+	tc.runTest(t)
+
+	// the value is older than the metricStalenessWindowSeconds, it is not used to scale.
+	tc.timestamp = time.Now().Add(-2 * time.Minute)
+	tc.expectedError = fmt.Errorf("is stale")
+	tc.runTest(t)
+}
+
+func TestReplicaCalcAbsoluteObject_Namespace(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := newObjectMetricSpec(4000, 2000)
+	metric1.Object.DescribedObject = v1alpha1.CrossVersionObjectReference{Kind: "Namespace", Name: "ignored", APIVersion: "v1"}
+	tc := replicaCalcTestCase{
+		expectedReplicas: 9,
+		scale:            makeScale(testDeploymentName, 4, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: testNamespace},
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm: "absolute",
+				Tolerance: *resource.NewMilliQuantity(20, resource.DecimalSI),
+				Metrics:   []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{8600}, // ceil(4 * 8600 / 4000) = 9
+			expectedUtilization: 8600,
+		},
+	}
+	tc.runTest(t)
+}
+
+func TestReplicaCalcAverageObject(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := newObjectMetricSpec(85000, 75000)
+	tc := replicaCalcTestCase{
+		expectedReplicas: 4,
+		scale:            makeScale(testDeploymentName, 5, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm: "average",
+				Tolerance: *resource.NewMilliQuantity(10, resource.DecimalSI),
+				Metrics:   []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{370000}, // We are below the LowWatermark we downscale to 4.
+			expectedUtilization: 74000,           // utilization was 370/5 = 74
+		},
+	}
+	tc.runTest(t)
+}
+
+func TestReplicaCalcPods(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.PodsMetricSourceType,
+		Pods: &v1alpha1.PodsMetricSource{
+			MetricName:    "requests_per_second",
+			HighWatermark: resource.NewMilliQuantity(50000, resource.DecimalSI),
+			LowWatermark:  resource.NewMilliQuantity(40000, resource.DecimalSI),
+		},
+	}
+	tests := []struct {
+		name                string
+		levels              []int64
+		expectedReplicas    int32
+		expectedUtilization int64
+		expectedReason      string
+	}{
+		{
+			// (60 + 90 + 30 + 100) / 4 = 70 > 50 so we scale to ceil(4 * 70 / 50) replicas.
+			name:                "average above the high watermark",
+			levels:              []int64{60000, 90000, 30000, 100000},
+			expectedReplicas:    6,
+			expectedUtilization: 70000,
+			expectedReason:      v1alpha1.DecisionReasonAboveHighWatermark,
+		},
+		{
+			name:                "average within the watermarks",
+			levels:              []int64{20000, 70000, 40000, 50000},
+			expectedReplicas:    4,
+			expectedUtilization: 45000,
+			expectedReason:      v1alpha1.DecisionReasonWithinTolerance,
 		},
-		metric: &metricInfo{
-			spec:                metric1,
-			levels:              []int64{200}, // We are 10 times lower than the LowWatermark, we divide by 10 the # of replicas.
-			expectedUtilization: 200,
+		{
+			// (5 + 15 + 10 + 10) / 4 = 10 < 40 so we scale to floor(4 * 10 / 40) replicas.
+			name:                "average below the low watermark",
+			levels:              []int64{5000, 15000, 10000, 10000},
+			expectedReplicas:    1,
+			expectedUtilization: 10000,
+			expectedReason:      v1alpha1.DecisionReasonBelowLowWatermark,
 		},
 	}
-	tc.runTest(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := replicaCalcTestCase{
+				expectedReplicas: tt.expectedReplicas,
+				expectedReason:   tt.expectedReason,
+				scale:            makeScale(testDeploymentName, 4, map[string]string{"name": "test-pod"}),
+				wpa: &v1alpha1.WatermarkPodAutoscaler{
+					Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+						// the values of a pods metric are averaged whatever the algorithm.
+						Algorithm: "absolute",
+						Tolerance: *resource.NewMilliQuantity(10, resource.DecimalSI),
+						Metrics:   []v1alpha1.MetricSpec{metric1},
+					},
+				},
+				metric: &metricInfo{
+					spec:                metric1,
+					levels:              tt.levels,
+					expectedUtilization: tt.expectedUtilization,
+				},
+			}
+			tc.runTest(t)
+		})
+	}
 }
 
-// TestReplicaCalcWithinAbsoluteExternal shows the impact of the tolerance. Here we are just below the Adjusted High Watermark
-func TestReplicaCalcWithinAbsoluteExternal(t *testing.T) {
+func TestReplicaCalcPods_IgnoresUnreadyAndMissingPods(t *testing.T) {
 	logf.SetLogger(logf.ZapLogger(true))
 
 	metric1 := v1alpha1.MetricSpec{
-		Type: v1alpha1.ExternalMetricSourceType,
-		External: &v1alpha1.ExternalMetricSource{
-			MetricName:     "deadbeef",
-			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
-			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
-			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		Type: v1alpha1.PodsMetricSourceType,
+		Pods: &v1alpha1.PodsMetricSource{
+			MetricName:    "requests_per_second",
+			HighWatermark: resource.NewMilliQuantity(40000, resource.DecimalSI),
+			LowWatermark:  resource.NewMilliQuantity(20000, resource.DecimalSI),
 		},
 	}
 	tc := replicaCalcTestCase{
-		expectedReplicas: 9,
-		scale:            makeScale(testDeploymentName, 9, map[string]string{"name": "test-pod"}),
+		// 2 ready pods with metrics: (60 + 30) / 2 = 45 > 40 so we scale to ceil(2 * 45 / 40) replicas.
+		expectedReplicas: 3,
+		scale:            makeScale(testDeploymentName, 4, map[string]string{"name": "test-pod"}),
 		wpa: &v1alpha1.WatermarkPodAutoscaler{
 			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
-				Algorithm: "absolute",
-				Tolerance: *resource.NewMilliQuantity(200, resource.DecimalSI),
-				Metrics:   []v1alpha1.MetricSpec{metric1},
+				Tolerance:                    *resource.NewMilliQuantity(20, resource.DecimalSI),
+				Metrics:                      []v1alpha1.MetricSpec{metric1},
+				ReadinessDelaySeconds:        readinessDelay,
+				InitialReadinessDelaySeconds: readinessDelay,
+			},
+		},
+		podCondition: []corev1.PodCondition{
+			{
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.Now(),
+			},
+			{
+				Status:             corev1.ConditionFalse,
+				LastTransitionTime: metav1.Now(),
+			},
+			{
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.Now(),
+			},
+			{
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.Now(),
 			},
 		},
 		metric: &metricInfo{
-			spec:                metric1,
-			levels:              []int64{4799}, // We are between the Watermarks
-			expectedUtilization: 4799,
+			spec: metric1,
+			// the second pod is unready and the last one is missing metrics.
+			levels:              []int64{60000, 90000, 30000},
+			expectedUtilization: 45000,
 		},
 	}
 	tc.runTest(t)
 }
 
-// Test Downscale1, Downscale2, Downscale3 and Downscale4 showcase the average algorithm.
-// Use case is: "1 replica of my application can handle LM to HM"
-// We show that going from X to Y to X again, we end up with the same number of replicas.
-// Here one replicas can handle between 75 and 85 qps and we currently have 5 (which means we should serve between 375-425 qps at the LB level)
-// Going to 370 we only need 4 replicas and we can handle between 300-340 qps.
 func TestReplicaCalcBelowAverageExternal_Downscale1(t *testing.T) {
 	logf.SetLogger(logf.ZapLogger(true))
 
@@ -1508,6 +4875,58 @@ func TestReplicaCalcBelowAverageExternal_Downscale4(t *testing.T) {
 	tc.runTest(t)
 }
 
+// The pending pod is tolerated as ready, but it is not serving the metric yet and is not averaged over.
+func TestReplicaCalcAverageExternal_PendingPods(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	metric1 := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "loadbalancer.request.per.seconds",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(85000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(75000, resource.DecimalSI),
+		},
+	}
+	now := metav1.Now()
+	startTime := metav1.Unix(now.Unix()-120, 0)
+	withinDuration := metav1.Unix(now.Unix()-readinessDelay/2, 0)
+	tc := replicaCalcTestCase{
+		expectedReplicas: 4,
+		scale:            makeScale(testDeploymentName, 3, map[string]string{"name": "test-pod"}),
+		wpa: &v1alpha1.WatermarkPodAutoscaler{
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm:             "average",
+				Tolerance:             *resource.NewMilliQuantity(10, resource.DecimalSI),
+				ReadinessDelaySeconds: readinessDelay,
+				Metrics:               []v1alpha1.MetricSpec{metric1},
+			},
+		},
+		podPhase: []corev1.PodPhase{corev1.PodPending, corev1.PodRunning, corev1.PodRunning},
+		podCondition: []corev1.PodCondition{
+			{
+				Status:             corev1.ConditionFalse,
+				LastTransitionTime: withinDuration,
+			},
+			{
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: startTime,
+			},
+			{
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: startTime,
+			},
+		},
+		podStartTime: []metav1.Time{startTime, startTime, startTime},
+		metric: &metricInfo{
+			spec:                metric1,
+			levels:              []int64{270000}, // 270 / 3 = 90 would be within the watermarks, 270 / 2 = 135 is above them.
+			expectedUtilization: 135000,
+		},
+	}
+	tc.runTest(t)
+}
+
 func TestGroupPods(t *testing.T) {
 	logf.SetLogger(logf.ZapLogger(true))
 
@@ -1938,7 +5357,7 @@ func TestGroupPods(t *testing.T) {
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			readyPods, ignoredPods := groupPods(logf.Log, tc.pods, tc.targetName, tc.metrics, tc.resource, time.Duration(readinessDelay)*time.Second)
+			readyPods, ignoredPods := groupPods(logf.Log, tc.pods, tc.targetName, tc.metrics, tc.resource, time.Duration(readinessDelay)*time.Second, 0, false)
 			readyPodCount := len(readyPods)
 			assert.Equal(t, tc.expectReadyPodCount, readyPodCount, "%s got readyPodCount %d, expected %d", tc.name, readyPodCount, tc.expectReadyPodCount)
 			assert.EqualValues(t, tc.expectIgnoredPods, ignoredPods, "%s got unreadyPods %v, expected %v", tc.name, ignoredPods, tc.expectIgnoredPods)
@@ -1946,6 +5365,69 @@ func TestGroupPods(t *testing.T) {
 	}
 }
 
+func TestGroupPodsInitialReadinessDelay(t *testing.T) {
+	newPod := func(name string, started time.Duration, ready corev1.ConditionStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				OwnerReferences: []metav1.OwnerReference{{Name: testReplicaSetName, Kind: replicaSetKind}},
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				StartTime:  &metav1.Time{Time: time.Now().Add(-started)},
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready, LastTransitionTime: metav1.Now()}},
+			},
+		}
+	}
+	tests := []struct {
+		name              string
+		pods              []*corev1.Pod
+		resource          corev1.ResourceName
+		expectReadyPods   sets.String
+		expectIgnoredPods sets.String
+	}{
+		{
+			name:              "ready pod within its initialization window ignored - CPU",
+			pods:              []*corev1.Pod{newPod("bentham", 5*time.Second, corev1.ConditionTrue), newPod("lucretius", time.Minute, corev1.ConditionTrue)},
+			resource:          corev1.ResourceCPU,
+			expectReadyPods:   sets.NewString("lucretius"),
+			expectIgnoredPods: sets.NewString("bentham"),
+		},
+		{
+			name:              "ready pod within its initialization window ignored - memory",
+			pods:              []*corev1.Pod{newPod("bentham", 5*time.Second, corev1.ConditionTrue), newPod("lucretius", time.Minute, corev1.ConditionTrue)},
+			resource:          corev1.ResourceMemory,
+			expectReadyPods:   sets.NewString("lucretius"),
+			expectIgnoredPods: sets.NewString("bentham"),
+		},
+		{
+			name:              "unready pod that was ready ignored - CPU",
+			pods:              []*corev1.Pod{newPod("bentham", time.Minute, corev1.ConditionFalse), newPod("lucretius", time.Minute, corev1.ConditionTrue)},
+			resource:          corev1.ResourceCPU,
+			expectReadyPods:   sets.NewString("lucretius"),
+			expectIgnoredPods: sets.NewString("bentham"),
+		},
+		{
+			name:              "unready pod ignored - memory",
+			pods:              []*corev1.Pod{newPod("bentham", time.Minute, corev1.ConditionFalse), newPod("lucretius", time.Minute, corev1.ConditionTrue)},
+			resource:          corev1.ResourceMemory,
+			expectReadyPods:   sets.NewString("lucretius"),
+			expectIgnoredPods: sets.NewString("bentham"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			podMetrics := metrics.PodMetricsInfo{}
+			for _, pod := range tt.pods {
+				podMetrics[pod.Name] = metrics.PodMetric{Value: 1}
+			}
+			readyPods, ignoredPods := groupPods(logf.Log, tt.pods, testDeploymentName, podMetrics, tt.resource, time.Duration(readinessDelay)*time.Second, 30*time.Second, false)
+			assert.Equal(t, tt.expectReadyPods, readyPods)
+			assert.Equal(t, tt.expectIgnoredPods, ignoredPods)
+		})
+	}
+}
+
 type fakeMetric struct {
 	podName string
 	ts      time.Time
@@ -2166,7 +5648,7 @@ func TestGetReadyPodsCount(t *testing.T) {
 			informerFactory := informers.NewSharedInformerFactory(fakeClient, 0)
 			informer := informerFactory.Core().V1().Pods()
 
-			replicaCalculator := NewReplicaCalculator(nil, informer.Lister())
+			replicaCalculator := NewReplicaCalculator(nil, nil, informer.Lister())
 
 			stop := make(chan struct{})
 			defer close(stop)
@@ -2174,7 +5656,7 @@ func TestGetReadyPodsCount(t *testing.T) {
 			if !cache.WaitForNamedCacheSync("HPA", stop, informer.Informer().HasSynced) {
 				return
 			}
-			val, err := replicaCalculator.getReadyPodsCount(logf.Log, tc.scale, labels.SelectorFromSet(f.selector), readinessDelay*time.Second)
+			val, err := replicaCalculator.getReadyPodsCount(logf.Log, tc.scale, labels.SelectorFromSet(f.selector), readinessDelay*time.Second, false)
 			assert.Equal(t, f.expected, val)
 			if f.errorExpected != nil {
 				assert.EqualError(t, f.errorExpected, err.Error())
@@ -2183,6 +5665,174 @@ func TestGetReadyPodsCount(t *testing.T) {
 	}
 }
 
+func TestGetAveragingPodsCount(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	now := metav1.Now()
+	startTime := metav1.Unix(now.Unix()-120, 0)
+	readyTolerated := metav1.Unix(now.Unix()-readinessDelay/2, 0)
+
+	tests := []struct {
+		name       string
+		phases     []corev1.PodPhase
+		conditions []corev1.PodCondition
+		tolerated  int32
+		expected   int32
+	}{
+		{
+			name:   "all pods ready",
+			phases: []corev1.PodPhase{corev1.PodRunning, corev1.PodRunning, corev1.PodRunning},
+			conditions: []corev1.PodCondition{
+				{Status: corev1.ConditionTrue, LastTransitionTime: startTime},
+				{Status: corev1.ConditionTrue, LastTransitionTime: startTime},
+				{Status: corev1.ConditionTrue, LastTransitionTime: startTime},
+			},
+			tolerated: 3,
+			expected:  3,
+		},
+		{
+			name:   "pending and unready pods are excluded",
+			phases: []corev1.PodPhase{corev1.PodPending, corev1.PodRunning, corev1.PodRunning},
+			conditions: []corev1.PodCondition{
+				{Status: corev1.ConditionFalse, LastTransitionTime: readyTolerated},
+				{Status: corev1.ConditionFalse, LastTransitionTime: startTime},
+				{Status: corev1.ConditionTrue, LastTransitionTime: startTime},
+			},
+			tolerated: 2,
+			expected:  1,
+		},
+		{
+			name:   "no pod ready yet",
+			phases: []corev1.PodPhase{corev1.PodPending, corev1.PodPending, corev1.PodRunning},
+			conditions: []corev1.PodCondition{
+				{Status: corev1.ConditionFalse, LastTransitionTime: readyTolerated},
+				{Status: corev1.ConditionFalse, LastTransitionTime: readyTolerated},
+				{Status: corev1.ConditionFalse, LastTransitionTime: startTime},
+			},
+			tolerated: 2,
+			expected:  2,
+		},
+	}
+
+	for _, f := range tests {
+		t.Run(f.name, func(t *testing.T) {
+			selector := labels.Set{"name": "test-pod"}
+			tc := replicaCalcTestCase{
+				podCondition: f.conditions,
+				podPhase:     f.phases,
+				podStartTime: []metav1.Time{startTime, startTime, startTime},
+				scale:        makeScale(testDeploymentName, 3, selector),
+				namespace:    testNamespace,
+			}
+			fakeClient := tc.prepareTestClientSet()
+
+			informerFactory := informers.NewSharedInformerFactory(fakeClient, 0)
+			informer := informerFactory.Core().V1().Pods()
+
+			replicaCalculator := NewReplicaCalculator(nil, nil, informer.Lister())
+
+			stop := make(chan struct{})
+			defer close(stop)
+			informerFactory.Start(stop)
+			if !cache.WaitForNamedCacheSync("HPA", stop, informer.Informer().HasSynced) {
+				return
+			}
+			assert.Equal(t, f.expected, replicaCalculator.getAveragingPodsCount(logf.Log, tc.scale, labels.SelectorFromSet(selector), f.tolerated, false))
+		})
+	}
+}
+
+func TestGetPodReadiness(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	startTime := metav1.Unix(metav1.Now().Unix()-120, 0)
+	tests := []struct {
+		name          string
+		phases        []corev1.PodPhase
+		conditions    []corev1.PodCondition
+		deleted       []bool
+		expectedReady int32
+		expectedPods  int32
+	}{
+		{
+			name:   "healthy fleet",
+			phases: []corev1.PodPhase{corev1.PodRunning, corev1.PodRunning, corev1.PodRunning},
+			conditions: []corev1.PodCondition{
+				{Status: corev1.ConditionTrue, LastTransitionTime: startTime},
+				{Status: corev1.ConditionTrue, LastTransitionTime: startTime},
+				{Status: corev1.ConditionTrue, LastTransitionTime: startTime},
+			},
+			expectedReady: 3,
+			expectedPods:  3,
+		},
+		{
+			name:   "crash-looping pods",
+			phases: []corev1.PodPhase{corev1.PodRunning, corev1.PodRunning, corev1.PodRunning},
+			conditions: []corev1.PodCondition{
+				{Status: corev1.ConditionFalse, LastTransitionTime: startTime},
+				{Status: corev1.ConditionFalse, LastTransitionTime: startTime},
+				{Status: corev1.ConditionTrue, LastTransitionTime: startTime},
+			},
+			expectedReady: 1,
+			expectedPods:  3,
+		},
+		{
+			name:   "pending and failed pods",
+			phases: []corev1.PodPhase{corev1.PodPending, corev1.PodFailed, corev1.PodRunning},
+			conditions: []corev1.PodCondition{
+				{Status: corev1.ConditionFalse, LastTransitionTime: startTime},
+				{Status: corev1.ConditionFalse, LastTransitionTime: startTime},
+				{Status: corev1.ConditionTrue, LastTransitionTime: startTime},
+			},
+			expectedReady: 1,
+			expectedPods:  3,
+		},
+		{
+			name:   "terminating pods are not counted",
+			phases: []corev1.PodPhase{corev1.PodRunning, corev1.PodRunning, corev1.PodRunning},
+			conditions: []corev1.PodCondition{
+				{Status: corev1.ConditionFalse, LastTransitionTime: startTime},
+				{Status: corev1.ConditionTrue, LastTransitionTime: startTime},
+				{Status: corev1.ConditionTrue, LastTransitionTime: startTime},
+			},
+			deleted:       []bool{true, false, false},
+			expectedReady: 2,
+			expectedPods:  2,
+		},
+	}
+
+	for _, f := range tests {
+		t.Run(f.name, func(t *testing.T) {
+			tc := replicaCalcTestCase{
+				podCondition:         f.conditions,
+				podPhase:             f.phases,
+				podStartTime:         []metav1.Time{startTime, startTime, startTime},
+				podDeletionTimestamp: f.deleted,
+				scale:                makeScale(testDeploymentName, 3, map[string]string{"name": "test-pod"}),
+				namespace:            testNamespace,
+			}
+			fakeClient := tc.prepareTestClientSet()
+
+			informerFactory := informers.NewSharedInformerFactory(fakeClient, 0)
+			informer := informerFactory.Core().V1().Pods()
+
+			replicaCalculator := NewReplicaCalculator(nil, nil, informer.Lister())
+
+			stop := make(chan struct{})
+			defer close(stop)
+			informerFactory.Start(stop)
+			if !cache.WaitForNamedCacheSync("HPA", stop, informer.Informer().HasSynced) {
+				return
+			}
+			wpa := &v1alpha1.WatermarkPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: testingWPAName, Namespace: testNamespace}}
+			readyPodCount, podCount, err := replicaCalculator.GetPodReadiness(context.TODO(), logf.Log, tc.scale, wpa)
+			require.NoError(t, err)
+			assert.Equal(t, f.expectedReady, readyPodCount)
+			assert.Equal(t, f.expectedPods, podCount)
+		})
+	}
+}
+
 func TestGetPodCondition(t *testing.T) {
 	tests := []struct {
 		name               string
@@ -2258,3 +5908,90 @@ func TestGetPodCondition(t *testing.T) {
 	}
 
 }
+
+// TestReplicaCalcExternal_Concurrent runs the replica calculator for several WPAs at once, as the controller does with
+// more than one concurrent reconcile, to let the race detector check the state shared by the WPAs.
+func TestReplicaCalcExternal_Concurrent(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	metric := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for i := 0; i < 4; i++ {
+		_ = indexer.Add(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            fmt.Sprintf("%s-%d", podNamePrefix, i),
+				Namespace:       testNamespace,
+				Labels:          map[string]string{"name": podNamePrefix},
+				OwnerReferences: []metav1.OwnerReference{{Kind: replicaSetKind, Name: testReplicaSetName}},
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				StartTime:  &metav1.Time{Time: time.Now()},
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		})
+	}
+	provider := fakeExternalMetricsProvider{
+		getExternalMetric: func(_ context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+			return []int64{3000}, time.Now(), nil
+		},
+	}
+	replicaCalculator := NewReplicaCalculator(nil, provider, corelisters.NewPodLister(indexer))
+	scale := makeScale(testDeploymentName, 4, map[string]string{"name": podNamePrefix})
+
+	// the WPAs smooth their usage and share the cached values of the metric, both being kept by the replica calculator.
+	var wpas []*v1alpha1.WatermarkPodAutoscaler
+	for i := 0; i < 8; i++ {
+		wpa := &v1alpha1.WatermarkPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("concurrent-%d", i), Namespace: testNamespace},
+			Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+				Algorithm:             "absolute",
+				Tolerance:             *resource.NewMilliQuantity(20, resource.DecimalSI),
+				ScaleTargetRef:        v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+				MaxReplicas:           20,
+				SmoothingFactor:       resource.NewMilliQuantity(500, resource.DecimalSI),
+				MetricCacheTTLSeconds: 60,
+				Metrics:               []v1alpha1.MetricSpec{metric},
+			},
+		}
+		defer cleanupAssociatedMetrics(wpa, false)
+		wpas = append(wpas, wpa)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(wpas)*10)
+	replicaCounts := make(chan int32, len(wpas)*10)
+	for _, wpa := range wpas {
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(wpa *v1alpha1.WatermarkPodAutoscaler) {
+				defer wg.Done()
+				replicaCalculation, err := replicaCalculator.GetExternalMetricReplicas(context.TODO(), logf.Log.WithName(wpa.Name), scale, metric, wpa)
+				if err != nil {
+					errs <- err
+					return
+				}
+				replicaCounts <- replicaCalculation.replicaCount
+			}(wpa)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	close(replicaCounts)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Len(t, replicaCounts, len(wpas)*10)
+	for replicaCount := range replicaCounts {
+		// 3000 is within the watermarks, the WPAs keep their 4 replicas.
+		assert.Equal(t, int32(4), replicaCount)
+	}
+}
@@ -0,0 +1,162 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
+)
+
+const (
+	// defaultScaleApprovalTimeout is the time given to the scale approval webhook of a WPA to answer when the
+	// ScaleApprovalTimeout of the reconciler is unset.
+	defaultScaleApprovalTimeout = 5 * time.Second
+	// maxScaleApprovalResponseBytes caps the size of the response of a scale approval webhook read by the controller.
+	maxScaleApprovalResponseBytes = 1 << 20
+	// scaleVetoRequeueInterval caps the interval before a WPA whose scaling change was vetoed is reconciled again, for
+	// the change to be proposed again once the webhook approves it rather than after a long reconcile interval.
+	scaleVetoRequeueInterval = 30 * time.Second
+)
+
+// defaultScaleApprovalClient calls the scale approval webhooks when the reconciler has no client of its own. It
+// doesn't follow the redirects, which could lead the controller to a host that isn't allowed.
+var defaultScaleApprovalClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// scaleApprovalRequest is the body POSTed to the scale approval webhook of a WPA before its target is scaled.
+type scaleApprovalRequest struct {
+	Namespace       string                               `json:"namespace"`
+	Name            string                               `json:"name"`
+	ScaleTargetRef  v1alpha1.CrossVersionObjectReference `json:"scaleTargetRef"`
+	CurrentReplicas int32                                `json:"currentReplicas"`
+	DesiredReplicas int32                                `json:"desiredReplicas"`
+	DecisionReason  string                               `json:"decisionReason,omitempty"`
+	MetricName      string                               `json:"metricName,omitempty"`
+	MetricValue     string                               `json:"metricValue,omitempty"`
+}
+
+// scaleApprovalResponse is the answer of the scale approval webhook of a WPA, the target is only scaled when it is approved.
+type scaleApprovalResponse struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// requestScaleApproval asks the scale approval webhook of the WPA whether its target can be scaled from the current to the
+// desired number of replicas. It returns whether the change is approved along with the reason given by the webhook.
+// An error is returned when the webhook can't be reached, doesn't answer within the timeout or returns an invalid answer.
+func (r *WatermarkPodAutoscalerReconciler) requestScaleApproval(wpa *v1alpha1.WatermarkPodAutoscaler, currentReplicas, desiredReplicas int32, metricName string) (bool, string, error) {
+	approvalRequest := scaleApprovalRequest{
+		Namespace:       wpa.Namespace,
+		Name:            wpa.Name,
+		ScaleTargetRef:  wpa.Spec.ScaleTargetRef,
+		CurrentReplicas: currentReplicas,
+		DesiredReplicas: desiredReplicas,
+		DecisionReason:  wpa.Status.LastDecisionReason,
+		MetricName:      metricName,
+	}
+	if metricName != "" && wpa.Status.ScalingMetricValue != nil {
+		approvalRequest.MetricValue = wpa.Status.ScalingMetricValue.String()
+	}
+	body, err := json.Marshal(approvalRequest)
+	if err != nil {
+		return false, "", fmt.Errorf("unable to encode the scale approval request: %v", err)
+	}
+
+	if err = r.checkScaleApprovalWebhookHost(wpa.Spec.ScaleApprovalWebhookURL); err != nil {
+		return false, "", err
+	}
+
+	timeout := r.ScaleApprovalTimeout
+	if timeout <= 0 {
+		timeout = defaultScaleApprovalTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wpa.Spec.ScaleApprovalWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, "", fmt.Errorf("invalid scale approval webhook: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.getScaleApprovalClient().Do(req)
+	if err != nil {
+		return false, "", fmt.Errorf("unable to get an answer from the scale approval webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return false, "", fmt.Errorf("the scale approval webhook returned the status %d", resp.StatusCode)
+	}
+
+	var approval scaleApprovalResponse
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxScaleApprovalResponseBytes)).Decode(&approval); err != nil {
+		return false, "", fmt.Errorf("unable to decode the answer of the scale approval webhook: %v", err)
+	}
+	return approval.Approved, approval.Reason, nil
+}
+
+// checkScaleApprovalWebhookHost returns an error unless the host of the webhook is one of the ScaleApprovalAllowedHosts
+// of the reconciler. The URL is set by the owners of the WPAs while the request comes from the controller, which
+// could otherwise be used to reach any endpoint of the cluster.
+func (r *WatermarkPodAutoscalerReconciler) checkScaleApprovalWebhookHost(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid scale approval webhook: %v", err)
+	}
+	if !isAllowedScaleApprovalHost(u, r.ScaleApprovalAllowedHosts) {
+		return fmt.Errorf("the host %s of the scale approval webhook is not allowed by the controller", u.Host)
+	}
+	return nil
+}
+
+// isAllowedScaleApprovalHost returns whether the host of the URL matches one of the allowed hosts, which are either a
+// host name, matching any port, or a host and a port. No host is allowed when the list is empty.
+func isAllowedScaleApprovalHost(u *url.URL, allowedHosts []string) bool {
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(allowed); err == nil {
+			if allowed == strings.ToLower(u.Host) {
+				return true
+			}
+			continue
+		}
+		if allowed == strings.ToLower(u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+// getScaleApprovalClient returns the client calling the scale approval webhooks, defaultScaleApprovalClient when none is set.
+func (r *WatermarkPodAutoscalerReconciler) getScaleApprovalClient() *http.Client {
+	if r.scaleApprovalClient == nil {
+		return defaultScaleApprovalClient
+	}
+	return r.scaleApprovalClient
+}
+
+// getScaleVetoRequeueAfter returns the interval before the WPA is reconciled again, capped at scaleVetoRequeueInterval
+// when its last scaling change was vetoed.
+func getScaleVetoRequeueAfter(wpa *v1alpha1.WatermarkPodAutoscaler, requeueAfter time.Duration) time.Duration {
+	if wpa.Status.LastDecisionReason == v1alpha1.DecisionReasonScaleVetoed && requeueAfter > scaleVetoRequeueInterval {
+		return scaleVetoRequeueInterval
+	}
+	return requeueAfter
+}
@@ -0,0 +1,290 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1/test"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta/testrestmapper"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	fakescale "k8s.io/client-go/scale/fake"
+	core "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestReconcileWatermarkPodAutoscaler_scaleApproval(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		hostNotAllowed bool
+		wantScaled     bool
+		wantReason     string
+	}{
+		{
+			name: "approved",
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				_, _ = w.Write([]byte(`{"approved": true}`))
+			},
+			wantScaled: true,
+		},
+		{
+			name: "vetoed",
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				_, _ = w.Write([]byte(`{"approved": false, "reason": "over budget"}`))
+			},
+			wantReason: "over budget",
+		},
+		{
+			name: "timeout",
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				time.Sleep(time.Second)
+				_, _ = w.Write([]byte(`{"approved": true}`))
+			},
+			wantReason: "unable to get an answer from the scale approval webhook",
+		},
+		{
+			name: "error status",
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantReason: "the scale approval webhook returned the status 500",
+		},
+		{
+			name: "redirect not followed",
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				http.Redirect(w, req, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+			},
+			wantReason: "the scale approval webhook returned the status 302",
+		},
+		{
+			name: "host not allowed",
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				_, _ = w.Write([]byte(`{"approved": true}`))
+			},
+			hostNotAllowed: true,
+			wantReason:     "is not allowed by the controller",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			approvalRequests := make(chan scaleApprovalRequest, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				var approvalRequest scaleApprovalRequest
+				if err := json.NewDecoder(req.Body).Decode(&approvalRequest); err == nil {
+					approvalRequests <- approvalRequest
+				}
+				tt.handler(w, req)
+			}))
+			defer server.Close()
+			serverURL, err := url.Parse(server.URL)
+			require.NoError(t, err)
+			allowedHosts := []string{serverURL.Host}
+			if tt.hostNotAllowed {
+				allowedHosts = []string{"approval.example.com"}
+			}
+
+			eventRecorder := record.NewFakeRecorder(10)
+			scaleClient := &fakescale.FakeScaleClient{}
+			scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				return true, newScaleForDeployment(3, 3), nil
+			})
+			scaleClient.AddReactor("update", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				return true, action.(core.UpdateAction).GetObject(), nil
+			})
+			r := &WatermarkPodAutoscalerReconciler{
+				Client:                    fake.NewFakeClient(),
+				scaleClient:               scaleClient,
+				restMapper:                testrestmapper.TestOnlyStaticRESTMapper(s),
+				Scheme:                    s,
+				eventRecorder:             eventRecorder,
+				ScaleApprovalTimeout:      500 * time.Millisecond,
+				ScaleApprovalAllowedHosts: allowedHosts,
+				replicaCalc: &fakeReplicaCalculator{
+					replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+						return ReplicaCalculation{4, 90000, time.Now(), "", "", nil}, nil
+					},
+				},
+			}
+			wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+				Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+					MaxReplicas:             10,
+					MinReplicas:             getReplicas(1),
+					ScaleApprovalWebhookURL: server.URL,
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type: v1alpha1.ExternalMetricSourceType,
+							External: &v1alpha1.ExternalMetricSource{
+								MetricName:     "deadbeef",
+								MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+								HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+								LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			})
+			wpa = v1alpha1.DefaultWatermarkPodAutoscaler(wpa)
+			wpa.Spec.ScaleTargetRef = testCrossVersionObjectRef
+			require.NoError(t, r.Client.Create(context.TODO(), wpa))
+			wpa = &v1alpha1.WatermarkPodAutoscaler{}
+			require.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Name: testingWPAName, Namespace: testingNamespace}, wpa))
+			defer cleanupAssociatedMetrics(wpa, false)
+
+			require.NoError(t, r.reconcileWPA(logf.Log.WithName(tt.name), wpa))
+
+			if tt.hostNotAllowed {
+				assert.Len(t, approvalRequests, 0)
+			} else {
+				require.Len(t, approvalRequests, 1)
+				approvalRequest := <-approvalRequests
+				assert.Equal(t, testingWPAName, approvalRequest.Name)
+				assert.Equal(t, testingDeployName, approvalRequest.ScaleTargetRef.Name)
+				assert.Equal(t, int32(3), approvalRequest.CurrentReplicas)
+				assert.Equal(t, int32(4), approvalRequest.DesiredReplicas)
+				assert.Equal(t, "deadbeef{map[label:value]}", approvalRequest.MetricName)
+			}
+
+			scaleUpdated := false
+			for _, action := range scaleClient.Actions() {
+				if action.GetVerb() == "update" {
+					scaleUpdated = true
+				}
+			}
+			assert.Equal(t, tt.wantScaled, scaleUpdated)
+			promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}
+			if tt.wantScaled {
+				assert.Equal(t, float64(0), testutil.ToFloat64(scaleVetoed.With(promLabels)))
+				return
+			}
+			assert.Equal(t, float64(1), testutil.ToFloat64(scaleVetoed.With(promLabels)))
+			assert.Equal(t, v1alpha1.DecisionReasonScaleVetoed, wpa.Status.LastDecisionReason)
+			assert.Equal(t, int32(3), wpa.Status.DesiredReplicas)
+			assert.Nil(t, wpa.Status.LastScaleTime)
+			require.Len(t, eventRecorder.Events, 1)
+			event := <-eventRecorder.Events
+			assert.Contains(t, event, v1alpha1.ReasonScaleVetoed)
+			assert.Contains(t, event, tt.wantReason)
+		})
+	}
+}
+
+func TestIsAllowedScaleApprovalHost(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		allowedHosts []string
+		want         bool
+	}{
+		{
+			name: "no allowed host",
+			url:  "https://approval.example.com/scale",
+			want: false,
+		},
+		{
+			name:         "host name matches any port",
+			url:          "https://approval.example.com:8443/scale",
+			allowedHosts: []string{"approval.example.com"},
+			want:         true,
+		},
+		{
+			name:         "host name is case insensitive",
+			url:          "https://Approval.Example.com/scale",
+			allowedHosts: []string{" approval.example.com "},
+			want:         true,
+		},
+		{
+			name:         "host and port match",
+			url:          "http://10.0.0.1:8080/scale",
+			allowedHosts: []string{"10.0.0.1:8080"},
+			want:         true,
+		},
+		{
+			name:         "host and port don't match",
+			url:          "http://10.0.0.1:9090/scale",
+			allowedHosts: []string{"10.0.0.1:8080"},
+			want:         false,
+		},
+		{
+			name:         "other host",
+			url:          "http://169.254.169.254/latest/meta-data",
+			allowedHosts: []string{"", "approval.example.com"},
+			want:         false,
+		},
+		{
+			name:         "allowed host as a suffix",
+			url:          "https://approval.example.com.evil.com/scale",
+			allowedHosts: []string{"approval.example.com"},
+			want:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, isAllowedScaleApprovalHost(u, tt.allowedHosts))
+		})
+	}
+}
+
+func TestGetScaleVetoRequeueAfter(t *testing.T) {
+	tests := []struct {
+		name         string
+		reason       string
+		requeueAfter time.Duration
+		want         time.Duration
+	}{
+		{
+			name:         "vetoed, capped",
+			reason:       v1alpha1.DecisionReasonScaleVetoed,
+			requeueAfter: 5 * time.Minute,
+			want:         scaleVetoRequeueInterval,
+		},
+		{
+			name:         "vetoed, shorter interval kept",
+			reason:       v1alpha1.DecisionReasonScaleVetoed,
+			requeueAfter: 15 * time.Second,
+			want:         15 * time.Second,
+		},
+		{
+			name:         "not vetoed",
+			reason:       v1alpha1.DecisionReasonWithinTolerance,
+			requeueAfter: 5 * time.Minute,
+			want:         5 * time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa := &v1alpha1.WatermarkPodAutoscaler{}
+			wpa.Status.LastDecisionReason = tt.reason
+			assert.Equal(t, tt.want, getScaleVetoRequeueAfter(wpa, tt.requeueAfter))
+		})
+	}
+}
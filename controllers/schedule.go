@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// getScheduledMetrics returns the metrics of the WPA with the watermarks of the active entries of its watermarkSchedule.
+// The watermarks of a metric are overridden by the first active entry applying to it, they are kept when none is active.
+// The watermarks derived from the targetValue of the external metrics are overridden the same way.
+func getScheduledMetrics(logger logr.Logger, wpa *v1alpha1.WatermarkPodAutoscaler, now time.Time) []v1alpha1.MetricSpec {
+	targetMetrics := getTargetMetrics(wpa.Spec.Metrics)
+	if len(wpa.Spec.WatermarkSchedule) == 0 {
+		return targetMetrics
+	}
+	location, err := time.LoadLocation(wpa.Spec.WatermarkScheduleTimezone)
+	if err != nil {
+		logger.Info("Ignoring the watermark schedule, the timezone is invalid", "timezone", wpa.Spec.WatermarkScheduleTimezone, "error", err)
+		return targetMetrics
+	}
+	var activeEntries []int
+	for i := range wpa.Spec.WatermarkSchedule {
+		active, err := wpa.Spec.WatermarkSchedule[i].IsActive(now.In(location))
+		if err != nil {
+			logger.Info("Ignoring an invalid entry of the watermark schedule", "entry", i, "error", err)
+			continue
+		}
+		if active {
+			activeEntries = append(activeEntries, i)
+		}
+	}
+
+	metrics := make([]v1alpha1.MetricSpec, 0, len(targetMetrics))
+	for _, metric := range targetMetrics {
+		scheduled := metric
+		for _, i := range activeEntries {
+			entry := &wpa.Spec.WatermarkSchedule[i]
+			if entry.MetricName != "" && entry.MetricName != getSpecMetricName(metric) {
+				continue
+			}
+			if overridden, ok := overrideWatermarks(metric, entry); ok {
+				logger.Info("Applying the watermark schedule", "entry", i, "metricName", getSpecMetricName(metric), "days", entry.Days, "start", entry.Start, "end", entry.End)
+				scheduled = overridden
+			} else {
+				logger.Info("Ignoring the watermark schedule, the low watermark would not be below the high watermark", "entry", i, "metricName", getSpecMetricName(metric))
+			}
+			break
+		}
+		metrics = append(metrics, scheduled)
+	}
+	return metrics
+}
+
+// getTargetMetrics returns the metrics with the watermarks of the external metrics derived from their targetValue.
+// The targetValue is cleared from the copies so the watermarks overridden by the schedule are not derived again.
+func getTargetMetrics(metrics []v1alpha1.MetricSpec) []v1alpha1.MetricSpec {
+	targetMetrics := make([]v1alpha1.MetricSpec, 0, len(metrics))
+	for _, metric := range metrics {
+		if metric.External != nil && metric.External.TargetValue != nil {
+			metric = *metric.DeepCopy()
+			metric.External.LowWatermark, metric.External.HighWatermark = metric.External.GetWatermarks()
+			metric.External.TargetValue = nil
+		}
+		targetMetrics = append(targetMetrics, metric)
+	}
+	return targetMetrics
+}
+
+// getSpecMetricName returns the name a watermarkSchedule entry refers to a metric with.
+func getSpecMetricName(metric v1alpha1.MetricSpec) string {
+	switch {
+	case metric.External != nil:
+		return metric.External.MetricName
+	case metric.Resource != nil:
+		return string(metric.Resource.Name)
+	case metric.Object != nil:
+		return metric.Object.MetricName
+	case metric.Pods != nil:
+		return metric.Pods.MetricName
+	default:
+		return ""
+	}
+}
+
+// overrideWatermarks returns a copy of the metric with the watermarks of the entry.
+// It returns false if the resulting low watermark is not strictly below the high watermark.
+func overrideWatermarks(metric v1alpha1.MetricSpec, entry *v1alpha1.WatermarkScheduleEntry) (v1alpha1.MetricSpec, bool) {
+	scheduled := *metric.DeepCopy()
+	var lowMark, highMark **resource.Quantity
+	switch {
+	case scheduled.External != nil:
+		lowMark, highMark = &scheduled.External.LowWatermark, &scheduled.External.HighWatermark
+	case scheduled.Resource != nil:
+		lowMark, highMark = &scheduled.Resource.LowWatermark, &scheduled.Resource.HighWatermark
+	case scheduled.Object != nil:
+		lowMark, highMark = &scheduled.Object.LowWatermark, &scheduled.Object.HighWatermark
+	case scheduled.Pods != nil:
+		lowMark, highMark = &scheduled.Pods.LowWatermark, &scheduled.Pods.HighWatermark
+	default:
+		return metric, false
+	}
+	if entry.LowWatermark != nil {
+		*lowMark = entry.LowWatermark
+	}
+	if entry.HighWatermark != nil {
+		*highMark = entry.HighWatermark
+	}
+	if *lowMark == nil || *highMark == nil || getMilliValue(*lowMark) >= getMilliValue(*highMark) {
+		return metric, false
+	}
+	return scheduled, true
+}
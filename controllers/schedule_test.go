@@ -0,0 +1,247 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/tools/record"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func newScheduledWPA(schedule []v1alpha1.WatermarkScheduleEntry, timezone string) *v1alpha1.WatermarkPodAutoscaler {
+	return test.NewWatermarkPodAutoscaler(testingNamespace, "watermark-schedule", &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+			ScaleTargetRef:            testCrossVersionObjectRef,
+			MaxReplicas:               12,
+			WatermarkSchedule:         schedule,
+			WatermarkScheduleTimezone: timezone,
+			Metrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "queue",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(10, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(5, resource.DecimalSI),
+					},
+				},
+				{
+					Type: v1alpha1.ResourceMetricSourceType,
+					Resource: &v1alpha1.ResourceMetricSource{
+						Name:           "cpu",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewMilliQuantity(800, resource.DecimalSI),
+						LowWatermark:   resource.NewMilliQuantity(600, resource.DecimalSI),
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestComputeReplicasForMetricsWatermarkSchedule(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	location, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	wpa := newScheduledWPA([]v1alpha1.WatermarkScheduleEntry{
+		{
+			// business hours, overlapping with the overnight window from 17:00 to 18:00.
+			Days:          "Mon-Fri",
+			Start:         "09:00",
+			End:           "18:00",
+			MetricName:    "queue",
+			HighWatermark: resource.NewQuantity(6, resource.DecimalSI),
+			LowWatermark:  resource.NewQuantity(4, resource.DecimalSI),
+		},
+		{
+			Start:         "17:00",
+			End:           "07:00",
+			MetricName:    "queue",
+			HighWatermark: resource.NewQuantity(12, resource.DecimalSI),
+			LowWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+		},
+	}, "Europe/Paris")
+	scale := &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 5}, Status: autoscalingv1.ScaleStatus{Replicas: 5}}
+	defer cleanupAssociatedMetrics(wpa, false)
+
+	fakeClock := clock.NewFakeClock(time.Now())
+	highMarks := map[string]int64{}
+	r := &WatermarkPodAutoscalerReconciler{
+		replicaCalc: &fakeReplicaCalculator{
+			replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+				if metric.External != nil {
+					highMarks[metric.External.MetricName] = metric.External.HighWatermark.MilliValue()
+				} else {
+					highMarks[string(metric.Resource.Name)] = metric.Resource.HighWatermark.MilliValue()
+				}
+				return ReplicaCalculation{5, 5000, fakeClock.Now(), "", "", nil}, nil
+			},
+		},
+		eventRecorder: record.NewFakeRecorder(100),
+		clock:         fakeClock,
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected int64
+	}{
+		{
+			name:     "overnight window started the day before",
+			now:      time.Date(2020, time.June, 1, 6, 59, 0, 0, location), // Monday
+			expected: 12000,
+		},
+		{
+			name:     "end of the overnight window",
+			now:      time.Date(2020, time.June, 1, 7, 0, 0, 0, location),
+			expected: 10000,
+		},
+		{
+			name:     "start of the business hours",
+			now:      time.Date(2020, time.June, 1, 9, 0, 0, 0, location),
+			expected: 6000,
+		},
+		{
+			name:     "overlapping windows",
+			now:      time.Date(2020, time.June, 1, 17, 30, 0, 0, location),
+			expected: 6000,
+		},
+		{
+			name:     "end of the business hours",
+			now:      time.Date(2020, time.June, 1, 18, 0, 0, 0, location),
+			expected: 12000,
+		},
+		{
+			name:     "business hours during the weekend",
+			now:      time.Date(2020, time.June, 6, 10, 0, 0, 0, location), // Saturday
+			expected: 10000,
+		},
+		{
+			name:     "timezone of the schedule",
+			now:      time.Date(2020, time.June, 1, 8, 30, 0, 0, time.UTC), // 10:30 in Paris
+			expected: 6000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClock.SetTime(tt.now)
+			_, _, _, _, err := r.computeReplicasForMetrics(logf.Log.WithName(tt.name), wpa, scale)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, highMarks["queue"])
+			// the entries only apply to the queue metric.
+			assert.Equal(t, int64(800), highMarks["cpu"])
+		})
+	}
+	// the spec of the WPA is left untouched.
+	assert.Equal(t, int64(10000), wpa.Spec.Metrics[0].External.HighWatermark.MilliValue())
+}
+
+func TestGetScheduledMetrics(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	now := time.Date(2020, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		schedule         []v1alpha1.WatermarkScheduleEntry
+		timezone         string
+		expectedQueue    [2]int64
+		expectedResource [2]int64
+	}{
+		{
+			name:             "no schedule",
+			expectedQueue:    [2]int64{5000, 10000},
+			expectedResource: [2]int64{600, 800},
+		},
+		{
+			name: "no active window",
+			schedule: []v1alpha1.WatermarkScheduleEntry{
+				{Start: "00:00", End: "08:00", HighWatermark: resource.NewQuantity(20, resource.DecimalSI)},
+			},
+			expectedQueue:    [2]int64{5000, 10000},
+			expectedResource: [2]int64{600, 800},
+		},
+		{
+			name: "entry applying to all of the metrics",
+			schedule: []v1alpha1.WatermarkScheduleEntry{
+				{Start: "08:00", End: "20:00", HighWatermark: resource.NewQuantity(20, resource.DecimalSI)},
+			},
+			expectedQueue:    [2]int64{5000, 20000},
+			expectedResource: [2]int64{600, 20000},
+		},
+		{
+			name: "entries applying to each metric",
+			schedule: []v1alpha1.WatermarkScheduleEntry{
+				{Start: "08:00", End: "20:00", MetricName: "cpu", LowWatermark: resource.NewMilliQuantity(400, resource.DecimalSI)},
+				{Start: "08:00", End: "20:00", LowWatermark: resource.NewQuantity(2, resource.DecimalSI), HighWatermark: resource.NewQuantity(4, resource.DecimalSI)},
+			},
+			expectedQueue:    [2]int64{2000, 4000},
+			expectedResource: [2]int64{400, 800},
+		},
+		{
+			name: "low watermark above the high watermark of the metric",
+			schedule: []v1alpha1.WatermarkScheduleEntry{
+				{Start: "08:00", End: "20:00", MetricName: "queue", LowWatermark: resource.NewQuantity(12, resource.DecimalSI)},
+			},
+			expectedQueue:    [2]int64{5000, 10000},
+			expectedResource: [2]int64{600, 800},
+		},
+		{
+			name: "invalid timezone",
+			schedule: []v1alpha1.WatermarkScheduleEntry{
+				{Start: "08:00", End: "20:00", HighWatermark: resource.NewQuantity(20, resource.DecimalSI)},
+			},
+			timezone:         "Mars/Olympus_Mons",
+			expectedQueue:    [2]int64{5000, 10000},
+			expectedResource: [2]int64{600, 800},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := getScheduledMetrics(logf.Log.WithName(tt.name), newScheduledWPA(tt.schedule, tt.timezone), now)
+			require.Len(t, metrics, 2)
+			assert.Equal(t, tt.expectedQueue, [2]int64{metrics[0].External.LowWatermark.MilliValue(), metrics[0].External.HighWatermark.MilliValue()})
+			assert.Equal(t, tt.expectedResource, [2]int64{metrics[1].Resource.LowWatermark.MilliValue(), metrics[1].Resource.HighWatermark.MilliValue()})
+		})
+	}
+}
+
+func TestGetScheduledMetricsTargetValue(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	now := time.Date(2020, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	wpa := newScheduledWPA(nil, "")
+	wpa.Spec.Metrics[0].External.LowWatermark = nil
+	wpa.Spec.Metrics[0].External.HighWatermark = nil
+	wpa.Spec.Metrics[0].External.TargetValue = resource.NewQuantity(8, resource.DecimalSI)
+	wpa.Spec.Metrics[0].External.DeadbandPercent = 25
+
+	metrics := getScheduledMetrics(logf.Log.WithName("target value"), wpa, now)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, [2]int64{6000, 10000}, [2]int64{metrics[0].External.LowWatermark.MilliValue(), metrics[0].External.HighWatermark.MilliValue()})
+	// the spec of the WPA is left untouched.
+	assert.Nil(t, wpa.Spec.Metrics[0].External.LowWatermark)
+	assert.Nil(t, wpa.Spec.Metrics[0].External.HighWatermark)
+
+	// an active entry of the schedule overrides the derived watermarks.
+	wpa.Spec.WatermarkSchedule = []v1alpha1.WatermarkScheduleEntry{
+		{Start: "08:00", End: "20:00", MetricName: "queue", HighWatermark: resource.NewQuantity(20, resource.DecimalSI)},
+	}
+	metrics = getScheduledMetrics(logf.Log.WithName("target value"), wpa, now)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, [2]int64{6000, 20000}, [2]int64{metrics[0].External.LowWatermark.MilliValue(), metrics[0].External.HighWatermark.MilliValue()})
+}
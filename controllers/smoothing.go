@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"sync"
+
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// smoothedUsageStore keeps the exponential moving average of the usage of each metric of each WPA.
+type smoothedUsageStore struct {
+	sync.Mutex
+	usages map[types.NamespacedName]map[string]float64
+}
+
+// smooth blends the usage of a metric with its previous smoothed usage, weighting the new usage by the factor,
+// and returns the new smoothed usage. The first usage of a metric is kept as is.
+// The smoothed usage is forgotten when the smoothing is disabled, i.e. with a factor of 1.
+func (s *smoothedUsageStore) smooth(key types.NamespacedName, name string, usage, factor float64) float64 {
+	usage = getSmoothedUsage(usage, s.get(key, name), factor)
+	s.set(key, name, usage, factor)
+	return usage
+}
+
+// get returns the smoothed usage of a metric, nil if there is none.
+func (s *smoothedUsageStore) get(key types.NamespacedName, name string) *float64 {
+	s.Lock()
+	defer s.Unlock()
+	if usage, found := s.usages[key][name]; found {
+		return &usage
+	}
+	return nil
+}
+
+// set keeps the smoothed usage of a metric, which is forgotten when the smoothing is disabled.
+func (s *smoothedUsageStore) set(key types.NamespacedName, name string, usage, factor float64) {
+	s.Lock()
+	defer s.Unlock()
+	if factor >= 1 {
+		delete(s.usages[key], name)
+		return
+	}
+	// a NaN or Inf would be kept in the average forever, it is left to getReplicaCount to handle it.
+	if !isValidMetricValue(usage) {
+		return
+	}
+	if s.usages == nil {
+		s.usages = make(map[types.NamespacedName]map[string]float64)
+	}
+	if s.usages[key] == nil {
+		s.usages[key] = make(map[string]float64)
+	}
+	s.usages[key][name] = usage
+}
+
+// delete frees the smoothed usages of a WPA.
+func (s *smoothedUsageStore) delete(key types.NamespacedName) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.usages, key)
+}
+
+// getSmoothingFactor returns the smoothingFactor of the WPA, 1 when it is unset.
+func getSmoothingFactor(wpa *v1alpha1.WatermarkPodAutoscaler) float64 {
+	if wpa.Spec.SmoothingFactor == nil {
+		return 1
+	}
+	return float64(wpa.Spec.SmoothingFactor.MilliValue()) / 1000
+}
+
+// getSmoothedUsage blends the usage of a metric with its previous smoothed usage, weighting the new usage by the factor.
+// The usage is kept as is when there is no previous one, when the smoothing is disabled and when it is invalid.
+func getSmoothedUsage(usage float64, previous *float64, factor float64) float64 {
+	if factor >= 1 || previous == nil || !isValidMetricValue(usage) {
+		return usage
+	}
+	return factor*usage + (1-factor)*(*previous)
+}
+
+// smoothUsage returns the exponential moving average of the usage of a metric with the smoothingFactor of the WPA.
+func (c *ReplicaCalculator) smoothUsage(logger logr.Logger, wpa *v1alpha1.WatermarkPodAutoscaler, name string, usage float64) float64 {
+	recordRawUsage(wpa, name, usage)
+	factor := getSmoothingFactor(wpa)
+	smoothed := c.smoothedUsages.smooth(types.NamespacedName{Namespace: wpa.Namespace, Name: wpa.Name}, name, usage, factor)
+	if factor < 1 {
+		logger.Info("Smoothing the usage of the metric", "metricName", name, "usage", usage, "smoothedUsage", smoothed, "smoothingFactor", factor)
+	}
+	return smoothed
+}
+
+// recordRawUsage exposes the usage of a metric before it is smoothed, the value gauge being the smoothed usage.
+func recordRawUsage(wpa *v1alpha1.WatermarkPodAutoscaler, name string, usage float64) {
+	rawValue.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind, metricNamePromLabel: name}).Set(usage)
+}
+
+// deleteSmoothedUsages frees the smoothed usages of a WPA kept by the replica calculator.
+func (r *WatermarkPodAutoscalerReconciler) deleteSmoothedUsages(key types.NamespacedName) {
+	if c, ok := r.replicaCalc.(*ReplicaCalculator); ok {
+		c.smoothedUsages.delete(key)
+	}
+}
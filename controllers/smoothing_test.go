@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestSmoothedUsageStore(t *testing.T) {
+	key := types.NamespacedName{Namespace: testingNamespace, Name: testingWPAName}
+	other := types.NamespacedName{Namespace: testingNamespace, Name: "other"}
+	store := &smoothedUsageStore{}
+
+	// the first usage is kept as is, the next ones are blended with the average.
+	assert.Equal(t, 1000.0, store.smooth(key, "queue", 1000, 0.5))
+	assert.Equal(t, 3000.0, store.smooth(key, "queue", 5000, 0.5))
+	assert.Equal(t, 4000.0, store.smooth(key, "queue", 5000, 0.5))
+	// each metric of each WPA has its own average.
+	assert.Equal(t, 200.0, store.smooth(key, "cpu", 200, 0.5))
+	assert.Equal(t, 5000.0, store.smooth(other, "queue", 5000, 0.5))
+
+	// an invalid usage is not blended in the average.
+	assert.True(t, math.IsNaN(store.smooth(key, "queue", math.NaN(), 0.5)))
+	assert.Equal(t, 4500.0, store.smooth(key, "queue", 5000, 0.5))
+
+	// disabling the smoothing forgets the average.
+	assert.Equal(t, 1000.0, store.smooth(key, "queue", 1000, 1))
+	assert.Equal(t, 5000.0, store.smooth(key, "queue", 5000, 0.5))
+
+	store.delete(key)
+	_, found := store.usages[key]
+	assert.False(t, found)
+	assert.Len(t, store.usages[other], 1)
+}
+
+func TestGetSmoothingFactor(t *testing.T) {
+	wpa := &v1alpha1.WatermarkPodAutoscaler{}
+	assert.Equal(t, 1.0, getSmoothingFactor(wpa))
+	wpa.Spec.SmoothingFactor = resource.NewMilliQuantity(250, resource.DecimalSI)
+	assert.Equal(t, 0.25, getSmoothingFactor(wpa))
+}
+
+func TestReplicaCalcExternal_Smoothing(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	metric := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:     "deadbeef",
+			MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			HighWatermark:  resource.NewMilliQuantity(4000, resource.DecimalSI),
+			LowWatermark:   resource.NewMilliQuantity(2000, resource.DecimalSI),
+		},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for i := 0; i < 4; i++ {
+		_ = indexer.Add(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            fmt.Sprintf("%s-%d", podNamePrefix, i),
+				Namespace:       testNamespace,
+				Labels:          map[string]string{"name": podNamePrefix},
+				OwnerReferences: []metav1.OwnerReference{{Kind: replicaSetKind, Name: testReplicaSetName}},
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				StartTime:  &metav1.Time{Time: time.Now()},
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		})
+	}
+
+	tests := []struct {
+		name             string
+		smoothingFactor  *resource.Quantity
+		expectedReplicas []int32
+		expectedUsages   []float64
+	}{
+		{
+			name:             "smoothing disabled",
+			expectedReplicas: []int32{4, 12, 12, 12},
+			expectedUsages:   []float64{3000, 12000, 12000, 12000},
+		},
+		{
+			name:             "smoothing factor of 1",
+			smoothingFactor:  resource.NewQuantity(1, resource.DecimalSI),
+			expectedReplicas: []int32{4, 12, 12, 12},
+			expectedUsages:   []float64{3000, 12000, 12000, 12000},
+		},
+		{
+			// the smoothed usage goes from 3000 to 7500, 9750 and 10875.
+			name:             "smoothing factor of 0.5",
+			smoothingFactor:  resource.NewMilliQuantity(500, resource.DecimalSI),
+			expectedReplicas: []int32{4, 8, 10, 11},
+			expectedUsages:   []float64{3000, 7500, 9750, 10875},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa := &v1alpha1.WatermarkPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "smoothing", Namespace: testNamespace},
+				Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+					Algorithm:       "absolute",
+					Tolerance:       *resource.NewMilliQuantity(20, resource.DecimalSI),
+					ScaleTargetRef:  v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testDeploymentName},
+					MaxReplicas:     20,
+					SmoothingFactor: tt.smoothingFactor,
+					Metrics:         []v1alpha1.MetricSpec{metric},
+				},
+			}
+			defer cleanupAssociatedMetrics(wpa, false)
+			// the metric steps from within the watermarks to three times the high watermark.
+			values := []int64{3000, 12000, 12000, 12000}
+			cycle := 0
+			provider := fakeExternalMetricsProvider{
+				getExternalMetric: func(_ context.Context, metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+					return []int64{values[cycle]}, time.Now(), nil
+				},
+			}
+			replicaCalculator := NewReplicaCalculator(nil, provider, corelisters.NewPodLister(indexer))
+			scale := makeScale(testDeploymentName, 4, map[string]string{"name": podNamePrefix})
+			promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: testDeploymentName, resourceKindPromLabel: "Deployment", metricNamePromLabel: "deadbeef"}
+
+			for ; cycle < len(values); cycle++ {
+				replicaCalculation, err := replicaCalculator.GetExternalMetricReplicas(context.TODO(), logf.Log.WithName(tt.name), scale, metric, wpa)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedReplicas[cycle], replicaCalculation.replicaCount, "cycle %d", cycle)
+				// the spike is attenuated in the value compared to the watermarks, not in the raw value.
+				assert.Equal(t, float64(values[cycle]), testutil.ToFloat64(rawValue.With(promLabels)), "cycle %d", cycle)
+				assert.Equal(t, tt.expectedUsages[cycle], testutil.ToFloat64(value.With(promLabels)), "cycle %d", cycle)
+			}
+		})
+	}
+}
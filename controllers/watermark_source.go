@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// defaultHighWatermarkKey is the key of the high watermark in the ConfigMap of a watermarkSource without highWatermarkKey.
+	defaultHighWatermarkKey = "highWatermark"
+	// defaultLowWatermarkKey is the key of the low watermark in the ConfigMap of a watermarkSource without lowWatermarkKey.
+	defaultLowWatermarkKey = "lowWatermark"
+)
+
+// getSourcedWPA returns the WPA with the watermarks of its metrics read from the ConfigMap of their watermarkSource.
+// The WPA itself is returned when none of its metrics has a watermarkSource, a copy otherwise.
+// The inline watermarks of a metric are kept for the keys that are missing or invalid, and a warning event is emitted.
+func (r *WatermarkPodAutoscalerReconciler) getSourcedWPA(logger logr.Logger, wpa *v1alpha1.WatermarkPodAutoscaler) *v1alpha1.WatermarkPodAutoscaler {
+	sourced := wpa
+	for i, metric := range wpa.Spec.Metrics {
+		if metric.WatermarkSource == nil {
+			continue
+		}
+		lowMark, highMark, warnings := r.getSourcedWatermarks(wpa.Namespace, metric.WatermarkSource)
+		applied := false
+		if lowMark != nil || highMark != nil {
+			overridden, ok := overrideWatermarks(metric, &v1alpha1.WatermarkScheduleEntry{LowWatermark: lowMark, HighWatermark: highMark})
+			if ok {
+				if sourced == wpa {
+					sourced = wpa.DeepCopy()
+				}
+				sourced.Spec.Metrics[i] = overridden
+				applied = true
+			} else {
+				warnings = append(warnings, "the low watermark is not strictly lower than the high watermark")
+			}
+		}
+		if len(warnings) > 0 {
+			name := getSpecMetricName(metric)
+			fallback := getWatermarkSourceFallback(applied, lowMark, highMark)
+			logger.Info("Falling back to the inline watermarks of the metric", "metricName", name, "configMap", metric.WatermarkSource.ConfigMapName, "fallback", fallback, "warnings", warnings)
+			r.recorder().Eventf(wpa, corev1.EventTypeWarning, v1alpha1.ReasonInvalidWatermarkSource, "Falling back to the inline %s of the metric %s: %s", fallback, name, strings.Join(warnings, "; "))
+		}
+	}
+	return sourced
+}
+
+// getWatermarkSourceFallback returns which inline watermarks of a metric are used: both of them unless the watermarks
+// read from the ConfigMap were applied, the one that is missing from the ConfigMap otherwise.
+func getWatermarkSourceFallback(applied bool, lowMark, highMark *resource.Quantity) string {
+	switch {
+	case !applied:
+		return "low and high watermarks"
+	case lowMark == nil:
+		return "low watermark"
+	case highMark == nil:
+		return "high watermark"
+	}
+	return "watermarks"
+}
+
+// getSourcedWatermarks reads the watermarks from the ConfigMap of the watermarkSource. A watermark is nil when its key
+// is missing or invalid, and the reason is returned as a warning.
+func (r *WatermarkPodAutoscalerReconciler) getSourcedWatermarks(namespace string, source *v1alpha1.WatermarkSource) (lowMark, highMark *resource.Quantity, warnings []string) {
+	configMap := &corev1.ConfigMap{}
+	err := r.getAPIReader().Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: source.ConfigMapName}, configMap)
+	if err != nil {
+		return nil, nil, []string{fmt.Sprintf("unable to get the ConfigMap %s: %v", source.ConfigMapName, err)}
+	}
+	lowMark, err = getConfigMapQuantity(configMap, source.LowWatermarkKey, defaultLowWatermarkKey)
+	if err != nil {
+		warnings = append(warnings, err.Error())
+	}
+	highMark, err = getConfigMapQuantity(configMap, source.HighWatermarkKey, defaultHighWatermarkKey)
+	if err != nil {
+		warnings = append(warnings, err.Error())
+	}
+	return lowMark, highMark, warnings
+}
+
+// getConfigMapQuantity parses the value of a key of the ConfigMap as a quantity, defaultKey being used when key is empty.
+func getConfigMapQuantity(configMap *corev1.ConfigMap, key, defaultKey string) (*resource.Quantity, error) {
+	if key == "" {
+		key = defaultKey
+	}
+	value, found := configMap.Data[key]
+	if !found {
+		return nil, fmt.Errorf("missing key %s in the ConfigMap %s", key, configMap.Name)
+	}
+	quantity, err := resource.ParseQuantity(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q of the key %s in the ConfigMap %s: %v", value, key, configMap.Name, err)
+	}
+	return &quantity, nil
+}
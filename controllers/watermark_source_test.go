@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1/test"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func newSourcedWPA(source *v1alpha1.WatermarkSource) *v1alpha1.WatermarkPodAutoscaler {
+	return test.NewWatermarkPodAutoscaler(testingNamespace, "watermark-source", &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+			ScaleTargetRef: testCrossVersionObjectRef,
+			MaxReplicas:    12,
+			Metrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "queue",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(10, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(5, resource.DecimalSI),
+					},
+					WatermarkSource: source,
+				},
+			},
+		},
+	})
+}
+
+func TestGetSourcedWPA(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testingNamespace, Name: "watermarks"},
+		Data: map[string]string{
+			"highWatermark": "20",
+			"lowWatermark":  "15",
+			"customHigh":    "30",
+			"customLow":     "25",
+			"invalid":       "not-a-quantity",
+			"tooHigh":       "50",
+		},
+	}
+
+	tests := []struct {
+		name        string
+		source      *v1alpha1.WatermarkSource
+		expectedLow int64
+		expectedHi  int64
+		fallback    string
+	}{
+		{
+			name:        "no watermarkSource",
+			source:      nil,
+			expectedLow: 5,
+			expectedHi:  10,
+		},
+		{
+			name:        "default keys",
+			source:      &v1alpha1.WatermarkSource{ConfigMapName: "watermarks"},
+			expectedLow: 15,
+			expectedHi:  20,
+		},
+		{
+			name:        "custom keys",
+			source:      &v1alpha1.WatermarkSource{ConfigMapName: "watermarks", LowWatermarkKey: "customLow", HighWatermarkKey: "customHigh"},
+			expectedLow: 25,
+			expectedHi:  30,
+		},
+		{
+			name:        "missing key falls back to the inline watermark",
+			source:      &v1alpha1.WatermarkSource{ConfigMapName: "watermarks", LowWatermarkKey: "missing"},
+			expectedLow: 5,
+			expectedHi:  20,
+			fallback:    "low watermark",
+		},
+		{
+			name:        "invalid value falls back to the inline watermark",
+			source:      &v1alpha1.WatermarkSource{ConfigMapName: "watermarks", HighWatermarkKey: "invalid"},
+			expectedLow: 15,
+			expectedHi:  10,
+			fallback:    "high watermark",
+		},
+		{
+			name:        "low watermark above the high watermark",
+			source:      &v1alpha1.WatermarkSource{ConfigMapName: "watermarks", LowWatermarkKey: "tooHigh"},
+			expectedLow: 5,
+			expectedHi:  10,
+			fallback:    "low and high watermarks",
+		},
+		{
+			name:        "missing ConfigMap",
+			source:      &v1alpha1.WatermarkSource{ConfigMapName: "missing"},
+			expectedLow: 5,
+			expectedHi:  10,
+			fallback:    "low and high watermarks",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			r := &WatermarkPodAutoscalerReconciler{
+				Client:        fake.NewFakeClient(),
+				apiReader:     fake.NewFakeClient(configMap),
+				eventRecorder: recorder,
+			}
+			wpa := newSourcedWPA(tt.source)
+			sourced := r.getSourcedWPA(logf.Log, wpa)
+			if tt.source == nil {
+				assert.True(t, sourced == wpa)
+			}
+			external := sourced.Spec.Metrics[0].External
+			assert.Equal(t, tt.expectedLow, external.LowWatermark.Value())
+			assert.Equal(t, tt.expectedHi, external.HighWatermark.Value())
+			// The spec of the WPA is never modified.
+			assert.Equal(t, int64(5), wpa.Spec.Metrics[0].External.LowWatermark.Value())
+			assert.Equal(t, int64(10), wpa.Spec.Metrics[0].External.HighWatermark.Value())
+			if tt.fallback != "" {
+				assert.Len(t, recorder.Events, 1)
+				event := <-recorder.Events
+				assert.Contains(t, event, v1alpha1.ReasonInvalidWatermarkSource)
+				assert.Contains(t, event, "Falling back to the inline "+tt.fallback+" of the metric")
+			} else {
+				assert.Len(t, recorder.Events, 0)
+			}
+		})
+	}
+}
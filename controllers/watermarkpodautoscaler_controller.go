@@ -9,7 +9,8 @@ import (
 	"context"
 	"fmt"
 	"math"
-	"strings"
+	"math/rand"
+	"net/http"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -22,6 +23,8 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	discocache "k8s.io/client-go/discovery/cached"
 	"k8s.io/client-go/dynamic"
@@ -35,8 +38,10 @@ import (
 	simplecontroller "k8s.io/kubernetes/pkg/controller"
 	"k8s.io/kubernetes/pkg/controller/podautoscaler/metrics"
 	resourceclient "k8s.io/metrics/pkg/client/clientset/versioned/typed/metrics/v1beta1"
+	"k8s.io/metrics/pkg/client/custom_metrics"
 	"k8s.io/metrics/pkg/client/external_metrics"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -51,10 +56,14 @@ import (
 
 const (
 	defaultSyncPeriod = 15 * time.Second
+	// defaultMetricFetchTimeout is the time given to the metrics providers to answer when the MetricFetchTimeout
+	// of the reconciler is unset.
+	defaultMetricFetchTimeout = 30 * time.Second
 )
 
 var (
 	dryRunCondition autoscalingv2.HorizontalPodAutoscalerConditionType = "DryRun"
+	pausedCondition autoscalingv2.HorizontalPodAutoscalerConditionType = "Paused"
 )
 
 // WatermarkPodAutoscalerReconciler reconciles a WatermarkPodAutoscaler object
@@ -69,13 +78,44 @@ type WatermarkPodAutoscalerReconciler struct {
 	syncPeriod    time.Duration
 	eventRecorder record.EventRecorder
 	replicaCalc   ReplicaCalculatorItf
+	// apiReader reads the objects the controller doesn't watch, e.g. the ConfigMaps of the watermarkSources, from the
+	// apiserver rather than from a cache, r.Client is used when it is unset
+	apiReader client.Reader
+	// recommendations keeps the recent recommendations of each WPA to apply the stabilization windows
+	recommendations recommendationStore
+	// breaches keeps the ongoing breach of the watermarks of each WPA to apply the delay counts and the delays
+	breaches breachCounter
+	// metricErrors keeps the consecutive failures to retrieve the metrics of each WPA to back off its requeue interval
+	metricErrors metricErrorBackoff
+	// lastRecommendations keeps the last recommendation computed from the metrics of each WPA for the lastKnownGood metric error policy
+	lastRecommendations lastRecommendationStore
+	// RequeueJitterPercent is the maximum jitter added to or removed from the interval between two reconcile cycles of
+	// a WPA, as a percentage of the interval, to spread the queries to the metrics provider.
+	RequeueJitterPercent int
+	// MaxConcurrentReconciles is the maximum number of WPAs reconciled at the same time, 1 when it is unset.
+	// A WPA is never reconciled by two workers at once, the state kept for each WPA is shared by the workers.
+	MaxConcurrentReconciles int
+	// ScaleApprovalTimeout is the time given to the scale approval webhook of a WPA to answer, 5 seconds when it is unset.
+	ScaleApprovalTimeout time.Duration
+	// ScaleApprovalAllowedHosts are the hosts the scale approval webhooks of the WPAs can be sent to, as host names or as
+	// host:port. The webhooks of the other hosts are not called and their changes are vetoed.
+	ScaleApprovalAllowedHosts []string
+	// scaleApprovalClient calls the scale approval webhooks, defaultScaleApprovalClient is used when it is unset
+	scaleApprovalClient *http.Client
+	// MetricFetchTimeout is the time given to the metrics providers to answer a query, 30 seconds when it is unset.
+	// The metric is unavailable after it.
+	MetricFetchTimeout time.Duration
+	// clock measures the time taken to compute the recommendations, the real one is used when it is unset
+	clock clock.Clock
+	// random draws the jitter of the requeue interval, math/rand is used when it is unset
+	random func() float64
 }
 
 // +kubebuilder:rbac:groups=apps;extensions,resources=deployments/finalizers,resourceNames=watermarkpodautoscalers,verbs=update
 // +kubebuilder:rbac:groups=,resources=pods,verbs=get;list
 // +kubebuilder:rbac:groups=datadoghq.com,resources=watermarkpodautoscalers;watermarkpodautoscalers/status,verbs=*
 // +kubebuilder:rbac:groups=,resources=pods,verbs=get;list
-// +kubebuilder:rbac:groups=,resources=configmaps,verbs=create
+// +kubebuilder:rbac:groups=,resources=configmaps,verbs=create;get
 // +kubebuilder:rbac:groups=,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=,resources=configmaps,resourceNames=watermarkpodautoscaler-lock,verbs=update;get
 // +kubebuilder:rbac:groups=apps;extensions,resources=replicasets/scale;deployments/scale;statefulsets/scale,verbs=update;get
@@ -88,10 +128,6 @@ func (r *WatermarkPodAutoscalerReconciler) Reconcile(request ctrl.Request) (ctrl
 	_ = context.Background()
 	log := r.Log.WithValues("watermarkpodautoscaler", request.NamespacedName)
 	var err error
-	// resRepeat will be returned if we want to re-run reconcile process
-	// NB: we can't return non-nil err, as the "reconcile" msg will be added to the rate-limited queue
-	// so that it'll slow down if we have several problems in a row
-	resRepeat := reconcile.Result{RequeueAfter: r.syncPeriod}
 
 	// Fetch the WatermarkPodAutoscaler instance
 	instance := &datadoghqv1alpha1.WatermarkPodAutoscaler{}
@@ -101,12 +137,23 @@ func (r *WatermarkPodAutoscalerReconciler) Reconcile(request ctrl.Request) (ctrl
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			r.recommendations.delete(request.NamespacedName)
+			r.breaches.delete(request.NamespacedName)
+			r.metricErrors.delete(request.NamespacedName)
+			r.lastRecommendations.delete(request.NamespacedName)
+			r.deleteSmoothedUsages(request.NamespacedName)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
 
+	// A WPA being deleted is finalized even if its spec is invalid, for its Prometheus series to be removed.
+	if instance.GetDeletionTimestamp() != nil {
+		_, err = r.handleFinalizer(log, instance)
+		return reconcile.Result{}, err
+	}
+
 	if !datadoghqv1alpha1.IsDefaultWatermarkPodAutoscaler(instance) {
 		log.Info("Some configuration options are missing, falling back to the default ones")
 		defaultWPA := datadoghqv1alpha1.DefaultWatermarkPodAutoscaler(instance)
@@ -121,11 +168,11 @@ func (r *WatermarkPodAutoscalerReconciler) Reconcile(request ctrl.Request) (ctrl
 		log.Info("Got an invalid WPA spec", "Instance", request.NamespacedName.String(), "error", err)
 		// If the WPA spec is incorrect (most likely, in "metrics" section) stop processing it
 		// When the spec is updated, the wpa will be re-added to the reconcile queue
-		r.eventRecorder.Event(instance, corev1.EventTypeWarning, datadoghqv1alpha1.ReasonFailedSpecCheck, err.Error())
+		r.recorder().Event(instance, corev1.EventTypeWarning, datadoghqv1alpha1.ReasonFailedSpecCheck, err.Error())
 		wpaStatusOriginal := instance.Status.DeepCopy()
 		setCondition(instance, autoscalingv2.AbleToScale, corev1.ConditionFalse, datadoghqv1alpha1.ReasonFailedSpecCheck, "Invalid WPA specification: %s", err)
 		if err = r.updateStatusIfNeeded(wpaStatusOriginal, instance); err != nil {
-			r.eventRecorder.Event(instance, corev1.EventTypeWarning, datadoghqv1alpha1.ReasonFailedUpdateStatus, err.Error())
+			r.recorder().Event(instance, corev1.EventTypeWarning, datadoghqv1alpha1.ReasonFailedUpdateStatus, err.Error())
 			return reconcile.Result{}, err
 		}
 		// we don't requeue here since the error was added properly in the WPA.Status
@@ -138,23 +185,142 @@ func (r *WatermarkPodAutoscalerReconciler) Reconcile(request ctrl.Request) (ctrl
 		return reconcile.Result{}, err
 	}
 
+	promLabelsForWpa := prometheus.Labels{wpaNamePromLabel: instance.Name, resourceNamespacePromLabel: instance.Namespace, resourceNamePromLabel: instance.Spec.ScaleTargetRef.Name, resourceKindPromLabel: instance.Spec.ScaleTargetRef.Kind}
 	if instance.Spec.DryRun {
 		setCondition(instance, dryRunCondition, corev1.ConditionTrue, "DryRun mode enabled", "Scaling changes won't be applied")
+		dryRun.With(promLabelsForWpa).Set(1)
 	} else {
 		setCondition(instance, dryRunCondition, corev1.ConditionFalse, "DryRun mode disabled", "Scaling changes can be applied")
+		dryRun.With(promLabelsForWpa).Set(0)
+		dryRunReplicas.Delete(promLabelsForWpa)
+	}
+	if isPaused(instance) {
+		setCondition(instance, pausedCondition, corev1.ConditionTrue, "Paused", "Scaling is paused with the %s annotation", datadoghqv1alpha1.PausedAnnotationKey)
+		paused.With(promLabelsForWpa).Set(1)
+	} else {
+		setCondition(instance, pausedCondition, corev1.ConditionFalse, "NotPaused", "Scaling is not paused")
+		paused.With(promLabelsForWpa).Set(0)
 	}
 	if err := r.reconcileWPA(log, instance); err != nil {
 		log.Info("Error during reconcileWPA", "error", err)
-		r.eventRecorder.Event(instance, corev1.EventTypeWarning, datadoghqv1alpha1.ReasonFailedProcessWPA, err.Error())
+		r.recorder().Event(instance, corev1.EventTypeWarning, datadoghqv1alpha1.ReasonFailedProcessWPA, err.Error())
 		setCondition(instance, autoscalingv2.AbleToScale, corev1.ConditionFalse, datadoghqv1alpha1.ReasonFailedProcessWPA, "Error happened while processing the WPA")
 		// In case of `reconcileWPA` error, we need to requeue the Resource in order to retry to process it again
 		// we put a delay of 1 second in order to not retry directly and limit the number of retries if it only a transient issue.
 		return reconcile.Result{RequeueAfter: time.Second}, nil
 	}
 
+	// resRepeat will be returned if we want to re-run reconcile process
+	// NB: we can't return non-nil err, as the "reconcile" msg will be added to the rate-limited queue
+	// so that it'll slow down if we have several problems in a row
+	// The interval is backed off while the metrics can't be retrieved, to not overload the metrics provider.
+	// A jitter is added for the WPAs sharing the same interval to not be reconciled at the same time.
+	requeueAfter := r.metricErrors.requeueAfter(request.NamespacedName, getSyncPeriod(instance, r.syncPeriod))
+	requeueAfter = getScaleVetoRequeueAfter(instance, requeueAfter)
+	resRepeat := reconcile.Result{RequeueAfter: addRequeueJitter(requeueAfter, r.RequeueJitterPercent, r.getRandom())}
 	return resRepeat, nil
 }
 
+// getNormalizedReason returns the reason of a recommendation changed by normalizeDesiredReplicas:
+// the bounds of the WPA if it was brought back to one of them, the scaling limits otherwise.
+func getNormalizedReason(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, prenormalizedDesiredReplicas, desiredReplicas int32) string {
+	switch {
+	case prenormalizedDesiredReplicas > wpa.Spec.MaxReplicas && desiredReplicas == wpa.Spec.MaxReplicas:
+		return datadoghqv1alpha1.DecisionReasonClampedToMax
+	case wpa.Spec.MinReplicas != nil && prenormalizedDesiredReplicas < *wpa.Spec.MinReplicas && desiredReplicas == *wpa.Spec.MinReplicas:
+		return datadoghqv1alpha1.DecisionReasonClampedToMin
+	default:
+		return datadoghqv1alpha1.DecisionReasonScaleLimited
+	}
+}
+
+// applyScaleDirection keeps the current number of replicas when the recommendation scales the target in a direction
+// disallowed by the scaleDirection of the WPA.
+func applyScaleDirection(logger logr.Logger, wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, currentReplicas, desiredReplicas int32) int32 {
+	var blockedDirection string
+	switch {
+	case desiredReplicas > currentReplicas && wpa.Spec.ScaleDirection == "down":
+		blockedDirection = upPromLabelVal
+	case desiredReplicas < currentReplicas && wpa.Spec.ScaleDirection == "up":
+		blockedDirection = downPromLabelVal
+	default:
+		return desiredReplicas
+	}
+	scaleBlocked.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, directionPromLabel: blockedDirection, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Inc()
+	wpa.Status.LastDecisionReason = datadoghqv1alpha1.DecisionReasonDirectionBlocked
+	logger.Info("Scaling blocked by the scale direction", "scaleDirection", wpa.Spec.ScaleDirection, "currentReplicas", currentReplicas, "desiredReplicas", desiredReplicas)
+	return currentReplicas
+}
+
+// applyMinReplicaChange keeps the current number of replicas when the recommendation differs from it by less than the
+// minReplicaChange of the WPA, not to scale back and forth by a replica. Scaling to and from zero replicas is not affected.
+func applyMinReplicaChange(logger logr.Logger, wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, currentReplicas, desiredReplicas int32) int32 {
+	change := desiredReplicas - currentReplicas
+	if change < 0 {
+		change = -change
+	}
+	if wpa.Spec.MinReplicaChange <= 0 || change == 0 || change >= wpa.Spec.MinReplicaChange || currentReplicas == 0 || desiredReplicas == 0 {
+		return desiredReplicas
+	}
+	wpa.Status.LastDecisionReason = datadoghqv1alpha1.DecisionReasonBelowMinReplicaChange
+	changeSuppressed.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Inc()
+	logger.Info("Scaling held back by the minimum replica change", "minReplicaChange", wpa.Spec.MinReplicaChange, "currentReplicas", currentReplicas, "desiredReplicas", desiredReplicas)
+	return currentReplicas
+}
+
+// getMetricErrorReplicas returns the number of replicas recommended by the metric error policy of the WPA when none of its
+// metrics can be retrieved. It returns false when the current number of replicas should be kept, which is the case of the
+// maintain policy and of the lastKnownGood one until a recommendation was computed from the metrics.
+func (r *WatermarkPodAutoscalerReconciler) getMetricErrorReplicas(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler) (int32, bool) {
+	switch wpa.Spec.MetricErrorPolicy {
+	case "scaleToMin":
+		if wpa.Spec.MinReplicas == nil {
+			return 0, false
+		}
+		return *wpa.Spec.MinReplicas, true
+	case "lastKnownGood":
+		return r.lastRecommendations.get(types.NamespacedName{Namespace: wpa.Namespace, Name: wpa.Name})
+	default:
+		return 0, false
+	}
+}
+
+// applyMinReadyPercentage keeps the current number of replicas when the recommendation scales the target while fewer of
+// its pods than the minReadyPercentage of the WPA are ready, as scaling an unhealthy target can make it worse.
+// The target is scaled if the readiness of its pods can't be retrieved.
+func (r *WatermarkPodAutoscalerReconciler) applyMinReadyPercentage(logger logr.Logger, wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, scale *autoscalingv1.Scale, desiredReplicas int32) int32 {
+	currentReplicas := scale.Status.Replicas
+	if wpa.Spec.MinReadyPercentage <= 0 || desiredReplicas == currentReplicas {
+		return desiredReplicas
+	}
+	readyPodCount, podCount, err := r.replicaCalc.GetPodReadiness(context.TODO(), logger, scale, wpa)
+	if err != nil {
+		logger.Info("Unable to get the readiness of the pods of the target, ignoring the minReadyPercentage", "error", err)
+		return desiredReplicas
+	}
+	if podCount == 0 || int64(readyPodCount)*100 >= int64(wpa.Spec.MinReadyPercentage)*int64(podCount) {
+		return desiredReplicas
+	}
+	wpa.Status.LastDecisionReason = datadoghqv1alpha1.DecisionReasonUnhealthy
+	logger.Info("Scaling blocked by the readiness of the pods of the target", "readyPodCount", readyPodCount, "podCount", podCount, "minReadyPercentage", wpa.Spec.MinReadyPercentage, "currentReplicas", currentReplicas, "desiredReplicas", desiredReplicas)
+	r.recorder().Eventf(wpa, corev1.EventTypeWarning, datadoghqv1alpha1.ReasonScalingBlockedUnhealthy, "Keeping %d replicas instead of %d: %d of the %d pods are ready, below the minReadyPercentage of %d%%", currentReplicas, desiredReplicas, readyPodCount, podCount, wpa.Spec.MinReadyPercentage)
+	return currentReplicas
+}
+
+// isPaused returns whether the scaling of the WPA is paused with the PausedAnnotationKey annotation.
+func isPaused(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler) bool {
+	return wpa.GetAnnotations()[datadoghqv1alpha1.PausedAnnotationKey] == "true"
+}
+
+// getSyncPeriod returns the interval after which the WPA is reconciled again, the sync period of the controller is used
+// unless the WPA sets its own.
+func getSyncPeriod(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, defaultPeriod time.Duration) time.Duration {
+	if wpa.Spec.ReconcileIntervalSeconds > 0 {
+		return time.Duration(wpa.Spec.ReconcileIntervalSeconds) * time.Second
+	}
+	return defaultPeriod
+}
+
 // reconcileWPA is the core of the controller.
 func (r *WatermarkPodAutoscalerReconciler) reconcileWPA(logger logr.Logger, wpa *datadoghqv1alpha1.WatermarkPodAutoscaler) error {
 	defer func() {
@@ -177,10 +343,11 @@ func (r *WatermarkPodAutoscalerReconciler) reconcileWPA(logger logr.Logger, wpa
 		return fmt.Errorf("unable to determine resource for scale target reference: %v", err)
 	}
 
+	// the target can be of any kind implementing the scale subresource, e.g. a Deployment, a StatefulSet or a custom resource.
 	currentScale, targetGR, err := r.getScaleForResourceMappings(wpa.Namespace, wpa.Spec.ScaleTargetRef.Name, mappings)
-	if currentScale == nil && strings.Contains(err.Error(), "not found") {
+	if currentScale == nil {
 		// it is possible that one of the GK in the mappings was not found, but if we have at least one that works, we can continue reconciling.
-		return err
+		return fmt.Errorf("unable to get the scale of the %s %s, it must exist and implement the scale subresource: %v", wpa.Spec.ScaleTargetRef.Kind, wpa.Spec.ScaleTargetRef.Name, err)
 	}
 	currentReplicas := currentScale.Status.Replicas
 	logger.Info("Target deploy", "replicas", currentReplicas)
@@ -201,37 +368,77 @@ func (r *WatermarkPodAutoscalerReconciler) reconcileWPA(logger logr.Logger, wpa
 
 	rescale := true
 	switch {
-	case currentScale.Spec.Replicas == 0:
+	case isPaused(wpa):
+		// the metrics are still computed to be exposed, but none of the scaling state is updated.
+		// an ongoing breach of the watermarks starts over once the WPA is resumed.
+		r.breaches.delete(types.NamespacedName{Namespace: wpa.Namespace, Name: wpa.Name})
+		knownMetricStatuses := metricStatuses
+		var recommendedReplicas int32
+		recommendedReplicas, metricName, metricStatuses, _, err = r.computeReplicasForMetrics(logger, wpa, currentScale)
+		if err != nil {
+			logger.Info("Failed to compute the metrics of the paused WPA", "reference", reference, "error", err)
+			metricStatuses = knownMetricStatuses
+			recommendedReplicas = currentReplicas
+		}
+		desiredReplicas = currentReplicas
+		rescale = false
+		wpa.Status.LastDecisionReason = datadoghqv1alpha1.DecisionReasonPaused
+		// the recommendation that would have been applied is logged to preview the scaling once the WPA is resumed.
+		logger.Info("Scaling is paused", "annotation", datadoghqv1alpha1.PausedAnnotationKey, "currentReplicas", currentReplicas, "recommendedReplicas", recommendedReplicas, "metricName", metricName)
+	case currentScale.Spec.Replicas == 0 && !wpa.Spec.ScaleDownToZeroEnabled:
 		// Autoscaling is disabled for this resource
 		desiredReplicas = 0
 		rescale = false
 		setCondition(wpa, autoscalingv2.ScalingActive, corev1.ConditionFalse, datadoghqv1alpha1.ConditionReasonScalingDisabled, "scaling is disabled since the replica count of the target is zero")
+		wpa.Status.LastDecisionReason = datadoghqv1alpha1.DecisionReasonScalingDisabled
 	case currentReplicas > wpa.Spec.MaxReplicas:
 		rescaleReason = "Current number of replicas above Spec.MaxReplicas"
 		desiredReplicas = wpa.Spec.MaxReplicas
+		wpa.Status.LastDecisionReason = datadoghqv1alpha1.DecisionReasonClampedToMax
 	case wpa.Spec.MinReplicas != nil && currentReplicas < *wpa.Spec.MinReplicas:
 		rescaleReason = "Current number of replicas below Spec.MinReplicas"
 		desiredReplicas = *wpa.Spec.MinReplicas
-	case currentReplicas == 0:
+		wpa.Status.LastDecisionReason = datadoghqv1alpha1.DecisionReasonClampedToMin
+	case currentReplicas == 0 && !wpa.Spec.ScaleDownToZeroEnabled:
 		rescaleReason = "Current number of replicas must be greater than 0"
 		desiredReplicas = 1
+		wpa.Status.LastDecisionReason = datadoghqv1alpha1.DecisionReasonClampedToMin
 	default:
 		var metricTimestamp time.Time
+		key := types.NamespacedName{Namespace: wpa.Namespace, Name: wpa.Name}
+		knownMetricStatuses := metricStatuses
+		metricErrorPolicy := ""
 
 		proposedReplicas, metricName, metricStatuses, metricTimestamp, err = r.computeReplicasForMetrics(logger, wpa, currentScale)
 		if err != nil {
-			r.setCurrentReplicasInStatus(wpa, currentReplicas)
-			if err2 := r.updateStatusIfNeeded(wpaStatusOriginal, wpa); err2 != nil {
-				r.eventRecorder.Event(wpa, corev1.EventTypeWarning, datadoghqv1alpha1.ConditionReasonFailedUpdateReplicasStatus, err2.Error())
-				setCondition(wpa, autoscalingv2.AbleToScale, corev1.ConditionFalse, datadoghqv1alpha1.ConditionReasonFailedUpdateReplicasStatus, "the WPA controller was unable to update the number of replicas: %v", err)
-				logger.Info("The WPA controller was unable to update the number of replicas", "error", err2)
+			r.metricErrors.failure(key)
+			// the breach of the watermarks can't be observed without the metrics, it starts over once they are available again.
+			r.breaches.delete(key)
+			metricErrorTotal.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Inc()
+			fallbackReplicas, found := r.getMetricErrorReplicas(wpa)
+			if !found {
+				r.setCurrentReplicasInStatus(wpa, currentReplicas)
+				if err2 := r.updateStatusIfNeeded(wpaStatusOriginal, wpa); err2 != nil {
+					r.recorder().Event(wpa, corev1.EventTypeWarning, datadoghqv1alpha1.ConditionReasonFailedUpdateReplicasStatus, err2.Error())
+					setCondition(wpa, autoscalingv2.AbleToScale, corev1.ConditionFalse, datadoghqv1alpha1.ConditionReasonFailedUpdateReplicasStatus, "the WPA controller was unable to update the number of replicas: %v", err)
+					logger.Info("The WPA controller was unable to update the number of replicas", "error", err2)
+					return nil
+				}
+				r.recorder().Event(wpa, corev1.EventTypeWarning, "FailedComputeMetricsReplicas", err.Error())
+				logger.Info("Failed to compute desired number of replicas based on listed metrics.", "reference", reference, "decisionReason", wpa.Status.LastDecisionReason, "error", err)
 				return nil
 			}
-			r.eventRecorder.Event(wpa, corev1.EventTypeWarning, "FailedComputeMetricsReplicas", err.Error())
-			logger.Info("Failed to compute desired number of replicas based on listed metrics.", "reference", reference, "error", err)
-			return nil
+			r.recorder().Event(wpa, corev1.EventTypeWarning, "FailedComputeMetricsReplicas", err.Error())
+			metricErrorPolicy = wpa.Spec.MetricErrorPolicy
+			logger.Info("Failed to compute desired number of replicas based on listed metrics, applying the metric error policy", "metricErrorPolicy", metricErrorPolicy, "proposedReplicas", fallbackReplicas, "reference", reference, "error", err)
+			proposedReplicas = fallbackReplicas
+			metricStatuses = knownMetricStatuses
+			metricTimestamp = time.Now()
+		} else {
+			r.metricErrors.success(key)
+			r.lastRecommendations.set(key, proposedReplicas)
+			logger.Info("Proposing replicas", "proposedReplicas", proposedReplicas, "metricName", metricName, "reference", reference)
 		}
-		logger.Info("Proposing replicas", "proposedReplicas", proposedReplicas, "metricName", metricName, "reference", reference)
 
 		rescaleMetric := ""
 		if proposedReplicas > desiredReplicas {
@@ -239,46 +446,113 @@ func (r *WatermarkPodAutoscalerReconciler) reconcileWPA(logger logr.Logger, wpa
 			now = metricTimestamp
 			rescaleMetric = metricName
 		}
+		if metricErrorPolicy == "" && (wpa.Spec.UpscaleDelayCount > 1 || wpa.Spec.DownscaleDelayCount > 1 || wpa.Spec.UpscaleDelaySeconds > 0 || wpa.Spec.DownscaleDelaySeconds > 0) {
+			upscaleDelay := time.Duration(wpa.Spec.UpscaleDelaySeconds) * time.Second
+			downscaleDelay := time.Duration(wpa.Spec.DownscaleDelaySeconds) * time.Second
+			breachReplicas := r.breaches.observe(key, time.Now(), currentReplicas, desiredReplicas, wpa.Spec.UpscaleDelayCount, wpa.Spec.DownscaleDelayCount, upscaleDelay, downscaleDelay)
+			if breachReplicas != desiredReplicas {
+				logger.Info("Waiting for the breach of the watermarks to persist before scaling", "desiredReplicas", desiredReplicas, "upscaleDelayCount", wpa.Spec.UpscaleDelayCount, "downscaleDelayCount", wpa.Spec.DownscaleDelayCount, "upscaleDelaySeconds", wpa.Spec.UpscaleDelaySeconds, "downscaleDelaySeconds", wpa.Spec.DownscaleDelaySeconds)
+				wpa.Status.LastDecisionReason = datadoghqv1alpha1.DecisionReasonBreachDelayed
+			}
+			desiredReplicas = breachReplicas
+		}
+		if wpa.Spec.UpscaleStabilizationWindowSeconds > 0 || wpa.Spec.DownscaleStabilizationWindowSeconds > 0 {
+			upscaleWindow := time.Duration(wpa.Spec.UpscaleStabilizationWindowSeconds) * time.Second
+			downscaleWindow := time.Duration(wpa.Spec.DownscaleStabilizationWindowSeconds) * time.Second
+			stabilizedReplicas := r.recommendations.stabilize(key, time.Now(), currentReplicas, desiredReplicas, upscaleWindow, downscaleWindow)
+			if stabilizedReplicas != desiredReplicas {
+				wpa.Status.LastDecisionReason = datadoghqv1alpha1.DecisionReasonStabilized
+			}
+			desiredReplicas = stabilizedReplicas
+			logger.Info("Stabilized Desired replicas", "desiredReplicas", desiredReplicas, "proposedReplicas", proposedReplicas)
+		}
+		desiredReplicas = applyScaleDirection(logger, wpa, currentReplicas, desiredReplicas)
+		desiredReplicas = applyMinReplicaChange(logger, wpa, currentReplicas, desiredReplicas)
+		desiredReplicas = r.applyMinReadyPercentage(logger, wpa, currentScale, desiredReplicas)
 		if desiredReplicas > currentReplicas {
 			rescaleReason = fmt.Sprintf("%s above target", rescaleMetric)
 		}
 		if desiredReplicas < currentReplicas {
 			rescaleReason = "All metrics below target"
 		}
+		if metricErrorPolicy != "" && desiredReplicas != currentReplicas {
+			rescaleReason = fmt.Sprintf("No metric available, applying the %s metric error policy", metricErrorPolicy)
+		}
 
+		prenormalizedDesiredReplicas := desiredReplicas
 		desiredReplicas = normalizeDesiredReplicas(logger, wpa, currentReplicas, desiredReplicas)
-		logger.Info("Normalized Desired replicas", "desiredReplicas", desiredReplicas)
+		logger.Info("Normalized Desired replicas", "prenormalizedDesiredReplicas", prenormalizedDesiredReplicas, "desiredReplicas", desiredReplicas)
+		if desiredReplicas != prenormalizedDesiredReplicas {
+			wpa.Status.LastDecisionReason = getNormalizedReason(wpa, prenormalizedDesiredReplicas, desiredReplicas)
+		}
 
 		rescale = shouldScale(logger, wpa, currentReplicas, desiredReplicas, now)
+		if !rescale && desiredReplicas != currentReplicas {
+			wpa.Status.LastDecisionReason = datadoghqv1alpha1.DecisionReasonInCooldown
+		}
+		setScalingLimitedReason(wpa, currentReplicas, desiredReplicas, rescale)
+	}
+	if rescale && wpa.Spec.DryRun && desiredReplicas != currentReplicas {
+		wpa.Status.LastDecisionReason = datadoghqv1alpha1.DecisionReasonDryRun
 	}
+	logger.Info("Scaling decision", "decisionReason", wpa.Status.LastDecisionReason, "currentReplicas", currentReplicas, "desiredReplicas", desiredReplicas, "rescale", rescale)
+	recommendedReplicas := desiredReplicas
 
 	if rescale {
 		setCondition(wpa, autoscalingv2.AbleToScale, corev1.ConditionTrue, datadoghqv1alpha1.ConditionReasonReadyForScale, "the last scaling time was sufficiently old as to warrant a new scale")
 		if wpa.Spec.DryRun {
-			logger.Info("DryRun mode: scaling change was inhibited", "currentReplicas", currentReplicas, "desiredReplicas", desiredReplicas)
+			logger.Info(fmt.Sprintf("DryRun mode: scaling change was inhibited, would scale from %d to %d", currentReplicas, desiredReplicas), "currentReplicas", currentReplicas, "desiredReplicas", desiredReplicas, "rescaleReason", rescaleReason)
+			dryRunReplicas.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Set(float64(desiredReplicas))
+			r.recorder().Eventf(wpa, corev1.EventTypeNormal, datadoghqv1alpha1.ReasonDryRun, "Would scale from %d to %d; reason: %s%s", currentReplicas, desiredReplicas, rescaleReason, describeScalingMetric(wpa, metricName))
+			appendRecommendationHistory(wpa, desiredReplicas, false)
 			setStatus(wpa, currentReplicas, desiredReplicas, metricStatuses, rescale)
 			return r.updateStatusIfNeeded(wpaStatusOriginal, wpa)
 		}
+		if wpa.Spec.ScaleApprovalWebhookURL != "" && desiredReplicas != currentReplicas {
+			approved, vetoReason, approvalErr := r.requestScaleApproval(wpa, currentReplicas, desiredReplicas, metricName)
+			if !approved {
+				// the change is only applied once approved, the webhook failing to answer in time counts as a veto.
+				if approvalErr != nil {
+					vetoReason = approvalErr.Error()
+				}
+				logger.Info("The scale approval webhook did not approve the scaling change", "currentReplicas", currentReplicas, "desiredReplicas", desiredReplicas, "vetoReason", vetoReason)
+				scaleVetoed.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Inc()
+				r.recorder().Eventf(wpa, corev1.EventTypeWarning, datadoghqv1alpha1.ReasonScaleVetoed, "Not scaling from %d to %d; reason: %s", currentReplicas, desiredReplicas, vetoReason)
+				setCondition(wpa, autoscalingv2.AbleToScale, corev1.ConditionFalse, datadoghqv1alpha1.ConditionReasonScaleVetoed, "the scale approval webhook did not approve the scale from %d to %d: %s", currentReplicas, desiredReplicas, vetoReason)
+				wpa.Status.LastDecisionReason = datadoghqv1alpha1.DecisionReasonScaleVetoed
+				appendRecommendationHistory(wpa, desiredReplicas, false)
+				setStatus(wpa, currentReplicas, currentReplicas, metricStatuses, false)
+				return r.updateStatusIfNeeded(wpaStatusOriginal, wpa)
+			}
+		}
 
 		currentScale.Spec.Replicas = desiredReplicas
 		_, err = r.scaleClient.Scales(wpa.Namespace).Update(context.TODO(), targetGR, currentScale, metav1.UpdateOptions{})
 		if err != nil {
-			r.eventRecorder.Eventf(wpa, corev1.EventTypeWarning, datadoghqv1alpha1.ReasonFailedScale, fmt.Sprintf("New size: %d; reason: %s; error: %v", desiredReplicas, rescaleReason, err.Error()))
+			r.recorder().Eventf(wpa, corev1.EventTypeWarning, datadoghqv1alpha1.ReasonFailedScale, fmt.Sprintf("New size: %d; reason: %s; error: %v", desiredReplicas, rescaleReason, err.Error()))
 			setCondition(wpa, autoscalingv2.AbleToScale, corev1.ConditionFalse, datadoghqv1alpha1.ConditionReasonFailedScale, "the WPA controller was unable to update the target scale: %v", err)
 			r.setCurrentReplicasInStatus(wpa, currentReplicas)
 			if err := r.updateStatusIfNeeded(wpaStatusOriginal, wpa); err != nil {
-				r.eventRecorder.Event(wpa, corev1.EventTypeWarning, datadoghqv1alpha1.ReasonFailedUpdateReplicasStatus, err.Error())
+				r.recorder().Event(wpa, corev1.EventTypeWarning, datadoghqv1alpha1.ReasonFailedUpdateReplicasStatus, err.Error())
 				setCondition(wpa, autoscalingv2.AbleToScale, corev1.ConditionFalse, datadoghqv1alpha1.ConditionReasonFailedUpdateReplicasStatus, "the WPA controller was unable to update the number of replicas: %v", err)
 				return nil
 			}
 			return nil
 		}
 		setCondition(wpa, autoscalingv2.AbleToScale, corev1.ConditionTrue, datadoghqv1alpha1.ConditionReasonSuccessfulScale, "the WPA controller was able to update the target scale to %d", desiredReplicas)
-		r.eventRecorder.Eventf(wpa, corev1.EventTypeNormal, datadoghqv1alpha1.ReasonScaling, fmt.Sprintf("New size: %d; reason: %s", desiredReplicas, rescaleReason))
+		r.recorder().Eventf(wpa, corev1.EventTypeNormal, scalingEventReason(currentReplicas, desiredReplicas), "New size: %d; old size: %d; reason: %s%s", desiredReplicas, currentReplicas, rescaleReason, describeScalingMetric(wpa, metricName))
 
 		logger.Info("Successful rescale", "currentReplicas", currentReplicas, "desiredReplicas", desiredReplicas, "rescaleReason", rescaleReason)
+		recordScaleTransition(wpa, currentReplicas, desiredReplicas)
 	} else {
-		r.eventRecorder.Eventf(wpa, corev1.EventTypeNormal, datadoghqv1alpha1.ReasonNotScaling, fmt.Sprintf("Decided not to scale %s to %d (last scale time was %v )", reference, desiredReplicas, wpa.Status.LastScaleTime))
+		if metricName != "" && desiredReplicas == currentReplicas && wpa.Status.LastDecisionReason == datadoghqv1alpha1.DecisionReasonWithinTolerance {
+			// only emitted when the metrics get back within their watermarks, not at every reconcile while they stay there.
+			if !isWithinBounds(wpaStatusOriginal) {
+				r.recorder().Eventf(wpa, corev1.EventTypeNormal, datadoghqv1alpha1.ReasonWithinBounds, "Keeping %d replicas%s", currentReplicas, describeScalingMetric(wpa, metricName))
+			}
+		} else {
+			r.recorder().Eventf(wpa, corev1.EventTypeNormal, datadoghqv1alpha1.ReasonNotScaling, fmt.Sprintf("Decided not to scale %s to %d (last scale time was %v )", reference, desiredReplicas, wpa.Status.LastScaleTime))
+		}
 		desiredReplicas = currentReplicas
 	}
 
@@ -292,10 +566,139 @@ func (r *WatermarkPodAutoscalerReconciler) reconcileWPA(logger logr.Logger, wpa
 	}
 	labelsInfo.With(promLabels).Set(1)
 
+	appendRecommendationHistory(wpa, recommendedReplicas, rescale && recommendedReplicas != currentReplicas)
 	setStatus(wpa, currentReplicas, desiredReplicas, metricStatuses, rescale)
 	return r.updateStatusIfNeeded(wpaStatusOriginal, wpa)
 }
 
+// appendRecommendationHistory appends the recommendation of the reconcile cycle to the recommendationHistory of the WPA,
+// and drops the oldest entries beyond its recommendationHistoryLimit.
+func appendRecommendationHistory(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, recommendation int32, applied bool) {
+	entry := datadoghqv1alpha1.RecommendationHistoryEntry{
+		Timestamp:      metav1.Now(),
+		Recommendation: recommendation,
+		Applied:        applied,
+	}
+	if wpa.Status.ScalingMetricValue != nil {
+		utilization := wpa.Status.ScalingMetricValue.DeepCopy()
+		entry.Utilization = &utilization
+	}
+	history := append(wpa.Status.RecommendationHistory, entry)
+	if limit := getRecommendationHistoryLimit(wpa); len(history) > limit {
+		history = append([]datadoghqv1alpha1.RecommendationHistoryEntry(nil), history[len(history)-limit:]...)
+	}
+	wpa.Status.RecommendationHistory = history
+}
+
+// getRecommendationHistoryLimit returns the number of recommendations kept in the status of the WPA, 10 if it is unset.
+func getRecommendationHistoryLimit(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler) int {
+	if wpa.Spec.RecommendationHistoryLimit > 0 {
+		return int(wpa.Spec.RecommendationHistoryLimit)
+	}
+	return datadoghqv1alpha1.DefaultRecommendationHistoryLimit
+}
+
+// recordScaleTransition exposes the time and the direction of a change of the number of replicas applied to the target.
+func recordScaleTransition(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, currentReplicas, desiredReplicas int32) {
+	if desiredReplicas == currentReplicas {
+		return
+	}
+	promLabelsForWpa := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}
+	lastScaleTimestamp.With(promLabelsForWpa).Set(float64(time.Now().Unix()))
+	promLabelsForWpa[directionPromLabel] = upPromLabelVal
+	if desiredReplicas < currentReplicas {
+		promLabelsForWpa[directionPromLabel] = downPromLabelVal
+	}
+	scaleTransitions.With(promLabelsForWpa).Inc()
+}
+
+// scalingEventReason returns the reason of the event emitted when the target is scaled.
+func scalingEventReason(currentReplicas, desiredReplicas int32) string {
+	if desiredReplicas < currentReplicas {
+		return datadoghqv1alpha1.ReasonScaledDown
+	}
+	return datadoghqv1alpha1.ReasonScaledUp
+}
+
+// describeScalingMetric returns the metric that drove the recommendation and its value, to be added to the events.
+// It is empty when the recommendation doesn't come from the metrics (e.g. when the replicas are out of the WPA bounds).
+func describeScalingMetric(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, metricName string) string {
+	if metricName == "" || wpa.Status.ScalingMetricValue == nil {
+		return ""
+	}
+	description := fmt.Sprintf("; metric: %s; utilization: %s", metricName, wpa.Status.ScalingMetricValue.String())
+	if lowMark, highMark := getMetricWatermarks(wpa, metricName); lowMark != nil && highMark != nil {
+		description += fmt.Sprintf("; low watermark: %s; high watermark: %s", lowMark.String(), highMark.String())
+	}
+	return description
+}
+
+// getMetricWatermarks returns the watermarks of the metric of the spec matching metricName, as formatted in computeReplicasForMetrics.
+func getMetricWatermarks(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, metricName string) (lowMark, highMark *resource.Quantity) {
+	for _, metricSpec := range wpa.Spec.Metrics {
+		switch metricSpec.Type {
+		case datadoghqv1alpha1.ExternalMetricSourceType:
+			if metricSpec.External != nil && metricSpec.External.MetricSelector != nil && fmt.Sprintf("%s{%v}", metricSpec.External.MetricName, metricSpec.External.MetricSelector.MatchLabels) == metricName {
+				return metricSpec.External.GetWatermarks()
+			}
+		case datadoghqv1alpha1.ResourceMetricSourceType:
+			if metricSpec.Resource != nil && metricSpec.Resource.MetricSelector != nil && fmt.Sprintf("%s{%v}", metricSpec.Resource.Name, metricSpec.Resource.MetricSelector.MatchLabels) == metricName {
+				return metricSpec.Resource.LowWatermark, metricSpec.Resource.HighWatermark
+			}
+		case datadoghqv1alpha1.ObjectMetricSourceType:
+			if metricSpec.Object != nil && getObjectMetricName(metricSpec.Object) == metricName {
+				return metricSpec.Object.LowWatermark, metricSpec.Object.HighWatermark
+			}
+		case datadoghqv1alpha1.PodsMetricSourceType:
+			if metricSpec.Pods != nil && getPodsMetricName(metricSpec.Pods) == metricName {
+				return metricSpec.Pods.LowWatermark, metricSpec.Pods.HighWatermark
+			}
+		}
+	}
+	return nil, nil
+}
+
+// getClock returns the clock of the reconciler, the real one when none is configured (e.g. in unit tests).
+func (r *WatermarkPodAutoscalerReconciler) getClock() clock.Clock {
+	if r.clock == nil {
+		return clock.RealClock{}
+	}
+	return r.clock
+}
+
+// getMetricFetchTimeout returns the time given to the metrics providers to answer a query.
+func (r *WatermarkPodAutoscalerReconciler) getMetricFetchTimeout() time.Duration {
+	if r.MetricFetchTimeout <= 0 {
+		return defaultMetricFetchTimeout
+	}
+	return r.MetricFetchTimeout
+}
+
+// getRandom returns the random source of the reconciler, math/rand when none is configured.
+func (r *WatermarkPodAutoscalerReconciler) getRandom() func() float64 {
+	if r.random == nil {
+		return rand.Float64
+	}
+	return r.random
+}
+
+// recorder returns the event recorder of the reconciler.
+// The events are dropped when none is configured (e.g. in unit tests).
+// getAPIReader returns the reader of the objects the controller doesn't watch, r.Client when none is set.
+func (r *WatermarkPodAutoscalerReconciler) getAPIReader() client.Reader {
+	if r.apiReader == nil {
+		return r.Client
+	}
+	return r.apiReader
+}
+
+func (r *WatermarkPodAutoscalerReconciler) recorder() record.EventRecorder {
+	if r.eventRecorder == nil {
+		return &record.FakeRecorder{}
+	}
+	return r.eventRecorder
+}
+
 // getScaleForResourceMappings attempts to fetch the scale for the
 // resource with the given name and namespace, trying each RESTMapping
 // in turn until a working one is found.  If none work, the first error
@@ -334,11 +737,13 @@ func shouldScale(logger logr.Logger, wpa *datadoghqv1alpha1.WatermarkPodAutoscal
 
 	if downscaleCountdown > 0 {
 		transitionCountdown.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, transitionPromLabel: "downscale", resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Set(downscaleCountdown)
+		forbiddenWindowActive.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, transitionPromLabel: "downscale", resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Set(1)
 		setCondition(wpa, autoscalingv2.AbleToScale, corev1.ConditionFalse, datadoghqv1alpha1.ConditionReasonBackOffDownscale, "the time since the previous scale is still within the downscale forbidden window")
 		backoffDown = true
-		logger.Info("Too early to downscale", "lastScaleTime", wpa.Status.LastScaleTime, "nextDownscaleTimestamp", metav1.Time{Time: wpa.Status.LastScaleTime.Add(downscaleForbiddenWindow)}, "lastMetricsTimestamp", metav1.Time{Time: timestamp})
+		logger.Info("Too early to downscale", "remainingSeconds", math.Ceil(downscaleCountdown), "lastScaleTime", wpa.Status.LastScaleTime, "nextDownscaleTimestamp", metav1.Time{Time: wpa.Status.LastScaleTime.Add(downscaleForbiddenWindow)}, "lastMetricsTimestamp", metav1.Time{Time: timestamp})
 	} else {
 		transitionCountdown.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, transitionPromLabel: "downscale", resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Set(0)
+		forbiddenWindowActive.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, transitionPromLabel: "downscale", resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Set(0)
 	}
 	upscaleForbiddenWindow := time.Duration(wpa.Spec.UpscaleForbiddenWindowSeconds) * time.Second
 	upscaleCountdown := wpa.Status.LastScaleTime.Add(upscaleForbiddenWindow).Sub(timestamp).Seconds()
@@ -346,8 +751,9 @@ func shouldScale(logger logr.Logger, wpa *datadoghqv1alpha1.WatermarkPodAutoscal
 	// Only upscale if there was no rescaling in the last upscaleForbiddenWindow
 	if upscaleCountdown > 0 {
 		transitionCountdown.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, transitionPromLabel: "upscale", resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Set(upscaleCountdown)
+		forbiddenWindowActive.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, transitionPromLabel: "upscale", resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Set(1)
 		backoffUp = true
-		logger.Info("Too early to upscale", "lastScaleTime", wpa.Status.LastScaleTime, "nextUpscaleTimestamp", metav1.Time{Time: wpa.Status.LastScaleTime.Add(upscaleForbiddenWindow)}, "lastMetricsTimestamp", metav1.Time{Time: timestamp})
+		logger.Info("Too early to upscale", "remainingSeconds", math.Ceil(upscaleCountdown), "lastScaleTime", wpa.Status.LastScaleTime, "nextUpscaleTimestamp", metav1.Time{Time: wpa.Status.LastScaleTime.Add(upscaleForbiddenWindow)}, "lastMetricsTimestamp", metav1.Time{Time: timestamp})
 
 		if backoffDown {
 			setCondition(wpa, autoscalingv2.AbleToScale, corev1.ConditionFalse, datadoghqv1alpha1.ConditionReasonBackOff, "the time since the previous scale is still within both the downscale and upscale forbidden windows")
@@ -356,6 +762,7 @@ func shouldScale(logger logr.Logger, wpa *datadoghqv1alpha1.WatermarkPodAutoscal
 		}
 	} else {
 		transitionCountdown.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, transitionPromLabel: "upscale", resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Set(0)
+		forbiddenWindowActive.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, transitionPromLabel: "upscale", resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Set(0)
 	}
 
 	return canScale(logger, backoffUp, backoffDown, currentReplicas, desiredReplicas)
@@ -393,23 +800,39 @@ func (r *WatermarkPodAutoscalerReconciler) updateWPA(wpa *datadoghqv1alpha1.Wate
 // desired replicas, as well as the metric statuses
 func setStatus(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, currentReplicas, desiredReplicas int32, metricStatuses []autoscalingv2.MetricStatus, rescale bool) {
 	wpa.Status = datadoghqv1alpha1.WatermarkPodAutoscalerStatus{
-		CurrentReplicas: currentReplicas,
-		DesiredReplicas: desiredReplicas,
-		CurrentMetrics:  metricStatuses,
-		LastScaleTime:   wpa.Status.LastScaleTime,
-		Conditions:      wpa.Status.Conditions,
+		CurrentReplicas:       currentReplicas,
+		DesiredReplicas:       desiredReplicas,
+		CurrentMetrics:        metricStatuses,
+		LastScaleTime:         wpa.Status.LastScaleTime,
+		Conditions:            wpa.Status.Conditions,
+		ScalingMetricName:     wpa.Status.ScalingMetricName,
+		ScalingMetricValue:    wpa.Status.ScalingMetricValue,
+		ScalingMetricPosition: wpa.Status.ScalingMetricPosition,
+		LastDecisionReason:    wpa.Status.LastDecisionReason,
+		ExternalMetricSeries:  wpa.Status.ExternalMetricSeries,
+		RecommendationHistory: wpa.Status.RecommendationHistory,
 	}
 
 	if rescale {
 		now := metav1.NewTime(time.Now())
 		wpa.Status.LastScaleTime = &now
 	}
+
+	promLabelsForWpa := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}
+	replicaCurrent.With(promLabelsForWpa).Set(float64(currentReplicas))
+	replicaDesired.With(promLabelsForWpa).Set(float64(desiredReplicas))
 }
 
 func (r *WatermarkPodAutoscalerReconciler) computeReplicasForMetrics(logger logr.Logger, wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, scale *autoscalingv1.Scale) (replicas int32, metric string, statuses []autoscalingv2.MetricStatus, timestamp time.Time, err error) {
-	statuses = make([]autoscalingv2.MetricStatus, len(wpa.Spec.Metrics))
+	statuses = make([]autoscalingv2.MetricStatus, 0, len(wpa.Spec.Metrics))
 
 	labels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}
+	start := r.getClock().Now()
+	// labels gets the name of the metrics later on, the duration is observed for the whole WPA.
+	durationLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}
+	defer func() {
+		reconcileDuration.With(durationLabels).Observe(r.getClock().Since(start).Seconds())
+	}()
 	minReplicas := float64(0)
 	if wpa.Spec.MinReplicas != nil {
 		minReplicas = float64(*wpa.Spec.MinReplicas)
@@ -417,117 +840,373 @@ func (r *WatermarkPodAutoscalerReconciler) computeReplicasForMetrics(logger logr
 	replicaMin.With(labels).Set(minReplicas)
 	replicaMax.With(labels).Set(float64(wpa.Spec.MaxReplicas))
 
-	for i, metricSpec := range wpa.Spec.Metrics {
-		if metricSpec.External == nil && metricSpec.Resource == nil {
+	// A metric that can't be computed should not prevent the other ones from driving the scaling.
+	// We only fail if none of the metrics could be used.
+	var invalidMetricsCount int
+	var invalidMetricError, invalidMetricConditionError error
+	var invalidMetricConditionReason string
+	// whether one of the metrics that can't be used is stale, to tell it apart from an unavailable one.
+	var staleMetricFound bool
+	var utilization int64
+	var reason string
+	var position string
+	// the metric name labels of the metrics that could be computed, and the one of the highest recommendation.
+	var computedMetricLabels []string
+	var winningMetricLabel string
+	// the position of the metric with the highest recommendation, kept when the metrics are combined with a weighted-sum.
+	var winningPosition string
+	// the load ratios of the metrics that could be computed, for the weighted-sum aggregation.
+	var recommendations []weightedRecommendation
+	// the series of the external metrics that could be computed, only reported with debug.
+	var externalMetricSeries []datadoghqv1alpha1.ExternalMetricSeriesStatus
+
+	for _, metricSpec := range getScheduledMetrics(logger, r.getSourcedWPA(logger, wpa), start) {
+		if metricSpec.External == nil && metricSpec.Resource == nil && metricSpec.Object == nil && metricSpec.Pods == nil {
 			continue
 		}
 
-		var replicaCountProposal int32
-		var utilizationProposal int64
-		var timestampProposal time.Time
-		var metricNameProposal string
+		var source metricSource
 		switch metricSpec.Type {
 		case datadoghqv1alpha1.ExternalMetricSourceType:
-			if metricSpec.External.HighWatermark != nil && metricSpec.External.LowWatermark != nil {
-				metricNameProposal = fmt.Sprintf("%s{%v}", metricSpec.External.MetricName, metricSpec.External.MetricSelector.MatchLabels)
-
-				promLabelsForWpaWithMetricName := prometheus.Labels{
-					wpaNamePromLabel:           wpa.Name,
-					resourceNamespacePromLabel: wpa.Namespace,
-					resourceNamePromLabel:      wpa.Spec.ScaleTargetRef.Name,
-					resourceKindPromLabel:      wpa.Spec.ScaleTargetRef.Kind,
-					metricNamePromLabel:        metricSpec.External.MetricName,
-				}
-
-				replicaCalculation, errMetricsServer := r.replicaCalc.GetExternalMetricReplicas(logger, scale, metricSpec, wpa)
-				if errMetricsServer != nil {
-					replicaProposal.Delete(promLabelsForWpaWithMetricName)
-					r.eventRecorder.Event(wpa, corev1.EventTypeWarning, datadoghqv1alpha1.ConditionReasonFailedGetExternalMetrics, errMetricsServer.Error())
-					setCondition(wpa, autoscalingv2.ScalingActive, corev1.ConditionFalse, datadoghqv1alpha1.ConditionReasonFailedGetExternalMetrics, "the HPA was unable to compute the replica count: %v", errMetricsServer)
-					return 0, "", nil, time.Time{}, fmt.Errorf("failed to get external metric %s: %v", metricSpec.External.MetricName, errMetricsServer)
-				}
-				replicaCountProposal = replicaCalculation.replicaCount
-				utilizationProposal = replicaCalculation.utilization
-				timestampProposal = replicaCalculation.timestamp
-
-				lowwm.With(promLabelsForWpaWithMetricName).Set(float64(metricSpec.External.LowWatermark.MilliValue()))
-				lowwmV2.With(promLabelsForWpaWithMetricName).Set(float64(metricSpec.External.LowWatermark.MilliValue()))
-				highwm.With(promLabelsForWpaWithMetricName).Set(float64(metricSpec.External.HighWatermark.MilliValue()))
-				highwmV2.With(promLabelsForWpaWithMetricName).Set(float64(metricSpec.External.HighWatermark.MilliValue()))
-				replicaProposal.With(promLabelsForWpaWithMetricName).Set(float64(replicaCountProposal))
-
-				statuses[i] = autoscalingv2.MetricStatus{
-					Type: autoscalingv2.ExternalMetricSourceType,
-					External: &autoscalingv2.ExternalMetricStatus{
-						MetricSelector: metricSpec.External.MetricSelector,
-						MetricName:     metricSpec.External.MetricName,
-						CurrentValue:   *resource.NewMilliQuantity(utilizationProposal, resource.DecimalSI),
-					},
-				}
-			} else {
-				errMsg := "invalid external metric source: the high watermark and the low watermark are required"
-				r.eventRecorder.Event(wpa, corev1.EventTypeWarning, "FailedGetExternalMetric", errMsg)
-				setCondition(wpa, autoscalingv2.ScalingActive, corev1.ConditionFalse, datadoghqv1alpha1.ConditionReasonFailedGetExternalMetrics, "the WPA was unable to compute the replica count: %v", err)
-				return 0, "", nil, time.Time{}, fmt.Errorf(errMsg)
+			source = metricSource{
+				kind:            "external",
+				name:            fmt.Sprintf("%s{%v}", metricSpec.External.MetricName, metricSpec.External.MetricSelector.MatchLabels),
+				label:           metricSpec.External.MetricName,
+				failedReason:    datadoghqv1alpha1.ConditionReasonFailedGetExternalMetrics,
+				lowMark:         metricSpec.External.LowWatermark,
+				highMark:        metricSpec.External.HighWatermark,
+				getReplicas:     r.replicaCalc.GetExternalMetricReplicas,
+				getMetricStatus: getExternalMetricStatus,
 			}
+			source.description = fmt.Sprintf("external metric %s", metricSpec.External.MetricName)
 		case datadoghqv1alpha1.ResourceMetricSourceType:
-			if metricSpec.Resource.HighWatermark != nil && metricSpec.Resource.LowWatermark != nil {
-				metricNameProposal = fmt.Sprintf("%s{%v}", metricSpec.Resource.Name, metricSpec.Resource.MetricSelector.MatchLabels)
-				promLabelsForWpaWithMetricName := prometheus.Labels{
-					wpaNamePromLabel:           wpa.Name,
-					resourceNamespacePromLabel: wpa.Namespace,
-					resourceNamePromLabel:      wpa.Spec.ScaleTargetRef.Name,
-					resourceKindPromLabel:      wpa.Spec.ScaleTargetRef.Kind,
-					metricNamePromLabel:        string(metricSpec.Resource.Name),
-				}
-
-				replicaCalculation, errMetricsServer := r.replicaCalc.GetResourceReplicas(logger, scale, metricSpec, wpa)
-				if errMetricsServer != nil {
-					replicaProposal.Delete(promLabelsForWpaWithMetricName)
-					r.eventRecorder.Event(wpa, corev1.EventTypeWarning, datadoghqv1alpha1.ConditionReasonFailedGetResourceMetric, errMetricsServer.Error())
-					setCondition(wpa, autoscalingv2.ScalingActive, corev1.ConditionFalse, datadoghqv1alpha1.ConditionReasonFailedGetResourceMetric, "the WPA was unable to compute the replica count: %v", errMetricsServer)
-					return 0, "", nil, time.Time{}, fmt.Errorf("failed to get resource metric %s: %v", metricSpec.Resource.Name, errMetricsServer)
-				}
-				replicaCountProposal = replicaCalculation.replicaCount
-				utilizationProposal = replicaCalculation.utilization
-				timestampProposal = replicaCalculation.timestamp
-
-				lowwm.With(promLabelsForWpaWithMetricName).Set(float64(metricSpec.Resource.LowWatermark.MilliValue()))
-				lowwmV2.With(promLabelsForWpaWithMetricName).Set(float64(metricSpec.Resource.LowWatermark.MilliValue()))
-				highwm.With(promLabelsForWpaWithMetricName).Set(float64(metricSpec.Resource.HighWatermark.MilliValue()))
-				highwmV2.With(promLabelsForWpaWithMetricName).Set(float64(metricSpec.Resource.HighWatermark.MilliValue()))
-				replicaProposal.With(promLabelsForWpaWithMetricName).Set(float64(replicaCountProposal))
-
-				statuses[i] = autoscalingv2.MetricStatus{
-					Type: autoscalingv2.ResourceMetricSourceType,
-					Resource: &autoscalingv2.ResourceMetricStatus{
-						Name:                metricSpec.Resource.Name,
-						CurrentAverageValue: *resource.NewMilliQuantity(utilizationProposal, resource.DecimalSI),
-					},
-				}
-
-			} else {
-				errMsg := "invalid resource metric source: the high watermark and the low watermark are required"
-				r.eventRecorder.Event(wpa, corev1.EventTypeWarning, datadoghqv1alpha1.ConditionReasonFailedGetResourceMetric, errMsg)
-				setCondition(wpa, autoscalingv2.ScalingActive, corev1.ConditionFalse, datadoghqv1alpha1.ConditionReasonFailedGetResourceMetric, "the WPA was unable to compute the replica count: %v", err)
-				return 0, "", nil, time.Time{}, fmt.Errorf(errMsg)
+			source = metricSource{
+				kind:            "resource",
+				name:            fmt.Sprintf("%s{%v}", metricSpec.Resource.Name, metricSpec.Resource.MetricSelector.MatchLabels),
+				label:           string(metricSpec.Resource.Name),
+				failedReason:    datadoghqv1alpha1.ConditionReasonFailedGetResourceMetric,
+				lowMark:         metricSpec.Resource.LowWatermark,
+				highMark:        metricSpec.Resource.HighWatermark,
+				getReplicas:     r.replicaCalc.GetResourceMetricReplicas,
+				getMetricStatus: getResourceMetricStatus,
 			}
-
+			source.description = fmt.Sprintf("resource metric %s", metricSpec.Resource.Name)
+		case datadoghqv1alpha1.ObjectMetricSourceType:
+			source = metricSource{
+				kind:            "object",
+				name:            getObjectMetricName(metricSpec.Object),
+				label:           metricSpec.Object.MetricName,
+				failedReason:    datadoghqv1alpha1.ConditionReasonFailedGetObjectMetric,
+				lowMark:         metricSpec.Object.LowWatermark,
+				highMark:        metricSpec.Object.HighWatermark,
+				getReplicas:     r.replicaCalc.GetObjectMetricReplicas,
+				getMetricStatus: getObjectMetricStatus,
+			}
+			source.description = fmt.Sprintf("object metric %s", source.name)
+		case datadoghqv1alpha1.PodsMetricSourceType:
+			source = metricSource{
+				kind:            "pods",
+				name:            getPodsMetricName(metricSpec.Pods),
+				label:           metricSpec.Pods.MetricName,
+				failedReason:    datadoghqv1alpha1.ConditionReasonFailedGetPodsMetric,
+				lowMark:         metricSpec.Pods.LowWatermark,
+				highMark:        metricSpec.Pods.HighWatermark,
+				getReplicas:     r.replicaCalc.GetPodsMetricReplicas,
+				getMetricStatus: getPodsMetricStatus,
+			}
+			source.description = fmt.Sprintf("pods metric %s", source.name)
 		default:
 			return 0, "", nil, time.Time{}, fmt.Errorf("metricSpec.Type:%s not supported", metricSpec.Type)
 		}
-		// replicas will end up being the max of the replicaCountProposal if there are several metrics
-		if replicas == 0 || replicaCountProposal > replicas {
-			timestamp = timestampProposal
-			replicas = replicaCountProposal
-			metric = metricNameProposal
+		if source.lowMark == nil || source.highMark == nil {
+			errMsg := fmt.Sprintf("invalid %s metric source: the high watermark and the low watermark are required", source.kind)
+			r.recorder().Event(wpa, corev1.EventTypeWarning, source.failedReason, errMsg)
+			setCondition(wpa, autoscalingv2.ScalingActive, corev1.ConditionFalse, source.failedReason, "the WPA was unable to compute the replica count: %v", errMsg)
+			return 0, "", nil, time.Time{}, fmt.Errorf(errMsg)
+		}
+
+		replicaCalculation, errMetricsServer := r.computeMetricReplicas(logger, wpa, scale, metricSpec, source)
+		if errMetricsServer != nil {
+			invalidMetricsCount++
+			staleMetricFound = staleMetricFound || replicaCalculation.reason == datadoghqv1alpha1.DecisionReasonMetricStale
+			if invalidMetricError == nil {
+				invalidMetricError = fmt.Errorf("failed to get %s: %v", source.description, errMetricsServer)
+				invalidMetricConditionReason = source.failedReason
+				invalidMetricConditionError = errMetricsServer
+			}
+			continue
+		}
+		if wpa.Spec.Debug && replicaCalculation.series != nil {
+			externalMetricSeries = append(externalMetricSeries, getExternalMetricSeriesStatus(source.name, replicaCalculation.series))
+		}
+		statuses = append(statuses, source.getMetricStatus(metricSpec, replicaCalculation.utilization))
+		computedMetricLabels = append(computedMetricLabels, source.label)
+		recommendations = append(recommendations, weightedRecommendation{ratio: getLoadRatio(metricSpec, replicaCalculation.utilization, replicaCalculation.position, replicaCalculation.reason), weight: getMetricWeight(metricSpec)})
+		// replicas will end up being the max of the replica counts if there are several metrics
+		if replicas == 0 || replicaCalculation.replicaCount > replicas {
+			timestamp = replicaCalculation.timestamp
+			replicas = replicaCalculation.replicaCount
+			metric = source.name
+			winningMetricLabel = source.label
+			utilization = replicaCalculation.utilization
+			reason = replicaCalculation.reason
+			position = replicaCalculation.position
+			winningPosition = replicaCalculation.position
+		}
+	}
+	// reported along with the other metrics when none of them could be used, and cleared once debug is disabled.
+	wpa.Status.ExternalMetricSeries = externalMetricSeries
+
+	// If none of the metrics are valid, we return the error of the first invalid one and don't scale.
+	if invalidMetricsCount > 0 && len(statuses) == 0 {
+		cleanupRestrictedScalingMetrics(wpa)
+		setCondition(wpa, autoscalingv2.ScalingActive, corev1.ConditionFalse, invalidMetricConditionReason, "the WPA was unable to compute the replica count: %v", invalidMetricConditionError)
+		// no metric drives the scaling anymore, the status doesn't point at the last one that could be used.
+		wpa.Status.ScalingMetricName = ""
+		wpa.Status.ScalingMetricValue = nil
+		wpa.Status.ScalingMetricPosition = ""
+		wpa.Status.LastDecisionReason = datadoghqv1alpha1.DecisionReasonMetricUnavailable
+		if staleMetricFound {
+			wpa.Status.LastDecisionReason = datadoghqv1alpha1.DecisionReasonMetricStale
 		}
+		return 0, "", nil, time.Time{}, invalidMetricError
+	}
+	if invalidMetricsCount > 0 {
+		logger.Info("Some metrics could not be computed, scaling on the valid ones", "invalidMetricsCount", invalidMetricsCount, "validMetricsCount", len(statuses), "error", invalidMetricError)
+	}
+	scalingMetricValue := resource.NewMilliQuantity(utilization, resource.DecimalSI)
+	// without any replica to scale from, the highest recommendation is kept to scale up from zero.
+	if wpa.Spec.MetricAggregation == "weighted-sum" && scale.Status.Replicas > 0 {
+		weightedRatio := getWeightedLoadRatio(recommendations)
+		replicas = roundReplicas(float64(scale.Status.Replicas)*weightedRatio, getReplicaRounding(wpa, "ceil"))
+		metric = "weighted-sum"
+		// the metrics have different units, there is no single value to report for the combination.
+		scalingMetricValue = nil
+		// the reason is derived from the combined recommendation rather than from a single metric.
+		reason = ""
+		position = ""
+		logger.Info("Combined the utilization of the metrics", "metricAggregation", wpa.Spec.MetricAggregation, "weightedRatio", weightedRatio, "replicaCount", replicas)
 	}
 	setCondition(wpa, autoscalingv2.ScalingActive, corev1.ConditionTrue, datadoghqv1alpha1.ConditionValidMetricFound, "the HPA was able to successfully calculate a replica count from %s", metric)
+	wpa.Status.ScalingMetricName = metric
+	wpa.Status.ScalingMetricValue = scalingMetricValue
+	wpa.Status.ScalingMetricPosition = position
+	wpa.Status.LastDecisionReason = getRecommendationReason(reason, scale.Status.Replicas, replicas)
+	for _, metricLabel := range computedMetricLabels {
+		labels[metricNamePromLabel] = metricLabel
+		if metricLabel == winningMetricLabel {
+			winningMetric.With(labels).Set(1)
+		} else {
+			winningMetric.With(labels).Set(0)
+		}
+	}
+	recordWithinBounds(wpa, winningMetricLabel, winningPosition)
 
 	return replicas, metric, statuses, timestamp, nil
 }
 
+// metricSource describes a metric of the spec for computeReplicasForMetrics, whatever its type.
+type metricSource struct {
+	// kind is the type of the metric in the error messages.
+	kind string
+	// name identifies the metric in the status and the events, label in the metrics of the controller.
+	name  string
+	label string
+	// description identifies the metric in the error returned when none of the metrics can be used.
+	description string
+	// failedReason is the reason of the condition and of the event when the metric can't be used.
+	failedReason      string
+	lowMark, highMark *resource.Quantity
+	getReplicas       func(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, metric datadoghqv1alpha1.MetricSpec, wpa *datadoghqv1alpha1.WatermarkPodAutoscaler) (ReplicaCalculation, error)
+	getMetricStatus   func(metricSpec datadoghqv1alpha1.MetricSpec, utilization int64) autoscalingv2.MetricStatus
+}
+
+// computeMetricReplicas returns the recommendation of a single metric, and exposes it with the metrics of the controller.
+// A metric that can't be computed is reported with an event, its gauges are removed and it is flagged as unavailable.
+func (r *WatermarkPodAutoscalerReconciler) computeMetricReplicas(logger logr.Logger, wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, scale *autoscalingv1.Scale, metricSpec datadoghqv1alpha1.MetricSpec, source metricSource) (ReplicaCalculation, error) {
+	promLabelsForWpaWithMetricName := prometheus.Labels{
+		wpaNamePromLabel:           wpa.Name,
+		resourceNamespacePromLabel: wpa.Namespace,
+		resourceNamePromLabel:      wpa.Spec.ScaleTargetRef.Name,
+		resourceKindPromLabel:      wpa.Spec.ScaleTargetRef.Kind,
+		metricNamePromLabel:        source.label,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.getMetricFetchTimeout())
+	defer cancel()
+	replicaCalculation, err := source.getReplicas(ctx, logger, scale, metricSpec, wpa)
+	if err != nil {
+		replicaProposal.Delete(promLabelsForWpaWithMetricName)
+		winningMetric.Delete(promLabelsForWpaWithMetricName)
+		metricUnavailable.With(promLabelsForWpaWithMetricName).Set(1)
+		r.recorder().Eventf(wpa, corev1.EventTypeWarning, datadoghqv1alpha1.ReasonMetricUnavailable, "Metric %s is unavailable: %v", source.name, err)
+		logger.Info("Failed to compute the replica count for metric", "metricName", source.name, "error", err)
+		return replicaCalculation, err
+	}
+	lowwm.With(promLabelsForWpaWithMetricName).Set(getMilliValue(source.lowMark))
+	lowwmV2.With(promLabelsForWpaWithMetricName).Set(getMilliValue(source.lowMark))
+	highwm.With(promLabelsForWpaWithMetricName).Set(getMilliValue(source.highMark))
+	highwmV2.With(promLabelsForWpaWithMetricName).Set(getMilliValue(source.highMark))
+	replicaProposal.With(promLabelsForWpaWithMetricName).Set(float64(replicaCalculation.replicaCount))
+	metricUnavailable.With(promLabelsForWpaWithMetricName).Set(0)
+	return replicaCalculation, nil
+}
+
+// getExternalMetricStatus returns the status of an external metric with its current value.
+func getExternalMetricStatus(metricSpec datadoghqv1alpha1.MetricSpec, utilization int64) autoscalingv2.MetricStatus {
+	return autoscalingv2.MetricStatus{
+		Type: autoscalingv2.ExternalMetricSourceType,
+		External: &autoscalingv2.ExternalMetricStatus{
+			MetricSelector: metricSpec.External.MetricSelector,
+			MetricName:     metricSpec.External.MetricName,
+			CurrentValue:   *resource.NewMilliQuantity(utilization, resource.DecimalSI),
+		},
+	}
+}
+
+// getResourceMetricStatus returns the status of a resource metric with its current average value.
+func getResourceMetricStatus(metricSpec datadoghqv1alpha1.MetricSpec, utilization int64) autoscalingv2.MetricStatus {
+	return autoscalingv2.MetricStatus{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricStatus{
+			Name:                metricSpec.Resource.Name,
+			CurrentAverageValue: *resource.NewMilliQuantity(utilization, resource.DecimalSI),
+		},
+	}
+}
+
+// getObjectMetricStatus returns the status of an object metric with its current value.
+func getObjectMetricStatus(metricSpec datadoghqv1alpha1.MetricSpec, utilization int64) autoscalingv2.MetricStatus {
+	return autoscalingv2.MetricStatus{
+		Type: autoscalingv2.ObjectMetricSourceType,
+		Object: &autoscalingv2.ObjectMetricStatus{
+			Target: autoscalingv2.CrossVersionObjectReference{
+				Kind:       metricSpec.Object.DescribedObject.Kind,
+				Name:       metricSpec.Object.DescribedObject.Name,
+				APIVersion: metricSpec.Object.DescribedObject.APIVersion,
+			},
+			MetricName:   metricSpec.Object.MetricName,
+			Selector:     metricSpec.Object.MetricSelector,
+			CurrentValue: *resource.NewMilliQuantity(utilization, resource.DecimalSI),
+		},
+	}
+}
+
+// getPodsMetricStatus returns the status of a pods metric with its current average value.
+func getPodsMetricStatus(metricSpec datadoghqv1alpha1.MetricSpec, utilization int64) autoscalingv2.MetricStatus {
+	return autoscalingv2.MetricStatus{
+		Type: autoscalingv2.PodsMetricSourceType,
+		Pods: &autoscalingv2.PodsMetricStatus{
+			MetricName:          metricSpec.Pods.MetricName,
+			Selector:            metricSpec.Pods.MetricSelector,
+			CurrentAverageValue: *resource.NewMilliQuantity(utilization, resource.DecimalSI),
+		},
+	}
+}
+
+// getRecommendationReason returns the reason of the recommendation given by the replica calculator,
+// or derives it from the current number of replicas when the calculator didn't give any.
+func getRecommendationReason(reason string, currentReplicas, replicas int32) string {
+	switch {
+	case reason != "":
+		return reason
+	case replicas > currentReplicas:
+		return datadoghqv1alpha1.DecisionReasonAboveHighWatermark
+	case replicas < currentReplicas:
+		return datadoghqv1alpha1.DecisionReasonBelowLowWatermark
+	default:
+		return datadoghqv1alpha1.DecisionReasonWithinTolerance
+	}
+}
+
+// weightedRecommendation is the load ratio of a metric along with the weight of the metric.
+type weightedRecommendation struct {
+	ratio  float64
+	weight float64
+}
+
+// getMetricWeight returns the weight of the metric, 1 if it is unset.
+func getMetricWeight(metricSpec datadoghqv1alpha1.MetricSpec) float64 {
+	if metricSpec.Weight != nil {
+		return float64(metricSpec.Weight.MilliValue()) / 1000
+	}
+	return 1
+}
+
+// getMetricSpecWatermarks returns the low and the high watermarks of the metric, whatever its type.
+func getMetricSpecWatermarks(metricSpec datadoghqv1alpha1.MetricSpec) (lowMark, highMark *resource.Quantity) {
+	switch {
+	case metricSpec.External != nil:
+		return metricSpec.External.GetWatermarks()
+	case metricSpec.Resource != nil:
+		return metricSpec.Resource.LowWatermark, metricSpec.Resource.HighWatermark
+	case metricSpec.Object != nil:
+		return metricSpec.Object.LowWatermark, metricSpec.Object.HighWatermark
+	case metricSpec.Pods != nil:
+		return metricSpec.Pods.LowWatermark, metricSpec.Pods.HighWatermark
+	default:
+		return nil, nil
+	}
+}
+
+// getLoadRatio returns the utilization of the metric compared to the watermark it breaches: the ratio to the high
+// watermark above it, to the low watermark below it, and 1 while the metric is within its watermarks or is not allowed
+// to scale the target in the direction of the breach. The ratio is also 1 when the watermark is not positive, as the
+// utilization can't be scaled by it.
+func getLoadRatio(metricSpec datadoghqv1alpha1.MetricSpec, utilization int64, position, reason string) float64 {
+	if reason == datadoghqv1alpha1.DecisionReasonDirectionBlocked {
+		return 1
+	}
+	lowMark, highMark := getMetricSpecWatermarks(metricSpec)
+	var watermark *resource.Quantity
+	switch position {
+	case datadoghqv1alpha1.DecisionReasonAboveHighWatermark:
+		watermark = highMark
+	case datadoghqv1alpha1.DecisionReasonBelowLowWatermark, datadoghqv1alpha1.DecisionReasonBelowIdleWatermark:
+		watermark = lowMark
+	}
+	if watermark == nil || watermark.MilliValue() <= 0 {
+		return 1
+	}
+	return float64(utilization) / float64(watermark.MilliValue())
+}
+
+// getWeightedLoadRatio returns the average of the load ratios of the metrics weighted by the weight of each metric,
+// the current number of replicas is multiplied by it to bring the weighted utilization of the metrics within the watermarks.
+func getWeightedLoadRatio(recommendations []weightedRecommendation) float64 {
+	var weightedSum, totalWeight float64
+	for _, recommendation := range recommendations {
+		weightedSum += recommendation.ratio * recommendation.weight
+		totalWeight += recommendation.weight
+	}
+	if totalWeight == 0 {
+		return 1
+	}
+	return weightedSum / totalWeight
+}
+
+// getObjectMetricName returns the name of an object metric as reported in the status, along with the object it describes.
+func getObjectMetricName(source *datadoghqv1alpha1.ObjectMetricSource) string {
+	return fmt.Sprintf("%s{%s/%s}", source.MetricName, source.DescribedObject.Kind, source.DescribedObject.Name)
+}
+
+// getPodsMetricName returns the name of a pods metric as reported in the status, along with the labels of its selector when it is set.
+func getPodsMetricName(source *datadoghqv1alpha1.PodsMetricSource) string {
+	if source.MetricSelector == nil {
+		return source.MetricName
+	}
+	return fmt.Sprintf("%s{%v}", source.MetricName, source.MetricSelector.MatchLabels)
+}
+
+// getExternalMetricSeriesStatus returns the status of the series returned for an external metric, without their range when none was returned.
+func getExternalMetricSeriesStatus(name string, series *metricSeries) datadoghqv1alpha1.ExternalMetricSeriesStatus {
+	status := datadoghqv1alpha1.ExternalMetricSeriesStatus{MetricName: name, Count: series.count}
+	if series.count > 0 {
+		status.Min = resource.NewMilliQuantity(series.min, resource.DecimalSI)
+		status.Max = resource.NewMilliQuantity(series.max, resource.DecimalSI)
+	}
+	return status
+}
+
 // setCondition sets the specific condition type on the given WPA to the specified value with the given reason
 // and message.  The message and args are treated like a format string.  The condition will be added if it is
 // not present.
@@ -590,6 +1269,46 @@ func normalizeDesiredReplicas(logger logr.Logger, wpa *datadoghqv1alpha1.Waterma
 	return desiredReplicas
 }
 
+// setScalingLimitedReason overrides the ScalingLimited condition set by normalizeDesiredReplicas when the target is
+// kept at its replica count for a reason other than the bounds of the WPA, so that a single reason is reported per cycle.
+func setScalingLimitedReason(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, currentReplicas, desiredReplicas int32, rescale bool) {
+	switch {
+	case !rescale && desiredReplicas != currentReplicas:
+		setCondition(wpa, autoscalingv2.ScalingLimited, corev1.ConditionTrue, datadoghqv1alpha1.ConditionReasonForbiddenWindow, "the desired replica count %d is not applied since the time since the previous scale is within the forbidden window", desiredReplicas)
+	case desiredReplicas == currentReplicas && wpa.Status.LastDecisionReason == datadoghqv1alpha1.DecisionReasonWithinTolerance:
+		setCondition(wpa, autoscalingv2.ScalingLimited, corev1.ConditionTrue, datadoghqv1alpha1.ConditionReasonWithinTolerance, "the metrics are within their watermarks")
+	}
+}
+
+// isWithinBounds returns whether the status reports the metrics within their watermarks, with the ScalingLimited
+// condition set by setScalingLimitedReason.
+func isWithinBounds(wpaStatus *datadoghqv1alpha1.WatermarkPodAutoscalerStatus) bool {
+	for _, condition := range wpaStatus.Conditions {
+		if condition.Type == autoscalingv2.ScalingLimited {
+			return condition.Status == corev1.ConditionTrue && condition.Reason == datadoghqv1alpha1.ConditionReasonWithinTolerance
+		}
+	}
+	return false
+}
+
+// recordWithinBounds sets the within_bounds series of restricted_scaling for the metric driving the scaling only, to 1
+// when it is within its watermarks, and removes the ones of the other metrics so that the decision is not ambiguous.
+func recordWithinBounds(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, metricLabel, position string) {
+	restricted := float64(0)
+	if position == datadoghqv1alpha1.DecisionReasonWithinTolerance {
+		restricted = 1
+	}
+	restrictedScaling.With(prometheus.Labels{
+		wpaNamePromLabel:           wpa.Name,
+		resourceNamespacePromLabel: wpa.Namespace,
+		resourceNamePromLabel:      wpa.Spec.ScaleTargetRef.Name,
+		resourceKindPromLabel:      wpa.Spec.ScaleTargetRef.Kind,
+		reasonPromLabel:            withinBoundsPromLabelVal,
+		metricNamePromLabel:        metricLabel,
+	}).Set(restricted)
+	cleanupWithinBoundsMetrics(wpa, metricLabel)
+}
+
 // convertDesiredReplicas performs the actual normalization, without depending on the `WatermarkPodAutoscaler`
 func convertDesiredReplicasWithRules(logger logr.Logger, wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, currentReplicas, desiredReplicas, wpaMinReplicas, wpaMaxReplicas int32) (int32, string, string) {
 
@@ -604,18 +1323,23 @@ func convertDesiredReplicasWithRules(logger logr.Logger, wpa *datadoghqv1alpha1.
 		resourceNamespacePromLabel: wpa.Namespace,
 		resourceNamePromLabel:      wpa.Spec.ScaleTargetRef.Name,
 		resourceKindPromLabel:      wpa.Spec.ScaleTargetRef.Kind,
-		reasonPromLabel:            "downscale_capping",
+		reasonPromLabel:            downscaleCappingPromLabelVal,
+		// the capping applies to the recommendation of the WPA, not to one of its metrics.
+		metricNamePromLabel: "",
 	}
 	// Compute the maximum and minimum number of replicas we can have
 	switch {
-	case wpaMinReplicas == 0:
+	case wpaMinReplicas == 0 && !wpa.Spec.ScaleDownToZeroEnabled:
 		minimumAllowedReplicas = 1
 	case desiredReplicas < scaleDownLimit:
 		minimumAllowedReplicas = int32(math.Max(float64(scaleDownLimit), float64(wpaMinReplicas)))
 		restrictedScaling.With(promLabelsForWpa).Set(1)
+		if scaleDownLimit > wpaMinReplicas {
+			scaleDownLimited.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Inc()
+		}
 		possibleLimitingCondition = "ScaleDownLimit"
 		possibleLimitingReason = "the desired replica count is decreasing faster than the maximum scale rate"
-		logger.Info("Downscaling rate higher than limit set by `scaleDownLimitFactor`, capping the maximum downscale to 'minimumAllowedReplicas'", "scaleDownLimitFactor", fmt.Sprintf("%.1f", float64(wpa.Spec.ScaleDownLimitFactor.MilliValue()/1000)), "wpaMinReplicas", wpaMinReplicas, "minimumAllowedReplicas", minimumAllowedReplicas)
+		logger.Info("Downscaling rate higher than limit set by `scaleDownLimitFactor` and `scaleDownLimit`, capping the maximum downscale to 'minimumAllowedReplicas'", "scaleDownLimitFactor", fmt.Sprintf("%.1f", float64(wpa.Spec.ScaleDownLimitFactor.MilliValue()/1000)), "scaleDownLimit", wpa.Spec.ScaleDownLimit, "wpaMinReplicas", wpaMinReplicas, "minimumAllowedReplicas", minimumAllowedReplicas)
 	case desiredReplicas >= scaleDownLimit:
 		minimumAllowedReplicas = wpaMinReplicas
 		restrictedScaling.With(promLabelsForWpa).Set(0)
@@ -633,7 +1357,10 @@ func convertDesiredReplicasWithRules(logger logr.Logger, wpa *datadoghqv1alpha1.
 		maximumAllowedReplicas = int32(math.Min(float64(scaleUpLimit), float64(wpaMaxReplicas)))
 		promLabelsForWpa[reasonPromLabel] = upscaleCappingPromLabelVal
 		restrictedScaling.With(promLabelsForWpa).Set(1)
-		logger.Info("Upscaling rate higher than limit set by 'ScaleUpLimitFactor', capping the maximum upscale to 'maximumAllowedReplicas'", "scaleUpLimitFactor", fmt.Sprintf("%.1f", float64(wpa.Spec.ScaleUpLimitFactor.MilliValue()/1000)), "wpaMaxReplicas", wpaMaxReplicas, "maximumAllowedReplicas", maximumAllowedReplicas)
+		if scaleUpLimit < wpaMaxReplicas {
+			scaleUpLimited.With(prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}).Inc()
+		}
+		logger.Info("Upscaling rate higher than limit set by 'ScaleUpLimitFactor' and 'ScaleUpLimit', capping the maximum upscale to 'maximumAllowedReplicas'", "scaleUpLimitFactor", fmt.Sprintf("%.1f", float64(wpa.Spec.ScaleUpLimitFactor.MilliValue()/1000)), "scaleUpLimit", wpa.Spec.ScaleUpLimit, "wpaMaxReplicas", wpaMaxReplicas, "maximumAllowedReplicas", maximumAllowedReplicas)
 		possibleLimitingCondition = "ScaleUpLimit"
 		possibleLimitingReason = "the desired replica count is increasing faster than the maximum scale rate"
 	} else {
@@ -662,7 +1389,16 @@ func calculateScaleUpLimit(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, curren
 		// Scale up disabled
 		return currentReplicas
 	}
-	return int32(float64(currentReplicas) + math.Max(1, math.Floor(float64(wpa.Spec.ScaleUpLimitFactor.MilliValue())/1000*float64(currentReplicas)/100)))
+	if isScaledToZero(wpa, currentReplicas) {
+		// the limit is relative to the current number of replicas, it can't cap the scale from zero.
+		return getScaleUpFromZeroReplicas(wpa)
+	}
+	scaleUpLimit := int32(float64(currentReplicas) + math.Max(1, math.Floor(float64(wpa.Spec.ScaleUpLimitFactor.MilliValue())/1000*float64(currentReplicas)/100)))
+	if wpa.Spec.ScaleUpLimit > 0 && currentReplicas+wpa.Spec.ScaleUpLimit < scaleUpLimit {
+		// the most restrictive of the two limits applies.
+		scaleUpLimit = currentReplicas + wpa.Spec.ScaleUpLimit
+	}
+	return scaleUpLimit
 }
 
 // Scaledown limit is used to maximize the downscaling rate.
@@ -671,7 +1407,12 @@ func calculateScaleDownLimit(wpa *datadoghqv1alpha1.WatermarkPodAutoscaler, curr
 		// Scale down disabled
 		return currentReplicas
 	}
-	return int32(float64(currentReplicas) - math.Max(1, math.Floor(float64(wpa.Spec.ScaleDownLimitFactor.MilliValue())/1000*float64(currentReplicas)/100)))
+	scaleDownLimit := int32(float64(currentReplicas) - math.Max(1, math.Floor(float64(wpa.Spec.ScaleDownLimitFactor.MilliValue())/1000*float64(currentReplicas)/100)))
+	if wpa.Spec.ScaleDownLimit > 0 && currentReplicas-wpa.Spec.ScaleDownLimit > scaleDownLimit {
+		// the most restrictive of the two limits applies.
+		scaleDownLimit = currentReplicas - wpa.Spec.ScaleDownLimit
+	}
+	return scaleDownLimit
 }
 
 // When the WPA is changed (status is changed, edited by the user, etc),
@@ -686,16 +1427,18 @@ func updatePredicate(ev event.UpdateEvent) bool {
 	hasChanged := !apiequality.Semantic.DeepEqual(newObject.Spec, oldObject.Spec)
 	if hasChanged {
 		// remove prometheus metrics associated to this WPA, only metrics associated to metrics
-		// since other could not have changed.
-		cleanupAssociatedMetrics(oldObject, true)
+		// unless the target changed, since the other ones are labelled with it.
+		cleanupAssociatedMetrics(oldObject, oldObject.Spec.ScaleTargetRef == newObject.Spec.ScaleTargetRef)
 	}
-	return hasChanged
+	// pausing or resuming the WPA takes effect right away.
+	return hasChanged || isPaused(oldObject) != isPaused(newObject)
 }
 
 // SetupWithManager creates a new Watermarkpodautoscaler controller
 func (r *WatermarkPodAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	b := ctrl.NewControllerManagedBy(mgr).
-		For(&datadoghqv1alpha1.WatermarkPodAutoscaler{}, builder.WithPredicates(predicate.Funcs{UpdateFunc: updatePredicate}))
+		For(&datadoghqv1alpha1.WatermarkPodAutoscaler{}, builder.WithPredicates(predicate.Funcs{UpdateFunc: updatePredicate})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles})
 	err := b.Complete(r)
 
 	if err != nil {
@@ -703,11 +1446,6 @@ func (r *WatermarkPodAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager) er
 	}
 
 	config := mgr.GetConfig()
-	mc := metrics.NewRESTMetricsClient(
-		resourceclient.NewForConfigOrDie(config),
-		nil,
-		external_metrics.NewForConfigOrDie(config),
-	)
 	var stop chan struct{}
 	pl := initializePodInformer(config, stop)
 
@@ -716,22 +1454,39 @@ func (r *WatermarkPodAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager) er
 		return err
 	}
 
-	// init the scaleClient
 	cachedDiscovery := discocache.NewMemCacheClient(clientSet.Discovery())
 	restMapper := restmapper.NewDeferredDiscoveryRESTMapper(cachedDiscovery)
 	restMapper.Reset()
+
+	// the custom metrics API serves the Object metrics, its versions are discovered again periodically like in the HPA controller.
+	apiVersionsGetter := custom_metrics.NewAvailableAPIsGetter(clientSet.Discovery())
+	go custom_metrics.PeriodicallyInvalidate(apiVersionsGetter, defaultSyncPeriod, stop)
+	// the clients of the metrics APIs don't take a context, the deadline only prevents new queries: the requests in flight
+	// are bounded by the timeout of the REST clients, which applies to each request separately.
+	metricsConfig := rest.CopyConfig(config)
+	metricsConfig.Timeout = r.getMetricFetchTimeout()
+	mc := NewMetricsClient(metrics.NewRESTMetricsClient(
+		resourceclient.NewForConfigOrDie(metricsConfig),
+		custom_metrics.NewForConfig(metricsConfig, restMapper, apiVersionsGetter),
+		external_metrics.NewForConfigOrDie(metricsConfig),
+	))
+
+	// init the scaleClient
 	scaleKindResolver := scale.NewDiscoveryScaleKindResolver(clientSet.Discovery())
 	scaleClient, err := scale.NewForConfig(config, restMapper, dynamic.LegacyAPIPathResolverFunc, scaleKindResolver)
 	if err != nil {
 		return err
 	}
-	replicaCalc := NewReplicaCalculator(mc, pl)
+	replicaCalc := NewReplicaCalculator(mc, nil, pl)
 
 	r.replicaCalc = replicaCalc
 	r.scaleClient = scaleClient
 	r.restMapper = restMapper
 	r.eventRecorder = mgr.GetEventRecorderFor("wpa_controller")
+	// the ConfigMaps are read directly, a cached client would start an informer on all of the ConfigMaps of the cluster.
+	r.apiReader = mgr.GetAPIReader()
 	r.syncPeriod = defaultSyncPeriod
+	r.clock = clock.RealClock{}
 
 	return nil
 }
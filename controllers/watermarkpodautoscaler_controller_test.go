@@ -9,6 +9,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,6 +17,9 @@ import (
 	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1/test"
 
 	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
@@ -29,14 +33,21 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/client-go/kubernetes/scheme"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/scale"
 	fakescale "k8s.io/client-go/scale/fake"
+	core "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/kubernetes/pkg/controller/podautoscaler/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	sigmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 )
@@ -602,7 +613,7 @@ func TestReconcileWatermarkPodAutoscaler_reconcileWPA(t *testing.T) {
 				},
 			}
 
-			r.replicaCalc = NewReplicaCalculator(mClient, nil)
+			r.replicaCalc = NewReplicaCalculator(NewMetricsClient(mClient), nil, nil)
 			if tt.args.loadFunc != nil {
 				tt.args.loadFunc(r.Client, r.scaleClient, tt.args.wpa, tt.args.scale)
 			}
@@ -685,7 +696,7 @@ func TestReconcileWatermarkPodAutoscaler_computeReplicasForMetrics(t *testing.T)
 			},
 			wantFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
 				// With 8 replicas, the avg algo and an external value returned of 100 we have 10 replicas and the utilization of 10
-				return ReplicaCalculation{10, 10, time.Time{}}, nil
+				return ReplicaCalculation{10, 10, time.Time{}, "", "", nil}, nil
 			},
 			err: nil,
 		},
@@ -718,7 +729,7 @@ func TestReconcileWatermarkPodAutoscaler_computeReplicasForMetrics(t *testing.T)
 			},
 			wantFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
 				// With 8 replicas, the avg algo and an external value returned of 100 we have 10 replicas and the utilization of 10
-				return ReplicaCalculation{0, 0, time.Time{}}, fmt.Errorf("unable to fetch metrics from external metrics API")
+				return ReplicaCalculation{}, fmt.Errorf("unable to fetch metrics from external metrics API")
 			},
 			err: fmt.Errorf("failed to get external metric deadbeef: unable to fetch metrics from external metrics API"),
 		},
@@ -764,12 +775,219 @@ func TestReconcileWatermarkPodAutoscaler_computeReplicasForMetrics(t *testing.T)
 			wantFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
 				// With 8 replicas, the avg algo and an external value returned of 100 we have 10 replicas and the utilization of 10
 				if metric.External.MetricName == "deadbeef" {
-					return ReplicaCalculation{10, 10, time.Time{}}, nil
+					return ReplicaCalculation{10, 10, time.Time{}, "", "", nil}, nil
 				}
-				return ReplicaCalculation{8, 5, time.Time{}}, nil
+				return ReplicaCalculation{8, 5, time.Time{}, "", "", nil}, nil
 			},
 			err: nil,
 		},
+		{
+			name: "Multiple metrics with one in error Case",
+			fields: fields{
+				eventRecorder: eventRecorder,
+			},
+			args: args{
+				validMetrics: 1,
+				replicas:     8,
+				MetricName:   "deadbeef2{map[label:value]}",
+				wpa: test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+					Labels: map[string]string{"foo-key": "bar-value"},
+					Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+						Algorithm: "average",
+						Metrics: []v1alpha1.MetricSpec{
+							{
+								Type: v1alpha1.ExternalMetricSourceType,
+								External: &v1alpha1.ExternalMetricSource{
+									MetricName:     "deadbeef",
+									MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+									HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+									LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+								},
+							},
+							{
+								Type: v1alpha1.ExternalMetricSourceType,
+								External: &v1alpha1.ExternalMetricSource{
+									MetricName:     "deadbeef2",
+									MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+									HighWatermark:  resource.NewQuantity(10, resource.DecimalSI),
+									LowWatermark:   resource.NewQuantity(5, resource.DecimalSI),
+								},
+							},
+						},
+						MinReplicas: getReplicas(4),
+						MaxReplicas: 12,
+					},
+				}),
+				scale: &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 8}, Status: autoscalingv1.ScaleStatus{Replicas: 8}},
+			},
+			wantFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+				// The first metric is not available, the second one should still drive the scaling
+				if metric.External.MetricName == "deadbeef" {
+					return ReplicaCalculation{}, fmt.Errorf("unable to fetch metrics from external metrics API")
+				}
+				return ReplicaCalculation{8, 5, time.Time{}, "", "", nil}, nil
+			},
+			err: nil,
+		},
+		{
+			name: "Object metric Case",
+			fields: fields{
+				eventRecorder: eventRecorder,
+			},
+			args: args{
+				validMetrics: 2,
+				replicas:     11,
+				MetricName:   "requests-per-second{Ingress/frontend}",
+				wpa: test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+					Labels: map[string]string{"foo-key": "bar-value"},
+					Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+						Algorithm: "absolute",
+						Metrics: []v1alpha1.MetricSpec{
+							{
+								Type: v1alpha1.ExternalMetricSourceType,
+								External: &v1alpha1.ExternalMetricSource{
+									MetricName:     "deadbeef",
+									MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+									HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+									LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+								},
+							},
+							{
+								Type: v1alpha1.ObjectMetricSourceType,
+								Object: &v1alpha1.ObjectMetricSource{
+									DescribedObject: v1alpha1.CrossVersionObjectReference{Kind: "Ingress", Name: "frontend", APIVersion: "extensions/v1beta1"},
+									MetricName:      "requests-per-second",
+									HighWatermark:   resource.NewQuantity(100, resource.DecimalSI),
+									LowWatermark:    resource.NewQuantity(50, resource.DecimalSI),
+								},
+							},
+						},
+						MinReplicas: getReplicas(4),
+						MaxReplicas: 12,
+					},
+				}),
+				scale: &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 8}, Status: autoscalingv1.ScaleStatus{Replicas: 8}},
+			},
+			wantFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+				// The object metric recommends more replicas than the external one, it drives the scaling
+				if metric.Object != nil {
+					return ReplicaCalculation{11, 140, time.Time{}, "", "", nil}, nil
+				}
+				return ReplicaCalculation{8, 5, time.Time{}, "", "", nil}, nil
+			},
+			err: nil,
+		},
+		{
+			name: "Object metric in error Case",
+			fields: fields{
+				eventRecorder: eventRecorder,
+			},
+			args: args{
+				validMetrics: 0,
+				replicas:     0,
+				MetricName:   "",
+				wpa: test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+					Labels: map[string]string{"foo-key": "bar-value"},
+					Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+						Metrics: []v1alpha1.MetricSpec{
+							{
+								Type: v1alpha1.ObjectMetricSourceType,
+								Object: &v1alpha1.ObjectMetricSource{
+									DescribedObject: v1alpha1.CrossVersionObjectReference{Kind: "Ingress", Name: "frontend", APIVersion: "extensions/v1beta1"},
+									MetricName:      "requests-per-second",
+									HighWatermark:   resource.NewQuantity(100, resource.DecimalSI),
+									LowWatermark:    resource.NewQuantity(50, resource.DecimalSI),
+								},
+							},
+						},
+					},
+				}),
+				scale: &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 8}, Status: autoscalingv1.ScaleStatus{Replicas: 8}},
+			},
+			wantFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+				return ReplicaCalculation{}, fmt.Errorf("unable to fetch metrics from custom metrics API")
+			},
+			err: fmt.Errorf("failed to get object metric requests-per-second{Ingress/frontend}: unable to fetch metrics from custom metrics API"),
+		},
+		{
+			name: "Pods metric Case",
+			fields: fields{
+				eventRecorder: eventRecorder,
+			},
+			args: args{
+				validMetrics: 2,
+				replicas:     10,
+				MetricName:   "active_connections",
+				wpa: test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+					Labels: map[string]string{"foo-key": "bar-value"},
+					Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+						Algorithm: "absolute",
+						Metrics: []v1alpha1.MetricSpec{
+							{
+								Type: v1alpha1.ExternalMetricSourceType,
+								External: &v1alpha1.ExternalMetricSource{
+									MetricName:     "deadbeef",
+									MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+									HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+									LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+								},
+							},
+							{
+								Type: v1alpha1.PodsMetricSourceType,
+								Pods: &v1alpha1.PodsMetricSource{
+									MetricName:    "active_connections",
+									HighWatermark: resource.NewQuantity(100, resource.DecimalSI),
+									LowWatermark:  resource.NewQuantity(50, resource.DecimalSI),
+								},
+							},
+						},
+						MinReplicas: getReplicas(4),
+						MaxReplicas: 12,
+					},
+				}),
+				scale: &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 8}, Status: autoscalingv1.ScaleStatus{Replicas: 8}},
+			},
+			wantFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+				// The average of the pods metric recommends more replicas than the external one, it drives the scaling
+				if metric.Pods != nil {
+					return ReplicaCalculation{10, 120000, time.Time{}, "", "", nil}, nil
+				}
+				return ReplicaCalculation{8, 5, time.Time{}, "", "", nil}, nil
+			},
+			err: nil,
+		},
+		{
+			name: "Pods metric in error Case",
+			fields: fields{
+				eventRecorder: eventRecorder,
+			},
+			args: args{
+				validMetrics: 0,
+				replicas:     0,
+				MetricName:   "",
+				wpa: test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+					Labels: map[string]string{"foo-key": "bar-value"},
+					Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+						Metrics: []v1alpha1.MetricSpec{
+							{
+								Type: v1alpha1.PodsMetricSourceType,
+								Pods: &v1alpha1.PodsMetricSource{
+									MetricName:     "active_connections",
+									MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"port": "http"}},
+									HighWatermark:  resource.NewQuantity(100, resource.DecimalSI),
+									LowWatermark:   resource.NewQuantity(50, resource.DecimalSI),
+								},
+							},
+						},
+					},
+				}),
+				scale: &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 8}, Status: autoscalingv1.ScaleStatus{Replicas: 8}},
+			},
+			wantFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+				return ReplicaCalculation{}, fmt.Errorf("unable to fetch metrics from custom metrics API")
+			},
+			err: fmt.Errorf("failed to get pods metric active_connections{map[port:http]}: unable to fetch metrics from custom metrics API"),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -795,131 +1013,2984 @@ func TestReconcileWatermarkPodAutoscaler_computeReplicasForMetrics(t *testing.T)
 			if len(statuses) != tt.args.validMetrics {
 				t.Errorf("Incorrect number of valid metrics")
 			}
+			if err == nil && tt.args.wpa.Status.ScalingMetricName != metric {
+				t.Errorf("Scaling metric in the status is incorrect")
+			}
 
 		})
 	}
 }
 
-type fakeReplicaCalculator struct {
-	replicasFunc func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error)
+func TestReconcileWatermarkPodAutoscaler_emptyMetric(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+
+	eventRecorder := record.NewFakeRecorder(10)
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		return true, newScaleForDeployment(5, 5), nil
+	})
+	scaled := false
+	scaleClient.AddReactor("update", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		scaled = true
+		return true, action.(core.UpdateAction).GetObject(), nil
+	})
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	require.NoError(t, indexer.Add(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "foo-0",
+			Namespace:       testingNamespace,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: testingDeployName + "-123"}},
+		},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			StartTime:  &metav1.Time{Time: time.Now()},
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}))
+	mClient := fakeMetricsClient{
+		getExternalMetrics: func(metricName string, namespace string, selector labels.Selector) ([]int64, time.Time, error) {
+			return []int64{}, time.Now(), nil
+		},
+	}
+	r := &WatermarkPodAutoscalerReconciler{
+		Client:        fake.NewFakeClient(),
+		scaleClient:   scaleClient,
+		restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+		Scheme:        s,
+		eventRecorder: eventRecorder,
+		replicaCalc:   NewReplicaCalculator(NewMetricsClient(mClient), nil, corelisters.NewPodLister(indexer)),
+	}
+	wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+			MaxReplicas:            10,
+			MinReplicas:            getReplicas(0),
+			ScaleDownToZeroEnabled: true,
+			Metrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "deadbeef",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+					},
+				},
+			},
+		},
+	})
+	wpa = v1alpha1.DefaultWatermarkPodAutoscaler(wpa)
+	wpa.Spec.ScaleTargetRef = testCrossVersionObjectRef
+	wpa.Status.LastScaleTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+	require.NoError(t, r.Client.Create(context.TODO(), wpa))
+	wpa = &v1alpha1.WatermarkPodAutoscaler{}
+	require.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Name: testingWPAName, Namespace: testingNamespace}, wpa))
+
+	require.NoError(t, r.reconcileWPA(logf.Log.WithName("empty metric"), wpa))
+	assert.False(t, scaled, "the target should not be scaled without a value for the metric")
+	assert.Equal(t, int32(5), wpa.Status.CurrentReplicas)
+	events := make([]string, 0, len(eventRecorder.Events))
+	for len(eventRecorder.Events) > 0 {
+		events = append(events, <-eventRecorder.Events)
+	}
+	assert.Contains(t, strings.Join(events, "\n"), v1alpha1.ReasonMetricUnavailable)
+
+	promLabels := prometheus.Labels{
+		wpaNamePromLabel:           testingWPAName,
+		resourceNamespacePromLabel: testingNamespace,
+		resourceNamePromLabel:      testingDeployName,
+		resourceKindPromLabel:      testCrossVersionObjectRef.Kind,
+		metricNamePromLabel:        "deadbeef",
+	}
+	defer cleanupAssociatedMetrics(wpa, false)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metricUnavailable.With(promLabels)))
 }
 
-func (f *fakeReplicaCalculator) GetExternalMetricReplicas(logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
-	if f.replicasFunc != nil {
-		return f.replicasFunc(metric, wpa)
+func TestReconcileWatermarkPodAutoscaler_metricErrorBackoff(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		return true, newScaleForDeployment(5, 5), nil
+	})
+	outage := true
+	r := &WatermarkPodAutoscalerReconciler{
+		Client:        fake.NewFakeClient(),
+		scaleClient:   scaleClient,
+		restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+		Scheme:        s,
+		eventRecorder: record.NewFakeRecorder(100),
+		replicaCalc: &fakeReplicaCalculator{
+			replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+				if outage {
+					return ReplicaCalculation{}, fmt.Errorf("unable to fetch metrics from external metrics API")
+				}
+				return ReplicaCalculation{5, 75000, time.Now(), "", "", nil}, nil
+			},
+		},
+	}
+	wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+			MaxReplicas: 10,
+			Metrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "deadbeef",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+					},
+				},
+			},
+		},
+	})
+	wpa = v1alpha1.DefaultWatermarkPodAutoscaler(wpa)
+	wpa.Spec.ScaleTargetRef = testCrossVersionObjectRef
+	require.NoError(t, r.Client.Create(context.TODO(), wpa))
+	defer cleanupAssociatedMetrics(wpa, false)
+	key := types.NamespacedName{Namespace: testingNamespace, Name: testingWPAName}
+
+	expectedIntervals := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, maxMetricErrorBackoff, maxMetricErrorBackoff}
+	for i, expected := range expectedIntervals {
+		require.NoError(t, r.reconcileWPA(logf.Log.WithName("metric error backoff"), wpa))
+		assert.Equal(t, expected, r.metricErrors.requeueAfter(key, defaultSyncPeriod), "failure %d", i+1)
 	}
-	return ReplicaCalculation{0, 0, time.Time{}}, nil
+
+	// the first success resets the interval.
+	outage = false
+	require.NoError(t, r.reconcileWPA(logf.Log.WithName("metric error backoff"), wpa))
+	assert.Equal(t, defaultSyncPeriod, r.metricErrors.requeueAfter(key, defaultSyncPeriod))
 }
 
-func (f *fakeReplicaCalculator) GetResourceReplicas(logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
-	if f.replicasFunc != nil {
-		return f.replicasFunc(metric, wpa)
+func TestReconcileWatermarkPodAutoscaler_breachReset(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+
+	scaleUpdates := 0
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		return true, newScaleForDeployment(5, 5), nil
+	})
+	scaleClient.AddReactor("update", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		scaleUpdates++
+		return true, action.(core.UpdateAction).GetObject(), nil
+	})
+	outage := false
+	r := &WatermarkPodAutoscalerReconciler{
+		Client:        fake.NewFakeClient(),
+		scaleClient:   scaleClient,
+		restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+		Scheme:        s,
+		eventRecorder: record.NewFakeRecorder(100),
+		replicaCalc: &fakeReplicaCalculator{
+			replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+				if outage {
+					return ReplicaCalculation{}, fmt.Errorf("unable to fetch metrics from external metrics API")
+				}
+				return ReplicaCalculation{8, 90000, time.Now(), v1alpha1.DecisionReasonAboveHighWatermark, v1alpha1.DecisionReasonAboveHighWatermark, nil}, nil
+			},
+		},
 	}
-	return ReplicaCalculation{0, 0, time.Time{}}, nil
+	wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+			MaxReplicas:       10,
+			UpscaleDelayCount: 2,
+			Metrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "deadbeef",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+					},
+				},
+			},
+		},
+	})
+	wpa = v1alpha1.DefaultWatermarkPodAutoscaler(wpa)
+	wpa.Spec.ScaleTargetRef = testCrossVersionObjectRef
+	require.NoError(t, r.Client.Create(context.TODO(), wpa))
+	defer cleanupAssociatedMetrics(wpa, false)
+	key := types.NamespacedName{Namespace: testingNamespace, Name: testingWPAName}
+	logger := logf.Log.WithName("breach reset")
+
+	// the first cycle above the high watermark starts the breach.
+	require.NoError(t, r.reconcileWPA(logger, wpa))
+	assert.Equal(t, int32(1), r.breaches.breaches[key].count)
+
+	// the breach starts over when the metrics are unavailable.
+	outage = true
+	require.NoError(t, r.reconcileWPA(logger, wpa))
+	assert.NotContains(t, r.breaches.breaches, key)
+
+	outage = false
+	require.NoError(t, r.reconcileWPA(logger, wpa))
+	assert.Equal(t, int32(1), r.breaches.breaches[key].count)
+	assert.Equal(t, 0, scaleUpdates)
+
+	// and when the WPA is paused.
+	wpa.Annotations = map[string]string{v1alpha1.PausedAnnotationKey: "true"}
+	require.NoError(t, r.reconcileWPA(logger, wpa))
+	assert.NotContains(t, r.breaches.breaches, key)
+
+	delete(wpa.Annotations, v1alpha1.PausedAnnotationKey)
+	require.NoError(t, r.reconcileWPA(logger, wpa))
+	assert.Equal(t, int32(1), r.breaches.breaches[key].count)
+	assert.Equal(t, 0, scaleUpdates)
+
+	// the target is only scaled after two consecutive cycles above the high watermark.
+	require.NoError(t, r.reconcileWPA(logger, wpa))
+	assert.Equal(t, 1, scaleUpdates)
 }
 
-func TestDefaultWatermarkPodAutoscaler(t *testing.T) {
+func TestReconcileWatermarkPodAutoscaler_metricErrorPolicy(t *testing.T) {
 	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+
 	tests := []struct {
-		name    string
-		wpaName string
-		wpaNs   string
-		err     error
-		spec    *v1alpha1.WatermarkPodAutoscalerSpec
+		name              string
+		metricErrorPolicy string
+		// healthyCycle recommends 8 replicas before the outage, the target is scaled from 5 to 6 as the upscale is limited to 1 replica.
+		healthyCycle     bool
+		expectedReplicas int32
 	}{
 		{
-			name:    "missing scaleTarget",
+			name:             "default policy keeps the current replicas",
+			healthyCycle:     true,
+			expectedReplicas: 6,
+		},
+		{
+			name:              "maintain keeps the current replicas",
+			metricErrorPolicy: "maintain",
+			healthyCycle:      true,
+			expectedReplicas:  6,
+		},
+		{
+			name:              "scaleToMin scales down to minReplicas",
+			metricErrorPolicy: "scaleToMin",
+			healthyCycle:      true,
+			expectedReplicas:  2,
+		},
+		{
+			name:              "lastKnownGood keeps scaling towards the last recommendation",
+			metricErrorPolicy: "lastKnownGood",
+			healthyCycle:      true,
+			expectedReplicas:  7,
+		},
+		{
+			name:              "lastKnownGood keeps the current replicas without a previous recommendation",
+			metricErrorPolicy: "lastKnownGood",
+			expectedReplicas:  5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetReplicas := int32(5)
+			scaleClient := &fakescale.FakeScaleClient{}
+			scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				return true, newScaleForDeployment(targetReplicas, targetReplicas), nil
+			})
+			scaleClient.AddReactor("update", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				scale := action.(core.UpdateAction).GetObject().(*autoscalingv1.Scale)
+				targetReplicas = scale.Spec.Replicas
+				return true, scale, nil
+			})
+			outage := false
+			r := &WatermarkPodAutoscalerReconciler{
+				Client:        fake.NewFakeClient(),
+				scaleClient:   scaleClient,
+				restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+				Scheme:        s,
+				eventRecorder: record.NewFakeRecorder(100),
+				replicaCalc: &fakeReplicaCalculator{
+					replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+						if outage {
+							return ReplicaCalculation{}, fmt.Errorf("unable to fetch metrics from external metrics API")
+						}
+						return ReplicaCalculation{8, 90000, time.Now(), "", "", nil}, nil
+					},
+				},
+			}
+			wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+				Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+					MinReplicas:          getReplicas(2),
+					MaxReplicas:          10,
+					ScaleUpLimit:         1,
+					ScaleDownLimitFactor: resource.NewQuantity(90, resource.DecimalSI),
+					MetricErrorPolicy:    tt.metricErrorPolicy,
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type: v1alpha1.ExternalMetricSourceType,
+							External: &v1alpha1.ExternalMetricSource{
+								MetricName:     "deadbeef",
+								MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+								HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+								LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			})
+			wpa = v1alpha1.DefaultWatermarkPodAutoscaler(wpa)
+			wpa.Spec.ScaleTargetRef = testCrossVersionObjectRef
+			require.NoError(t, r.Client.Create(context.TODO(), wpa))
+			defer cleanupAssociatedMetrics(wpa, false)
+
+			if tt.healthyCycle {
+				require.NoError(t, r.reconcileWPA(logf.Log.WithName(tt.name), wpa))
+				require.Equal(t, int32(6), targetReplicas)
+				// the outage cycle should not be blocked by the forbidden windows.
+				wpa.Status.LastScaleTime = nil
+			}
+
+			outage = true
+			require.NoError(t, r.reconcileWPA(logf.Log.WithName(tt.name), wpa))
+			assert.Equal(t, tt.expectedReplicas, targetReplicas)
+			promLabelsForWpa := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}
+			assert.Equal(t, float64(1), testutil.ToFloat64(metricErrorTotal.With(promLabelsForWpa)))
+		})
+	}
+}
+
+func TestReconcileWatermarkPodAutoscaler_scaleDirection(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+
+	tests := []struct {
+		name             string
+		scaleDirection   string
+		expectedReplicas int32
+		expectedBlocked  float64
+	}{
+		{
+			name:             "both directions",
+			expectedReplicas: 4,
+		},
+		{
+			name:             "scale up only",
+			scaleDirection:   "up",
+			expectedReplicas: 5,
+			expectedBlocked:  1,
+		},
+		{
+			name:             "scale down only",
+			scaleDirection:   "down",
+			expectedReplicas: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetReplicas := int32(5)
+			scaleClient := &fakescale.FakeScaleClient{}
+			scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				return true, newScaleForDeployment(targetReplicas, targetReplicas), nil
+			})
+			scaleClient.AddReactor("update", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				scale := action.(core.UpdateAction).GetObject().(*autoscalingv1.Scale)
+				targetReplicas = scale.Spec.Replicas
+				return true, scale, nil
+			})
+			r := &WatermarkPodAutoscalerReconciler{
+				Client:        fake.NewFakeClient(),
+				scaleClient:   scaleClient,
+				restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+				Scheme:        s,
+				eventRecorder: record.NewFakeRecorder(100),
+				replicaCalc: &fakeReplicaCalculator{
+					replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+						// the metric is below the low watermark.
+						return ReplicaCalculation{3, 40000, time.Now(), "", "", nil}, nil
+					},
+				},
+			}
+			wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+				Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+					MinReplicas:    getReplicas(2),
+					MaxReplicas:    10,
+					ScaleDirection: tt.scaleDirection,
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type: v1alpha1.ExternalMetricSourceType,
+							External: &v1alpha1.ExternalMetricSource{
+								MetricName:     "deadbeef",
+								MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+								HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+								LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			})
+			wpa = v1alpha1.DefaultWatermarkPodAutoscaler(wpa)
+			wpa.Spec.ScaleTargetRef = testCrossVersionObjectRef
+			require.NoError(t, r.Client.Create(context.TODO(), wpa))
+			defer cleanupAssociatedMetrics(wpa, false)
+
+			require.NoError(t, r.reconcileWPA(logf.Log.WithName(tt.name), wpa))
+			assert.Equal(t, tt.expectedReplicas, targetReplicas)
+			assert.Equal(t, tt.expectedReplicas, wpa.Status.DesiredReplicas)
+			promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, directionPromLabel: downPromLabelVal, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}
+			assert.Equal(t, tt.expectedBlocked, testutil.ToFloat64(scaleBlocked.With(promLabels)))
+		})
+	}
+}
+
+func TestReconcileWatermarkPodAutoscaler_minReadyPercentage(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+
+	tests := []struct {
+		name               string
+		minReadyPercentage int32
+		readyPodCount      int32
+		podCount           int32
+		readinessErr       error
+		expectedReplicas   int32
+		expectedBlocked    bool
+	}{
+		{
+			name:               "healthy fleet",
+			minReadyPercentage: 80,
+			readyPodCount:      3,
+			podCount:           3,
+			expectedReplicas:   4,
+		},
+		{
+			name:               "ready percentage at the minimum",
+			minReadyPercentage: 80,
+			readyPodCount:      4,
+			podCount:           5,
+			expectedReplicas:   4,
+		},
+		{
+			name:               "unhealthy fleet",
+			minReadyPercentage: 80,
+			readyPodCount:      1,
+			podCount:           3,
+			expectedReplicas:   3,
+			expectedBlocked:    true,
+		},
+		{
+			name:             "unhealthy fleet without minReadyPercentage",
+			readyPodCount:    1,
+			podCount:         3,
+			expectedReplicas: 4,
+		},
+		{
+			name:               "readiness unavailable",
+			minReadyPercentage: 80,
+			readinessErr:       fmt.Errorf("unable to list the pods"),
+			expectedReplicas:   4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetReplicas := int32(3)
+			eventRecorder := record.NewFakeRecorder(10)
+			scaleClient := &fakescale.FakeScaleClient{}
+			scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				return true, newScaleForDeployment(targetReplicas, targetReplicas), nil
+			})
+			scaleClient.AddReactor("update", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				scale := action.(core.UpdateAction).GetObject().(*autoscalingv1.Scale)
+				targetReplicas = scale.Spec.Replicas
+				return true, scale, nil
+			})
+			r := &WatermarkPodAutoscalerReconciler{
+				Client:        fake.NewFakeClient(),
+				scaleClient:   scaleClient,
+				restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+				Scheme:        s,
+				eventRecorder: eventRecorder,
+				replicaCalc: &fakeReplicaCalculator{
+					replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+						// the metric is above the high watermark.
+						return ReplicaCalculation{4, 90000, time.Now(), "", "", nil}, nil
+					},
+					readinessFunc: func(target *autoscalingv1.Scale) (int32, int32, error) {
+						return tt.readyPodCount, tt.podCount, tt.readinessErr
+					},
+				},
+			}
+			wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+				Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+					MinReplicas:        getReplicas(1),
+					MaxReplicas:        10,
+					MinReadyPercentage: tt.minReadyPercentage,
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type: v1alpha1.ExternalMetricSourceType,
+							External: &v1alpha1.ExternalMetricSource{
+								MetricName:     "deadbeef",
+								MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+								HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+								LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			})
+			wpa = v1alpha1.DefaultWatermarkPodAutoscaler(wpa)
+			wpa.Spec.ScaleTargetRef = testCrossVersionObjectRef
+			require.NoError(t, r.Client.Create(context.TODO(), wpa))
+			defer cleanupAssociatedMetrics(wpa, false)
+
+			require.NoError(t, r.reconcileWPA(logf.Log.WithName(tt.name), wpa))
+			assert.Equal(t, tt.expectedReplicas, targetReplicas)
+			assert.Equal(t, tt.expectedReplicas, wpa.Status.DesiredReplicas)
+
+			blocked := false
+			for len(eventRecorder.Events) > 0 {
+				event := <-eventRecorder.Events
+				if strings.Contains(event, v1alpha1.ReasonScalingBlockedUnhealthy) {
+					blocked = true
+					assert.Equal(t, fmt.Sprintf("%s %s Keeping 3 replicas instead of 4: 1 of the 3 pods are ready, below the minReadyPercentage of 80%%", corev1.EventTypeWarning, v1alpha1.ReasonScalingBlockedUnhealthy), event)
+				}
+			}
+			assert.Equal(t, tt.expectedBlocked, blocked)
+			if tt.expectedBlocked {
+				assert.Equal(t, v1alpha1.DecisionReasonUnhealthy, wpa.Status.LastDecisionReason)
+			}
+		})
+	}
+}
+
+func TestReconcileWatermarkPodAutoscaler_lastDecisionReason(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+
+	tests := []struct {
+		name             string
+		currentReplicas  int32
+		modify           func(wpa *v1alpha1.WatermarkPodAutoscaler)
+		calculation      ReplicaCalculation
+		calculationErr   error
+		expectedReplicas int32
+		expectedReason   string
+	}{
+		{
+			name:             "within the watermarks",
+			currentReplicas:  5,
+			calculation:      ReplicaCalculation{5, 75000, time.Now(), v1alpha1.DecisionReasonWithinTolerance, "", nil},
+			expectedReplicas: 5,
+			expectedReason:   v1alpha1.DecisionReasonWithinTolerance,
+		},
+		{
+			name:             "above the high watermark",
+			currentReplicas:  5,
+			calculation:      ReplicaCalculation{6, 90000, time.Now(), v1alpha1.DecisionReasonAboveHighWatermark, "", nil},
+			expectedReplicas: 6,
+			expectedReason:   v1alpha1.DecisionReasonAboveHighWatermark,
+		},
+		{
+			name:             "recommendation above maxReplicas",
+			currentReplicas:  5,
+			modify:           func(wpa *v1alpha1.WatermarkPodAutoscaler) { wpa.Spec.MaxReplicas = 6 },
+			calculation:      ReplicaCalculation{12, 90000, time.Now(), v1alpha1.DecisionReasonAboveHighWatermark, "", nil},
+			expectedReplicas: 6,
+			expectedReason:   v1alpha1.DecisionReasonClampedToMax,
+		},
+		{
+			name:             "recommendation above the scale up limit",
+			currentReplicas:  5,
+			calculation:      ReplicaCalculation{9, 90000, time.Now(), v1alpha1.DecisionReasonAboveHighWatermark, "", nil},
+			expectedReplicas: 7,
+			expectedReason:   v1alpha1.DecisionReasonScaleLimited,
+		},
+		{
+			name:             "current replicas above maxReplicas",
+			currentReplicas:  5,
+			modify:           func(wpa *v1alpha1.WatermarkPodAutoscaler) { wpa.Spec.MaxReplicas = 4 },
+			calculation:      ReplicaCalculation{5, 75000, time.Now(), v1alpha1.DecisionReasonWithinTolerance, "", nil},
+			expectedReplicas: 4,
+			expectedReason:   v1alpha1.DecisionReasonClampedToMax,
+		},
+		{
+			name:            "within the downscale forbidden window",
+			currentReplicas: 5,
+			modify: func(wpa *v1alpha1.WatermarkPodAutoscaler) {
+				wpa.Status.LastScaleTime = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+			},
+			calculation:      ReplicaCalculation{4, 40000, time.Now(), v1alpha1.DecisionReasonBelowLowWatermark, "", nil},
+			expectedReplicas: 5,
+			expectedReason:   v1alpha1.DecisionReasonInCooldown,
+		},
+		{
+			name:             "scale down blocked by the direction",
+			currentReplicas:  5,
+			modify:           func(wpa *v1alpha1.WatermarkPodAutoscaler) { wpa.Spec.ScaleDirection = "up" },
+			calculation:      ReplicaCalculation{4, 40000, time.Now(), v1alpha1.DecisionReasonBelowLowWatermark, "", nil},
+			expectedReplicas: 5,
+			expectedReason:   v1alpha1.DecisionReasonDirectionBlocked,
+		},
+		{
+			name:             "scale down below the minimum replica change",
+			currentReplicas:  5,
+			modify:           func(wpa *v1alpha1.WatermarkPodAutoscaler) { wpa.Spec.MinReplicaChange = 2 },
+			calculation:      ReplicaCalculation{4, 40000, time.Now(), v1alpha1.DecisionReasonBelowLowWatermark, "", nil},
+			expectedReplicas: 5,
+			expectedReason:   v1alpha1.DecisionReasonBelowMinReplicaChange,
+		},
+		{
+			name:             "dry run",
+			currentReplicas:  5,
+			modify:           func(wpa *v1alpha1.WatermarkPodAutoscaler) { wpa.Spec.DryRun = true },
+			calculation:      ReplicaCalculation{4, 40000, time.Now(), v1alpha1.DecisionReasonBelowLowWatermark, "", nil},
+			expectedReplicas: 5,
+			expectedReason:   v1alpha1.DecisionReasonDryRun,
+		},
+		{
+			name:             "target scaled to zero",
+			currentReplicas:  0,
+			calculation:      ReplicaCalculation{4, 40000, time.Now(), v1alpha1.DecisionReasonAboveHighWatermark, "", nil},
+			expectedReplicas: 0,
+			expectedReason:   v1alpha1.DecisionReasonScalingDisabled,
+		},
+		{
+			name:             "metric unavailable",
+			currentReplicas:  5,
+			calculationErr:   fmt.Errorf("unable to fetch metrics from external metrics API"),
+			expectedReplicas: 5,
+			expectedReason:   v1alpha1.DecisionReasonMetricUnavailable,
+		},
+		{
+			name:             "metric stale",
+			currentReplicas:  5,
+			calculation:      ReplicaCalculation{reason: v1alpha1.DecisionReasonMetricStale},
+			calculationErr:   fmt.Errorf("external metric deadbeef is stale"),
+			expectedReplicas: 5,
+			expectedReason:   v1alpha1.DecisionReasonMetricStale,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetReplicas := tt.currentReplicas
+			scaleClient := &fakescale.FakeScaleClient{}
+			scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				return true, newScaleForDeployment(targetReplicas, targetReplicas), nil
+			})
+			scaleClient.AddReactor("update", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				scale := action.(core.UpdateAction).GetObject().(*autoscalingv1.Scale)
+				targetReplicas = scale.Spec.Replicas
+				return true, scale, nil
+			})
+			r := &WatermarkPodAutoscalerReconciler{
+				Client:        fake.NewFakeClient(),
+				scaleClient:   scaleClient,
+				restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+				Scheme:        s,
+				eventRecorder: record.NewFakeRecorder(100),
+				replicaCalc: &fakeReplicaCalculator{
+					replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+						return tt.calculation, tt.calculationErr
+					},
+				},
+			}
+			wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+				Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+					MinReplicas: getReplicas(2),
+					MaxReplicas: 10,
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type: v1alpha1.ExternalMetricSourceType,
+							External: &v1alpha1.ExternalMetricSource{
+								MetricName:     "deadbeef",
+								MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+								HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+								LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			})
+			wpa = v1alpha1.DefaultWatermarkPodAutoscaler(wpa)
+			wpa.Spec.ScaleTargetRef = testCrossVersionObjectRef
+			if tt.modify != nil {
+				tt.modify(wpa)
+			}
+			require.NoError(t, r.Client.Create(context.TODO(), wpa))
+			defer cleanupAssociatedMetrics(wpa, false)
+
+			require.NoError(t, r.reconcileWPA(logf.Log.WithName(tt.name), wpa))
+			assert.Equal(t, tt.expectedReplicas, targetReplicas)
+			assert.Equal(t, tt.expectedReason, wpa.Status.LastDecisionReason)
+		})
+	}
+}
+
+func TestReconcileWatermarkPodAutoscaler_statusDetails(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+
+	tests := []struct {
+		name                    string
+		modify                  func(wpa *v1alpha1.WatermarkPodAutoscaler)
+		calculation             ReplicaCalculation
+		calculationErr          error
+		expectedDesiredReplicas int32
+		expectedValue           int64
+		expectedPosition        string
+		expectedScalingActive   corev1.ConditionStatus
+		expectedRescale         bool
+	}{
+		{
+			name:                    "above the high watermark",
+			calculation:             ReplicaCalculation{6, 90000, time.Now(), v1alpha1.DecisionReasonAboveHighWatermark, v1alpha1.DecisionReasonAboveHighWatermark, nil},
+			expectedDesiredReplicas: 6,
+			expectedValue:           90000,
+			expectedPosition:        v1alpha1.DecisionReasonAboveHighWatermark,
+			expectedScalingActive:   corev1.ConditionTrue,
+			expectedRescale:         true,
+		},
+		{
+			name:                    "below the low watermark",
+			calculation:             ReplicaCalculation{4, 40000, time.Now(), v1alpha1.DecisionReasonBelowLowWatermark, v1alpha1.DecisionReasonBelowLowWatermark, nil},
+			expectedDesiredReplicas: 4,
+			expectedValue:           40000,
+			expectedPosition:        v1alpha1.DecisionReasonBelowLowWatermark,
+			expectedScalingActive:   corev1.ConditionTrue,
+			expectedRescale:         true,
+		},
+		{
+			name:                    "within the watermarks",
+			calculation:             ReplicaCalculation{5, 75000, time.Now(), v1alpha1.DecisionReasonWithinTolerance, v1alpha1.DecisionReasonWithinTolerance, nil},
+			expectedDesiredReplicas: 5,
+			expectedValue:           75000,
+			expectedPosition:        v1alpha1.DecisionReasonWithinTolerance,
+			expectedScalingActive:   corev1.ConditionTrue,
+		},
+		{
+			// the position is kept when the recommendation is clamped, unlike the reason.
+			name:                    "above the high watermark clamped to maxReplicas",
+			modify:                  func(wpa *v1alpha1.WatermarkPodAutoscaler) { wpa.Spec.MaxReplicas = 5 },
+			calculation:             ReplicaCalculation{5, 90000, time.Now(), v1alpha1.DecisionReasonClampedToMax, v1alpha1.DecisionReasonAboveHighWatermark, nil},
+			expectedDesiredReplicas: 5,
+			expectedValue:           90000,
+			expectedPosition:        v1alpha1.DecisionReasonAboveHighWatermark,
+			expectedScalingActive:   corev1.ConditionTrue,
+		},
+		{
+			name: "metric unavailable",
+			modify: func(wpa *v1alpha1.WatermarkPodAutoscaler) {
+				wpa.Status.ScalingMetricName = "deadbeef{map[label:value]}"
+				wpa.Status.ScalingMetricValue = resource.NewMilliQuantity(90000, resource.DecimalSI)
+				wpa.Status.ScalingMetricPosition = v1alpha1.DecisionReasonAboveHighWatermark
+			},
+			calculationErr:        fmt.Errorf("unable to fetch metrics from external metrics API"),
+			expectedScalingActive: corev1.ConditionFalse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scaleClient := &fakescale.FakeScaleClient{}
+			scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				return true, newScaleForDeployment(5, 5), nil
+			})
+			scaleClient.AddReactor("update", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				return true, action.(core.UpdateAction).GetObject(), nil
+			})
+			r := &WatermarkPodAutoscalerReconciler{
+				Client:        fake.NewFakeClient(),
+				scaleClient:   scaleClient,
+				restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+				Scheme:        s,
+				eventRecorder: record.NewFakeRecorder(100),
+				replicaCalc: &fakeReplicaCalculator{
+					replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+						return tt.calculation, tt.calculationErr
+					},
+				},
+			}
+			wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+				Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+					MinReplicas: getReplicas(2),
+					MaxReplicas: 10,
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type: v1alpha1.ExternalMetricSourceType,
+							External: &v1alpha1.ExternalMetricSource{
+								MetricName:     "deadbeef",
+								MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+								HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+								LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			})
+			wpa = v1alpha1.DefaultWatermarkPodAutoscaler(wpa)
+			wpa.Spec.ScaleTargetRef = testCrossVersionObjectRef
+			if tt.modify != nil {
+				tt.modify(wpa)
+			}
+			require.NoError(t, r.Client.Create(context.TODO(), wpa))
+			defer cleanupAssociatedMetrics(wpa, false)
+
+			require.NoError(t, r.reconcileWPA(logf.Log.WithName(tt.name), wpa))
+			// the details of the recommendation are persisted in the status subresource.
+			updated := &v1alpha1.WatermarkPodAutoscaler{}
+			require.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Name: testingWPAName, Namespace: testingNamespace}, updated))
+			assert.Equal(t, int32(5), updated.Status.CurrentReplicas)
+			assert.Equal(t, tt.expectedPosition, updated.Status.ScalingMetricPosition)
+			if tt.calculationErr == nil {
+				assert.Equal(t, tt.expectedDesiredReplicas, updated.Status.DesiredReplicas)
+				require.NotNil(t, updated.Status.ScalingMetricValue)
+				assert.Equal(t, tt.expectedValue, updated.Status.ScalingMetricValue.MilliValue())
+			} else {
+				// the last usable metric isn't reported as driving the scaling anymore.
+				assert.Empty(t, updated.Status.ScalingMetricName)
+				assert.Nil(t, updated.Status.ScalingMetricValue)
+			}
+			assert.Equal(t, tt.expectedRescale, updated.Status.LastScaleTime != nil)
+			var scalingActive *v2beta1.HorizontalPodAutoscalerCondition
+			for i := range updated.Status.Conditions {
+				if updated.Status.Conditions[i].Type == v2beta1.ScalingActive {
+					scalingActive = &updated.Status.Conditions[i]
+				}
+			}
+			require.NotNil(t, scalingActive)
+			assert.Equal(t, tt.expectedScalingActive, scalingActive.Status)
+		})
+	}
+}
+
+func TestReconcileWatermarkPodAutoscaler_debugSeries(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+
+	tests := []struct {
+		name           string
+		debug          bool
+		series         *metricSeries
+		previousSeries []v1alpha1.ExternalMetricSeriesStatus
+		expectedSeries []v1alpha1.ExternalMetricSeriesStatus
+	}{
+		{
+			name:   "series reported with debug",
+			debug:  true,
+			series: &metricSeries{count: 3, min: 1000, max: 90000},
+			expectedSeries: []v1alpha1.ExternalMetricSeriesStatus{
+				{
+					MetricName: "deadbeef{map[label:value]}",
+					Count:      3,
+					Min:        resource.NewMilliQuantity(1000, resource.DecimalSI),
+					Max:        resource.NewMilliQuantity(90000, resource.DecimalSI),
+				},
+			},
+		},
+		{
+			// there is no range to report without any series.
+			name:           "no series with debug",
+			debug:          true,
+			series:         &metricSeries{},
+			expectedSeries: []v1alpha1.ExternalMetricSeriesStatus{{MetricName: "deadbeef{map[label:value]}"}},
+		},
+		{
+			name:   "series not reported without debug",
+			series: &metricSeries{count: 3, min: 1000, max: 90000},
+		},
+		{
+			name:           "series cleared once debug is disabled",
+			series:         &metricSeries{count: 3, min: 1000, max: 90000},
+			previousSeries: []v1alpha1.ExternalMetricSeriesStatus{{MetricName: "deadbeef{map[label:value]}", Count: 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scaleClient := &fakescale.FakeScaleClient{}
+			scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				return true, newScaleForDeployment(5, 5), nil
+			})
+			r := &WatermarkPodAutoscalerReconciler{
+				Client:        fake.NewFakeClient(),
+				scaleClient:   scaleClient,
+				restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+				Scheme:        s,
+				eventRecorder: record.NewFakeRecorder(100),
+				replicaCalc: &fakeReplicaCalculator{
+					replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+						return ReplicaCalculation{5, 75000, time.Now(), v1alpha1.DecisionReasonWithinTolerance, v1alpha1.DecisionReasonWithinTolerance, tt.series}, nil
+					},
+				},
+			}
+			wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+				Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+					MinReplicas: getReplicas(2),
+					MaxReplicas: 10,
+					Debug:       tt.debug,
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type: v1alpha1.ExternalMetricSourceType,
+							External: &v1alpha1.ExternalMetricSource{
+								MetricName:     "deadbeef",
+								MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+								HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+								LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			})
+			wpa = v1alpha1.DefaultWatermarkPodAutoscaler(wpa)
+			wpa.Spec.ScaleTargetRef = testCrossVersionObjectRef
+			wpa.Status.ExternalMetricSeries = tt.previousSeries
+			require.NoError(t, r.Client.Create(context.TODO(), wpa))
+			defer cleanupAssociatedMetrics(wpa, false)
+
+			require.NoError(t, r.reconcileWPA(logf.Log.WithName(tt.name), wpa))
+			updated := &v1alpha1.WatermarkPodAutoscaler{}
+			require.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Name: testingWPAName, Namespace: testingNamespace}, updated))
+			require.Len(t, updated.Status.ExternalMetricSeries, len(tt.expectedSeries))
+			for i, expected := range tt.expectedSeries {
+				actual := updated.Status.ExternalMetricSeries[i]
+				assert.Equal(t, expected.MetricName, actual.MetricName)
+				assert.Equal(t, expected.Count, actual.Count)
+				if expected.Min == nil {
+					assert.Nil(t, actual.Min)
+					assert.Nil(t, actual.Max)
+					continue
+				}
+				require.NotNil(t, actual.Min)
+				require.NotNil(t, actual.Max)
+				assert.Equal(t, expected.Min.MilliValue(), actual.Min.MilliValue())
+				assert.Equal(t, expected.Max.MilliValue(), actual.Max.MilliValue())
+			}
+		})
+	}
+}
+
+func TestGetRecommendationReason(t *testing.T) {
+	assert.Equal(t, v1alpha1.DecisionReasonClampedToMin, getRecommendationReason(v1alpha1.DecisionReasonClampedToMin, 5, 2))
+	assert.Equal(t, v1alpha1.DecisionReasonAboveHighWatermark, getRecommendationReason("", 5, 6))
+	assert.Equal(t, v1alpha1.DecisionReasonBelowLowWatermark, getRecommendationReason("", 5, 4))
+	assert.Equal(t, v1alpha1.DecisionReasonWithinTolerance, getRecommendationReason("", 5, 5))
+}
+
+func TestGetNormalizedReason(t *testing.T) {
+	wpa := &v1alpha1.WatermarkPodAutoscaler{Spec: v1alpha1.WatermarkPodAutoscalerSpec{MinReplicas: getReplicas(2), MaxReplicas: 10}}
+	assert.Equal(t, v1alpha1.DecisionReasonClampedToMax, getNormalizedReason(wpa, 12, 10))
+	assert.Equal(t, v1alpha1.DecisionReasonClampedToMin, getNormalizedReason(wpa, 1, 2))
+	assert.Equal(t, v1alpha1.DecisionReasonScaleLimited, getNormalizedReason(wpa, 9, 7))
+	assert.Equal(t, v1alpha1.DecisionReasonScaleLimited, getNormalizedReason(wpa, 2, 4))
+}
+
+func TestReconcileWatermarkPodAutoscaler_metricUnavailableEvent(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	eventRecorder := record.NewFakeRecorder(10)
+	r := &WatermarkPodAutoscalerReconciler{
+		eventRecorder: eventRecorder,
+		replicaCalc: &fakeReplicaCalculator{
+			replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+				return ReplicaCalculation{}, fmt.Errorf("unable to fetch metrics from external metrics API")
+			},
+		},
+	}
+	wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+			Metrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "deadbeef",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+					},
+				},
+			},
+			MinReplicas: getReplicas(4),
+			MaxReplicas: 12,
+		},
+	})
+	scale := &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 8}, Status: autoscalingv1.ScaleStatus{Replicas: 8}}
+
+	_, _, _, _, err := r.computeReplicasForMetrics(logf.Log, wpa, scale)
+	require.Error(t, err)
+	require.Len(t, eventRecorder.Events, 1)
+	event := <-eventRecorder.Events
+	assert.Contains(t, event, fmt.Sprintf("%s %s", corev1.EventTypeWarning, v1alpha1.ReasonMetricUnavailable))
+	assert.Contains(t, event, "deadbeef{map[label:value]}")
+}
+
+func TestReconcileWatermarkPodAutoscaler_scalingDecisionEvents(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+
+	tests := []struct {
+		name             string
+		currentReplicas  int32
+		proposedReplicas int32
+		lastScaleTime    *metav1.Time
+		dryRun           bool
+		wantReason       string
+		wantMessage      string
+	}{
+		{
+			name:             "scale up",
+			currentReplicas:  3,
+			proposedReplicas: 4,
+			wantReason:       v1alpha1.ReasonScaledUp,
+			wantMessage:      "New size: 4; old size: 3; reason: deadbeef{map[label:value]} above target; metric: deadbeef{map[label:value]}; utilization: 90; low watermark: 70; high watermark: 80",
+		},
+		{
+			name:             "scale down",
+			currentReplicas:  5,
+			proposedReplicas: 4,
+			wantReason:       v1alpha1.ReasonScaledDown,
+			wantMessage:      "New size: 4; old size: 5; reason: All metrics below target; metric: deadbeef{map[label:value]}; utilization: 90; low watermark: 70; high watermark: 80",
+		},
+		{
+			name:             "within bounds",
+			currentReplicas:  3,
+			proposedReplicas: 3,
+			lastScaleTime:    &metav1.Time{Time: time.Now().Add(-time.Hour)},
+			wantReason:       v1alpha1.ReasonWithinBounds,
+			wantMessage:      "Keeping 3 replicas; metric: deadbeef{map[label:value]}; utilization: 90; low watermark: 70; high watermark: 80",
+		},
+		{
+			name:             "dry run",
+			currentReplicas:  3,
+			proposedReplicas: 4,
+			dryRun:           true,
+			wantReason:       v1alpha1.ReasonDryRun,
+			wantMessage:      "Would scale from 3 to 4; reason: deadbeef{map[label:value]} above target; metric: deadbeef{map[label:value]}; utilization: 90; low watermark: 70; high watermark: 80",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventRecorder := record.NewFakeRecorder(10)
+			scaleClient := &fakescale.FakeScaleClient{}
+			scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				return true, newScaleForDeployment(tt.currentReplicas, tt.currentReplicas), nil
+			})
+			scaleClient.AddReactor("update", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				return true, action.(core.UpdateAction).GetObject(), nil
+			})
+			r := &WatermarkPodAutoscalerReconciler{
+				Client:        fake.NewFakeClient(),
+				scaleClient:   scaleClient,
+				restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+				Scheme:        s,
+				eventRecorder: eventRecorder,
+				replicaCalc: &fakeReplicaCalculator{
+					replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+						return ReplicaCalculation{tt.proposedReplicas, 90000, time.Now(), "", "", nil}, nil
+					},
+				},
+			}
+			wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+				Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+					MaxReplicas: 10,
+					MinReplicas: getReplicas(1),
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type: v1alpha1.ExternalMetricSourceType,
+							External: &v1alpha1.ExternalMetricSource{
+								MetricName:     "deadbeef",
+								MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+								HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+								LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			})
+			wpa = v1alpha1.DefaultWatermarkPodAutoscaler(wpa)
+			wpa.Spec.ScaleTargetRef = testCrossVersionObjectRef
+			wpa.Status.LastScaleTime = tt.lastScaleTime
+			wpa.Spec.DryRun = tt.dryRun
+			require.NoError(t, r.Client.Create(context.TODO(), wpa))
+			wpa = &v1alpha1.WatermarkPodAutoscaler{}
+			require.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Name: testingWPAName, Namespace: testingNamespace}, wpa))
+
+			require.NoError(t, r.reconcileWPA(logf.Log.WithName(tt.name), wpa))
+			require.Len(t, eventRecorder.Events, 1)
+			event := <-eventRecorder.Events
+			assert.Equal(t, fmt.Sprintf("%s %s %s", corev1.EventTypeNormal, tt.wantReason, tt.wantMessage), event)
+
+			scaleUpdated := false
+			for _, action := range scaleClient.Actions() {
+				if action.GetVerb() == "update" {
+					scaleUpdated = true
+				}
+			}
+			assert.Equal(t, !tt.dryRun && tt.proposedReplicas != tt.currentReplicas, scaleUpdated)
+			require.Len(t, wpa.Status.RecommendationHistory, 1)
+			assert.Equal(t, tt.proposedReplicas, wpa.Status.RecommendationHistory[0].Recommendation)
+			assert.Equal(t, scaleUpdated, wpa.Status.RecommendationHistory[0].Applied)
+			if tt.dryRun {
+				promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}
+				assert.Equal(t, float64(tt.proposedReplicas), testutil.ToFloat64(dryRunReplicas.With(promLabels)))
+			}
+			if tt.wantReason == v1alpha1.ReasonWithinBounds {
+				// the event is not emitted again while the metrics stay within their watermarks.
+				require.NoError(t, r.reconcileWPA(logf.Log.WithName(tt.name), wpa))
+				assert.Len(t, eventRecorder.Events, 0)
+			}
+		})
+	}
+}
+
+func TestReconcileWatermarkPodAutoscaler_scalingLimitedCondition(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+
+	tests := []struct {
+		name             string
+		minReplicas      int32
+		currentReplicas  int32
+		proposedReplicas int32
+		lastScaleTime    *metav1.Time
+		wantStatus       corev1.ConditionStatus
+		wantReason       string
+	}{
+		{
+			name:             "desired within range",
+			minReplicas:      1,
+			currentReplicas:  3,
+			proposedReplicas: 4,
+			wantStatus:       corev1.ConditionFalse,
+			wantReason:       "DesiredWithinRange",
+		},
+		{
+			name:             "too many replicas",
+			minReplicas:      1,
+			currentReplicas:  8,
+			proposedReplicas: 11,
+			wantStatus:       corev1.ConditionTrue,
+			wantReason:       "TooManyReplicas",
+		},
+		{
+			name:             "too few replicas",
+			minReplicas:      9,
+			currentReplicas:  10,
+			proposedReplicas: 8,
+			wantStatus:       corev1.ConditionTrue,
+			wantReason:       "TooFewReplicas",
+		},
+		{
+			name:             "within tolerance",
+			minReplicas:      1,
+			currentReplicas:  3,
+			proposedReplicas: 3,
+			lastScaleTime:    &metav1.Time{Time: time.Now().Add(-time.Hour)},
+			wantStatus:       corev1.ConditionTrue,
+			wantReason:       v1alpha1.ConditionReasonWithinTolerance,
+		},
+		{
+			name:             "forbidden window",
+			minReplicas:      1,
+			currentReplicas:  3,
+			proposedReplicas: 4,
+			lastScaleTime:    &metav1.Time{Time: time.Now()},
+			wantStatus:       corev1.ConditionTrue,
+			wantReason:       v1alpha1.ConditionReasonForbiddenWindow,
+		},
+		{
+			name:             "forbidden window takes precedence over the bounds",
+			minReplicas:      1,
+			currentReplicas:  8,
+			proposedReplicas: 11,
+			lastScaleTime:    &metav1.Time{Time: time.Now()},
+			wantStatus:       corev1.ConditionTrue,
+			wantReason:       v1alpha1.ConditionReasonForbiddenWindow,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scaleClient := &fakescale.FakeScaleClient{}
+			scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				return true, newScaleForDeployment(tt.currentReplicas, tt.currentReplicas), nil
+			})
+			scaleClient.AddReactor("update", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				return true, action.(core.UpdateAction).GetObject(), nil
+			})
+			r := &WatermarkPodAutoscalerReconciler{
+				Client:        fake.NewFakeClient(),
+				scaleClient:   scaleClient,
+				restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+				Scheme:        s,
+				eventRecorder: record.NewFakeRecorder(10),
+				replicaCalc: &fakeReplicaCalculator{
+					replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+						return ReplicaCalculation{tt.proposedReplicas, 90000, time.Now(), "", "", nil}, nil
+					},
+				},
+			}
+			wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+				Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+					MaxReplicas: 10,
+					MinReplicas: getReplicas(tt.minReplicas),
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type: v1alpha1.ExternalMetricSourceType,
+							External: &v1alpha1.ExternalMetricSource{
+								MetricName:     "deadbeef",
+								MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+								HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+								LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			})
+			wpa = v1alpha1.DefaultWatermarkPodAutoscaler(wpa)
+			wpa.Spec.ScaleTargetRef = testCrossVersionObjectRef
+			wpa.Status.LastScaleTime = tt.lastScaleTime
+			require.NoError(t, r.Client.Create(context.TODO(), wpa))
+			wpa = &v1alpha1.WatermarkPodAutoscaler{}
+			require.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Name: testingWPAName, Namespace: testingNamespace}, wpa))
+
+			require.NoError(t, r.reconcileWPA(logf.Log.WithName(tt.name), wpa))
+			var limited []v2beta1.HorizontalPodAutoscalerCondition
+			for _, condition := range wpa.Status.Conditions {
+				if condition.Type == v2beta1.ScalingLimited {
+					limited = append(limited, condition)
+				}
+			}
+			require.Len(t, limited, 1)
+			assert.Equal(t, tt.wantStatus, limited[0].Status)
+			assert.Equal(t, tt.wantReason, limited[0].Reason)
+		})
+	}
+}
+
+// scalableResource stands for a custom resource implementing the scale subresource.
+type scalableResource struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+}
+
+func (in *scalableResource) DeepCopyObject() runtime.Object {
+	out := *in
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	return &out
+}
+
+func TestReconcileWatermarkPodAutoscaler_scaleTargetKinds(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{}, &appsv1.StatefulSet{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+	s.AddKnownTypeWithName(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Scalable"}, &scalableResource{})
+
+	tests := []struct {
+		name         string
+		target       v1alpha1.CrossVersionObjectReference
+		wantResource string
+		wantErr      string
+	}{
+		{
+			name:         "deployment",
+			target:       v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testingDeployName, APIVersion: "apps/v1"},
+			wantResource: "deployments",
+		},
+		{
+			name:         "statefulset",
+			target:       v1alpha1.CrossVersionObjectReference{Kind: "StatefulSet", Name: testingDeployName, APIVersion: "apps/v1"},
+			wantResource: "statefulsets",
+		},
+		{
+			name:         "custom resource with the scale subresource",
+			target:       v1alpha1.CrossVersionObjectReference{Kind: "Scalable", Name: testingDeployName, APIVersion: "example.com/v1"},
+			wantResource: "scalables",
+		},
+		{
+			name:    "kind without the scale subresource",
+			target:  v1alpha1.CrossVersionObjectReference{Kind: "ConfigMap", Name: testingDeployName, APIVersion: "v1"},
+			wantErr: "unable to get the scale of the ConfigMap",
+		},
+		{
+			name:    "unknown kind",
+			target:  v1alpha1.CrossVersionObjectReference{Kind: "Unknown", Name: testingDeployName, APIVersion: "apps/v1"},
+			wantErr: "unable to determine resource for scale target reference",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scaleClient := &fakescale.FakeScaleClient{}
+			scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				if action.GetResource().Resource == "configmaps" {
+					return true, nil, fmt.Errorf("the server could not find the requested resource")
+				}
+				return true, newScaleForDeployment(3, 3), nil
+			})
+			scaleClient.AddReactor("update", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				return true, action.(core.UpdateAction).GetObject(), nil
+			})
+			r := &WatermarkPodAutoscalerReconciler{
+				Client:        fake.NewFakeClient(),
+				scaleClient:   scaleClient,
+				restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+				Scheme:        s,
+				eventRecorder: record.NewFakeRecorder(10),
+				replicaCalc: &fakeReplicaCalculator{
+					replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+						return ReplicaCalculation{4, 90000, time.Now(), "", "", nil}, nil
+					},
+				},
+			}
+			wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+				Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+					MaxReplicas: 10,
+					MinReplicas: getReplicas(1),
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type: v1alpha1.ExternalMetricSourceType,
+							External: &v1alpha1.ExternalMetricSource{
+								MetricName:     "deadbeef",
+								MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+								HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+								LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			})
+			wpa = v1alpha1.DefaultWatermarkPodAutoscaler(wpa)
+			wpa.Spec.ScaleTargetRef = tt.target
+			require.NoError(t, r.Client.Create(context.TODO(), wpa))
+			wpa = &v1alpha1.WatermarkPodAutoscaler{}
+			require.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Name: testingWPAName, Namespace: testingNamespace}, wpa))
+
+			err := r.reconcileWPA(logf.Log.WithName(tt.name), wpa)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			var updated []string
+			for _, action := range scaleClient.Actions() {
+				if action.GetVerb() == "update" {
+					updated = append(updated, action.GetResource().Resource)
+					assert.Equal(t, int32(4), action.(core.UpdateAction).GetObject().(*autoscalingv1.Scale).Spec.Replicas)
+				}
+			}
+			assert.Equal(t, []string{tt.wantResource}, updated)
+		})
+	}
+}
+
+func TestReconcileWatermarkPodAutoscaler_scaleToZero(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+
+	tests := []struct {
+		name             string
+		currentReplicas  int32
+		proposedReplicas int32
+		wantReplicas     int32
+		wantReason       string
+	}{
+		{
+			name:             "scale down to zero",
+			currentReplicas:  1,
+			proposedReplicas: 0,
+			wantReplicas:     0,
+			wantReason:       v1alpha1.ReasonScaledDown,
+		},
+		{
+			name:             "scale up from zero",
+			currentReplicas:  0,
+			proposedReplicas: 3,
+			wantReplicas:     3,
+			wantReason:       v1alpha1.ReasonScaledUp,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventRecorder := record.NewFakeRecorder(10)
+			scaleClient := &fakescale.FakeScaleClient{}
+			scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				return true, newScaleForDeployment(tt.currentReplicas, tt.currentReplicas), nil
+			})
+			var updatedReplicas *int32
+			scaleClient.AddReactor("update", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+				scale := action.(core.UpdateAction).GetObject().(*autoscalingv1.Scale)
+				updatedReplicas = &scale.Spec.Replicas
+				return true, scale, nil
+			})
+			r := &WatermarkPodAutoscalerReconciler{
+				Client:        fake.NewFakeClient(),
+				scaleClient:   scaleClient,
+				restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+				Scheme:        s,
+				eventRecorder: eventRecorder,
+				replicaCalc: &fakeReplicaCalculator{
+					replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+						return ReplicaCalculation{tt.proposedReplicas, 90000, time.Now(), "", "", nil}, nil
+					},
+				},
+			}
+			wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+				Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+					MaxReplicas:             10,
+					MinReplicas:             getReplicas(0),
+					ScaleDownToZeroEnabled:  true,
+					ScaleUpFromZeroReplicas: 3,
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type: v1alpha1.ExternalMetricSourceType,
+							External: &v1alpha1.ExternalMetricSource{
+								MetricName:     "deadbeef",
+								MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+								HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+								LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			})
+			wpa = v1alpha1.DefaultWatermarkPodAutoscaler(wpa)
+			wpa.Spec.ScaleTargetRef = testCrossVersionObjectRef
+			// the downscale forbidden window acts as the cooldown before scaling down to zero.
+			wpa.Status.LastScaleTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+			require.NoError(t, v1alpha1.CheckWPAValidity(wpa))
+			require.NoError(t, r.Client.Create(context.TODO(), wpa))
+			wpa = &v1alpha1.WatermarkPodAutoscaler{}
+			require.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Name: testingWPAName, Namespace: testingNamespace}, wpa))
+
+			require.NoError(t, r.reconcileWPA(logf.Log.WithName(tt.name), wpa))
+			require.NotNil(t, updatedReplicas, "the scale of the target should have been updated")
+			assert.Equal(t, tt.wantReplicas, *updatedReplicas)
+			require.Len(t, eventRecorder.Events, 1)
+			assert.Contains(t, <-eventRecorder.Events, tt.wantReason)
+		})
+	}
+}
+
+// seriesOfWPA returns the name of the metrics that still report a series for the WPA.
+func seriesOfWPA(t *testing.T, namespace, name string) []string {
+	families, err := sigmetrics.Registry.Gather()
+	require.NoError(t, err)
+	var names []string
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labelValues := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labelValues[label.GetName()] = label.GetValue()
+			}
+			if labelValues[wpaNamePromLabel] == name && labelValues[resourceNamespacePromLabel] == namespace {
+				names = append(names, family.GetName())
+				break
+			}
+		}
+	}
+	return names
+}
+
+func TestReconcileWatermarkPodAutoscaler_cleanupMetricsOnDeletion(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+	// a dedicated name keeps the series of the other tests out of the assertions.
+	wpaName := "deleted-wpa"
+
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		return true, newScaleForDeployment(3, 3), nil
+	})
+	scaleClient.AddReactor("update", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		return true, action.(core.UpdateAction).GetObject(), nil
+	})
+	r := &WatermarkPodAutoscalerReconciler{
+		Client:        fake.NewFakeClient(),
+		Log:           logf.Log.WithName("TestReconcileWatermarkPodAutoscaler_cleanupMetricsOnDeletion"),
+		scaleClient:   scaleClient,
+		restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+		Scheme:        s,
+		eventRecorder: record.NewFakeRecorder(10),
+		replicaCalc: &fakeReplicaCalculator{
+			replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+				return ReplicaCalculation{4, 90000, time.Now(), "", "", nil}, nil
+			},
+		},
+	}
+	wpa := test.NewWatermarkPodAutoscaler(testingNamespace, wpaName, &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+			MaxReplicas:    10,
+			MinReplicas:    getReplicas(1),
+			ScaleTargetRef: testCrossVersionObjectRef,
+			Metrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "deadbeef",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, r.Client.Create(context.TODO(), v1alpha1.DefaultWatermarkPodAutoscaler(wpa)))
+	request := newRequest(testingNamespace, wpaName)
+
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Contains(t, seriesOfWPA(t, testingNamespace, wpaName), "wpa_controller_replicas_scaling_effective")
+	assert.Contains(t, seriesOfWPA(t, testingNamespace, wpaName), "wpa_controller_dry_run")
+
+	// the fake client doesn't handle the finalizers, the deletion is simulated with the deletion timestamp.
+	// The spec is also made invalid to check that it doesn't prevent the finalization.
+	wpa = &v1alpha1.WatermarkPodAutoscaler{}
+	require.NoError(t, r.Client.Get(context.TODO(), request.NamespacedName, wpa))
+	require.Contains(t, wpa.GetFinalizers(), watermarkpodautoscalerFinalizer)
+	wpa.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	wpa.Spec.MaxReplicas = 0
+	require.NoError(t, r.Client.Update(context.TODO(), wpa))
+
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Empty(t, seriesOfWPA(t, testingNamespace, wpaName))
+	require.NoError(t, r.Client.Get(context.TODO(), request.NamespacedName, wpa))
+	assert.NotContains(t, wpa.GetFinalizers(), watermarkpodautoscalerFinalizer)
+}
+
+func TestReconcileWatermarkPodAutoscaler_paused(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+	wpaName := "paused-wpa"
+
+	scaleUpdates := 0
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		return true, newScaleForDeployment(3, 3), nil
+	})
+	scaleClient.AddReactor("update", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		scaleUpdates++
+		return true, action.(core.UpdateAction).GetObject(), nil
+	})
+	metricCalls := 0
+	r := &WatermarkPodAutoscalerReconciler{
+		Client:        fake.NewFakeClient(),
+		Log:           logf.Log.WithName("TestReconcileWatermarkPodAutoscaler_paused"),
+		scaleClient:   scaleClient,
+		restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+		Scheme:        s,
+		eventRecorder: record.NewFakeRecorder(100),
+		replicaCalc: &fakeReplicaCalculator{
+			replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+				metricCalls++
+				return ReplicaCalculation{4, 90000, time.Now(), v1alpha1.DecisionReasonAboveHighWatermark, v1alpha1.DecisionReasonAboveHighWatermark, nil}, nil
+			},
+		},
+	}
+	wpa := test.NewWatermarkPodAutoscaler(testingNamespace, wpaName, &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+			MaxReplicas:    10,
+			MinReplicas:    getReplicas(1),
+			ScaleTargetRef: testCrossVersionObjectRef,
+			Metrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "deadbeef",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+					},
+				},
+			},
+		},
+	})
+	wpa.Annotations[v1alpha1.PausedAnnotationKey] = "true"
+	require.NoError(t, r.Client.Create(context.TODO(), v1alpha1.DefaultWatermarkPodAutoscaler(wpa)))
+	defer cleanupAssociatedMetrics(wpa, false)
+	request := newRequest(testingNamespace, wpaName)
+	promLabels := prometheus.Labels{wpaNamePromLabel: wpaName, resourceNamespacePromLabel: testingNamespace, resourceNamePromLabel: testCrossVersionObjectRef.Name, resourceKindPromLabel: testCrossVersionObjectRef.Kind}
+	getPausedCondition := func(wpa *v1alpha1.WatermarkPodAutoscaler) *v2beta1.HorizontalPodAutoscalerCondition {
+		for i := range wpa.Status.Conditions {
+			if wpa.Status.Conditions[i].Type == pausedCondition {
+				return &wpa.Status.Conditions[i]
+			}
+		}
+		return nil
+	}
+
+	// while paused, the metrics are still computed but the target isn't scaled.
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	require.NoError(t, r.Client.Get(context.TODO(), request.NamespacedName, wpa))
+	assert.Equal(t, 0, scaleUpdates)
+	assert.Equal(t, 1, metricCalls)
+	assert.Equal(t, float64(1), testutil.ToFloat64(paused.With(promLabels)))
+	assert.Equal(t, v1alpha1.DecisionReasonPaused, wpa.Status.LastDecisionReason)
+	assert.Equal(t, int32(3), wpa.Status.DesiredReplicas)
+	assert.Len(t, wpa.Status.CurrentMetrics, 1)
+	require.NotNil(t, getPausedCondition(wpa))
+	assert.Equal(t, corev1.ConditionTrue, getPausedCondition(wpa).Status)
+
+	// once the annotation is removed, the next reconcile scales the target.
+	delete(wpa.Annotations, v1alpha1.PausedAnnotationKey)
+	require.NoError(t, r.Client.Update(context.TODO(), wpa))
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	require.NoError(t, r.Client.Get(context.TODO(), request.NamespacedName, wpa))
+	assert.Equal(t, 1, scaleUpdates)
+	assert.Equal(t, 2, metricCalls)
+	assert.Equal(t, float64(0), testutil.ToFloat64(paused.With(promLabels)))
+	assert.Equal(t, int32(4), wpa.Status.DesiredReplicas)
+	require.NotNil(t, getPausedCondition(wpa))
+	assert.Equal(t, corev1.ConditionFalse, getPausedCondition(wpa).Status)
+
+	// pausing it again holds the replicas again.
+	wpa.Annotations = map[string]string{v1alpha1.PausedAnnotationKey: "true"}
+	require.NoError(t, r.Client.Update(context.TODO(), wpa))
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	require.NoError(t, r.Client.Get(context.TODO(), request.NamespacedName, wpa))
+	assert.Equal(t, 1, scaleUpdates)
+	assert.Equal(t, v1alpha1.DecisionReasonPaused, wpa.Status.LastDecisionReason)
+}
+
+func TestUpdatePredicatePaused(t *testing.T) {
+	oldWPA := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{ScaleTargetRef: testCrossVersionObjectRef, MaxReplicas: 10},
+	})
+	newWPA := oldWPA.DeepCopy()
+	newWPA.Annotations[v1alpha1.PausedAnnotationKey] = "true"
+	// the annotation takes effect without waiting for the next resync.
+	assert.True(t, updatePredicate(event.UpdateEvent{ObjectOld: oldWPA, ObjectNew: newWPA}))
+	assert.True(t, updatePredicate(event.UpdateEvent{ObjectOld: newWPA, ObjectNew: oldWPA}))
+	// the other annotations are ignored.
+	otherWPA := oldWPA.DeepCopy()
+	otherWPA.Annotations["foo"] = "bar"
+	assert.False(t, updatePredicate(event.UpdateEvent{ObjectOld: oldWPA, ObjectNew: otherWPA}))
+}
+
+func TestReconcileWatermarkPodAutoscaler_scaleTransitionMetrics(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	s := scheme.Scheme
+	s.AddKnownTypes(appsv1.SchemeGroupVersion, &appsv1.Deployment{})
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.WatermarkPodAutoscaler{})
+	wpaName := "scale-transition-wpa"
+
+	replicas := int32(3)
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		return true, newScaleForDeployment(replicas, replicas), nil
+	})
+	scaleClient.AddReactor("update", "*", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		scale := action.(core.UpdateAction).GetObject().(*autoscalingv1.Scale)
+		replicas = scale.Spec.Replicas
+		return true, scale, nil
+	})
+	recommendation := int32(3)
+	r := &WatermarkPodAutoscalerReconciler{
+		Client:        fake.NewFakeClient(),
+		Log:           logf.Log.WithName("TestReconcileWatermarkPodAutoscaler_scaleTransitionMetrics"),
+		scaleClient:   scaleClient,
+		restMapper:    testrestmapper.TestOnlyStaticRESTMapper(s),
+		Scheme:        s,
+		eventRecorder: record.NewFakeRecorder(100),
+		replicaCalc: &fakeReplicaCalculator{
+			replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+				return ReplicaCalculation{recommendation, 90000, time.Now(), v1alpha1.DecisionReasonAboveHighWatermark, v1alpha1.DecisionReasonAboveHighWatermark, nil}, nil
+			},
+		},
+	}
+	wpa := test.NewWatermarkPodAutoscaler(testingNamespace, wpaName, &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+			MaxReplicas:    10,
+			MinReplicas:    getReplicas(1),
+			ScaleTargetRef: testCrossVersionObjectRef,
+			Metrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "deadbeef",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(80, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(70, resource.DecimalSI),
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, r.Client.Create(context.TODO(), v1alpha1.DefaultWatermarkPodAutoscaler(wpa)))
+	defer cleanupAssociatedMetrics(wpa, false)
+	request := newRequest(testingNamespace, wpaName)
+	promLabels := prometheus.Labels{wpaNamePromLabel: wpaName, resourceNamespacePromLabel: testingNamespace, resourceNamePromLabel: testCrossVersionObjectRef.Name, resourceKindPromLabel: testCrossVersionObjectRef.Kind}
+	upLabels := prometheus.Labels{wpaNamePromLabel: wpaName, directionPromLabel: upPromLabelVal, resourceNamespacePromLabel: testingNamespace, resourceNamePromLabel: testCrossVersionObjectRef.Name, resourceKindPromLabel: testCrossVersionObjectRef.Kind}
+	downLabels := prometheus.Labels{wpaNamePromLabel: wpaName, directionPromLabel: downPromLabelVal, resourceNamespacePromLabel: testingNamespace, resourceNamePromLabel: testCrossVersionObjectRef.Name, resourceKindPromLabel: testCrossVersionObjectRef.Kind}
+	start := float64(time.Now().Unix())
+
+	// the replicas are kept, no transition is recorded.
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), replicas)
+	assert.Equal(t, float64(0), testutil.ToFloat64(lastScaleTimestamp.With(promLabels)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(scaleTransitions.With(upLabels)))
+
+	// the target is scaled up.
+	recommendation = 4
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), replicas)
+	lastScale := testutil.ToFloat64(lastScaleTimestamp.With(promLabels))
+	assert.True(t, lastScale >= start, "the timestamp of the last scale should be set")
+	assert.Equal(t, float64(1), testutil.ToFloat64(scaleTransitions.With(upLabels)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(scaleTransitions.With(downLabels)))
+
+	// the recommendation is within bounds, the timestamp doesn't advance.
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), replicas)
+	assert.Equal(t, lastScale, testutil.ToFloat64(lastScaleTimestamp.With(promLabels)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(scaleTransitions.With(upLabels)))
+
+	// the series are removed along with the WPA.
+	cleanupAssociatedMetrics(wpa, false)
+	assert.False(t, lastScaleTimestamp.Delete(promLabels))
+	assert.False(t, scaleTransitions.Delete(upLabels))
+}
+
+func TestUpdatePredicateCleanupMetrics(t *testing.T) {
+	oldWPA := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{ScaleTargetRef: testCrossVersionObjectRef, MaxReplicas: 10},
+	})
+	promLabels := prometheus.Labels{wpaNamePromLabel: oldWPA.Name, resourceNamespacePromLabel: oldWPA.Namespace, resourceNamePromLabel: oldWPA.Spec.ScaleTargetRef.Name, resourceKindPromLabel: oldWPA.Spec.ScaleTargetRef.Kind}
+
+	// the series labelled with the target are kept as long as the target is the same.
+	replicaEffective.With(promLabels).Set(3)
+	newWPA := oldWPA.DeepCopy()
+	newWPA.Spec.MaxReplicas = 12
+	assert.True(t, updatePredicate(event.UpdateEvent{ObjectOld: oldWPA, ObjectNew: newWPA}))
+	assert.True(t, replicaEffective.Delete(promLabels))
+
+	replicaEffective.With(promLabels).Set(3)
+	newWPA.Spec.ScaleTargetRef.Name = "other-deployment"
+	assert.True(t, updatePredicate(event.UpdateEvent{ObjectOld: oldWPA, ObjectNew: newWPA}))
+	assert.False(t, replicaEffective.Delete(promLabels))
+}
+
+func TestComputeReplicasForMetricsWithoutRecorder(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	r := &WatermarkPodAutoscalerReconciler{
+		replicaCalc: &fakeReplicaCalculator{
+			replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+				return ReplicaCalculation{}, fmt.Errorf("unable to fetch metrics from external metrics API")
+			},
+		},
+	}
+	wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+			Metrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "deadbeef",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+					},
+				},
+			},
+			MaxReplicas: 12,
+		},
+	})
+	scale := &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 8}, Status: autoscalingv1.ScaleStatus{Replicas: 8}}
+
+	assert.NotPanics(t, func() {
+		_, _, _, _, err := r.computeReplicasForMetrics(logf.Log, wpa, scale)
+		assert.Error(t, err)
+	})
+}
+
+func TestComputeReplicasForMetricsWinningMetric(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+			ScaleTargetRef: testCrossVersionObjectRef,
+			Metrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "queue",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+					},
+				},
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "latency",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+					},
+				},
+			},
+			MaxReplicas: 12,
+		},
+	})
+	scale := &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 5}, Status: autoscalingv1.ScaleStatus{Replicas: 5}}
+	promLabels := func(metricName string) prometheus.Labels {
+		return prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind, metricNamePromLabel: metricName}
+	}
+	defer cleanupAssociatedMetrics(wpa, false)
+
+	tests := []struct {
+		name             string
+		recommendations  map[string]int32
+		expectedReplicas int32
+		expectedMetric   string
+	}{
+		{
+			name:             "the queue dominates",
+			recommendations:  map[string]int32{"queue": 9, "latency": 4},
+			expectedReplicas: 9,
+			expectedMetric:   "queue",
+		},
+		{
+			name:             "the latency dominates",
+			recommendations:  map[string]int32{"queue": 2, "latency": 7},
+			expectedReplicas: 7,
+			expectedMetric:   "latency",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &WatermarkPodAutoscalerReconciler{
+				replicaCalc: &fakeReplicaCalculator{
+					replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+						return ReplicaCalculation{tt.recommendations[metric.External.MetricName], 5000, time.Time{}, "", "", nil}, nil
+					},
+				},
+				eventRecorder: record.NewFakeRecorder(10),
+			}
+			replicas, metric, statuses, _, err := r.computeReplicasForMetrics(logf.Log, wpa, scale)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedReplicas, replicas)
+			assert.Equal(t, fmt.Sprintf("%s{map[label:value]}", tt.expectedMetric), metric)
+			assert.Equal(t, metric, wpa.Status.ScalingMetricName)
+			assert.Len(t, statuses, 2)
+			for metricName := range tt.recommendations {
+				expected := float64(0)
+				if metricName == tt.expectedMetric {
+					expected = 1
+				}
+				assert.Equal(t, expected, testutil.ToFloat64(winningMetric.With(promLabels(metricName))), metricName)
+				// the per-metric series are kept for every metric.
+				assert.Equal(t, float64(tt.recommendations[metricName]), testutil.ToFloat64(replicaProposal.With(promLabels(metricName))), metricName)
+			}
+		})
+	}
+}
+
+func TestComputeReplicasForMetricsRestrictedScaling(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+			ScaleTargetRef: testCrossVersionObjectRef,
+			Metrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "queue",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+					},
+				},
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "latency",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+					},
+				},
+			},
+			MaxReplicas: 12,
+		},
+	})
+	scale := &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 5}, Status: autoscalingv1.ScaleStatus{Replicas: 5}}
+	promLabels := func(metricName string) prometheus.Labels {
+		return prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind, reasonPromLabel: withinBoundsPromLabelVal, metricNamePromLabel: metricName}
+	}
+	defer cleanupAssociatedMetrics(wpa, false)
+
+	type proposal struct {
+		replicas int32
+		position string
+	}
+	tests := []struct {
+		name           string
+		proposals      map[string]proposal
+		expectedMetric string
+		expected       float64
+	}{
+		{
+			name: "only the queue breaches its high watermark",
+			proposals: map[string]proposal{
+				"queue":   {9, v1alpha1.DecisionReasonAboveHighWatermark},
+				"latency": {5, v1alpha1.DecisionReasonWithinTolerance},
+			},
+			expectedMetric: "queue",
+			expected:       0,
+		},
+		{
+			name: "only the queue breaches its low watermark",
+			proposals: map[string]proposal{
+				"queue":   {2, v1alpha1.DecisionReasonBelowLowWatermark},
+				"latency": {5, v1alpha1.DecisionReasonWithinTolerance},
+			},
+			expectedMetric: "latency",
+			expected:       1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &WatermarkPodAutoscalerReconciler{
+				replicaCalc: &fakeReplicaCalculator{
+					replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+						p := tt.proposals[metric.External.MetricName]
+						return ReplicaCalculation{p.replicas, 5000, time.Time{}, p.position, p.position, nil}, nil
+					},
+				},
+				eventRecorder: record.NewFakeRecorder(10),
+			}
+			_, _, _, _, err := r.computeReplicasForMetrics(logf.Log, wpa, scale)
+			require.NoError(t, err)
+			// only the series of the metric driving the scaling is set, the one of the other metric is removed.
+			for metricName := range tt.proposals {
+				if metricName == tt.expectedMetric {
+					assert.Equal(t, tt.expected, testutil.ToFloat64(restrictedScaling.With(promLabels(metricName))), metricName)
+				} else {
+					assert.False(t, restrictedScaling.Delete(promLabels(metricName)), metricName)
+				}
+			}
+		})
+	}
+}
+
+func TestComputeReplicasForMetricsDuration(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	wpa := test.NewWatermarkPodAutoscaler(testingNamespace, "reconcile-duration", &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+			ScaleTargetRef: testCrossVersionObjectRef,
+			Metrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "queue",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+					},
+				},
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "latency",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+						LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+					},
+				},
+			},
+			MaxReplicas: 12,
+		},
+	})
+	scale := &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 5}, Status: autoscalingv1.ScaleStatus{Replicas: 5}}
+	promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}
+	defer cleanupAssociatedMetrics(wpa, false)
+
+	fakeClock := clock.NewFakeClock(time.Now())
+	r := &WatermarkPodAutoscalerReconciler{
+		replicaCalc: &fakeReplicaCalculator{
+			replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+				// each metric takes 1.5 seconds to be fetched and computed.
+				fakeClock.Step(1500 * time.Millisecond)
+				return ReplicaCalculation{5, 5000, fakeClock.Now(), "", "", nil}, nil
+			},
+		},
+		eventRecorder: record.NewFakeRecorder(10),
+		clock:         fakeClock,
+	}
+
+	_, _, _, _, err := r.computeReplicasForMetrics(logf.Log, wpa, scale)
+	require.NoError(t, err)
+	count, sum := getHistogram(t, reconcileDuration.With(promLabels))
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, float64(3), sum)
+}
+
+// getHistogram returns the number of observations of a histogram and their sum.
+func getHistogram(t *testing.T, observer prometheus.Observer) (uint64, float64) {
+	metric := &dto.Metric{}
+	require.NoError(t, observer.(prometheus.Metric).Write(metric))
+	return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+}
+
+func TestGetLoadRatio(t *testing.T) {
+	metricSpec := v1alpha1.MetricSpec{
+		Type: v1alpha1.ExternalMetricSourceType,
+		External: &v1alpha1.ExternalMetricSource{
+			MetricName:    "deadbeef",
+			HighWatermark: resource.NewQuantity(8, resource.DecimalSI),
+			LowWatermark:  resource.NewQuantity(4, resource.DecimalSI),
+		},
+	}
+	tests := []struct {
+		name        string
+		metricSpec  v1alpha1.MetricSpec
+		utilization int64
+		position    string
+		reason      string
+		expected    float64
+	}{
+		{
+			name:        "above the high watermark",
+			metricSpec:  metricSpec,
+			utilization: 12000,
+			position:    v1alpha1.DecisionReasonAboveHighWatermark,
+			expected:    1.5,
+		},
+		{
+			name:        "below the low watermark",
+			metricSpec:  metricSpec,
+			utilization: 2000,
+			position:    v1alpha1.DecisionReasonBelowLowWatermark,
+			expected:    0.5,
+		},
+		{
+			name:        "within the watermarks",
+			metricSpec:  metricSpec,
+			utilization: 6000,
+			position:    v1alpha1.DecisionReasonWithinTolerance,
+			expected:    1,
+		},
+		{
+			name:        "direction blocked",
+			metricSpec:  metricSpec,
+			utilization: 12000,
+			position:    v1alpha1.DecisionReasonAboveHighWatermark,
+			reason:      v1alpha1.DecisionReasonDirectionBlocked,
+			expected:    1,
+		},
+		{
+			name: "resource metric",
+			metricSpec: v1alpha1.MetricSpec{
+				Type: v1alpha1.ResourceMetricSourceType,
+				Resource: &v1alpha1.ResourceMetricSource{
+					Name:          corev1.ResourceCPU,
+					HighWatermark: resource.NewMilliQuantity(800, resource.DecimalSI),
+					LowWatermark:  resource.NewMilliQuantity(400, resource.DecimalSI),
+				},
+			},
+			utilization: 1600,
+			position:    v1alpha1.DecisionReasonAboveHighWatermark,
+			expected:    2,
+		},
+		{
+			name: "watermark not positive",
+			metricSpec: v1alpha1.MetricSpec{
+				Type: v1alpha1.ExternalMetricSourceType,
+				External: &v1alpha1.ExternalMetricSource{
+					MetricName:    "deadbeef",
+					HighWatermark: resource.NewQuantity(8, resource.DecimalSI),
+					LowWatermark:  resource.NewQuantity(0, resource.DecimalSI),
+				},
+			},
+			utilization: -1000,
+			position:    v1alpha1.DecisionReasonBelowLowWatermark,
+			expected:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, getLoadRatio(tt.metricSpec, tt.utilization, tt.position, tt.reason), 1e-9)
+		})
+	}
+}
+
+func TestGetWeightedLoadRatio(t *testing.T) {
+	tests := []struct {
+		name            string
+		recommendations []weightedRecommendation
+		expected        float64
+	}{
+		{
+			name:     "no recommendation",
+			expected: 1,
+		},
+		{
+			name:            "single metric",
+			recommendations: []weightedRecommendation{{ratio: 1.5, weight: 3}},
+			expected:        1.5,
+		},
+		{
+			name:            "same weights",
+			recommendations: []weightedRecommendation{{ratio: 0.5, weight: 1}, {ratio: 2, weight: 1}},
+			expected:        1.25,
+		},
+		{
+			name:            "heavier metric drives the recommendation",
+			recommendations: []weightedRecommendation{{ratio: 1, weight: 3}, {ratio: 3, weight: 1}},
+			expected:        1.5,
+		},
+		{
+			name:            "fractional weights",
+			recommendations: []weightedRecommendation{{ratio: 2, weight: 0.25}, {ratio: 0.5, weight: 0.5}, {ratio: 1, weight: 0.25}},
+			expected:        1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, getWeightedLoadRatio(tt.recommendations), 1e-9)
+		})
+	}
+}
+
+func TestComputeReplicasForMetricsWeightedSum(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	newMetric := func(name string, weight *resource.Quantity) v1alpha1.MetricSpec {
+		return v1alpha1.MetricSpec{
+			Type: v1alpha1.ExternalMetricSourceType,
+			External: &v1alpha1.ExternalMetricSource{
+				MetricName:     name,
+				MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+				HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+				LowWatermark:   resource.NewQuantity(4, resource.DecimalSI),
+			},
+			Weight: weight,
+		}
+	}
+	// the queue is twice its high watermark, the latency within its watermarks or half its low watermark.
+	queue := ReplicaCalculation{10, 16000, time.Time{}, v1alpha1.DecisionReasonAboveHighWatermark, v1alpha1.DecisionReasonAboveHighWatermark, nil}
+	latencyWithin := ReplicaCalculation{5, 6000, time.Time{}, v1alpha1.DecisionReasonWithinTolerance, v1alpha1.DecisionReasonWithinTolerance, nil}
+	latencyBelow := ReplicaCalculation{3, 2000, time.Time{}, v1alpha1.DecisionReasonBelowLowWatermark, v1alpha1.DecisionReasonBelowLowWatermark, nil}
+
+	tests := []struct {
+		name              string
+		metricAggregation string
+		replicaRounding   string
+		currentReplicas   int32
+		queueWeight       *resource.Quantity
+		latency           ReplicaCalculation
+		expectedReplicas  int32
+		expectedMetric    string
+		expectedValue     *resource.Quantity
+	}{
+		{
+			name:             "max by default",
+			currentReplicas:  5,
+			queueWeight:      resource.NewQuantity(3, resource.DecimalSI),
+			latency:          latencyWithin,
+			expectedReplicas: 10,
+			expectedMetric:   "queue{map[label:value]}",
+			expectedValue:    resource.NewMilliQuantity(16000, resource.DecimalSI),
+		},
+		{
+			name:              "same weights",
+			metricAggregation: "weighted-sum",
+			currentReplicas:   5,
+			latency:           latencyWithin,
+			expectedReplicas:  8,
+			expectedMetric:    "weighted-sum",
+		},
+		{
+			name:              "heavier queue",
+			metricAggregation: "weighted-sum",
+			currentReplicas:   5,
+			queueWeight:       resource.NewQuantity(3, resource.DecimalSI),
+			latency:           latencyWithin,
+			expectedReplicas:  9,
+			expectedMetric:    "weighted-sum",
+		},
+		{
+			name:              "lighter queue rounded down",
+			metricAggregation: "weighted-sum",
+			replicaRounding:   "floor",
+			currentReplicas:   5,
+			queueWeight:       resource.NewMilliQuantity(500, resource.DecimalSI),
+			latency:           latencyWithin,
+			expectedReplicas:  6,
+			expectedMetric:    "weighted-sum",
+		},
+		{
+			name:              "latency below its low watermark",
+			metricAggregation: "weighted-sum",
+			currentReplicas:   5,
+			latency:           latencyBelow,
+			expectedReplicas:  7,
+			expectedMetric:    "weighted-sum",
+		},
+		{
+			name:              "highest recommendation from zero",
+			metricAggregation: "weighted-sum",
+			currentReplicas:   0,
+			latency:           latencyWithin,
+			expectedReplicas:  10,
+			expectedMetric:    "queue{map[label:value]}",
+			expectedValue:     resource.NewMilliQuantity(16000, resource.DecimalSI),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+				Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+					ScaleTargetRef:    testCrossVersionObjectRef,
+					MetricAggregation: tt.metricAggregation,
+					ReplicaRounding:   tt.replicaRounding,
+					Metrics:           []v1alpha1.MetricSpec{newMetric("queue", tt.queueWeight), newMetric("latency", nil)},
+					MaxReplicas:       12,
+				},
+			})
+			defer cleanupAssociatedMetrics(wpa, false)
+			calculations := map[string]ReplicaCalculation{"queue": queue, "latency": tt.latency}
+			r := &WatermarkPodAutoscalerReconciler{
+				replicaCalc: &fakeReplicaCalculator{
+					replicasFunc: func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+						return calculations[metric.External.MetricName], nil
+					},
+				},
+				eventRecorder: record.NewFakeRecorder(10),
+			}
+			scale := &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: tt.currentReplicas}, Status: autoscalingv1.ScaleStatus{Replicas: tt.currentReplicas}}
+			replicas, metric, statuses, _, err := r.computeReplicasForMetrics(logf.Log, wpa, scale)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedReplicas, replicas)
+			assert.Equal(t, tt.expectedMetric, metric)
+			assert.Equal(t, tt.expectedMetric, wpa.Status.ScalingMetricName)
+			assert.Equal(t, tt.expectedValue, wpa.Status.ScalingMetricValue)
+			assert.Len(t, statuses, 2)
+		})
+	}
+}
+
+func TestSetStatusReplicasGauges(t *testing.T) {
+	wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{ScaleTargetRef: testCrossVersionObjectRef},
+	})
+	promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}
+
+	setStatus(wpa, 3, 5, nil, true)
+	assert.Equal(t, float64(3), testutil.ToFloat64(replicaCurrent.With(promLabels)))
+	assert.Equal(t, float64(5), testutil.ToFloat64(replicaDesired.With(promLabels)))
+
+	// the gauges are updated even when the recommendation is the current number of replicas
+	setStatus(wpa, 5, 5, nil, false)
+	assert.Equal(t, float64(5), testutil.ToFloat64(replicaCurrent.With(promLabels)))
+	assert.Equal(t, float64(5), testutil.ToFloat64(replicaDesired.With(promLabels)))
+
+	cleanupAssociatedMetrics(wpa, false)
+	assert.False(t, replicaCurrent.Delete(promLabels))
+	assert.False(t, replicaDesired.Delete(promLabels))
+}
+
+func TestAppendRecommendationHistory(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int32
+		want  int
+	}{
+		{
+			name: "default limit",
+			want: v1alpha1.DefaultRecommendationHistoryLimit,
+		},
+		{
+			name:  "custom limit",
+			limit: 3,
+			want:  3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa := &v1alpha1.WatermarkPodAutoscaler{Spec: v1alpha1.WatermarkPodAutoscalerSpec{RecommendationHistoryLimit: tt.limit}}
+			for i := int32(1); i <= 25; i++ {
+				wpa.Status.ScalingMetricValue = resource.NewQuantity(int64(i*10), resource.DecimalSI)
+				appendRecommendationHistory(wpa, i, i%2 == 0)
+				require.True(t, len(wpa.Status.RecommendationHistory) <= tt.want)
+			}
+			history := wpa.Status.RecommendationHistory
+			require.Len(t, history, tt.want)
+			// the oldest entries are dropped first.
+			assert.Equal(t, int32(25-tt.want+1), history[0].Recommendation)
+			last := history[len(history)-1]
+			assert.Equal(t, int32(25), last.Recommendation)
+			assert.False(t, last.Applied)
+			assert.Equal(t, int64(250), last.Utilization.Value())
+			// the utilization is copied from the status.
+			wpa.Status.ScalingMetricValue.Set(0)
+			assert.Equal(t, int64(250), last.Utilization.Value())
+		})
+	}
+}
+
+func TestScalingEventReason(t *testing.T) {
+	assert.Equal(t, v1alpha1.ReasonScaledUp, scalingEventReason(3, 5))
+	assert.Equal(t, v1alpha1.ReasonScaledDown, scalingEventReason(5, 3))
+}
+
+func TestDescribeScalingMetric(t *testing.T) {
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		Status: v1alpha1.WatermarkPodAutoscalerStatus{
+			ScalingMetricValue: resource.NewMilliQuantity(1500, resource.DecimalSI),
+		},
+	}
+	assert.Equal(t, "; metric: deadbeef{map[label:value]}; utilization: 1500m", describeScalingMetric(wpa, "deadbeef{map[label:value]}"))
+	assert.Equal(t, "", describeScalingMetric(wpa, ""))
+
+	wpa.Spec.Metrics = []v1alpha1.MetricSpec{
+		{
+			Type: v1alpha1.ExternalMetricSourceType,
+			External: &v1alpha1.ExternalMetricSource{
+				MetricName:     "deadbeef",
+				MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+				HighWatermark:  resource.NewQuantity(2, resource.DecimalSI),
+				LowWatermark:   resource.NewQuantity(1, resource.DecimalSI),
+			},
+		},
+	}
+	assert.Equal(t, "; metric: deadbeef{map[label:value]}; utilization: 1500m; low watermark: 1; high watermark: 2", describeScalingMetric(wpa, "deadbeef{map[label:value]}"))
+}
+
+type fakeReplicaCalculator struct {
+	replicasFunc  func(metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error)
+	readinessFunc func(target *autoscalingv1.Scale) (readyPodCount, podCount int32, err error)
+}
+
+func (f *fakeReplicaCalculator) GetExternalMetricReplicas(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+	if f.replicasFunc != nil {
+		return f.replicasFunc(metric, wpa)
+	}
+	return ReplicaCalculation{}, nil
+}
+
+func (f *fakeReplicaCalculator) GetResourceMetricReplicas(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+	if f.replicasFunc != nil {
+		return f.replicasFunc(metric, wpa)
+	}
+	return ReplicaCalculation{}, nil
+}
+
+func (f *fakeReplicaCalculator) GetObjectMetricReplicas(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+	if f.replicasFunc != nil {
+		return f.replicasFunc(metric, wpa)
+	}
+	return ReplicaCalculation{}, nil
+}
+
+func (f *fakeReplicaCalculator) GetPodsMetricReplicas(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, metric v1alpha1.MetricSpec, wpa *v1alpha1.WatermarkPodAutoscaler) (replicaCalculation ReplicaCalculation, err error) {
+	if f.replicasFunc != nil {
+		return f.replicasFunc(metric, wpa)
+	}
+	return ReplicaCalculation{}, nil
+}
+
+func (f *fakeReplicaCalculator) GetPodReadiness(ctx context.Context, logger logr.Logger, target *autoscalingv1.Scale, wpa *v1alpha1.WatermarkPodAutoscaler) (readyPodCount, podCount int32, err error) {
+	if f.readinessFunc != nil {
+		return f.readinessFunc(target)
+	}
+	return target.Status.Replicas, target.Status.Replicas, nil
+}
+
+func TestDefaultWatermarkPodAutoscaler(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	tests := []struct {
+		name    string
+		wpaName string
+		wpaNs   string
+		err     error
+		spec    *v1alpha1.WatermarkPodAutoscalerSpec
+	}{
+		{
+			name:    "missing scaleTarget",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec:    &v1alpha1.WatermarkPodAutoscalerSpec{},
+			err:     fmt.Errorf("the Spec.ScaleTargetRef should be populated, currently Kind: and/or Name: are not set properly"),
+		},
+		{
+			name:    "number of MinReplicas is missing",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef: testCrossVersionObjectRef,
+			},
+			err: fmt.Errorf("watermark pod autoscaler requires the minimum number of replicas to be configured and inferior to the maximum"),
+		},
+		{
+			name:    "number of MinReplicas is incorrect",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef: testCrossVersionObjectRef,
+				MinReplicas:    getReplicas(4),
+				MaxReplicas:    3,
+			},
+			err: fmt.Errorf("watermark pod autoscaler requires the minimum number of replicas to be configured and inferior to the maximum"),
+		},
+		{
+			name:    "tolerance is out of bounds",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef: testCrossVersionObjectRef,
+				MinReplicas:    getReplicas(4),
+				MaxReplicas:    7,
+				Tolerance:      *resource.NewMilliQuantity(5000, resource.DecimalSI),
+			},
+			err: fmt.Errorf("tolerance should be set as a quantity between 0 and 1, currently set to : 5, which is 500%%"),
+		},
+		{
+			name:    "scaleuplimitfactor is out of bounds",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				ScaleUpLimitFactor:   resource.NewQuantity(101, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+				Tolerance:            *resource.NewMilliQuantity(50, resource.DecimalSI),
+			},
+			err: fmt.Errorf("scaleuplimitfactor should be set as a quantity between 0 and 100, currently set to : 101, which could yield a 101%% growth"),
+		},
+		{
+			name:    "scaledownlimitfactor is out of bounds",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				ScaleUpLimitFactor:   resource.NewQuantity(34, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(134, resource.DecimalSI),
+				Tolerance:            *resource.NewMilliQuantity(50, resource.DecimalSI),
+			},
+			err: fmt.Errorf("scaledownlimitfactor should be set as a quantity between 0 and 100 (exc.), currently set to : 134, which could yield a 134%% decrease"),
+		},
+		{
+			name:    "upscaletolerance is out of bounds",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:   testCrossVersionObjectRef,
+				MinReplicas:      getReplicas(4),
+				MaxReplicas:      7,
+				Tolerance:        *resource.NewMilliQuantity(50, resource.DecimalSI),
+				UpscaleTolerance: resource.NewMilliQuantity(1500, resource.DecimalSI),
+			},
+			err: fmt.Errorf("upscaletolerance should be set as a quantity between 0 and 1, currently set to : 1500m, which is 150%%"),
+		},
+		{
+			name:    "downscaletolerance is out of bounds",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:     testCrossVersionObjectRef,
+				MinReplicas:        getReplicas(4),
+				MaxReplicas:        7,
+				Tolerance:          *resource.NewMilliQuantity(50, resource.DecimalSI),
+				DownscaleTolerance: resource.NewMilliQuantity(-100, resource.DecimalSI),
+			},
+			err: fmt.Errorf("downscaletolerance should be set as a quantity between 0 and 1, currently set to : -100m, which is -10%%"),
+		},
+		{
+			name:    "smoothingFactor is out of bounds",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:  testCrossVersionObjectRef,
+				MinReplicas:     getReplicas(4),
+				MaxReplicas:     7,
+				Tolerance:       *resource.NewMilliQuantity(50, resource.DecimalSI),
+				SmoothingFactor: resource.NewQuantity(0, resource.DecimalSI),
+			},
+			err: fmt.Errorf("smoothingFactor should be set as a quantity between 0 (exc.) and 1, currently set to : 0"),
+		},
+		{
+			name:    "tolerance of a metric is out of bounds",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				Tolerance:            *resource.NewMilliQuantity(50, resource.DecimalSI),
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+				Metrics: []v1alpha1.MetricSpec{
+					{
+						Type: v1alpha1.ExternalMetricSourceType,
+						External: &v1alpha1.ExternalMetricSource{
+							MetricName:     "deadbeef",
+							MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+							HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+							LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+							Tolerance:      resource.NewMilliQuantity(1200, resource.DecimalSI),
+						},
+					},
+				},
+			},
+			err: fmt.Errorf("tolerance of External metric deadbeef{map[label:value]} should be set as a quantity between 0 and 1, currently set to : 1200m, which is 120%%"),
+		},
+		{
+			name:    "algorithm is unknown",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				Algorithm:            "median",
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+			},
+			err: fmt.Errorf("algorithm should be either absolute, average, averageByRequest, count or logarithmic, currently set to : median"),
+		},
+		{
+			name:    "downscale algorithm is unknown",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				UpscaleAlgorithm:     "average",
+				DownscaleAlgorithm:   "median",
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+			},
+			err: fmt.Errorf("downscaleAlgorithm should be either absolute, average, averageByRequest, count or logarithmic, currently set to : median"),
+		},
+		{
+			name:    "tolerance mode is unknown",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				ToleranceMode:        "additive",
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+			},
+			err: fmt.Errorf("toleranceMode should be either multiplicative or band, currently set to : additive"),
+		},
+		{
+			name:    "replica rounding is unknown",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				ReplicaRounding:      "round",
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+			},
+			err: fmt.Errorf("replicaRounding should be either legacy, ceil, floor or nearest, currently set to : round"),
+		},
+		{
+			name:    "algorithm of a metric is unknown",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				Algorithm:            "absolute",
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+				Metrics: []v1alpha1.MetricSpec{
+					{
+						Type: v1alpha1.ExternalMetricSourceType,
+						External: &v1alpha1.ExternalMetricSource{
+							MetricName:     "deadbeef",
+							MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+							HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+							LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+							Algorithm:      "Average",
+						},
+					},
+				},
+			},
+			err: fmt.Errorf("algorithm of External metric deadbeef{map[label:value]} should be either absolute, average, averageByRequest, count or logarithmic, currently set to : Average"),
+		},
+		{
+			name:    "target type of a metric is unknown",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				Algorithm:            "absolute",
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+				Metrics: []v1alpha1.MetricSpec{
+					{
+						Type: v1alpha1.ExternalMetricSourceType,
+						External: &v1alpha1.ExternalMetricSource{
+							MetricName:     "deadbeef",
+							MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+							HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+							LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+							TargetType:     "Utilization",
+						},
+					},
+				},
+			},
+			err: fmt.Errorf("targetType of External metric deadbeef{map[label:value]} should be either AverageValue or Value, currently set to : Utilization"),
+		},
+		{
+			name:    "target value of a metric",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				Algorithm:            "absolute",
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+				Metrics: []v1alpha1.MetricSpec{
+					{
+						Type: v1alpha1.ExternalMetricSourceType,
+						External: &v1alpha1.ExternalMetricSource{
+							MetricName:      "deadbeef",
+							MetricSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+							TargetValue:     resource.NewQuantity(70, resource.DecimalSI),
+							DeadbandPercent: 10,
+						},
+					},
+				},
+			},
+			err: nil,
+		},
+		{
+			name:    "target value of a metric along with the watermarks",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				Algorithm:            "absolute",
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+				Metrics: []v1alpha1.MetricSpec{
+					{
+						Type: v1alpha1.ExternalMetricSourceType,
+						External: &v1alpha1.ExternalMetricSource{
+							MetricName:      "deadbeef",
+							MetricSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+							HighWatermark:   resource.NewQuantity(80, resource.DecimalSI),
+							TargetValue:     resource.NewQuantity(70, resource.DecimalSI),
+							DeadbandPercent: 10,
+						},
+					},
+				},
+			},
+			err: fmt.Errorf("targetValue of External metric deadbeef can't be set along with the watermarks"),
+		},
+		{
+			name:    "target value of a metric without a deadband",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				Algorithm:            "absolute",
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+				Metrics: []v1alpha1.MetricSpec{
+					{
+						Type: v1alpha1.ExternalMetricSourceType,
+						External: &v1alpha1.ExternalMetricSource{
+							MetricName:     "deadbeef",
+							MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+							TargetValue:    resource.NewQuantity(70, resource.DecimalSI),
+						},
+					},
+				},
+			},
+			err: fmt.Errorf("deadbandPercent of External metric deadbeef should be between 1 and 100, currently set to : 0"),
+		},
+		{
+			name:    "utilization watermark of a resource metric above 100%",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				Algorithm:            "average",
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+				Metrics: []v1alpha1.MetricSpec{
+					{
+						Type: v1alpha1.ResourceMetricSourceType,
+						Resource: &v1alpha1.ResourceMetricSource{
+							Name:           "cpu",
+							MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+							HighWatermark:  resource.NewQuantity(120, resource.DecimalSI),
+							LowWatermark:   resource.NewQuantity(60, resource.DecimalSI),
+							TargetType:     "Utilization",
+						},
+					},
+				},
+			},
+			err: fmt.Errorf("watermarks of Resource metric cpu{map[label:value]} should be percentages between 0 and 100 with the Utilization targetType, currently set to : 60 and 120"),
+		},
+		{
+			name:    "aggregator function of a metric is unknown",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				Algorithm:            "absolute",
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+				Metrics: []v1alpha1.MetricSpec{
+					{
+						Type: v1alpha1.ExternalMetricSourceType,
+						External: &v1alpha1.ExternalMetricSource{
+							MetricName:     "deadbeef",
+							MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+							HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+							LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+							AggregatorFunc: "median",
+						},
+					},
+				},
+			},
+			err: fmt.Errorf("aggregatorFunc of External metric deadbeef{map[label:value]} should be one of sum, avg, max, min, p50, p90, p95 or p99, currently set to : median"),
+		},
+		{
+			name:    "idle watermark of a metric above the low watermark",
 			wpaName: "test-1",
 			wpaNs:   "default",
-			spec:    &v1alpha1.WatermarkPodAutoscalerSpec{},
-			err:     fmt.Errorf("the Spec.ScaleTargetRef should be populated, currently Kind: and/or Name: are not set properly"),
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:         testCrossVersionObjectRef,
+				MinReplicas:            getReplicas(0),
+				MaxReplicas:            7,
+				Algorithm:              "absolute",
+				ScaleDownToZeroEnabled: true,
+				ScaleUpLimitFactor:     resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				Metrics: []v1alpha1.MetricSpec{
+					{
+						Type: v1alpha1.ExternalMetricSourceType,
+						External: &v1alpha1.ExternalMetricSource{
+							MetricName:     "deadbeef",
+							MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+							HighWatermark:  resource.NewQuantity(8, resource.DecimalSI),
+							LowWatermark:   resource.NewQuantity(3, resource.DecimalSI),
+							IdleWatermark:  resource.NewQuantity(4, resource.DecimalSI),
+						},
+					},
+				},
+			},
+			err: fmt.Errorf("idleWatermark of External metric deadbeef{map[label:value]} has to be strictly inferior to the Low Watermark"),
 		},
 		{
-			name:    "number of MinReplicas is missing",
+			name:    "object metric without a described object, spec is invalid",
 			wpaName: "test-1",
 			wpaNs:   "default",
 			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
-				ScaleTargetRef: testCrossVersionObjectRef,
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+				Metrics: []v1alpha1.MetricSpec{
+					{
+						Type: v1alpha1.ObjectMetricSourceType,
+						Object: &v1alpha1.ObjectMetricSource{
+							DescribedObject: v1alpha1.CrossVersionObjectReference{Kind: "Ingress"},
+							MetricName:      "requests-per-second",
+							HighWatermark:   resource.NewQuantity(100, resource.DecimalSI),
+							LowWatermark:    resource.NewQuantity(50, resource.DecimalSI),
+						},
+					},
+				},
 			},
-			err: fmt.Errorf("watermark pod autoscaler requires the minimum number of replicas to be configured and inferior to the maximum"),
+			err: fmt.Errorf("the describedObject of the Object metric requests-per-second should be populated, currently Kind:Ingress and/or Name: are not set properly"),
 		},
 		{
-			name:    "number of MinReplicas is incorrect",
+			name:    "object metric with inverted watermarks, spec is invalid",
 			wpaName: "test-1",
 			wpaNs:   "default",
 			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
-				ScaleTargetRef: testCrossVersionObjectRef,
-				MinReplicas:    getReplicas(4),
-				MaxReplicas:    3,
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+				Metrics: []v1alpha1.MetricSpec{
+					{
+						Type: v1alpha1.ObjectMetricSourceType,
+						Object: &v1alpha1.ObjectMetricSource{
+							DescribedObject: v1alpha1.CrossVersionObjectReference{Kind: "Ingress", Name: "frontend"},
+							MetricName:      "requests-per-second",
+							HighWatermark:   resource.NewQuantity(50, resource.DecimalSI),
+							LowWatermark:    resource.NewQuantity(100, resource.DecimalSI),
+						},
+					},
+				},
 			},
-			err: fmt.Errorf("watermark pod autoscaler requires the minimum number of replicas to be configured and inferior to the maximum"),
+			err: fmt.Errorf("Low WaterMark of Object metric requests-per-second{Ingress/frontend} has to be strictly inferior to the High Watermark"),
 		},
 		{
-			name:    "tolerance is out of bounds",
+			// If Tolerance is unset, it will be considered to be 0 but it is not invalid.
+			// As we call the defaulting methods prior in the controller, the value will be defaulted to the defined `defaultTolerance`
+			name:    "tolerance is not set, spec is valid",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:       testCrossVersionObjectRef,
+				MinReplicas:          getReplicas(4),
+				MaxReplicas:          7,
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
+			},
+			err: nil,
+		},
+		{
+			// If scaleup or scaledown is unset, it would be defaulted later on but as we use pointers we need to ensure they are not nil.
+			name:    "scaleup or scaledown factors unset, spec is invalid",
 			wpaName: "test-1",
 			wpaNs:   "default",
 			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
 				ScaleTargetRef: testCrossVersionObjectRef,
 				MinReplicas:    getReplicas(4),
 				MaxReplicas:    7,
-				Tolerance:      *resource.NewMilliQuantity(5000, resource.DecimalSI),
 			},
-			err: fmt.Errorf("tolerance should be set as a quantity between 0 and 1, currently set to : 5, which is 500%%"),
+			err: fmt.Errorf("scaleuplimitfactor and scaledownlimitfactor can't be nil, make sure the WPA spec is defaulted"),
 		},
 		{
-			name:    "scaleuplimitfactor is out of bounds",
+			name:    "unknown aggregation of the metrics",
 			wpaName: "test-1",
 			wpaNs:   "default",
 			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
 				ScaleTargetRef:       testCrossVersionObjectRef,
 				MinReplicas:          getReplicas(4),
 				MaxReplicas:          7,
-				ScaleUpLimitFactor:   resource.NewQuantity(101, resource.DecimalSI),
+				MetricAggregation:    "avg",
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
 				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
-				Tolerance:            *resource.NewMilliQuantity(50, resource.DecimalSI),
 			},
-			err: fmt.Errorf("scaleuplimitfactor should be set as a quantity between 0 and 100, currently set to : 101, which could yield a 101%% growth"),
+			err: fmt.Errorf("metricAggregation should be either max or weighted-sum, currently set to : avg"),
 		},
 		{
-			name:    "scaledownlimitfactor is out of bounds",
+			name:    "unknown metric error policy",
 			wpaName: "test-1",
 			wpaNs:   "default",
 			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
 				ScaleTargetRef:       testCrossVersionObjectRef,
 				MinReplicas:          getReplicas(4),
 				MaxReplicas:          7,
-				ScaleUpLimitFactor:   resource.NewQuantity(34, resource.DecimalSI),
-				ScaleDownLimitFactor: resource.NewQuantity(134, resource.DecimalSI),
-				Tolerance:            *resource.NewMilliQuantity(50, resource.DecimalSI),
+				MetricErrorPolicy:    "scaleToMax",
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
 			},
-			err: fmt.Errorf("scaledownlimitfactor should be set as a quantity between 0 and 100 (exc.), currently set to : 134, which could yield a 134%% decrease"),
+			err: fmt.Errorf("metricErrorPolicy should be either maintain, scaleToMin or lastKnownGood, currently set to : scaleToMax"),
 		},
 		{
-			// If Tolerance is unset, it will be considered to be 0 but it is not invalid.
-			// As we call the defaulting methods prior in the controller, the value will be defaulted to the defined `defaultTolerance`
-			name:    "tolerance is not set, spec is valid",
+			name:    "unknown scale direction",
 			wpaName: "test-1",
 			wpaNs:   "default",
 			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
 				ScaleTargetRef:       testCrossVersionObjectRef,
 				MinReplicas:          getReplicas(4),
 				MaxReplicas:          7,
+				ScaleDirection:       "none",
 				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
 				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
 			},
-			err: nil,
+			err: fmt.Errorf("scaleDirection should be either both, up or down, currently set to : none"),
 		},
 		{
-			// If scaleup or scaledown is unset, it would be defaulted later on but as we use pointers we need to ensure they are not nil.
-			name:    "scaleup or scaledown factors unset, spec is invalid",
+			name:    "scale approval webhook without scheme",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:          testCrossVersionObjectRef,
+				MinReplicas:             getReplicas(4),
+				MaxReplicas:             7,
+				ScaleApprovalWebhookURL: "approval.example.com/scale",
+				ScaleUpLimitFactor:      resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor:    resource.NewQuantity(10, resource.DecimalSI),
+			},
+			err: fmt.Errorf("scaleApprovalWebhookURL should be an absolute http or https URL, currently set to : approval.example.com/scale"),
+		},
+		{
+			name:    "recommendation history limit above the maximum",
+			wpaName: "test-1",
+			wpaNs:   "default",
+			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:             testCrossVersionObjectRef,
+				MinReplicas:                getReplicas(4),
+				MaxReplicas:                7,
+				RecommendationHistoryLimit: 51,
+				ScaleUpLimitFactor:         resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor:       resource.NewQuantity(10, resource.DecimalSI),
+			},
+			err: fmt.Errorf("recommendationHistoryLimit should be between 0 and 50, currently set to : 51"),
+		},
+		{
+			name:    "invalid window of the watermark schedule",
 			wpaName: "test-1",
 			wpaNs:   "default",
 			spec: &v1alpha1.WatermarkPodAutoscalerSpec{
 				ScaleTargetRef: testCrossVersionObjectRef,
 				MinReplicas:    getReplicas(4),
 				MaxReplicas:    7,
+				WatermarkSchedule: []v1alpha1.WatermarkScheduleEntry{
+					{Start: "09:00", End: "6pm", HighWatermark: resource.NewQuantity(10, resource.DecimalSI)},
+				},
+				ScaleUpLimitFactor:   resource.NewQuantity(10, resource.DecimalSI),
+				ScaleDownLimitFactor: resource.NewQuantity(10, resource.DecimalSI),
 			},
-			err: fmt.Errorf("scaleuplimitfactor and scaledownlimitfactor can't be nil, make sure the WPA spec is defaulted"),
+			err: fmt.Errorf("the window of the entry 0 of the watermarkSchedule is invalid: invalid time \"6pm\", should be formatted as HH:MM"),
 		},
 		{
 			name:    "correct case",
@@ -1063,6 +4134,81 @@ func TestReconcileWatermarkPodAutoscaler_shouldScale(t *testing.T) {
 	}
 }
 
+func TestReconcileWatermarkPodAutoscaler_shouldScaleForbiddenWindowExpiry(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	lastScaleTime := time.Unix(1232000, 0)
+	wpa := test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+		Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+			ScaleTargetRef:                  testCrossVersionObjectRef,
+			UpscaleForbiddenWindowSeconds:   60,
+			DownscaleForbiddenWindowSeconds: 300,
+		},
+		Status: &v1alpha1.WatermarkPodAutoscalerStatus{
+			LastScaleTime: &metav1.Time{Time: lastScaleTime},
+		},
+	})
+	defer cleanupAssociatedMetrics(wpa, false)
+	promLabels := func(transition string) prometheus.Labels {
+		return prometheus.Labels{
+			wpaNamePromLabel:           wpa.Name,
+			transitionPromLabel:        transition,
+			resourceNamespacePromLabel: wpa.Namespace,
+			resourceNamePromLabel:      wpa.Spec.ScaleTargetRef.Name,
+			resourceKindPromLabel:      wpa.Spec.ScaleTargetRef.Kind,
+		}
+	}
+
+	tests := []struct {
+		name            string
+		elapsed         time.Duration
+		desiredReplicas int32
+		shouldScale     bool
+		upscaleActive   float64
+		downscaleActive float64
+	}{
+		{
+			name:            "upscale ignored within the upscale forbidden window",
+			elapsed:         30 * time.Second,
+			desiredReplicas: 10,
+			shouldScale:     false,
+			upscaleActive:   1,
+			downscaleActive: 1,
+		},
+		{
+			name:            "upscale honored once the upscale forbidden window expired",
+			elapsed:         60 * time.Second,
+			desiredReplicas: 10,
+			shouldScale:     true,
+			upscaleActive:   0,
+			downscaleActive: 1,
+		},
+		{
+			name:            "downscale ignored within the downscale forbidden window",
+			elapsed:         299 * time.Second,
+			desiredReplicas: 6,
+			shouldScale:     false,
+			upscaleActive:   0,
+			downscaleActive: 1,
+		},
+		{
+			name:            "downscale honored once the downscale forbidden window expired",
+			elapsed:         300 * time.Second,
+			desiredReplicas: 6,
+			shouldScale:     true,
+			upscaleActive:   0,
+			downscaleActive: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scale := shouldScale(logf.Log.WithName(tt.name), wpa, 8, tt.desiredReplicas, lastScaleTime.Add(tt.elapsed))
+			assert.Equal(t, tt.shouldScale, scale)
+			assert.Equal(t, tt.upscaleActive, testutil.ToFloat64(forbiddenWindowActive.With(promLabels("upscale"))))
+			assert.Equal(t, tt.downscaleActive, testutil.ToFloat64(forbiddenWindowActive.With(promLabels("downscale"))))
+		})
+	}
+}
+
 func TestCalculateScaleUpLimit(t *testing.T) {
 	logf.SetLogger(logf.ZapLogger(true))
 
@@ -1102,6 +4248,36 @@ func TestCalculateScaleUpLimit(t *testing.T) {
 			cappedUpscale:   731,
 			currentReplicas: 423,
 		},
+		{
+			name:            "scale up from zero",
+			wpa:             makeWPAScaleToZero(makeWPAScaleFactor(50, 0), 4),
+			cappedUpscale:   4,
+			currentReplicas: 0,
+		},
+		{
+			name:            "absolute limit below the factor",
+			wpa:             makeWPAScaleUpLimit(makeWPAScaleFactor(100, 0), 10),
+			cappedUpscale:   40,
+			currentReplicas: 30,
+		},
+		{
+			name:            "factor below the absolute limit",
+			wpa:             makeWPAScaleUpLimit(makeWPAScaleFactor(100, 0), 10),
+			cappedUpscale:   6,
+			currentReplicas: 3,
+		},
+		{
+			name:            "absolute limit with upscaling disabled",
+			wpa:             makeWPAScaleUpLimit(makeWPAScaleFactor(0, 0), 10),
+			cappedUpscale:   3,
+			currentReplicas: 3,
+		},
+		{
+			name:            "absolute limit does not cap the scale up from zero",
+			wpa:             makeWPAScaleUpLimit(makeWPAScaleToZero(makeWPAScaleFactor(50, 0), 4), 2),
+			cappedUpscale:   4,
+			currentReplicas: 0,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1145,6 +4321,24 @@ func TestCalculateScaleDownLimit(t *testing.T) {
 			cappedDownscale: 115,
 			currentReplicas: 423,
 		},
+		{
+			name:            "absolute limit more restrictive than the factor",
+			wpa:             makeWPAScaleDownLimit(makeWPAScaleFactor(0, 50), 5),
+			cappedDownscale: 95,
+			currentReplicas: 100,
+		},
+		{
+			name:            "factor more restrictive than the absolute limit",
+			wpa:             makeWPAScaleDownLimit(makeWPAScaleFactor(0, 10), 20),
+			cappedDownscale: 90,
+			currentReplicas: 100,
+		},
+		{
+			name:            "absolute limit with downscaling disabled",
+			wpa:             makeWPAScaleDownLimit(makeWPAScaleFactor(0, 0), 5),
+			cappedDownscale: 100,
+			currentReplicas: 100,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1171,6 +4365,22 @@ func makeWPAScaleFactor(scaleUpLimit, scaleDownLimit int32) *v1alpha1.WatermarkP
 	}
 }
 
+func makeWPAScaleUpLimit(wpa *v1alpha1.WatermarkPodAutoscaler, scaleUpLimit int32) *v1alpha1.WatermarkPodAutoscaler {
+	wpa.Spec.ScaleUpLimit = scaleUpLimit
+	return wpa
+}
+
+func makeWPAScaleDownLimit(wpa *v1alpha1.WatermarkPodAutoscaler, scaleDownLimit int32) *v1alpha1.WatermarkPodAutoscaler {
+	wpa.Spec.ScaleDownLimit = scaleDownLimit
+	return wpa
+}
+
+func makeWPAScaleToZero(wpa *v1alpha1.WatermarkPodAutoscaler, scaleUpFromZeroReplicas int32) *v1alpha1.WatermarkPodAutoscaler {
+	wpa.Spec.ScaleDownToZeroEnabled = true
+	wpa.Spec.ScaleUpFromZeroReplicas = scaleUpFromZeroReplicas
+	return wpa
+}
+
 func TestConvertDesiredReplicasWithRules(t *testing.T) {
 	logf.SetLogger(logf.ZapLogger(true))
 
@@ -1246,6 +4456,60 @@ func TestConvertDesiredReplicasWithRules(t *testing.T) {
 			normalizedReplicas:        55,
 			wpa:                       makeWPASpec(3, 60, 40, 0),
 		},
+		{
+			name:                      "desiredReplicas far below the absolute scaleDownLimit",
+			possibleLimitingCondition: "ScaleDownLimit",
+			possibleLimitingReason:    "the desired replica count is decreasing faster than the maximum scale rate",
+			desiredReplicas:           2,
+			currentReplicas:           100,
+			normalizedReplicas:        95,
+			wpa:                       makeWPAScaleDownLimit(makeWPASpec(1, 500, 0, 50), 5),
+		},
+		{
+			name:                      "minReplicas above the absolute scaleDownLimit",
+			possibleLimitingCondition: "ScaleDownLimit",
+			possibleLimitingReason:    "the desired replica count is decreasing faster than the maximum scale rate",
+			desiredReplicas:           2,
+			currentReplicas:           100,
+			normalizedReplicas:        98,
+			wpa:                       makeWPAScaleDownLimit(makeWPASpec(98, 500, 0, 50), 5),
+		},
+		{
+			name:                      "desiredReplicas above the absolute scaleUpLimit",
+			possibleLimitingCondition: "ScaleUpLimit",
+			possibleLimitingReason:    "the desired replica count is increasing faster than the maximum scale rate",
+			desiredReplicas:           300,
+			currentReplicas:           3,
+			normalizedReplicas:        5,
+			wpa:                       makeWPAScaleUpLimit(makeWPASpec(1, 500, 100, 0), 2),
+		},
+		{
+			name:                      "desiredReplicas above the scaleUpLimitFactor, below the absolute scaleUpLimit",
+			possibleLimitingCondition: "ScaleUpLimit",
+			possibleLimitingReason:    "the desired replica count is increasing faster than the maximum scale rate",
+			desiredReplicas:           300,
+			currentReplicas:           3,
+			normalizedReplicas:        4,
+			wpa:                       makeWPAScaleUpLimit(makeWPASpec(1, 500, 50, 20), 10),
+		},
+		{
+			name:                      "scale down to zero",
+			possibleLimitingCondition: "DesiredWithinRange",
+			possibleLimitingReason:    "the desired count is within the acceptable range",
+			desiredReplicas:           0,
+			currentReplicas:           1,
+			normalizedReplicas:        0,
+			wpa:                       makeWPAScaleToZero(makeWPASpec(0, 10, 50, 60), 0),
+		},
+		{
+			name:                      "scale up from zero",
+			possibleLimitingCondition: "DesiredWithinRange",
+			possibleLimitingReason:    "the desired count is within the acceptable range",
+			desiredReplicas:           4,
+			currentReplicas:           0,
+			normalizedReplicas:        4,
+			wpa:                       makeWPAScaleToZero(makeWPASpec(0, 10, 50, 60), 4),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1257,6 +4521,59 @@ func TestConvertDesiredReplicasWithRules(t *testing.T) {
 	}
 }
 
+func TestConvertDesiredReplicasWithRulesScaleUpLimited(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	wpa := makeWPAScaleUpLimit(makeWPASpec(1, 10, 100, 0), 2)
+	wpa.Name = testingWPAName
+	wpa.Namespace = testingNamespace
+	wpa.Spec.ScaleTargetRef = v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testingDeployName}
+	promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}
+	defer cleanupAssociatedMetrics(wpa, false)
+
+	// capped by the scaleUpLimit.
+	des, _, _ := convertDesiredReplicasWithRules(logf.Log.WithName(t.Name()), wpa, 3, 8, *wpa.Spec.MinReplicas, wpa.Spec.MaxReplicas)
+	assert.Equal(t, int32(5), des)
+	assert.Equal(t, float64(1), testutil.ToFloat64(scaleUpLimited.With(promLabels)))
+
+	// capped by the maxReplicas, the limit was not hit.
+	des, _, _ = convertDesiredReplicasWithRules(logf.Log.WithName(t.Name()), wpa, 9, 15, *wpa.Spec.MinReplicas, wpa.Spec.MaxReplicas)
+	assert.Equal(t, int32(10), des)
+	assert.Equal(t, float64(1), testutil.ToFloat64(scaleUpLimited.With(promLabels)))
+
+	// within the limit.
+	des, _, _ = convertDesiredReplicasWithRules(logf.Log.WithName(t.Name()), wpa, 3, 4, *wpa.Spec.MinReplicas, wpa.Spec.MaxReplicas)
+	assert.Equal(t, int32(4), des)
+	assert.Equal(t, float64(1), testutil.ToFloat64(scaleUpLimited.With(promLabels)))
+}
+
+func TestConvertDesiredReplicasWithRulesScaleDownLimited(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+
+	wpa := makeWPAScaleDownLimit(makeWPASpec(1, 100, 0, 50), 5)
+	wpa.Name = testingWPAName
+	wpa.Namespace = testingNamespace
+	wpa.Spec.ScaleTargetRef = v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: testingDeployName}
+	promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}
+	defer cleanupAssociatedMetrics(wpa, false)
+
+	// capped by the scaleDownLimit.
+	des, _, _ := convertDesiredReplicasWithRules(logf.Log.WithName(t.Name()), wpa, 50, 1, *wpa.Spec.MinReplicas, wpa.Spec.MaxReplicas)
+	assert.Equal(t, int32(45), des)
+	assert.Equal(t, float64(1), testutil.ToFloat64(scaleDownLimited.With(promLabels)))
+
+	// capped by the minReplicas, the limit was not hit.
+	*wpa.Spec.MinReplicas = 48
+	des, _, _ = convertDesiredReplicasWithRules(logf.Log.WithName(t.Name()), wpa, 50, 1, *wpa.Spec.MinReplicas, wpa.Spec.MaxReplicas)
+	assert.Equal(t, int32(48), des)
+	assert.Equal(t, float64(1), testutil.ToFloat64(scaleDownLimited.With(promLabels)))
+
+	// within the limit.
+	des, _, _ = convertDesiredReplicasWithRules(logf.Log.WithName(t.Name()), wpa, 50, 49, *wpa.Spec.MinReplicas, wpa.Spec.MaxReplicas)
+	assert.Equal(t, int32(49), des)
+	assert.Equal(t, float64(1), testutil.ToFloat64(scaleDownLimited.With(promLabels)))
+}
+
 func newScaleForDeployment(replicasSpec, replicasStatus int32) *autoscalingv1.Scale {
 	return &autoscalingv1.Scale{
 		TypeMeta: metav1.TypeMeta{Kind: "Scale"},
@@ -1272,3 +4589,200 @@ func newScaleForDeployment(replicasSpec, replicasStatus int32) *autoscalingv1.Sc
 		},
 	}
 }
+
+func TestGetSyncPeriod(t *testing.T) {
+	newWPA := func(reconcileIntervalSeconds int32) *v1alpha1.WatermarkPodAutoscaler {
+		return test.NewWatermarkPodAutoscaler(testingNamespace, testingWPAName, &test.NewWatermarkPodAutoscalerOptions{
+			Spec: &v1alpha1.WatermarkPodAutoscalerSpec{
+				ScaleTargetRef:           testCrossVersionObjectRef,
+				ReconcileIntervalSeconds: reconcileIntervalSeconds,
+			},
+		})
+	}
+
+	assert.Equal(t, defaultSyncPeriod, getSyncPeriod(newWPA(0), defaultSyncPeriod))
+
+	short := getSyncPeriod(newWPA(5), defaultSyncPeriod)
+	long := getSyncPeriod(newWPA(120), defaultSyncPeriod)
+	assert.Equal(t, 5*time.Second, short)
+	assert.Equal(t, 120*time.Second, long)
+	assert.True(t, short < long)
+}
+
+func TestApplyScaleDirection(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	tests := []struct {
+		name            string
+		scaleDirection  string
+		desiredReplicas int32
+		expected        int32
+		blocked         string
+	}{
+		{
+			name:            "both directions allow scaling up",
+			desiredReplicas: 8,
+			expected:        8,
+		},
+		{
+			name:            "both directions allow scaling down",
+			scaleDirection:  "both",
+			desiredReplicas: 3,
+			expected:        3,
+		},
+		{
+			name:            "scale up only allows scaling up",
+			scaleDirection:  "up",
+			desiredReplicas: 8,
+			expected:        8,
+		},
+		{
+			name:            "scale up only blocks scaling down",
+			scaleDirection:  "up",
+			desiredReplicas: 3,
+			expected:        5,
+			blocked:         downPromLabelVal,
+		},
+		{
+			name:            "scale down only blocks scaling up",
+			scaleDirection:  "down",
+			desiredReplicas: 8,
+			expected:        5,
+			blocked:         upPromLabelVal,
+		},
+		{
+			name:            "scale down only allows scaling down",
+			scaleDirection:  "down",
+			desiredReplicas: 3,
+			expected:        3,
+		},
+		{
+			name:            "not scaling is never blocked",
+			scaleDirection:  "up",
+			desiredReplicas: 5,
+			expected:        5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa := &v1alpha1.WatermarkPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: testingWPAName, Namespace: testingNamespace},
+				Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+					ScaleTargetRef: testCrossVersionObjectRef,
+					ScaleDirection: tt.scaleDirection,
+				},
+			}
+			defer cleanupAssociatedMetrics(wpa, false)
+			assert.Equal(t, tt.expected, applyScaleDirection(logf.Log.WithName(tt.name), wpa, 5, tt.desiredReplicas))
+			for _, direction := range scaleDirectionValues {
+				expected := float64(0)
+				if direction == tt.blocked {
+					expected = 1
+				}
+				promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, directionPromLabel: direction, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}
+				assert.Equal(t, expected, testutil.ToFloat64(scaleBlocked.With(promLabels)), direction)
+			}
+		})
+	}
+}
+
+func TestApplyMinReplicaChange(t *testing.T) {
+	logf.SetLogger(logf.ZapLogger(true))
+	tests := []struct {
+		name             string
+		minReplicaChange int32
+		currentReplicas  int32
+		desiredReplicas  int32
+		expected         int32
+	}{
+		{
+			name:            "disabled",
+			currentReplicas: 10,
+			desiredReplicas: 11,
+			expected:        11,
+		},
+		{
+			name:             "scale up just under the threshold",
+			minReplicaChange: 2,
+			currentReplicas:  10,
+			desiredReplicas:  11,
+			expected:         10,
+		},
+		{
+			name:             "scale up at the threshold",
+			minReplicaChange: 2,
+			currentReplicas:  10,
+			desiredReplicas:  12,
+			expected:         12,
+		},
+		{
+			name:             "scale up over the threshold",
+			minReplicaChange: 2,
+			currentReplicas:  10,
+			desiredReplicas:  13,
+			expected:         13,
+		},
+		{
+			name:             "scale down just under the threshold",
+			minReplicaChange: 3,
+			currentReplicas:  10,
+			desiredReplicas:  8,
+			expected:         10,
+		},
+		{
+			name:             "scale down at the threshold",
+			minReplicaChange: 3,
+			currentReplicas:  10,
+			desiredReplicas:  7,
+			expected:         7,
+		},
+		{
+			name:             "scale down over the threshold",
+			minReplicaChange: 3,
+			currentReplicas:  10,
+			desiredReplicas:  6,
+			expected:         6,
+		},
+		{
+			name:             "not scaling",
+			minReplicaChange: 2,
+			currentReplicas:  10,
+			desiredReplicas:  10,
+			expected:         10,
+		},
+		{
+			name:             "scale up from zero",
+			minReplicaChange: 2,
+			currentReplicas:  0,
+			desiredReplicas:  1,
+			expected:         1,
+		},
+		{
+			name:             "scale down to zero",
+			minReplicaChange: 2,
+			currentReplicas:  1,
+			desiredReplicas:  0,
+			expected:         0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa := &v1alpha1.WatermarkPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: testingWPAName, Namespace: testingNamespace},
+				Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+					ScaleTargetRef:   testCrossVersionObjectRef,
+					MinReplicaChange: tt.minReplicaChange,
+				},
+			}
+			defer cleanupAssociatedMetrics(wpa, false)
+			promLabels := prometheus.Labels{wpaNamePromLabel: wpa.Name, resourceNamespacePromLabel: wpa.Namespace, resourceNamePromLabel: wpa.Spec.ScaleTargetRef.Name, resourceKindPromLabel: wpa.Spec.ScaleTargetRef.Kind}
+			assert.Equal(t, tt.expected, applyMinReplicaChange(logf.Log.WithName(tt.name), wpa, tt.currentReplicas, tt.desiredReplicas))
+			if tt.expected != tt.desiredReplicas {
+				assert.Equal(t, v1alpha1.DecisionReasonBelowMinReplicaChange, wpa.Status.LastDecisionReason)
+				assert.Equal(t, float64(1), testutil.ToFloat64(changeSuppressed.With(promLabels)))
+			} else {
+				assert.Empty(t, wpa.Status.LastDecisionReason)
+				assert.Equal(t, float64(0), testutil.ToFloat64(changeSuppressed.With(promLabels)))
+			}
+		})
+	}
+}
@@ -8,10 +8,16 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -19,10 +25,12 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	ctrlzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
 
 	datadoghqv1alpha1 "github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
 	"github.com/DataDog/watermarkpodautoscaler/controllers"
 	"github.com/DataDog/watermarkpodautoscaler/pkg/config"
+	"github.com/DataDog/watermarkpodautoscaler/pkg/convert"
 	"github.com/DataDog/watermarkpodautoscaler/pkg/version"
 	// +kubebuilder:scaffold:imports
 )
@@ -47,12 +55,30 @@ func main() {
 	var enableLeaderElection bool
 	var printVersionArg bool
 	var logEncoder string
+	var enableWebhooks bool
+	var requeueJitterPercent int
+	var maxConcurrentReconciles int
+	var scaleApprovalTimeout time.Duration
+	var scaleApprovalAllowedHosts string
+	var metricFetchTimeout time.Duration
+	var convertHPAPath, convertHPARequests, convertHPAPodSelector string
+	var convertHPABand float64
 	flag.BoolVar(&printVersionArg, "version", false, "print version and exit")
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", true,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
 	flag.IntVar(&healthPort, "health-port", healthPort, "Port to use for the health probe")
 	flag.StringVar(&logEncoder, "logEncoder", "json", "log encoding ('json' or 'console')")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false, "Enable the validating webhook of the WatermarkPodAutoscaler. It requires the webhook server certificates.")
+	flag.IntVar(&requeueJitterPercent, "requeue-jitter-percent", 10, "Maximum random jitter added to or removed from the interval between two reconcile cycles of a WPA, as a percentage of the interval (between 0 and 100). The jitter never brings the interval below 5 seconds.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1, "Maximum number of WatermarkPodAutoscalers reconciled at the same time, so that a slow metrics provider doesn't hold back the other WPAs.")
+	flag.DurationVar(&scaleApprovalTimeout, "scale-approval-timeout", 5*time.Second, "Time given to the scale approval webhook of a WatermarkPodAutoscaler to answer, the scaling change is not applied after it.")
+	flag.StringVar(&scaleApprovalAllowedHosts, "scale-approval-webhook-allowed-hosts", "", "Comma-separated hosts (e.g. approval.svc.cluster.local or approval.svc.cluster.local:8443) the scale approval webhooks of the WatermarkPodAutoscalers can be sent to. The changes of the WPAs with a webhook on another host are vetoed, none is allowed by default.")
+	flag.DurationVar(&metricFetchTimeout, "metric-fetch-timeout", 30*time.Second, "Time given to the metrics providers to answer a query, the metric is considered unavailable after it.")
+	flag.StringVar(&convertHPAPath, "convert-hpa", "", "Print the WatermarkPodAutoscaler equivalent to the HorizontalPodAutoscaler (autoscaling/v2beta2) of the given file and exit.")
+	flag.Float64Var(&convertHPABand, "convert-hpa-band", convert.DefaultBand, "Width of the band between the watermarks of the converted metrics, as a fraction of their target.")
+	flag.StringVar(&convertHPARequests, "convert-hpa-requests", "", "Requests of a pod of the target of the converted HPA (e.g. cpu=500m,memory=1Gi), to convert the utilization targets.")
+	flag.StringVar(&convertHPAPodSelector, "convert-hpa-pod-selector", "", "Label selector of the pods of the target of the converted HPA (e.g. app=foo), to convert the resource metrics.")
 	logLevel := zap.LevelFlag("loglevel", zapcore.InfoLevel, "Set log level")
 
 	flag.Parse()
@@ -65,7 +91,31 @@ func main() {
 		version.PrintVersionWriter(os.Stdout)
 		os.Exit(0)
 	}
+	if convertHPAPath != "" {
+		if err := convertHPA(os.Stdout, convertHPAPath, convertHPABand, convertHPARequests, convertHPAPodSelector); err != nil {
+			setupLog.Error(err, "unable to convert the HorizontalPodAutoscaler", "path", convertHPAPath)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 	version.PrintVersionLogs(setupLog)
+	if requeueJitterPercent < 0 || requeueJitterPercent > 100 {
+		setupLog.Error(fmt.Errorf("invalid requeue jitter percent: %d", requeueJitterPercent), "the requeue jitter percent should be between 0 and 100")
+		os.Exit(1)
+	}
+	if maxConcurrentReconciles < 1 {
+		setupLog.Error(fmt.Errorf("invalid max concurrent reconciles: %d", maxConcurrentReconciles), "the max concurrent reconciles should be at least 1")
+		os.Exit(1)
+	}
+
+	if scaleApprovalTimeout <= 0 {
+		setupLog.Error(fmt.Errorf("invalid scale approval timeout: %v", scaleApprovalTimeout), "the scale approval timeout should be strictly positive")
+		os.Exit(1)
+	}
+	if metricFetchTimeout <= 0 {
+		setupLog.Error(fmt.Errorf("invalid metric fetch timeout: %v", metricFetchTimeout), "the metric fetch timeout should be strictly positive")
+		os.Exit(1)
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), config.ManagerOptionsWithNamespaces(setupLog, ctrl.Options{
 		Scheme:                 scheme,
@@ -81,13 +131,24 @@ func main() {
 	}
 
 	if err = (&controllers.WatermarkPodAutoscalerReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("WatermarkPodAutoscaler"),
-		Scheme: mgr.GetScheme(),
+		Client:                    mgr.GetClient(),
+		Log:                       ctrl.Log.WithName("controllers").WithName("WatermarkPodAutoscaler"),
+		Scheme:                    mgr.GetScheme(),
+		RequeueJitterPercent:      requeueJitterPercent,
+		MaxConcurrentReconciles:   maxConcurrentReconciles,
+		ScaleApprovalTimeout:      scaleApprovalTimeout,
+		ScaleApprovalAllowedHosts: splitAllowedHosts(scaleApprovalAllowedHosts),
+		MetricFetchTimeout:        metricFetchTimeout,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "WatermarkPodAutoscaler")
 		os.Exit(1)
 	}
+	if enableWebhooks {
+		if err = (&datadoghqv1alpha1.WatermarkPodAutoscaler{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "WatermarkPodAutoscaler")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("health-probe", healthz.Ping); err != nil {
@@ -102,6 +163,48 @@ func main() {
 	}
 }
 
+// convertHPA writes the WatermarkPodAutoscaler equivalent to the HorizontalPodAutoscaler of the file, in YAML.
+func convertHPA(w io.Writer, path string, band float64, requests, podSelector string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	hpa := &autoscalingv2beta2.HorizontalPodAutoscaler{}
+	if err = yaml.Unmarshal(data, hpa); err != nil {
+		return fmt.Errorf("unable to parse the HorizontalPodAutoscaler: %v", err)
+	}
+	opts := convert.Options{Band: band}
+	if opts.Requests, err = convert.ParseRequests(requests); err != nil {
+		return err
+	}
+	if podSelector != "" {
+		if opts.PodSelector, err = metav1.ParseToLabelSelector(podSelector); err != nil {
+			return fmt.Errorf("invalid pod selector: %v", err)
+		}
+	}
+	wpa, err := convert.HPAToWPA(hpa, opts)
+	if err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(wpa)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// splitAllowedHosts returns the hosts of a comma-separated list, leaving out the empty ones.
+func splitAllowedHosts(hosts string) []string {
+	var allowedHosts []string
+	for _, host := range strings.Split(hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			allowedHosts = append(allowedHosts, host)
+		}
+	}
+	return allowedHosts
+}
+
 func customSetupLogging(logLevel zapcore.Level, logEncoder string) error {
 	var encoder zapcore.Encoder
 	switch logEncoder {
@@ -0,0 +1,255 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package convert converts HorizontalPodAutoscalers into equivalent WatermarkPodAutoscalers.
+package convert
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
+
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultBand is the default width of the band between the watermarks, as a fraction of the target of the HPA.
+const DefaultBand = 0.2
+
+// Options configures the conversion of a HorizontalPodAutoscaler.
+type Options struct {
+	// Band is the width of the band between the watermarks, as a fraction of the target of the HPA.
+	// The watermarks are centered on the target: a band of 0.2 puts them 10% below and 10% above it.
+	Band float64
+	// Requests are the requests of a pod of the target, needed to convert the Utilization targets of the resource metrics.
+	Requests corev1.ResourceList
+	// PodSelector selects the pods of the target, needed to convert the resource metrics.
+	PodSelector *metav1.LabelSelector
+}
+
+// HPAToWPA returns the WatermarkPodAutoscaler equivalent to the HorizontalPodAutoscaler, with watermarks around the targets of its metrics.
+// The algorithm is shared by the resource and object metrics, so their targets should all be either values or average values.
+func HPAToWPA(hpa *autoscalingv2beta2.HorizontalPodAutoscaler, opts Options) (*v1alpha1.WatermarkPodAutoscaler, error) {
+	if opts.Band <= 0 || opts.Band >= 2 {
+		return nil, fmt.Errorf("the band should be between 0 and 2 (exc.), currently set to : %v", opts.Band)
+	}
+	wpa := &v1alpha1.WatermarkPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       "WatermarkPodAutoscaler",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        hpa.Name,
+			Namespace:   hpa.Namespace,
+			Labels:      hpa.Labels,
+			Annotations: hpa.Annotations,
+		},
+		Spec: v1alpha1.WatermarkPodAutoscalerSpec{
+			ScaleTargetRef: v1alpha1.CrossVersionObjectReference{
+				Kind:       hpa.Spec.ScaleTargetRef.Kind,
+				Name:       hpa.Spec.ScaleTargetRef.Name,
+				APIVersion: hpa.Spec.ScaleTargetRef.APIVersion,
+			},
+			MinReplicas: hpa.Spec.MinReplicas,
+			MaxReplicas: hpa.Spec.MaxReplicas,
+		},
+	}
+	if behavior := hpa.Spec.Behavior; behavior != nil {
+		if behavior.ScaleUp != nil && behavior.ScaleUp.StabilizationWindowSeconds != nil {
+			wpa.Spec.UpscaleStabilizationWindowSeconds = *behavior.ScaleUp.StabilizationWindowSeconds
+		}
+		if behavior.ScaleDown != nil && behavior.ScaleDown.StabilizationWindowSeconds != nil {
+			wpa.Spec.DownscaleStabilizationWindowSeconds = *behavior.ScaleDown.StabilizationWindowSeconds
+		}
+	}
+
+	for i, metric := range hpa.Spec.Metrics {
+		converted, algorithm, err := convertMetric(metric, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert the metric %d of %s/%s: %v", i, hpa.Namespace, hpa.Name, err)
+		}
+		if algorithm != "" {
+			if wpa.Spec.Algorithm != "" && wpa.Spec.Algorithm != algorithm {
+				return nil, fmt.Errorf("unable to convert the metric %d of %s/%s: the resource and object metrics should all target either values or average values", i, hpa.Namespace, hpa.Name)
+			}
+			wpa.Spec.Algorithm = algorithm
+		}
+		wpa.Spec.Metrics = append(wpa.Spec.Metrics, converted)
+	}
+	return wpa, nil
+}
+
+// convertMetric returns the WPA metric equivalent to the metric of an HPA, and the algorithm of the WPA it requires if any.
+func convertMetric(metric autoscalingv2beta2.MetricSpec, opts Options) (v1alpha1.MetricSpec, string, error) {
+	switch metric.Type {
+	case autoscalingv2beta2.ExternalMetricSourceType:
+		if metric.External == nil {
+			return v1alpha1.MetricSpec{}, "", fmt.Errorf("missing external metric source")
+		}
+		target, err := getTargetValue(metric.External.Target)
+		if err != nil {
+			return v1alpha1.MetricSpec{}, "", err
+		}
+		lowMark, highMark, err := getWatermarks(target, opts.Band)
+		if err != nil {
+			return v1alpha1.MetricSpec{}, "", err
+		}
+		// the target type of an external metric overrides the algorithm of the WPA.
+		return v1alpha1.MetricSpec{
+			Type: v1alpha1.ExternalMetricSourceType,
+			External: &v1alpha1.ExternalMetricSource{
+				MetricName:     metric.External.Metric.Name,
+				MetricSelector: metric.External.Metric.Selector,
+				HighWatermark:  highMark,
+				LowWatermark:   lowMark,
+				TargetType:     string(metric.External.Target.Type),
+			},
+		}, "", nil
+	case autoscalingv2beta2.ResourceMetricSourceType:
+		if metric.Resource == nil {
+			return v1alpha1.MetricSpec{}, "", fmt.Errorf("missing resource metric source")
+		}
+		if opts.PodSelector == nil {
+			return v1alpha1.MetricSpec{}, "", fmt.Errorf("the pod selector is needed to convert the resource metric %s", metric.Resource.Name)
+		}
+		target, err := getResourceTargetValue(metric.Resource, opts.Requests)
+		if err != nil {
+			return v1alpha1.MetricSpec{}, "", err
+		}
+		lowMark, highMark, err := getWatermarks(target, opts.Band)
+		if err != nil {
+			return v1alpha1.MetricSpec{}, "", err
+		}
+		// the targets of the resource metrics are per pod.
+		return v1alpha1.MetricSpec{
+			Type: v1alpha1.ResourceMetricSourceType,
+			Resource: &v1alpha1.ResourceMetricSource{
+				Name:           metric.Resource.Name,
+				MetricSelector: opts.PodSelector,
+				HighWatermark:  highMark,
+				LowWatermark:   lowMark,
+			},
+		}, "average", nil
+	case autoscalingv2beta2.ObjectMetricSourceType:
+		if metric.Object == nil {
+			return v1alpha1.MetricSpec{}, "", fmt.Errorf("missing object metric source")
+		}
+		target, err := getTargetValue(metric.Object.Target)
+		if err != nil {
+			return v1alpha1.MetricSpec{}, "", err
+		}
+		lowMark, highMark, err := getWatermarks(target, opts.Band)
+		if err != nil {
+			return v1alpha1.MetricSpec{}, "", err
+		}
+		algorithm := "absolute"
+		if metric.Object.Target.Type == autoscalingv2beta2.AverageValueMetricType {
+			algorithm = "average"
+		}
+		return v1alpha1.MetricSpec{
+			Type: v1alpha1.ObjectMetricSourceType,
+			Object: &v1alpha1.ObjectMetricSource{
+				DescribedObject: v1alpha1.CrossVersionObjectReference{
+					Kind:       metric.Object.DescribedObject.Kind,
+					Name:       metric.Object.DescribedObject.Name,
+					APIVersion: metric.Object.DescribedObject.APIVersion,
+				},
+				MetricName:     metric.Object.Metric.Name,
+				MetricSelector: metric.Object.Metric.Selector,
+				HighWatermark:  highMark,
+				LowWatermark:   lowMark,
+			},
+		}, algorithm, nil
+	case autoscalingv2beta2.PodsMetricSourceType:
+		if metric.Pods == nil {
+			return v1alpha1.MetricSpec{}, "", fmt.Errorf("missing pods metric source")
+		}
+		target, err := getTargetValue(metric.Pods.Target)
+		if err != nil {
+			return v1alpha1.MetricSpec{}, "", err
+		}
+		lowMark, highMark, err := getWatermarks(target, opts.Band)
+		if err != nil {
+			return v1alpha1.MetricSpec{}, "", err
+		}
+		// the values of the pods metrics are averaged over the pods whatever the algorithm.
+		return v1alpha1.MetricSpec{
+			Type: v1alpha1.PodsMetricSourceType,
+			Pods: &v1alpha1.PodsMetricSource{
+				MetricName:     metric.Pods.Metric.Name,
+				MetricSelector: metric.Pods.Metric.Selector,
+				HighWatermark:  highMark,
+				LowWatermark:   lowMark,
+			},
+		}, "", nil
+	default:
+		return v1alpha1.MetricSpec{}, "", fmt.Errorf("unsupported metric type %q", metric.Type)
+	}
+}
+
+// getTargetValue returns the value or the average value targeted by an external or object metric.
+func getTargetValue(target autoscalingv2beta2.MetricTarget) (*resource.Quantity, error) {
+	switch {
+	case target.Type == autoscalingv2beta2.ValueMetricType && target.Value != nil:
+		return target.Value, nil
+	case target.Type == autoscalingv2beta2.AverageValueMetricType && target.AverageValue != nil:
+		return target.AverageValue, nil
+	default:
+		return nil, fmt.Errorf("unsupported target of type %q", target.Type)
+	}
+}
+
+// getResourceTargetValue returns the usage per pod targeted by a resource metric.
+// A Utilization target is a percentage of the request of a pod, which is taken from the requests.
+func getResourceTargetValue(metric *autoscalingv2beta2.ResourceMetricSource, requests corev1.ResourceList) (*resource.Quantity, error) {
+	target := metric.Target
+	switch {
+	case target.Type == autoscalingv2beta2.AverageValueMetricType && target.AverageValue != nil:
+		return target.AverageValue, nil
+	case target.Type == autoscalingv2beta2.UtilizationMetricType && target.AverageUtilization != nil:
+		request, found := requests[metric.Name]
+		if !found {
+			return nil, fmt.Errorf("the request of %s is needed to convert its utilization target", metric.Name)
+		}
+		return resource.NewMilliQuantity(request.MilliValue()*int64(*target.AverageUtilization)/100, request.Format), nil
+	default:
+		return nil, fmt.Errorf("unsupported target of type %q for the resource metric %s", target.Type, metric.Name)
+	}
+}
+
+// getWatermarks returns the watermarks centered on the target, the band between them being a fraction of the target.
+func getWatermarks(target *resource.Quantity, band float64) (lowMark, highMark *resource.Quantity, err error) {
+	milliValue := float64(target.MilliValue())
+	lowMark = resource.NewMilliQuantity(int64(math.Round(milliValue*(1-band/2))), target.Format)
+	highMark = resource.NewMilliQuantity(int64(math.Round(milliValue*(1+band/2))), target.Format)
+	if lowMark.MilliValue() >= highMark.MilliValue() {
+		return nil, nil, fmt.Errorf("the target %s is too small for a band of %v", target.String(), band)
+	}
+	return lowMark, highMark, nil
+}
+
+// ParseRequests parses requests formatted as a comma separated list of resource=quantity, e.g. cpu=500m,memory=1Gi.
+func ParseRequests(requests string) (corev1.ResourceList, error) {
+	list := corev1.ResourceList{}
+	if requests == "" {
+		return list, nil
+	}
+	for _, request := range strings.Split(requests, ",") {
+		parts := strings.SplitN(request, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid request %q, expected resource=quantity", request)
+		}
+		quantity, err := resource.ParseQuantity(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity for the request of %s: %v", parts[0], err)
+		}
+		list[corev1.ResourceName(parts[0])] = quantity
+	}
+	return list, nil
+}
@@ -0,0 +1,352 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package convert
+
+import (
+	"testing"
+
+	"github.com/DataDog/watermarkpodautoscaler/api/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newHPA(metrics ...autoscalingv2beta2.MetricSpec) *autoscalingv2beta2.HorizontalPodAutoscaler {
+	return &autoscalingv2beta2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar", Labels: map[string]string{"app": "foo"}},
+		Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{Kind: "Deployment", Name: "foo", APIVersion: "apps/v1"},
+			MinReplicas:    v1alpha1.NewInt32(2),
+			MaxReplicas:    10,
+			Metrics:        metrics,
+		},
+	}
+}
+
+func externalMetric(targetType autoscalingv2beta2.MetricTargetType, target *resource.Quantity) autoscalingv2beta2.MetricSpec {
+	metric := autoscalingv2beta2.MetricSpec{
+		Type: autoscalingv2beta2.ExternalMetricSourceType,
+		External: &autoscalingv2beta2.ExternalMetricSource{
+			Metric: autoscalingv2beta2.MetricIdentifier{
+				Name:     "queue_length",
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"queue": "jobs"}},
+			},
+			Target: autoscalingv2beta2.MetricTarget{Type: targetType},
+		},
+	}
+	if targetType == autoscalingv2beta2.ValueMetricType {
+		metric.External.Target.Value = target
+	} else {
+		metric.External.Target.AverageValue = target
+	}
+	return metric
+}
+
+func resourceMetric(target autoscalingv2beta2.MetricTarget) autoscalingv2beta2.MetricSpec {
+	return autoscalingv2beta2.MetricSpec{
+		Type:     autoscalingv2beta2.ResourceMetricSourceType,
+		Resource: &autoscalingv2beta2.ResourceMetricSource{Name: corev1.ResourceCPU, Target: target},
+	}
+}
+
+func objectMetric(targetType autoscalingv2beta2.MetricTargetType, target *resource.Quantity) autoscalingv2beta2.MetricSpec {
+	metric := autoscalingv2beta2.MetricSpec{
+		Type: autoscalingv2beta2.ObjectMetricSourceType,
+		Object: &autoscalingv2beta2.ObjectMetricSource{
+			DescribedObject: autoscalingv2beta2.CrossVersionObjectReference{Kind: "Ingress", Name: "main", APIVersion: "networking.k8s.io/v1beta1"},
+			Metric:          autoscalingv2beta2.MetricIdentifier{Name: "requests_per_second"},
+			Target:          autoscalingv2beta2.MetricTarget{Type: targetType},
+		},
+	}
+	if targetType == autoscalingv2beta2.ValueMetricType {
+		metric.Object.Target.Value = target
+	} else {
+		metric.Object.Target.AverageValue = target
+	}
+	return metric
+}
+
+func TestHPAToWPA(t *testing.T) {
+	podSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}}
+	opts := Options{
+		Band:        DefaultBand,
+		Requests:    corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+		PodSelector: podSelector,
+	}
+
+	tests := []struct {
+		name              string
+		hpa               *autoscalingv2beta2.HorizontalPodAutoscaler
+		opts              Options
+		expectedAlgorithm string
+		expectedMetrics   []v1alpha1.MetricSpec
+		expectedError     string
+	}{
+		{
+			name: "external metric with a value target",
+			hpa:  newHPA(externalMetric(autoscalingv2beta2.ValueMetricType, resource.NewQuantity(100, resource.DecimalSI))),
+			opts: opts,
+			expectedMetrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "queue_length",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"queue": "jobs"}},
+						HighWatermark:  resource.NewMilliQuantity(110000, resource.DecimalSI),
+						LowWatermark:   resource.NewMilliQuantity(90000, resource.DecimalSI),
+						TargetType:     "Value",
+					},
+				},
+			},
+		},
+		{
+			name: "external metric with an average value target and a wider band",
+			hpa:  newHPA(externalMetric(autoscalingv2beta2.AverageValueMetricType, resource.NewQuantity(30, resource.DecimalSI))),
+			opts: Options{Band: 1},
+			expectedMetrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "queue_length",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"queue": "jobs"}},
+						HighWatermark:  resource.NewMilliQuantity(45000, resource.DecimalSI),
+						LowWatermark:   resource.NewMilliQuantity(15000, resource.DecimalSI),
+						TargetType:     "AverageValue",
+					},
+				},
+			},
+		},
+		{
+			// 70% of a request of 500m is 350m.
+			name:              "resource metric with a utilization target",
+			hpa:               newHPA(resourceMetric(autoscalingv2beta2.MetricTarget{Type: autoscalingv2beta2.UtilizationMetricType, AverageUtilization: v1alpha1.NewInt32(70)})),
+			opts:              opts,
+			expectedAlgorithm: "average",
+			expectedMetrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ResourceMetricSourceType,
+					Resource: &v1alpha1.ResourceMetricSource{
+						Name:           corev1.ResourceCPU,
+						MetricSelector: podSelector,
+						HighWatermark:  resource.NewMilliQuantity(385, resource.DecimalSI),
+						LowWatermark:   resource.NewMilliQuantity(315, resource.DecimalSI),
+					},
+				},
+			},
+		},
+		{
+			name:              "resource metric with an average value target",
+			hpa:               newHPA(resourceMetric(autoscalingv2beta2.MetricTarget{Type: autoscalingv2beta2.AverageValueMetricType, AverageValue: resource.NewMilliQuantity(200, resource.DecimalSI)})),
+			opts:              opts,
+			expectedAlgorithm: "average",
+			expectedMetrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ResourceMetricSourceType,
+					Resource: &v1alpha1.ResourceMetricSource{
+						Name:           corev1.ResourceCPU,
+						MetricSelector: podSelector,
+						HighWatermark:  resource.NewMilliQuantity(220, resource.DecimalSI),
+						LowWatermark:   resource.NewMilliQuantity(180, resource.DecimalSI),
+					},
+				},
+			},
+		},
+		{
+			name:              "object metric with a value target",
+			hpa:               newHPA(objectMetric(autoscalingv2beta2.ValueMetricType, resource.NewQuantity(1000, resource.DecimalSI))),
+			opts:              opts,
+			expectedAlgorithm: "absolute",
+			expectedMetrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ObjectMetricSourceType,
+					Object: &v1alpha1.ObjectMetricSource{
+						DescribedObject: v1alpha1.CrossVersionObjectReference{Kind: "Ingress", Name: "main", APIVersion: "networking.k8s.io/v1beta1"},
+						MetricName:      "requests_per_second",
+						HighWatermark:   resource.NewMilliQuantity(1100000, resource.DecimalSI),
+						LowWatermark:    resource.NewMilliQuantity(900000, resource.DecimalSI),
+					},
+				},
+			},
+		},
+		{
+			name: "external and resource metrics",
+			hpa: newHPA(
+				externalMetric(autoscalingv2beta2.ValueMetricType, resource.NewQuantity(100, resource.DecimalSI)),
+				resourceMetric(autoscalingv2beta2.MetricTarget{Type: autoscalingv2beta2.UtilizationMetricType, AverageUtilization: v1alpha1.NewInt32(70)}),
+			),
+			opts:              opts,
+			expectedAlgorithm: "average",
+			expectedMetrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.ExternalMetricSourceType,
+					External: &v1alpha1.ExternalMetricSource{
+						MetricName:     "queue_length",
+						MetricSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"queue": "jobs"}},
+						HighWatermark:  resource.NewMilliQuantity(110000, resource.DecimalSI),
+						LowWatermark:   resource.NewMilliQuantity(90000, resource.DecimalSI),
+						TargetType:     "Value",
+					},
+				},
+				{
+					Type: v1alpha1.ResourceMetricSourceType,
+					Resource: &v1alpha1.ResourceMetricSource{
+						Name:           corev1.ResourceCPU,
+						MetricSelector: podSelector,
+						HighWatermark:  resource.NewMilliQuantity(385, resource.DecimalSI),
+						LowWatermark:   resource.NewMilliQuantity(315, resource.DecimalSI),
+					},
+				},
+			},
+		},
+		{
+			name: "resource and object metrics with different algorithms",
+			hpa: newHPA(
+				resourceMetric(autoscalingv2beta2.MetricTarget{Type: autoscalingv2beta2.UtilizationMetricType, AverageUtilization: v1alpha1.NewInt32(70)}),
+				objectMetric(autoscalingv2beta2.ValueMetricType, resource.NewQuantity(1000, resource.DecimalSI)),
+			),
+			opts:          opts,
+			expectedError: "should all target either values or average values",
+		},
+		{
+			name:          "utilization target without the request",
+			hpa:           newHPA(resourceMetric(autoscalingv2beta2.MetricTarget{Type: autoscalingv2beta2.UtilizationMetricType, AverageUtilization: v1alpha1.NewInt32(70)})),
+			opts:          Options{Band: DefaultBand, PodSelector: podSelector},
+			expectedError: "the request of cpu is needed",
+		},
+		{
+			name:          "resource metric without the pod selector",
+			hpa:           newHPA(resourceMetric(autoscalingv2beta2.MetricTarget{Type: autoscalingv2beta2.AverageValueMetricType, AverageValue: resource.NewMilliQuantity(200, resource.DecimalSI)})),
+			opts:          Options{Band: DefaultBand},
+			expectedError: "the pod selector is needed",
+		},
+		{
+			name: "pods metric",
+			hpa: newHPA(autoscalingv2beta2.MetricSpec{
+				Type: autoscalingv2beta2.PodsMetricSourceType,
+				Pods: &autoscalingv2beta2.PodsMetricSource{
+					Metric: autoscalingv2beta2.MetricIdentifier{Name: "requests_per_second"},
+					Target: autoscalingv2beta2.MetricTarget{Type: autoscalingv2beta2.AverageValueMetricType, AverageValue: resource.NewQuantity(10, resource.DecimalSI)},
+				},
+			}),
+			opts: opts,
+			expectedMetrics: []v1alpha1.MetricSpec{
+				{
+					Type: v1alpha1.PodsMetricSourceType,
+					Pods: &v1alpha1.PodsMetricSource{
+						MetricName:    "requests_per_second",
+						HighWatermark: resource.NewMilliQuantity(11000, resource.DecimalSI),
+						LowWatermark:  resource.NewMilliQuantity(9000, resource.DecimalSI),
+					},
+				},
+			},
+		},
+		{
+			name:          "unsupported metric type",
+			hpa:           newHPA(autoscalingv2beta2.MetricSpec{Type: "Unknown"}),
+			opts:          opts,
+			expectedError: `unsupported metric type "Unknown"`,
+		},
+		{
+			name:          "target too small for the band",
+			hpa:           newHPA(externalMetric(autoscalingv2beta2.ValueMetricType, resource.NewMilliQuantity(1, resource.DecimalSI))),
+			opts:          opts,
+			expectedError: "too small",
+		},
+		{
+			name:          "invalid band",
+			hpa:           newHPA(externalMetric(autoscalingv2beta2.ValueMetricType, resource.NewQuantity(100, resource.DecimalSI))),
+			opts:          Options{Band: 2},
+			expectedError: "the band should be between 0 and 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wpa, err := HPAToWPA(tt.hpa, tt.opts)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "WatermarkPodAutoscaler", wpa.Kind)
+			assert.Equal(t, "datadoghq.com/v1alpha1", wpa.APIVersion)
+			assert.Equal(t, tt.hpa.ObjectMeta, wpa.ObjectMeta)
+			assert.Equal(t, v1alpha1.CrossVersionObjectReference{Kind: "Deployment", Name: "foo", APIVersion: "apps/v1"}, wpa.Spec.ScaleTargetRef)
+			assert.Equal(t, int32(2), *wpa.Spec.MinReplicas)
+			assert.Equal(t, int32(10), wpa.Spec.MaxReplicas)
+			assert.Equal(t, tt.expectedAlgorithm, wpa.Spec.Algorithm)
+			require.Len(t, wpa.Spec.Metrics, len(tt.expectedMetrics))
+			for i, expected := range tt.expectedMetrics {
+				assertEqualMetrics(t, expected, wpa.Spec.Metrics[i])
+			}
+		})
+	}
+}
+
+// assertEqualMetrics compares the metrics with the milliValues of their watermarks, whatever the format of the quantities.
+func assertEqualMetrics(t *testing.T, expected, actual v1alpha1.MetricSpec) {
+	assert.Equal(t, expected.Type, actual.Type)
+	switch {
+	case expected.External != nil:
+		require.NotNil(t, actual.External)
+		assert.Equal(t, expected.External.MetricName, actual.External.MetricName)
+		assert.Equal(t, expected.External.MetricSelector, actual.External.MetricSelector)
+		assert.Equal(t, expected.External.TargetType, actual.External.TargetType)
+		assert.Equal(t, expected.External.HighWatermark.MilliValue(), actual.External.HighWatermark.MilliValue())
+		assert.Equal(t, expected.External.LowWatermark.MilliValue(), actual.External.LowWatermark.MilliValue())
+	case expected.Resource != nil:
+		require.NotNil(t, actual.Resource)
+		assert.Equal(t, expected.Resource.Name, actual.Resource.Name)
+		assert.Equal(t, expected.Resource.MetricSelector, actual.Resource.MetricSelector)
+		assert.Equal(t, expected.Resource.HighWatermark.MilliValue(), actual.Resource.HighWatermark.MilliValue())
+		assert.Equal(t, expected.Resource.LowWatermark.MilliValue(), actual.Resource.LowWatermark.MilliValue())
+	case expected.Object != nil:
+		require.NotNil(t, actual.Object)
+		assert.Equal(t, expected.Object.DescribedObject, actual.Object.DescribedObject)
+		assert.Equal(t, expected.Object.MetricName, actual.Object.MetricName)
+		assert.Equal(t, expected.Object.MetricSelector, actual.Object.MetricSelector)
+		assert.Equal(t, expected.Object.HighWatermark.MilliValue(), actual.Object.HighWatermark.MilliValue())
+		assert.Equal(t, expected.Object.LowWatermark.MilliValue(), actual.Object.LowWatermark.MilliValue())
+	case expected.Pods != nil:
+		require.NotNil(t, actual.Pods)
+		assert.Equal(t, expected.Pods.MetricName, actual.Pods.MetricName)
+		assert.Equal(t, expected.Pods.MetricSelector, actual.Pods.MetricSelector)
+		assert.Equal(t, expected.Pods.HighWatermark.MilliValue(), actual.Pods.HighWatermark.MilliValue())
+		assert.Equal(t, expected.Pods.LowWatermark.MilliValue(), actual.Pods.LowWatermark.MilliValue())
+	}
+}
+
+func TestHPAToWPABehavior(t *testing.T) {
+	hpa := newHPA(externalMetric(autoscalingv2beta2.ValueMetricType, resource.NewQuantity(100, resource.DecimalSI)))
+	hpa.Spec.Behavior = &autoscalingv2beta2.HorizontalPodAutoscalerBehavior{
+		ScaleUp:   &autoscalingv2beta2.HPAScalingRules{StabilizationWindowSeconds: v1alpha1.NewInt32(30)},
+		ScaleDown: &autoscalingv2beta2.HPAScalingRules{StabilizationWindowSeconds: v1alpha1.NewInt32(300)},
+	}
+	wpa, err := HPAToWPA(hpa, Options{Band: DefaultBand})
+	require.NoError(t, err)
+	assert.Equal(t, int32(30), wpa.Spec.UpscaleStabilizationWindowSeconds)
+	assert.Equal(t, int32(300), wpa.Spec.DownscaleStabilizationWindowSeconds)
+}
+
+func TestParseRequests(t *testing.T) {
+	requests, err := ParseRequests("cpu=500m,memory=1Gi")
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), requests.Cpu().MilliValue())
+	assert.Equal(t, int64(1<<30), requests.Memory().Value())
+
+	requests, err = ParseRequests("")
+	require.NoError(t, err)
+	assert.Empty(t, requests)
+
+	_, err = ParseRequests("cpu")
+	assert.Error(t, err)
+	_, err = ParseRequests("cpu=lots")
+	assert.Error(t, err)
+}